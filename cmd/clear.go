@@ -0,0 +1,182 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"strings"
+	"time"
+
+	"git-mini-commit/internal/git"
+	"git-mini-commit/internal/types"
+
+	"github.com/spf13/cobra"
+)
+
+var clearCmd = &cobra.Command{
+	Use:   "clear",
+	Short: "Remove all mini-commits, or those matching the given filters",
+	Long: `Remove all mini-commits from the stack, moving them to the trash. The
+removal can be undone with "undo" or "trash restore".
+
+--integrated-only limits the removal to mini-commits whose changes are already
+in HEAD, and --older-than to those created before the given date; it accepts the
+same values as list --since ("2.weeks", "36h", "2025-01-31"). Both can be combined.
+
+The command asks for confirmation unless --force is given. --json and
+--porcelain never prompt and therefore require --force.`,
+	Args: exactArgs(0),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		mode, err := outputMode(cmd)
+		if err != nil {
+			return err
+		}
+
+		force, _ := cmd.Flags().GetBool("force")
+		dryRun, _ := cmd.Flags().GetBool("dry-run")
+		integratedOnly, _ := cmd.Flags().GetBool("integrated-only")
+		olderThan, _ := cmd.Flags().GetString("older-than")
+		if mode != outputHuman && !force && !dryRun {
+			return newCommandError(codeUsage, "--force is required with --json or --porcelain")
+		}
+
+		var cutoff time.Time
+		if olderThan != "" {
+			cutoff, err = parseApproxDate(olderThan, time.Now())
+			if err != nil {
+				return newCommandError(codeUsage, "invalid --older-than: %v", err)
+			}
+		}
+
+		store, err := openStore()
+		if err != nil {
+			return err
+		}
+
+		var head string
+		if integratedOnly {
+			head, err = git.HeadCommit()
+			if err != nil {
+				return gitError(err)
+			}
+		}
+
+		// Mini-commits already in HEAD reverse-apply cleanly on top of it;
+		// before the first commit nothing can be integrated. Patches are
+		// streamed into git one at a time, so none is held in memory.
+		match := func(mc *types.MiniCommit) (bool, error) {
+			if olderThan != "" && !mc.CreatedAt.Before(cutoff) {
+				return false, nil
+			}
+			if !integratedOnly {
+				return true, nil
+			}
+			if head == "" {
+				return false, nil
+			}
+			p, err := store.OpenPatch(mc)
+			if err != nil {
+				return false, storageError(err, "failed to load mini-commit")
+			}
+			defer p.Close()
+			return git.CheckPatchAgainstFrom(head, p, true) == nil, nil
+		}
+
+		miniCommits, err := store.LoadMiniCommits()
+		if err != nil {
+			return storageError(err, "failed to load mini-commits")
+		}
+		var candidates types.MiniCommitList
+		for i := range miniCommits {
+			matched, err := match(&miniCommits[i])
+			if err != nil {
+				return err
+			}
+			if matched {
+				candidates = append(candidates, miniCommits[i])
+			}
+		}
+
+		out := cmd.OutOrStdout()
+		if len(candidates) == 0 {
+			return writeCleared(cmd, mode, nil, false)
+		}
+		if dryRun {
+			return writeCleared(cmd, mode, candidates, true)
+		}
+
+		if !force {
+			fmt.Fprintf(out, "This will remove %d mini-commit%s:\n", len(candidates), pluralS(len(candidates)))
+			for _, mc := range candidates {
+				fmt.Fprintf(out, "  %s %s\n", shortID(mc.ID), subject(mc.Message))
+			}
+			fmt.Fprintf(out, "Continue? [y/N] ")
+
+			answer, _ := bufio.NewReader(cmd.InOrStdin()).ReadString('\n')
+			answer = strings.ToLower(strings.TrimSpace(answer))
+			if answer != "y" && answer != "yes" {
+				fmt.Fprintln(out)
+				return newCommandError(codeAborted, "aborted; no mini-commits were removed")
+			}
+		}
+
+		// Only the mini-commits listed are removed, even if others were created
+		// meanwhile by another process
+		ids := make([]string, len(candidates))
+		for i, mc := range candidates {
+			ids[i] = mc.ID
+		}
+		removed, err := store.ClearMiniCommits(ids)
+		if err != nil {
+			return storageError(err, "failed to clear mini-commits")
+		}
+		return writeCleared(cmd, mode, removed, false)
+	},
+}
+
+// writeCleared reports the mini-commits removed (or, with dryRun, that would be removed) by clear
+func writeCleared(cmd *cobra.Command, mode string, removed types.MiniCommitList, dryRun bool) error {
+	out := cmd.OutOrStdout()
+	action := "cleared"
+	if dryRun {
+		action = "would-clear"
+	}
+
+	switch mode {
+	case outputJSON:
+		docs := make([]jsonMiniCommit, 0, len(removed))
+		for i := range removed {
+			docs = append(docs, toJSON(&removed[i], false))
+		}
+		return writeJSON(out, jsonDocument{Action: "clear", MiniCommits: &docs})
+	case outputPorcelainV1:
+		for i := range removed {
+			writePorcelainAction(out, action, &removed[i])
+		}
+		return nil
+	}
+
+	if len(removed) == 0 {
+		fmt.Fprintln(out, "No mini-commits to remove")
+		return nil
+	}
+	if dryRun {
+		fmt.Fprintf(out, "Would remove %d mini-commit%s:\n", len(removed), pluralS(len(removed)))
+	} else {
+		fmt.Fprintf(out, "Removed %d mini-commit%s:\n", len(removed), pluralS(len(removed)))
+	}
+	for _, mc := range removed {
+		fmt.Fprintf(out, "  %s %s\n", shortID(mc.ID), subject(mc.Message))
+	}
+	if !dryRun {
+		fmt.Fprintln(out, `Use "git mini-commit undo" or "git mini-commit trash restore <hash>" to bring them back.`)
+	}
+	return nil
+}
+
+func init() {
+	clearCmd.Flags().BoolP("force", "f", false, "remove without asking for confirmation")
+	clearCmd.Flags().BoolP("dry-run", "n", false, "only show what would be removed")
+	clearCmd.Flags().Bool("integrated-only", false, "only remove mini-commits whose changes are already in HEAD")
+	clearCmd.Flags().String("older-than", "", "only remove mini-commits created before this date")
+	rootCmd.AddCommand(clearCmd)
+}
@@ -0,0 +1,92 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"git-mini-commit/testutils"
+)
+
+func TestCLIClear(t *testing.T) {
+	// テスト用Gitリポジトリを作成
+	repo := testutils.NewTestGitRepo(t)
+	defer repo.Cleanup()
+
+	// テスト用CLIを作成
+	cli := testutils.NewTestCLI(t)
+	cli.SetRepo(repo)
+
+	// 1. コミット済みになるmini-commitと未コミットのmini-commitを作成
+	integrated := saveMiniCommit(t, repo, cli, "a.txt", "one\n", "Integrated")
+	if err := repo.CommitFile("Commit a.txt"); err != nil {
+		t.Fatalf("Failed to commit: %v", err)
+	}
+	pending := saveMiniCommit(t, repo, cli, "b.txt", "two\n", "Pending")
+
+	// 2. 確認で拒否すると何も削除しない
+	cli.SetStdin("n\n")
+	output := cli.AssertCommandFailure(t, "clear")
+	cli.AssertOutputContains(t, output, "This will remove 2 mini-commits")
+	cli.AssertOutputContains(t, output, "aborted")
+	output = cli.AssertCommandSuccess(t, "list", "--porcelain")
+	if strings.Count(output, "\n") != 2 {
+		t.Errorf("Expected both mini-commits to remain, but got: %s", output)
+	}
+
+	// 3. 確認なしの機械可読出力は --force が必要
+	cli.SetStdin("")
+	output = cli.AssertCommandFailure(t, "clear", "--porcelain")
+	cli.AssertOutputContains(t, output, "--force")
+
+	// 4. --dry-run は削除対象を表示するだけ
+	output = cli.AssertCommandSuccess(t, "clear", "--integrated-only", "--dry-run", "--porcelain")
+	if output != "would-clear "+integrated+"\n" {
+		t.Errorf("Unexpected --dry-run output: %q", output)
+	}
+
+	// 5. --integrated-only はHEADに含まれるものだけを削除
+	output = cli.AssertCommandSuccess(t, "clear", "--integrated-only", "--force")
+	cli.AssertOutputContains(t, output, "Removed 1 mini-commit:")
+	cli.AssertOutputContains(t, output, "Integrated")
+	output = cli.AssertCommandSuccess(t, "list", "--porcelain")
+	if !strings.HasPrefix(output, pending+" ") || strings.Contains(output, integrated) {
+		t.Errorf("Expected only the pending mini-commit to remain, but got: %s", output)
+	}
+
+	// 6. --older-than に一致しない場合は何もしない
+	output = cli.AssertCommandSuccess(t, "clear", "--older-than", "1.day", "--force")
+	cli.AssertOutputContains(t, output, "No mini-commits to remove")
+
+	// 7. 確認で承認すると全て削除し、ゴミ箱に移動
+	cli.SetStdin("y\n")
+	output = cli.AssertCommandSuccess(t, "clear")
+	cli.AssertOutputContains(t, output, "Removed 1 mini-commit:")
+	output = cli.AssertCommandSuccess(t, "trash", "--porcelain")
+	if !strings.Contains(output, pending+" ") || !strings.Contains(output, " clear ") {
+		t.Errorf("Expected the cleared mini-commit in the trash, but got: %s", output)
+	}
+
+	// 8. undo で元に戻せる
+	cli.SetStdin("")
+	cli.AssertCommandSuccess(t, "undo")
+	output = cli.AssertCommandSuccess(t, "list", "--porcelain")
+	if !strings.HasPrefix(output, pending+" ") {
+		t.Errorf("Expected undo to restore the cleared mini-commit, but got: %s", output)
+	}
+
+	// 9. 終了したプロセスが残したロックは待たずに報告する
+	exited := exec.Command("true")
+	if err := exited.Run(); err != nil {
+		t.Fatalf("Failed to run a process: %v", err)
+	}
+	lockPath := filepath.Join(repo.RepoPath, ".git", "mini-commits", "index.lock")
+	os.WriteFile(lockPath, []byte(fmt.Sprintf("%d\n", exited.Process.Pid)), 0644)
+	output = cli.AssertExitCode(t, 8, "clear", "--force")
+	cli.AssertOutputContains(t, output, fmt.Sprintf("left behind by process %d, which is no longer running", exited.Process.Pid))
+	os.Remove(lockPath)
+	cli.AssertCommandSuccess(t, "clear", "--force")
+}
@@ -0,0 +1,132 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+
+	"git-mini-commit/internal/config"
+	"git-mini-commit/internal/git"
+	"git-mini-commit/internal/storage"
+
+	"github.com/spf13/cobra"
+)
+
+// settings is the configuration of the running command, loaded before it runs
+var settings *config.Config
+
+var configCmd = &cobra.Command{
+	Use:   "config (--list | <key>)",
+	Short: "Show the effective configuration",
+	Long: `Show the effective value of the minicommit.* settings and where each comes from.
+
+Settings are read from git config (system, global, then local, as git reads
+them). An environment variable named after the key, such as
+GIT_MINI_COMMIT_LINT_SUBJECT_MAX_LENGTH for minicommit.lintSubjectMaxLength,
+overrides git config, and a command-line flag such as list --format overrides both.
+
+Use git config to change them:
+
+  git config minicommit.lint true`,
+	Args: rangeArgs(0, 1),
+	// Unlike the other commands, config must work while a setting is invalid
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		return loadSettings()
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		mode, err := outputMode(cmd)
+		if err != nil {
+			return err
+		}
+
+		list, _ := cmd.Flags().GetBool("list")
+		if list == (len(args) == 1) {
+			return newCommandError(codeUsage, "give either --list or a key")
+		}
+		selected := settings.Settings()
+		if !list {
+			s, ok := settings.Lookup(args[0])
+			if !ok {
+				return newCommandError(codeNotFound, "unknown key '%s'", args[0])
+			}
+			selected = []config.Setting{s}
+		}
+		return writeSettings(cmd.OutOrStdout(), mode, selected, list)
+	},
+}
+
+// writeSettings prints settings; a single key prints only its value, like git config --get
+func writeSettings(w io.Writer, mode string, selected []config.Setting, list bool) error {
+	switch mode {
+	case outputJSON:
+		docs := make([]jsonSetting, 0, len(selected))
+		for _, s := range selected {
+			docs = append(docs, jsonSetting{Key: s.Key, Value: s.Value, Source: s.Source, Origin: s.Origin})
+		}
+		return writeJSON(w, jsonDocument{Settings: &docs})
+	case outputPorcelainV1:
+		for _, s := range selected {
+			fmt.Fprintf(w, "%s %s %s\n", s.Source, s.Key, s.Value)
+		}
+		return nil
+	}
+
+	if !list {
+		fmt.Fprintln(w, selected[0].Value)
+		return nil
+	}
+	for _, s := range selected {
+		origin := s.Origin
+		if origin == "" {
+			origin = "-"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s=%s\n", s.Source, origin, s.Key, s.Value)
+	}
+	return nil
+}
+
+// loadSettings reads the configuration for the running command
+func loadSettings() error {
+	cfg, err := config.Load()
+	if err != nil {
+		return gitError(err)
+	}
+	settings = cfg
+	return nil
+}
+
+// applySettings loads the configuration and applies the settings of the store
+// and of the git commands run
+func applySettings() error {
+	if err := loadSettings(); err != nil {
+		return err
+	}
+	timeout, err := settings.Duration(config.LockTimeout)
+	if err != nil {
+		return configError(err)
+	}
+	storage.SetLockTimeout(timeout)
+	gitTimeout, err := settings.Duration(config.GitTimeout)
+	if err != nil {
+		return configError(err)
+	}
+	git.SetTimeout(gitTimeout)
+	return nil
+}
+
+// flagSetting returns the value of key, overridden by flag when it is given
+func flagSetting(cmd *cobra.Command, flag, key string) string {
+	if f := cmd.Flags().Lookup(flag); f != nil && f.Changed {
+		settings.SetFlag(key, f.Value.String(), "--"+flag)
+	}
+	return settings.String(key)
+}
+
+// configError reports an invalid setting
+func configError(err error) error {
+	return newCommandError(codeUsage, "%v", err)
+}
+
+func init() {
+	configCmd.Flags().BoolP("list", "l", false, "list all settings with where they come from")
+	rootCmd.AddCommand(configCmd)
+}
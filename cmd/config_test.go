@@ -0,0 +1,67 @@
+package cmd
+
+import (
+	"encoding/json"
+	"testing"
+
+	"git-mini-commit/testutils"
+)
+
+func TestCLIConfig(t *testing.T) {
+	repo := testutils.NewTestGitRepo(t)
+	defer repo.Cleanup()
+	cli := testutils.NewTestCLI(t)
+	cli.SetRepo(repo)
+
+	// 1. 既定値と設定元の一覧
+	output := cli.AssertCommandSuccess(t, "config", "--list")
+	cli.AssertOutputContains(t, output, "default\t-\tminicommit.trashRetention=30.days\n")
+
+	// 2. git config の値はファイルと共に表示され、環境変数が優先される
+	gitConfig(t, "minicommit.listFormat", "oneline")
+	output = cli.AssertCommandSuccess(t, "config", "--list")
+	cli.AssertOutputContains(t, output, "local\tfile:.git/config\tminicommit.listFormat=oneline\n")
+	t.Setenv("GIT_MINI_COMMIT_LIST_FORMAT", "{{.Subject}}")
+	if output := cli.AssertCommandSuccess(t, "config", "minicommit.listformat"); output != "{{.Subject}}\n" {
+		t.Errorf("Expected the environment to win, got %q", output)
+	}
+
+	var doc jsonDocument
+	output = cli.AssertCommandSuccess(t, "config", "minicommit.listFormat", "--json")
+	if err := json.Unmarshal([]byte(output), &doc); err != nil || doc.Settings == nil {
+		t.Fatalf("Failed to parse JSON output: %v, output: %s", err, output)
+	}
+	if s := (*doc.Settings)[0]; s.Source != "env" || s.Origin != "GIT_MINI_COMMIT_LIST_FORMAT" {
+		t.Errorf("Unexpected setting: %+v", s)
+	}
+
+	// 3. 環境変数の設定は list に反映され、フラグはそれより優先される
+	repo.CreateTestFile("a.txt", "a\n")
+	repo.StageFile("a.txt")
+	cli.AssertCommandSuccess(t, "-m", "Add a")
+	if output := cli.AssertCommandSuccess(t, "list"); output != "Add a\n" {
+		t.Errorf("Expected the configured format, got %q", output)
+	}
+	if output := cli.AssertCommandSuccess(t, "list", "--format", "[{{.Subject}}]"); output != "[Add a]\n" {
+		t.Errorf("Expected the flag to win, got %q", output)
+	}
+
+	// 4. 不正な値はコマンドを止めるが、config では確認できる
+	t.Setenv("GIT_MINI_COMMIT_LOCK_TIMEOUT", "soon")
+	output = cli.AssertExitCode(t, 2, "list")
+	cli.AssertOutputContains(t, output, "minicommit.lockTimeout: invalid duration 'soon' (from GIT_MINI_COMMIT_LOCK_TIMEOUT)")
+	output = cli.AssertCommandSuccess(t, "config", "--list")
+	cli.AssertOutputContains(t, output, "env\tGIT_MINI_COMMIT_LOCK_TIMEOUT\tminicommit.lockTimeout=soon\n")
+
+	// 5. gitTimeout より長くかかる git は止めて git_error にする
+	t.Setenv("GIT_MINI_COMMIT_LOCK_TIMEOUT", "10s")
+	t.Setenv("GIT_MINI_COMMIT_GIT_TIMEOUT", "1ns")
+	output = cli.AssertExitCode(t, 12, "list")
+	cli.AssertOutputContains(t, output, "timed out after 1ns")
+	t.Setenv("GIT_MINI_COMMIT_GIT_TIMEOUT", "0")
+	cli.AssertCommandSuccess(t, "list")
+
+	// 6. 使い方の誤りと未知のキー
+	cli.AssertExitCode(t, 2, "config")
+	cli.AssertExitCode(t, 5, "config", "minicommit.nothing")
+}
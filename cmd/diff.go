@@ -0,0 +1,193 @@
+package cmd
+
+import (
+	"fmt"
+
+	"git-mini-commit/internal/git"
+	"git-mini-commit/internal/patch"
+	"git-mini-commit/internal/storage"
+
+	"github.com/spf13/cobra"
+)
+
+var diffCmd = &cobra.Command{
+	Use:   "diff <a> [<b>]",
+	Short: "Show the net change between two mini-commits, or against the index or working tree",
+	Long: `Show the difference between the states after two mini-commits: the
+repository as it was when <a> was saved compared with the repository as it
+was when <b> was saved.
+
+Each state is rebuilt from the mini-commit's patch and the commit HEAD pointed
+to when it was saved, in a temporary index; the index and working tree are not
+touched. Mini-commits can be named by ID or by stack position: @N is the N-th
+mini-commit as numbered by list, @-N the N-th newest (@-1 is the latest).
+
+  git mini-commit diff @-4 @-1
+
+With a single mini-commit, --cached (or --staged) compares the current index and
+--worktree the working tree with the state the mini-commit would produce,
+limited to the files it touches. The diff shows what would still change: an
+empty diff means the changes are already present.
+
+--check reports whether pop would apply: clean, applied (already present),
+partial (some files are already present or conflict) or conflict. It checks
+against the index, or against the working tree with --worktree. It exits with
+0 for clean and applied, 16 for conflict and 17 for partial.
+
+--stat, --numstat, --summary, --name-only and --name-status work as in show.`,
+	Args: rangeArgs(1, 2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		mode, err := outputMode(cmd)
+		if err != nil {
+			return err
+		}
+
+		cached, _ := cmd.Flags().GetBool("cached")
+		staged, _ := cmd.Flags().GetBool("staged")
+		cached = cached || staged
+		worktree, _ := cmd.Flags().GetBool("worktree")
+		check, _ := cmd.Flags().GetBool("check")
+		switch {
+		case len(args) == 2 && (cached || worktree || check):
+			return newCommandError(codeUsage, "--cached, --worktree and --check take a single mini-commit")
+		case len(args) == 1 && !cached && !worktree && !check:
+			return newCommandError(codeUsage, "diff needs two mini-commits, or one with --cached, --worktree or --check")
+		case cached && worktree:
+			return newCommandError(codeUsage, "--cached and --worktree cannot be used together")
+		}
+
+		// Check if it's a Git repository
+		if err := git.CheckRepository(); err != nil {
+			return gitError(err)
+		}
+
+		// Initialize storage
+		storage, err := storage.NewStorage()
+		if err != nil {
+			return storageError(err, "failed to initialize storage")
+		}
+
+		miniCommits, err := storage.LoadMiniCommits()
+		if err != nil {
+			return storageError(err, "failed to load mini-commits")
+		}
+		to, pos, err := resolveMiniCommit(miniCommits, args[len(args)-1])
+		if err != nil {
+			return err
+		}
+		if err := loadPatches(storage, miniCommits[pos:pos+1]); err != nil {
+			return err
+		}
+
+		target := targetIndex
+		if worktree {
+			target = targetWorktree
+		}
+
+		out := cmd.OutOrStdout()
+		if check {
+			status, files := checkMiniCommit(to, !worktree)
+			switch mode {
+			case outputJSON:
+				doc := jsonCheck{ID: to.ID, Target: target, Status: status, Files: make([]jsonFileCheck, 0, len(files))}
+				for _, f := range files {
+					doc.Files = append(doc.Files, jsonFileCheck{Path: f.path, Status: f.status})
+				}
+				if err := writeJSON(out, jsonDocument{Check: &doc}); err != nil {
+					return err
+				}
+				return checkError(to, target, status)
+			case outputPorcelainV1:
+				writePorcelainCheck(out, to, target, status, files)
+				return checkError(to, target, status)
+			}
+
+			useColor, err := colorEnabled(cmd, "color.diff")
+			if err != nil {
+				return err
+			}
+			writeCheck(out, to, target, status, files, useColor)
+			return checkError(to, target, status)
+		}
+
+		// Rebuild the state after the mini-commit and compare it with the other side
+		toTree, err := stateAfter(to)
+		if err != nil {
+			return err
+		}
+		fromName, diff := "", ""
+		if len(args) == 2 {
+			from, pos, err := resolveMiniCommit(miniCommits, args[0])
+			if err != nil {
+				return err
+			}
+			if err := loadPatches(storage, miniCommits[pos:pos+1]); err != nil {
+				return err
+			}
+			fromTree, err := stateAfter(from)
+			if err != nil {
+				return err
+			}
+			fromName = from.ID
+			diff, err = git.DiffTrees(fromTree, toTree)
+			if err != nil {
+				return gitError(err)
+			}
+		} else if paths := patch.ChangedPaths(to.Patch); len(paths) > 0 {
+			fromName = target
+			diff, err = git.DiffAgainstTree(toTree, cached, paths)
+			if err != nil {
+				return gitError(err)
+			}
+		}
+
+		switch mode {
+		case outputJSON:
+			files := patch.Parse(diff)
+			return writeJSON(out, jsonDocument{Diff: &jsonDiff{
+				From:  fromName,
+				To:    to.ID,
+				Stats: patch.Summarize(files),
+				Files: toJSONFiles(files),
+				Patch: diff,
+			}})
+		case outputPorcelainV1:
+			stats := patch.ComputeStats(diff)
+			fmt.Fprintf(out, "from %s\n", fromName)
+			fmt.Fprintf(out, "to %s\n", to.ID)
+			fmt.Fprintf(out, "stats %d %d %d\n", stats.Files, stats.Insertions, stats.Deletions)
+			fmt.Fprintf(out, "patch %d\n%s", len(diff), diff)
+			return nil
+		}
+
+		useColor, err := colorEnabled(cmd, "color.diff")
+		if err != nil {
+			return err
+		}
+		stopPager, err := startPager(cmd)
+		if err != nil {
+			return err
+		}
+		defer stopPager()
+		out = cmd.OutOrStdout()
+
+		if diff == "" {
+			return nil
+		}
+		if !writeDiffFormats(cmd, out, diff, useColor) {
+			return nil
+		}
+		writePatch(out, diff, useColor)
+
+		return nil
+	},
+}
+
+func init() {
+	addDiffFormatFlags(diffCmd)
+	diffCmd.Flags().Bool("cached", false, "compare the mini-commit with the index")
+	diffCmd.Flags().Bool("staged", false, "synonym for --cached")
+	diffCmd.Flags().Bool("worktree", false, "compare the mini-commit with the working tree")
+	diffCmd.Flags().Bool("check", false, "report whether pop would apply cleanly, partially or is already applied")
+	rootCmd.AddCommand(diffCmd)
+}
@@ -0,0 +1,127 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+
+	"git-mini-commit/internal/color"
+	"git-mini-commit/internal/git"
+	"git-mini-commit/internal/patch"
+	"git-mini-commit/internal/types"
+)
+
+// Results of diff --check, for the whole mini-commit and for each file
+const (
+	checkClean    = "clean"
+	checkApplied  = "applied"
+	checkPartial  = "partial"
+	checkConflict = "conflict"
+)
+
+// Targets a mini-commit can be compared against
+const (
+	targetIndex    = "index"
+	targetWorktree = "worktree"
+)
+
+// fileCheck is the diff --check result for one file of a mini-commit
+type fileCheck struct {
+	path   string
+	status string
+}
+
+// checkMiniCommit reports whether popping mc would apply cleanly, is already
+// applied, or only some of its files would apply. Files are only checked one
+// by one when the patch as a whole neither applies nor reverts.
+func checkMiniCommit(mc *types.MiniCommit, cached bool) (string, []fileCheck) {
+	parts := patch.SplitFiles(mc.Patch)
+	files := make([]fileCheck, len(parts))
+	for i, part := range parts {
+		files[i].path = patch.Parse(part)[0].Path()
+	}
+	setAll := func(status string) {
+		for i := range files {
+			files[i].status = status
+		}
+	}
+
+	if len(parts) == 0 {
+		return checkApplied, files
+	}
+	if git.CheckPatch(mc.Patch, cached, false) == nil {
+		setAll(checkClean)
+		return checkClean, files
+	}
+	if git.CheckPatch(mc.Patch, cached, true) == nil {
+		setAll(checkApplied)
+		return checkApplied, files
+	}
+
+	usable := false
+	for i, part := range parts {
+		switch {
+		case git.CheckPatch(part, cached, false) == nil:
+			files[i].status = checkClean
+			usable = true
+		case git.CheckPatch(part, cached, true) == nil:
+			files[i].status = checkApplied
+			usable = true
+		default:
+			files[i].status = checkConflict
+		}
+	}
+	if usable {
+		return checkPartial, files
+	}
+	return checkConflict, files
+}
+
+// checkDescriptions explain each overall diff --check result in human output
+var checkDescriptions = map[string]string{
+	checkClean:    "pop would apply cleanly",
+	checkApplied:  "the changes are already present",
+	checkPartial:  "some files are already present or conflict; pop would fail",
+	checkConflict: "pop would fail with conflicts",
+}
+
+// checkError reports a diff --check result that pop would fail on, so that it
+// exits with its own status; clean and applied mini-commits are not errors
+func checkError(mc *types.MiniCommit, target, status string) error {
+	switch status {
+	case checkConflict:
+		return newCommandError(codeCheckConflict, "mini-commit %s conflicts with the %s", shortID(mc.ID), target)
+	case checkPartial:
+		return newCommandError(codeCheckPartial, "mini-commit %s is partly present in the %s or conflicts with it",
+			shortID(mc.ID), target)
+	}
+	return nil
+}
+
+// writeCheck writes the human-readable diff --check result
+func writeCheck(w io.Writer, mc *types.MiniCommit, target, status string, files []fileCheck, useColor bool) {
+	fmt.Fprintf(w, "Mini-commit %s against the %s: %s (%s)\n", shortID(mc.ID), target, status, checkDescriptions[status])
+	if status != checkPartial && status != checkConflict {
+		return
+	}
+	for _, f := range files {
+		label := fmt.Sprintf("%-8s", f.status)
+		if useColor {
+			switch f.status {
+			case checkClean:
+				label = color.Wrap(color.Green, label)
+			case checkConflict:
+				label = color.Wrap(color.Red, label)
+			}
+		}
+		fmt.Fprintf(w, "  %s %s\n", label, f.path)
+	}
+}
+
+// writePorcelainCheck writes "<status> <id> <target>" followed by one
+// "file <status> <path>" line per file
+func writePorcelainCheck(w io.Writer, mc *types.MiniCommit, target, status string, files []fileCheck) {
+	fmt.Fprintf(w, "%s %s %s\n", status, mc.ID, target)
+	for _, f := range files {
+		fmt.Fprintf(w, "file %s %s\n", f.status, f.path)
+	}
+}
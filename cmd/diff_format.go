@@ -0,0 +1,81 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"git-mini-commit/internal/color"
+	"git-mini-commit/internal/patch"
+
+	"github.com/spf13/cobra"
+)
+
+// addDiffFormatFlags registers the --stat family of flags shared by show and diff
+func addDiffFormatFlags(c *cobra.Command) {
+	c.Flags().Bool("stat", false, "show a diffstat instead of the diff")
+	c.Flags().Bool("numstat", false, "show added and deleted line counts per file")
+	c.Flags().Bool("summary", false, "show created, deleted, renamed and mode-changed files")
+	c.Flags().Bool("name-only", false, "show only the names of changed files")
+	c.Flags().Bool("name-status", false, "show the names and status of changed files")
+	c.Flags().BoolP("patch", "p", false, "show the diff in addition to --stat and friends")
+}
+
+// diffFormatsRequested reports whether any of the --stat family of flags is set
+func diffFormatsRequested(cmd *cobra.Command) bool {
+	for _, name := range []string{"stat", "numstat", "summary", "name-only", "name-status"} {
+		if set, _ := cmd.Flags().GetBool(name); set {
+			return true
+		}
+	}
+	return false
+}
+
+// writeDiffFormats writes the file summaries selected by the --stat family of flags.
+// It reports whether the diff itself should be shown as well.
+func writeDiffFormats(cmd *cobra.Command, out io.Writer, p string, useColor bool) bool {
+	flags := cmd.Flags()
+	numstat, _ := flags.GetBool("numstat")
+	stat, _ := flags.GetBool("stat")
+	summary, _ := flags.GetBool("summary")
+	nameOnly, _ := flags.GetBool("name-only")
+	nameStatus, _ := flags.GetBool("name-status")
+	withPatch, _ := flags.GetBool("patch")
+
+	if !diffFormatsRequested(cmd) {
+		return true
+	}
+
+	files := patch.Parse(p)
+	if numstat {
+		patch.WriteNumstat(out, files)
+	}
+	if stat {
+		var sb strings.Builder
+		patch.WriteStat(&sb, files, patch.DefaultStatWidth)
+		if useColor {
+			fmt.Fprint(out, color.Stat(sb.String()))
+		} else {
+			fmt.Fprint(out, sb.String())
+		}
+	}
+	if summary {
+		patch.WriteSummary(out, files)
+	}
+	if nameOnly {
+		patch.WriteNameOnly(out, files)
+	}
+	if nameStatus {
+		patch.WriteNameStatus(out, files)
+	}
+	return withPatch
+}
+
+// writePatch writes a diff, colored when requested
+func writePatch(out io.Writer, p string, useColor bool) {
+	if useColor {
+		fmt.Fprint(out, color.Diff(p))
+	} else {
+		fmt.Fprint(out, p)
+	}
+}
@@ -0,0 +1,230 @@
+package cmd
+
+import (
+	"encoding/json"
+	"os/exec"
+	"strings"
+	"testing"
+
+	"git-mini-commit/testutils"
+)
+
+// saveMiniCommit ファイルを書き込んでステージングし、mini-commitを作成してIDを返す
+func saveMiniCommit(t *testing.T, repo *testutils.TestGitRepo, cli *testutils.TestCLI, file, content, message string) string {
+	t.Helper()
+	if err := repo.CreateTestFile(file, content); err != nil {
+		t.Fatalf("Failed to create file: %v", err)
+	}
+	repo.StageFile(file)
+	output := cli.AssertCommandSuccess(t, "-m", message, "--porcelain")
+	return strings.TrimSpace(strings.TrimPrefix(output, "created "))
+}
+
+func TestCLIDiff(t *testing.T) {
+	// テスト用Gitリポジトリを作成
+	repo := testutils.NewTestGitRepo(t)
+	defer repo.Cleanup()
+
+	// テスト用CLIを作成
+	cli := testutils.NewTestCLI(t)
+	cli.SetRepo(repo)
+
+	// 1. ベースコミットを作成
+	repo.CreateTestFile("a.txt", "one\n")
+	repo.StageFile("a.txt")
+	repo.CommitFile("initial")
+
+	// 2. 段階的にmini-commitを作成
+	first := saveMiniCommit(t, repo, cli, "a.txt", "one\ntwo\n", "Add two")
+	saveMiniCommit(t, repo, cli, "a.txt", "one\ntwo\nthree\n", "Add three")
+	last := saveMiniCommit(t, repo, cli, "b.txt", "new\n", "Add b")
+
+	// 3. 作業ツリーを変更しても、保存時の状態同士を比較する
+	exec.Command("git", "reset", "-q", "--hard").Run()
+
+	output := cli.AssertCommandSuccess(t, "diff", first, last)
+	cli.AssertOutputContains(t, output, "+three")
+	cli.AssertOutputContains(t, output, "+++ b/b.txt")
+	cli.AssertOutputNotContains(t, output, "+two")
+
+	// スタック位置で指定
+	output = cli.AssertCommandSuccess(t, "diff", "@1", "@-1", "--name-status")
+	if !strings.Contains(output, "M\ta.txt\nA\tb.txt\n") {
+		t.Errorf("Unexpected --name-status output: %s", output)
+	}
+
+	// 同じ状態同士の差分は空
+	output = cli.AssertCommandSuccess(t, "diff", "@2", "@2")
+	if output != "" {
+		t.Errorf("Expected no output, but got: %s", output)
+	}
+
+	// JSON出力
+	output = cli.AssertCommandSuccess(t, "diff", "@1", "@3", "--json")
+	var doc struct {
+		Diff struct {
+			From  string
+			To    string
+			Stats struct{ Files, Insertions int }
+		}
+	}
+	if err := json.Unmarshal([]byte(output), &doc); err != nil {
+		t.Fatalf("Invalid JSON output: %v\n%s", err, output)
+	}
+	if doc.Diff.From != first || doc.Diff.To != last || doc.Diff.Stats.Files != 2 || doc.Diff.Stats.Insertions != 2 {
+		t.Errorf("Unexpected JSON output: %s", output)
+	}
+
+	// 存在しない位置とID
+	output = cli.AssertCommandFailure(t, "diff", "@1", "@4")
+	cli.AssertOutputContains(t, output, "no mini-commit at position @4")
+	output = cli.AssertCommandFailure(t, "diff", first, "nonexistent")
+	cli.AssertOutputContains(t, output, "not found")
+}
+
+func TestCLIRangeDiff(t *testing.T) {
+	// テスト用Gitリポジトリを作成
+	repo := testutils.NewTestGitRepo(t)
+	defer repo.Cleanup()
+
+	// テスト用CLIを作成
+	cli := testutils.NewTestCLI(t)
+	cli.SetRepo(repo)
+
+	repo.CreateTestFile("a.txt", "one\n")
+	repo.StageFile("a.txt")
+	repo.CommitFile("initial")
+
+	// 1. 最初のスタック
+	saveMiniCommit(t, repo, cli, "b.txt", "b\n", "Add b")
+	saveMiniCommit(t, repo, cli, "c.txt", "c\n", "Add c")
+	exec.Command("git", "reset", "-q", "--hard").Run()
+
+	// 2. 作り直したスタック: b は同じ、c は内容が変わり、d が追加
+	saveMiniCommit(t, repo, cli, "b.txt", "b\n", "Add b")
+	changed := saveMiniCommit(t, repo, cli, "c.txt", "C\n", "Add c")
+	saveMiniCommit(t, repo, cli, "d.txt", "d\n", "Add d")
+
+	output := cli.AssertCommandSuccess(t, "range-diff", "@1..@2", "@3..@5")
+	lines := strings.Split(output, "\n")
+	if !strings.Contains(lines[0], " = 1:") || !strings.HasSuffix(lines[0], "Add b") {
+		t.Errorf("Expected the first mini-commit to be unchanged, but got: %s", lines[0])
+	}
+	if !strings.Contains(lines[1], " ! 2:") {
+		t.Errorf("Expected the second mini-commit to be changed, but got: %s", lines[1])
+	}
+	cli.AssertOutputContains(t, output, "    -+c\n    ++C\n")
+	cli.AssertOutputContains(t, output, "-:  -------- > 3:")
+
+	// 古い側だけにある mini-commit
+	output = cli.AssertCommandSuccess(t, "range-diff", "@1..@2", "@3..@3", "--porcelain")
+	if !strings.HasPrefix(output, "= 1 ") || !strings.Contains(output, "\n< 2 ") {
+		t.Errorf("Unexpected porcelain output: %s", output)
+	}
+
+	// 3. 操作の前のスタックと比べると、削除した mini-commit も含めて対応付ける
+	cli.AssertCommandSuccess(t, "drop", changed)
+	output = cli.AssertCommandSuccess(t, "range-diff", "@{0}:@3..@5", "@3..@4", "--porcelain")
+	lines = strings.Split(strings.TrimSuffix(output, "\n"), "\n")
+	if len(lines) != 3 || !strings.HasPrefix(lines[0], "= 1 ") || !strings.HasPrefix(lines[1], "< 2 ") ||
+		!strings.HasSuffix(lines[1], " Add c") || !strings.HasPrefix(lines[2], "= 3 ") {
+		t.Errorf("Expected the dropped mini-commit to be compared, got:\n%s", output)
+	}
+	output = cli.AssertCommandSuccess(t, "range-diff", "@{0}", "@1..@-1", "--porcelain")
+	if strings.Count(output, "\n= ") != 3 || !strings.Contains(output, "\n< 4 ") {
+		t.Errorf("Expected the whole previous stack to be compared, got:\n%s", output)
+	}
+	output = cli.AssertExitCode(t, 5, "range-diff", "@{99}", "@1..@-1")
+	cli.AssertOutputContains(t, output, "no operation @{99}")
+
+	// 不正な範囲
+	output = cli.AssertCommandFailure(t, "range-diff", "@2..@1", "@3..@5")
+	cli.AssertOutputContains(t, output, "comes after")
+	output = cli.AssertCommandFailure(t, "range-diff", "@1", "@3..@5")
+	cli.AssertOutputContains(t, output, "invalid range")
+}
+
+func TestCLIDiffAgainstIndex(t *testing.T) {
+	// テスト用Gitリポジトリを作成
+	repo := testutils.NewTestGitRepo(t)
+	defer repo.Cleanup()
+
+	// テスト用CLIを作成
+	cli := testutils.NewTestCLI(t)
+	cli.SetRepo(repo)
+
+	repo.CreateTestFile("a.txt", "one\n")
+	repo.CreateTestFile("b.txt", "x\n")
+	repo.StageFile("a.txt")
+	repo.StageFile("b.txt")
+	repo.CommitFile("initial")
+
+	// 1. 2ファイルを変更するmini-commitを作成してから元に戻す
+	repo.CreateTestFile("a.txt", "one\ntwo\n")
+	repo.StageFile("a.txt")
+	id := saveMiniCommit(t, repo, cli, "b.txt", "x\ny\n", "Change a and b")
+	exec.Command("git", "reset", "-q", "--hard").Run()
+
+	// 2. 未適用: --check は clean、--cached は残りの変更を表示
+	output := cli.AssertCommandSuccess(t, "diff", id, "--check")
+	cli.AssertOutputContains(t, output, ": clean")
+	output = cli.AssertCommandSuccess(t, "diff", id, "--cached")
+	cli.AssertOutputContains(t, output, "--- a/a.txt\n+++ b/a.txt")
+	cli.AssertOutputContains(t, output, "+two")
+	cli.AssertOutputContains(t, output, "+y")
+
+	// 3. 一部だけステージング済み: partial、残りの差分は b.txt のみ
+	repo.CreateTestFile("a.txt", "one\ntwo\n")
+	repo.StageFile("a.txt")
+	output = cli.AssertExitCode(t, 17, "diff", id, "--check", "--porcelain")
+	if !strings.HasPrefix(output, "partial "+id+" index\n") || !strings.Contains(output, "error check_partial ") ||
+		!strings.Contains(output, "file applied a.txt\n") || !strings.Contains(output, "file clean b.txt\n") {
+		t.Errorf("Unexpected porcelain output: %s", output)
+	}
+	output = cli.AssertCommandSuccess(t, "diff", id, "--cached", "--name-only")
+	if strings.TrimSpace(output) != "b.txt" {
+		t.Errorf("Expected only b.txt to remain, but got: %s", output)
+	}
+
+	// 作業ツリーとの比較
+	repo.CreateTestFile("b.txt", "x\ny\n")
+	output = cli.AssertCommandSuccess(t, "diff", id, "--worktree")
+	if output != "" {
+		t.Errorf("Expected no difference with the working tree, but got: %s", output)
+	}
+	output = cli.AssertCommandSuccess(t, "diff", id, "--check", "--worktree")
+	cli.AssertOutputContains(t, output, "against the worktree: applied")
+
+	// 4. すべて適用済み
+	repo.StageFile("b.txt")
+	output = cli.AssertCommandSuccess(t, "diff", id, "--check", "--json")
+	var doc struct {
+		Check struct{ Status string }
+	}
+	if err := json.Unmarshal([]byte(output), &doc); err != nil {
+		t.Fatalf("Invalid JSON output: %v\n%s", err, output)
+	}
+	if doc.Check.Status != "applied" {
+		t.Errorf("Expected status 'applied', but got: %s", output)
+	}
+
+	// 5. 競合する変更
+	repo.CreateTestFile("a.txt", "other\n")
+	repo.CreateTestFile("b.txt", "other\n")
+	repo.StageFile("a.txt")
+	repo.StageFile("b.txt")
+	output = cli.AssertExitCode(t, 16, "diff", id, "--check")
+	cli.AssertOutputContains(t, output, ": conflict")
+	cli.AssertOutputContains(t, output, "conflicts with the index\nhint: ")
+	output = cli.AssertExitCode(t, 16, "diff", id, "--check", "--json")
+	// 標準出力の結果に標準エラーのエラーが続く
+	if err := json.NewDecoder(strings.NewReader(output)).Decode(&doc); err != nil || doc.Check.Status != "conflict" {
+		t.Errorf("Expected the conflict in the JSON output, got %v: %s", err, output)
+	}
+
+	// 引数とフラグの組み合わせ
+	output = cli.AssertCommandFailure(t, "diff", id)
+	cli.AssertOutputContains(t, output, "--cached, --worktree or --check")
+	output = cli.AssertCommandFailure(t, "diff", id, id, "--cached")
+	cli.AssertOutputContains(t, output, "take a single mini-commit")
+}
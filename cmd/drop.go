@@ -4,7 +4,9 @@ import (
 	"fmt"
 
 	"git-mini-commit/internal/git"
+	"git-mini-commit/internal/hooks"
 	"git-mini-commit/internal/storage"
+	"git-mini-commit/internal/types"
 
 	"github.com/spf13/cobra"
 )
@@ -13,32 +15,62 @@ var dropCmd = &cobra.Command{
 	Use:   "drop <hash>",
 	Short: "Delete specified mini-commit",
 	Long:  `Delete the mini-commit with the specified ID.`,
-	Args:  cobra.ExactArgs(1),
+	Args:  exactArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) error {
 		hash := args[0]
 
+		mode, err := outputMode(cmd)
+		if err != nil {
+			return err
+		}
+
 		// Check if it's a Git repository
-		if !git.IsGitRepository() {
-			return fmt.Errorf("not a git repository")
+		if err := git.CheckRepository(); err != nil {
+			return gitError(err)
 		}
 
 		// Initialize storage
 		storage, err := storage.NewStorage()
 		if err != nil {
-			return fmt.Errorf("failed to initialize storage: %v", err)
+			return storageError(err, "failed to initialize storage")
+		}
+
+		// Look up the mini-commit first so that machine-readable output can report it;
+		// its patch is not needed
+		mc, err := storage.FindMiniCommit(hash)
+		if err != nil {
+			return storageError(err, "failed to delete mini-commit")
+		}
+
+		if err := dropMiniCommit(cmd, storage, mc); err != nil {
+			return err
 		}
 
-		// Delete mini-commit
-		if err := storage.DeleteMiniCommit(hash); err != nil {
-			return fmt.Errorf("failed to delete mini-commit: %v", err)
+		out := cmd.OutOrStdout()
+		switch mode {
+		case outputJSON:
+			doc := toJSON(mc, false)
+			return writeJSON(out, jsonDocument{Action: "drop", MiniCommit: &doc})
+		case outputPorcelainV1:
+			writePorcelainAction(out, "dropped", mc)
+			return nil
 		}
 
-		fmt.Printf("Deleted mini-commit '%s'\n", hash[:8])
+		fmt.Fprintf(out, "Deleted mini-commit '%s'\n", shortID(mc.ID))
 
 		return nil
 	},
 }
 
+// dropMiniCommit moves mc to the trash and runs the post-drop hook
+func dropMiniCommit(cmd *cobra.Command, store *storage.Storage, mc *types.MiniCommit) error {
+	if err := store.DeleteMiniCommit(mc.ID); err != nil {
+		return storageError(err, "failed to delete mini-commit")
+	}
+	runPostHook(cmd, hooks.PostDrop, mc)
+	return nil
+}
+
 func init() {
 	rootCmd.AddCommand(dropCmd)
 }
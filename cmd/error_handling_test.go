@@ -119,6 +119,42 @@ func TestCLIInNonGitRepository(t *testing.T) {
 	if !strings.Contains(output, "not a git repository") {
 		t.Errorf("Expected 'not a git repository' in output, but got: %s", output)
 	}
+	cli.AssertExitCode(t, 3, "list")
+}
+
+func TestCLIExitCodes(t *testing.T) {
+	repo := testutils.NewTestGitRepo(t)
+	defer repo.Cleanup()
+
+	cli := testutils.NewTestCLI(t)
+	cli.SetRepo(repo)
+
+	// 1. 失敗の種類ごとに決まった終了コードとヒントを返す
+	output := cli.AssertExitCode(t, 4, "-m", "Nothing staged")
+	cli.AssertOutputContains(t, output, "hint: stage the changes")
+	cli.AssertExitCode(t, 2, "show")
+	cli.AssertExitCode(t, 2, "show", "a", "b")
+	output = cli.AssertExitCode(t, 5, "show", "deadbeef")
+	cli.AssertOutputContains(t, output, "git mini-commit list")
+
+	// 2. JSONのエラーにもコード・終了コード・ヒントが入る
+	output = cli.AssertExitCode(t, 5, "show", "deadbeef", "--json")
+	for _, expected := range []string{`"code": "not_found"`, `"exitCode": 5`, `"hint": "run`} {
+		cli.AssertOutputContains(t, output, expected)
+	}
+
+	// 3. IDは4文字以上の一意な接頭辞で指定できる
+	id := saveMiniCommit(t, repo, cli, "a.txt", "a\n", "First")
+	output = cli.AssertCommandSuccess(t, "show", id[:4])
+	cli.AssertOutputContains(t, output, "First")
+	cli.AssertExitCode(t, 5, "show", id[:3])
+
+	// 4. 壊れたストアは store_corrupt として報告される
+	if err := os.WriteFile(filepath.Join(".git", "mini-commits", "index.json"), []byte("invalid json"), 0644); err != nil {
+		t.Fatalf("Failed to corrupt index: %v", err)
+	}
+	output = cli.AssertExitCode(t, 9, "list")
+	cli.AssertOutputContains(t, output, "fsck --repair")
 }
 
 func TestCLIWithCorruptedStorage(t *testing.T) {
@@ -155,6 +191,37 @@ func TestCLIWithCorruptedStorage(t *testing.T) {
 	}
 }
 
+func TestCLIWithNewerStoreFormat(t *testing.T) {
+	// テスト用Gitリポジトリを作成
+	repo := testutils.NewTestGitRepo(t)
+	defer repo.Cleanup()
+
+	// テスト用CLIを作成
+	cli := testutils.NewTestCLI(t)
+
+	// 1. 新しいバージョンが書いたストアを用意
+	storeDir := filepath.Join(".git", "mini-commits")
+	if err := os.MkdirAll(storeDir, 0755); err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	index := `{"formatVersion": 99, "toolVersion": "9.0.0", "createdBy": "git-mini-commit 9.0.0", "miniCommits": []}`
+	if err := os.WriteFile(filepath.Join(storeDir, "index.json"), []byte(index), 0644); err != nil {
+		t.Fatalf("Failed to write index file: %v", err)
+	}
+
+	// 2. 古いバイナリは読み込みを拒否
+	output := cli.AssertCommandFailure(t, "list", "--porcelain")
+	if !strings.Contains(output, "error unsupported_format ") || !strings.Contains(output, "please upgrade") {
+		t.Errorf("Expected an unsupported_format error, but got: %s", output)
+	}
+
+	// 3. --version でバージョンを表示
+	output = cli.AssertCommandSuccess(t, "--version")
+	if !strings.HasPrefix(output, "git-mini-commit version ") {
+		t.Errorf("Unexpected --version output: %s", output)
+	}
+}
+
 func TestCLIWithPermissionErrors(t *testing.T) {
 	// Windows環境ではこのテストをスキップ（パーミッション処理が異なる）
 	if runtime.GOOS == "windows" {
@@ -328,16 +395,19 @@ func TestCLIWithInvalidPatchContent(t *testing.T) {
 		t.Fatalf("Failed to extract mini-commit ID from output: %s", output)
 	}
 
-	// 4. patchファイルを破損
-	patchPath := filepath.Join(".git", "mini-commits", miniCommitID+".patch")
-	if err := os.WriteFile(patchPath, []byte("invalid patch content"), 0644); err != nil {
-		t.Fatalf("Failed to corrupt patch file: %v", err)
+	// 4. パッチオブジェクトを破損
+	objects, _ := filepath.Glob(filepath.Join(".git", "mini-commits", "objects", "*", "*"))
+	if len(objects) != 1 {
+		t.Fatalf("Expected one patch object, got %v", objects)
+	}
+	if err := os.WriteFile(objects[0], []byte("invalid patch content"), 0644); err != nil {
+		t.Fatalf("Failed to corrupt patch object: %v", err)
 	}
 
-	// 5. 破損したpatchでpopを実行（エラー）
+	// 5. 破損したpatchでpopを実行（エラー）。短いIDでも指定できる
 	output = cli.AssertCommandFailure(t, "pop", miniCommitID)
-	if !strings.Contains(output, "not found") {
-		t.Errorf("Expected 'not found' in output, but got: %s", output)
+	if !strings.Contains(output, "corrupt") || !strings.Contains(output, "fsck --repair") {
+		t.Errorf("Expected the corrupt patch to be reported, but got: %s", output)
 	}
 }
 
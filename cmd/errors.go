@@ -0,0 +1,179 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+
+	"git-mini-commit/internal/git"
+	"git-mini-commit/internal/storage"
+
+	"github.com/spf13/cobra"
+)
+
+// Error codes reported in machine-readable output
+const (
+	codeError          = "error"
+	codeUsage          = "usage"
+	codeNotRepository  = "not_a_repository"
+	codeMissingMessage = "missing_message"
+	codeNoStaged       = "no_staged_changes"
+	codeNotFound       = "not_found"
+	codeAmbiguousID    = "ambiguous_id"
+	codeStorage        = "storage_error"
+	codeLocked         = "locked"
+	codeGit            = "git_error"
+	codeApplyFailed    = "apply_failed"
+	codeAborted        = "aborted"
+	codeCorrupt        = "store_corrupt"
+	codeNewerFormat    = "unsupported_format"
+	codeLintFailed     = "lint_failed"
+	codeHookFailed     = "hook_failed"
+	codeCheckConflict  = "check_conflict"
+	codeCheckPartial   = "check_partial"
+)
+
+// exitStatuses are the documented exit statuses of the error codes; anything
+// else exits with 1
+var exitStatuses = map[string]int{
+	codeUsage:          2,
+	codeMissingMessage: 2,
+	codeNotRepository:  3,
+	codeNoStaged:       4,
+	codeNotFound:       5,
+	codeAmbiguousID:    6,
+	codeApplyFailed:    7,
+	codeLocked:         8,
+	codeCorrupt:        9,
+	codeNewerFormat:    10,
+	codeAborted:        11,
+	codeGit:            12,
+	codeStorage:        13,
+	codeLintFailed:     14,
+	codeHookFailed:     15,
+	codeCheckConflict:  16,
+	codeCheckPartial:   17,
+}
+
+// errorHints tell the user what to do about an error, printed after it
+var errorHints = map[string]string{
+	codeNotRepository:  "run git mini-commit inside a git working tree",
+	codeNoStaged:       "stage the changes to save with \"git add\" first",
+	codeNotFound:       "run \"git mini-commit list\" to see the mini-commits and their IDs",
+	codeAmbiguousID:    "give more characters of the ID",
+	codeApplyFailed:    "the patch conflicts with the changes it was applied to; commit, stash or revert the conflicting changes and try again",
+	codeLocked:         "if no other git-mini-commit is running, remove the lock file and try again",
+	codeCorrupt:        "run \"git mini-commit fsck --repair\" to repair the store",
+	codeNewerFormat:    "upgrade git-mini-commit to use this store",
+	codeMissingMessage: "pass the message with -m or -F, or set core.editor or EDITOR to write it in an editor",
+	codeHookFailed:     "fix what the hook reported, or skip it with --no-verify",
+	codeLintFailed:     "reword the message to follow the rules, which are set with the minicommit.lint* git config keys",
+	codeCheckConflict:  "commit, stash or revert the conflicting changes before popping it",
+	codeCheckPartial:   "revert or resolve the files listed as applied or conflict before popping it",
+}
+
+// errorKinds map the errors of the storage and git packages to their codes
+var errorKinds = []struct {
+	target error
+	code   string
+}{
+	{storage.ErrNotFound, codeNotFound},
+	{storage.ErrAmbiguousID, codeAmbiguousID},
+	{storage.ErrLocked, codeLocked},
+	{storage.ErrCorrupt, codeCorrupt},
+	{storage.ErrNewerFormat, codeNewerFormat},
+	{git.ErrNotRepository, codeNotRepository},
+	{git.ErrNoStagedChanges, codeNoStaged},
+	{git.ErrConflict, codeApplyFailed},
+}
+
+// commandError is an error carrying a stable code for machine-readable output
+type commandError struct {
+	code string
+	err  error
+}
+
+func (e *commandError) Error() string {
+	return e.err.Error()
+}
+
+func (e *commandError) Unwrap() error {
+	return e.err
+}
+
+// newCommandError creates an error with the given code and message; use %w to
+// keep the error it reports, so that a more specific code can be found
+func newCommandError(code, format string, args ...interface{}) error {
+	return &commandError{code: code, err: fmt.Errorf(format, args...)}
+}
+
+// storageError wraps a storage failure, reported as storage_error unless it is
+// one of the errors with its own code, such as a missing mini-commit
+func storageError(err error, action string) error {
+	return &commandError{code: codeStorage, err: fmt.Errorf("%s: %w", action, err)}
+}
+
+// gitError wraps a git failure, reported as git_error unless it is one of the
+// errors with its own code, such as not being in a repository
+func gitError(err error) error {
+	return &commandError{code: codeGit, err: err}
+}
+
+// errorCode returns the machine-readable code of err. The generic codes give
+// way to the code of a known error found in the chain of err.
+func errorCode(err error) string {
+	code := codeError
+	var cmdErr *commandError
+	if errors.As(err, &cmdErr) {
+		code = cmdErr.code
+	}
+	if code != codeError && code != codeStorage && code != codeGit {
+		return code
+	}
+	for _, kind := range errorKinds {
+		if errors.Is(err, kind.target) {
+			return kind.code
+		}
+	}
+	return code
+}
+
+// exitStatus returns the exit status of a command that failed with code
+func exitStatus(code string) int {
+	if status, ok := exitStatuses[code]; ok {
+		return status
+	}
+	return 1
+}
+
+// exactArgs wraps cobra.ExactArgs so that argument errors are reported as usage errors
+func exactArgs(n int) cobra.PositionalArgs {
+	validate := cobra.ExactArgs(n)
+	return func(cmd *cobra.Command, args []string) error {
+		if err := validate(cmd, args); err != nil {
+			return &commandError{code: codeUsage, err: err}
+		}
+		return nil
+	}
+}
+
+// rangeArgs wraps cobra.RangeArgs so that argument errors are reported as usage errors
+func rangeArgs(min, max int) cobra.PositionalArgs {
+	validate := cobra.RangeArgs(min, max)
+	return func(cmd *cobra.Command, args []string) error {
+		if err := validate(cmd, args); err != nil {
+			return &commandError{code: codeUsage, err: err}
+		}
+		return nil
+	}
+}
+
+// minimumArgs wraps cobra.MinimumNArgs so that argument errors are reported as usage errors
+func minimumArgs(n int) cobra.PositionalArgs {
+	validate := cobra.MinimumNArgs(n)
+	return func(cmd *cobra.Command, args []string) error {
+		if err := validate(cmd, args); err != nil {
+			return &commandError{code: codeUsage, err: err}
+		}
+		return nil
+	}
+}
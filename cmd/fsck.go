@@ -0,0 +1,116 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+
+	"git-mini-commit/internal/storage"
+
+	"github.com/spf13/cobra"
+)
+
+var fsckCmd = &cobra.Command{
+	Use:   "fsck",
+	Short: "Verify the mini-commit store and optionally repair it",
+	Long: `Check that index.json and the patch objects agree: the index must parse,
+every entry needs a readable patch object, the stack recorded in the operation
+log must be in the index, each ID must match its patch and creation time, and
+every patch must be a well-formed diff.
+
+With --repair, the index is rebuilt from the operation log and the surviving
+patch objects. Mini-commits missing from the index are added back, using the
+operation log to recover their metadata; data that cannot be kept, such as a
+corrupt index, damaged objects or malformed patches, is moved to
+.git/mini-commits/lost-found/. The repair is recorded in the operation log, so
+"undo" can revert it.
+
+Exits with an error when problems are found and --repair is not given.`,
+	Args: exactArgs(0),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		mode, err := outputMode(cmd)
+		if err != nil {
+			return err
+		}
+
+		store, err := openStore()
+		if err != nil {
+			return err
+		}
+
+		repair, _ := cmd.Flags().GetBool("repair")
+		var problems []storage.Problem
+		if repair {
+			problems, err = store.Repair()
+			if err != nil {
+				return storageError(err, "failed to repair store")
+			}
+		} else {
+			problems, err = store.Check()
+			if err != nil {
+				return storageError(err, "failed to check store")
+			}
+		}
+
+		if err := writeProblems(cmd.OutOrStdout(), mode, problems, repair); err != nil {
+			return err
+		}
+		if len(problems) > 0 && !repair {
+			return newCommandError(codeCorrupt, "%d problem%s found; run \"git mini-commit fsck --repair\" to fix them",
+				len(problems), pluralS(len(problems)))
+		}
+		return nil
+	},
+}
+
+// writeProblems reports the problems found by fsck and, after a repair, what was done about them
+func writeProblems(w io.Writer, mode string, problems []storage.Problem, repaired bool) error {
+	action := "fsck"
+	if repaired {
+		action = "repair"
+	}
+
+	switch mode {
+	case outputJSON:
+		docs := make([]jsonProblem, 0, len(problems))
+		for _, p := range problems {
+			docs = append(docs, jsonProblem{Kind: p.Kind, ID: p.ID, Detail: p.Detail, Repair: p.Repair})
+		}
+		return writeJSON(w, jsonDocument{Action: action, Problems: &docs})
+	case outputPorcelainV1:
+		for _, p := range problems {
+			id := p.ID
+			if id == "" {
+				id = "-"
+			}
+			fmt.Fprintf(w, "problem %s %s %s\n", p.Kind, id, p.Detail)
+			if p.Repair != "" {
+				fmt.Fprintf(w, "repaired %s %s %s\n", p.Kind, id, p.Repair)
+			}
+		}
+		return nil
+	}
+
+	if len(problems) == 0 {
+		fmt.Fprintln(w, "No problems found")
+		return nil
+	}
+	for _, p := range problems {
+		if p.ID != "" {
+			fmt.Fprintf(w, "%s %s: %s\n", p.Kind, shortID(p.ID), p.Detail)
+		} else {
+			fmt.Fprintf(w, "%s: %s\n", p.Kind, p.Detail)
+		}
+		if p.Repair != "" {
+			fmt.Fprintf(w, "  repaired: %s\n", p.Repair)
+		}
+	}
+	if repaired {
+		fmt.Fprintf(w, "\nRepaired %d problem%s\n", len(problems), pluralS(len(problems)))
+	}
+	return nil
+}
+
+func init() {
+	fsckCmd.Flags().Bool("repair", false, "rebuild the index from the operation log and patch objects and move bad data to lost-found")
+	rootCmd.AddCommand(fsckCmd)
+}
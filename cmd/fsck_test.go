@@ -0,0 +1,80 @@
+package cmd
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"git-mini-commit/testutils"
+)
+
+func TestCLIFsck(t *testing.T) {
+	// テスト用Gitリポジトリを作成
+	repo := testutils.NewTestGitRepo(t)
+	defer repo.Cleanup()
+
+	// テスト用CLIを作成
+	cli := testutils.NewTestCLI(t)
+	cli.SetRepo(repo)
+
+	first := saveMiniCommit(t, repo, cli, "a.txt", "one\n", "First")
+	second := saveMiniCommit(t, repo, cli, "b.txt", "two\n", "Second")
+
+	// 1. 正常なストア
+	output := cli.AssertCommandSuccess(t, "fsck")
+	cli.AssertOutputContains(t, output, "No problems found")
+
+	// 2. パッチオブジェクトを1つ削除し、インデックスを破損
+	storeDir := filepath.Join(".git", "mini-commits")
+	data, err := os.ReadFile(filepath.Join(storeDir, "index.json"))
+	if err != nil {
+		t.Fatalf("Failed to read index file: %v", err)
+	}
+	var index struct {
+		MiniCommits []struct {
+			ID        string `json:"id"`
+			PatchHash string `json:"patchHash"`
+		} `json:"miniCommits"`
+	}
+	if err := json.Unmarshal(data, &index); err != nil || len(index.MiniCommits) != 2 || index.MiniCommits[0].ID != first {
+		t.Fatalf("Unexpected index: %s (%v)", data, err)
+	}
+	hash := index.MiniCommits[0].PatchHash
+	if err := os.Remove(filepath.Join(storeDir, "objects", hash[:2], hash[2:])); err != nil {
+		t.Fatalf("Failed to remove patch object: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(storeDir, "index.json"), []byte("invalid json"), 0644); err != nil {
+		t.Fatalf("Failed to corrupt index file: %v", err)
+	}
+	output = cli.AssertCommandFailure(t, "list")
+	cli.AssertOutputContains(t, output, "fsck --repair")
+
+	// 3. fsck は問題を報告してエラー終了
+	output = cli.AssertCommandFailure(t, "fsck", "--porcelain")
+	cli.AssertOutputContains(t, output, "problem corrupt-index - ")
+	cli.AssertOutputContains(t, output, "problem orphan-patch "+second+" ")
+	cli.AssertOutputContains(t, output, "problem missing-patch "+first+" ")
+	cli.AssertOutputContains(t, output, "error "+codeCorrupt+" ")
+
+	// 4. --repair で操作ログと残ったパッチオブジェクトからインデックスを再構築
+	output = cli.AssertCommandSuccess(t, "fsck", "--repair")
+	cli.AssertOutputContains(t, output, "repaired: moved to lost-found/")
+	cli.AssertOutputContains(t, output, "Repaired 3 problems")
+
+	output = cli.AssertCommandSuccess(t, "list", "--porcelain")
+	if !strings.HasPrefix(output, second+" ") || !strings.HasSuffix(output, " Second\n") || strings.Count(output, "\n") != 1 {
+		t.Errorf("Expected the surviving mini-commit with its message, but got: %s", output)
+	}
+	entries, _ := filepath.Glob(filepath.Join(storeDir, "lost-found", "*", "index.json"))
+	if len(entries) != 1 {
+		t.Errorf("Expected the corrupt index in lost-found, got %v", entries)
+	}
+
+	// 5. 修復後は問題なし、修復は操作ログに記録される
+	output = cli.AssertCommandSuccess(t, "fsck", "--json")
+	cli.AssertOutputContains(t, output, `"action": "fsck"`)
+	output = cli.AssertCommandSuccess(t, "reflog", "-n", "1")
+	cli.AssertOutputContains(t, output, "repair: 3 problem(s)")
+}
@@ -0,0 +1,102 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"git-mini-commit/internal/config"
+	"git-mini-commit/internal/storage"
+
+	"github.com/spf13/cobra"
+)
+
+var gcCmd = &cobra.Command{
+	Use:   "gc",
+	Short: "Purge old trashed mini-commits and reflog entries, and delete unused patch objects",
+	Long: `Permanently remove mini-commits that were moved to the trash longer ago than
+the retention period. The period is read from minicommit.trashRetention
+(default 30.days) and can be overridden with --prune. Both accept the same
+values as list --since ("2.weeks", "36h", "2025-01-31"), plus "now" to purge
+everything and "never" to keep everything.
+
+Purged mini-commits are removed from the operation log as well, so "undo"
+cannot bring them back.
+
+Operations logged longer ago than minicommit.reflogRetention (default 90.days,
+overridden with --expire-reflog) are removed from the operation log; "undo"
+cannot go back further than the oldest operation left. The most recent
+operation is always kept.
+
+Then delete the compressed patch objects that nothing in the stack, the trash
+or the operation log refers to any more, and report the disk space reclaimed.`,
+	Args: exactArgs(0),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		mode, err := outputMode(cmd)
+		if err != nil {
+			return err
+		}
+
+		store, err := openStore()
+		if err != nil {
+			return err
+		}
+
+		retention := flagSetting(cmd, "prune", config.TrashRetention)
+
+		var purged []storage.TrashEntry
+		summary := "Nothing to purge (retention: never)"
+		if !strings.EqualFold(strings.TrimSpace(retention), "never") {
+			cutoff, err := parseApproxDate(retention, time.Now())
+			if err != nil {
+				return newCommandError(codeUsage, "invalid trash retention: %v", err)
+			}
+			purged, err = store.PurgeTrash(cutoff)
+			if err != nil {
+				return storageError(err, "failed to purge trash")
+			}
+			summary = fmt.Sprintf("Purged %d trashed mini-commit%s removed before %s", len(purged), pluralS(len(purged)),
+				cutoff.Format("2006-01-02 15:04:05"))
+		}
+
+		expired := 0
+		reflogRetention := flagSetting(cmd, "expire-reflog", config.ReflogRetention)
+		if !strings.EqualFold(strings.TrimSpace(reflogRetention), "never") {
+			cutoff, err := parseApproxDate(reflogRetention, time.Now())
+			if err != nil {
+				return newCommandError(codeUsage, "invalid reflog retention: %v", err)
+			}
+			expired, err = store.ExpireOperations(cutoff)
+			if err != nil {
+				return storageError(err, "failed to expire the reflog")
+			}
+		}
+
+		pruned, err := store.PruneObjects()
+		if err != nil {
+			return storageError(err, "failed to prune patch objects")
+		}
+
+		return writePurged(cmd.OutOrStdout(), mode, "gc", purged, &expired, pruned, summary)
+	},
+}
+
+// formatBytes formats a size for people, e.g. "12.3 KiB"
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
+
+func init() {
+	gcCmd.Flags().String("prune", "", "purge trashed mini-commits removed before this date instead of the configured retention")
+	gcCmd.Flags().String("expire-reflog", "", "remove operations logged before this date instead of the configured retention")
+	rootCmd.AddCommand(gcCmd)
+}
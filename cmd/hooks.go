@@ -0,0 +1,63 @@
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+
+	"git-mini-commit/internal/git"
+	"git-mini-commit/internal/hooks"
+	"git-mini-commit/internal/types"
+
+	"github.com/spf13/cobra"
+)
+
+// runHook runs the hook name if the repository has one, with mc described in
+// the environment and as a JSON document on stdin. A failing hook is reported
+// as hook_failed.
+func runHook(cmd *cobra.Command, name string, mc *types.MiniCommit) error {
+	dir, workTree, err := git.HookPaths()
+	if err != nil {
+		return gitError(err)
+	}
+	path, err := hooks.Find(dir, name)
+	if errors.Is(err, hooks.ErrNotExecutable) {
+		fmt.Fprintf(cmd.ErrOrStderr(), "hint: the '%s' hook was ignored because it is not executable\n", path)
+		return nil
+	}
+	if path == "" {
+		return nil
+	}
+
+	var stdin bytes.Buffer
+	doc := toJSON(mc, false)
+	if err := writeJSON(&stdin, jsonDocument{Action: name, MiniCommit: &doc}); err != nil {
+		return err
+	}
+	if err := hooks.Run(path, workTree, hookEnv(name, mc), stdin.Bytes(), cmd.ErrOrStderr()); err != nil {
+		return newCommandError(codeHookFailed, "%v", err)
+	}
+	return nil
+}
+
+// runPostHook runs a hook once the operation is done, when a failure can only be reported
+func runPostHook(cmd *cobra.Command, name string, mc *types.MiniCommit) {
+	if err := runHook(cmd, name, mc); err != nil {
+		fmt.Fprintf(cmd.ErrOrStderr(), "warning: %v\n", err)
+	}
+}
+
+// hookEnv describes mc to a hook; the ID is not known before the mini-commit is saved
+func hookEnv(name string, mc *types.MiniCommit) []string {
+	env := []string{
+		"MINI_COMMIT_HOOK=" + name,
+		"MINI_COMMIT_MESSAGE=" + mc.Message,
+		"MINI_COMMIT_BRANCH=" + mc.Branch,
+		"MINI_COMMIT_AUTHOR=" + mc.Author,
+		"MINI_COMMIT_BASE=" + mc.Base,
+	}
+	if mc.ID != "" {
+		env = append(env, "MINI_COMMIT_ID="+mc.ID, "MINI_COMMIT_SHORT_ID="+shortID(mc.ID))
+	}
+	return env
+}
@@ -0,0 +1,116 @@
+package cmd
+
+import (
+	"encoding/json"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"git-mini-commit/testutils"
+)
+
+// gitOutput テスト用リポジトリで git を実行し、出力を返す
+func gitOutput(t *testing.T, args ...string) string {
+	t.Helper()
+	out, err := exec.Command("git", args...).Output()
+	if err != nil {
+		t.Fatalf("git %v failed: %v", args, err)
+	}
+	return string(out)
+}
+
+// writeHook テスト用リポジトリにフックを作成する
+func writeHook(t *testing.T, dir, name, script string) {
+	t.Helper()
+	os.MkdirAll(dir, 0755)
+	if err := os.WriteFile(filepath.Join(dir, name), []byte("#!/bin/sh\n"+script), 0755); err != nil {
+		t.Fatalf("Failed to write the %s hook: %v", name, err)
+	}
+}
+
+func TestCLIHooks(t *testing.T) {
+	repo := testutils.NewTestGitRepo(t)
+	defer repo.Cleanup()
+	cli := testutils.NewTestCLI(t)
+	cli.SetRepo(repo)
+
+	hooksDir := filepath.Join(".git", "hooks")
+	log := filepath.Join(t.TempDir(), "hooks.log")
+
+	// 1. pre-mini-commit が失敗すると保存されず、--no-verify で省略できる
+	repo.CreateTestFile("a.txt", "a\n")
+	repo.StageFile("a.txt")
+	writeHook(t, hooksDir, "pre-mini-commit", "echo \"checking $MINI_COMMIT_MESSAGE on $MINI_COMMIT_BRANCH\"\nexit 1\n")
+	output := cli.AssertExitCode(t, 15, "-m", "Add a")
+	cli.AssertOutputContains(t, output, "checking Add a on")
+	cli.AssertOutputContains(t, output, "the pre-mini-commit hook exited with status 1")
+	cli.AssertOutputContains(t, output, "--no-verify")
+	if list := cli.AssertCommandSuccess(t, "list", "--porcelain"); list != "" {
+		t.Errorf("Expected nothing saved, got %q", list)
+	}
+	cli.AssertCommandSuccess(t, "-m", "Add a", "--no-verify")
+
+	// 2. pre-mini-commit がステージングしたファイルも保存される
+	repo.CreateTestFile("b.txt", "b\n")
+	repo.StageFile("b.txt")
+	repo.CreateTestFile("formatted.txt", "formatted\n")
+	writeHook(t, hooksDir, "pre-mini-commit", "git add formatted.txt\n")
+	writeHook(t, hooksDir, "post-mini-commit", "echo \"$MINI_COMMIT_HOOK $MINI_COMMIT_SHORT_ID\" >> "+log+"\ncat >> "+log+"\n")
+	var doc jsonDocument
+	output = cli.AssertCommandSuccess(t, "-m", "Add b", "--json")
+	if err := json.Unmarshal([]byte(output), &doc); err != nil || doc.MiniCommit == nil {
+		t.Fatalf("Failed to parse JSON output: %v, output: %s", err, output)
+	}
+	id := doc.MiniCommit.ShortID
+	if doc.MiniCommit.Stats.Files != 3 {
+		t.Errorf("Expected the file staged by the hook to be saved, got %+v", doc.MiniCommit.Stats)
+	}
+
+	// 3. post-mini-commit は環境変数と標準入力のJSONでmini-commitを受け取る
+	content, _ := os.ReadFile(log)
+	header, input, _ := strings.Cut(string(content), "\n")
+	if header != "post-mini-commit "+id {
+		t.Errorf("Unexpected hook environment: %q", header)
+	}
+	var hookDoc jsonDocument
+	if err := json.Unmarshal([]byte(input), &hookDoc); err != nil || hookDoc.Action != "post-mini-commit" ||
+		hookDoc.MiniCommit == nil || hookDoc.MiniCommit.ShortID != id || hookDoc.MiniCommit.Message != "Add b" {
+		t.Errorf("Unexpected hook input: %v\n%s", err, input)
+	}
+
+	// 4. pre-pop が失敗するとステージングされない。post-pop は適用後に実行される
+	gitOutput(t, "rm", "-q", "--cached", "a.txt", "b.txt", "formatted.txt")
+	writeHook(t, hooksDir, "pre-pop", "exit 2\n")
+	writeHook(t, hooksDir, "post-pop", "echo \"popped $MINI_COMMIT_ID\" > "+log+"\n")
+	output = cli.AssertExitCode(t, 15, "pop", id)
+	cli.AssertOutputContains(t, output, "the pre-pop hook exited with status 2")
+	if staged := gitOutput(t, "diff", "--cached", "--name-only"); staged != "" {
+		t.Errorf("Expected nothing applied, got %q", staged)
+	}
+	cli.AssertCommandSuccess(t, "pop", id, "--no-verify")
+	if content, _ := os.ReadFile(log); !strings.HasPrefix(string(content), "popped "+id) {
+		t.Errorf("Expected the post-pop hook to run, got %q", content)
+	}
+
+	// 5. post フックの失敗は警告のみ。core.hooksPath のフックが使われる
+	custom := filepath.Join(t.TempDir(), "hooks")
+	gitConfig(t, "core.hooksPath", custom)
+	writeHook(t, custom, "post-drop", "echo \"dropped $MINI_COMMIT_SHORT_ID\"\nexit 1\n")
+	_, stderr, err := cli.RunCommand("drop", id)
+	if err != nil {
+		t.Fatalf("drop failed: %v", err)
+	}
+	cli.AssertOutputContains(t, stderr, "dropped "+id)
+	cli.AssertOutputContains(t, stderr, "warning: the post-drop hook exited with status 1")
+
+	// 6. 実行できないフックは無視してヒントを表示する
+	os.Chmod(filepath.Join(custom, "post-drop"), 0644)
+	first, _, _ := strings.Cut(cli.AssertCommandSuccess(t, "list", "--porcelain"), " ")
+	_, stderr, err = cli.RunCommand("drop", first)
+	if err != nil {
+		t.Fatalf("drop failed: %v", err)
+	}
+	cli.AssertOutputContains(t, stderr, "hook was ignored because it is not executable")
+}
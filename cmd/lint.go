@@ -0,0 +1,207 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"git-mini-commit/internal/config"
+	"git-mini-commit/internal/lint"
+	"git-mini-commit/internal/types"
+
+	"github.com/spf13/cobra"
+)
+
+var lintCmd = &cobra.Command{
+	Use:   "lint [<mini-commit>...]",
+	Short: "Check messages against the conventional commit rules",
+	Long: `Check the messages of the given mini-commits, or of the whole stack, against
+the conventional commit rules: a "type(scope): description" subject line of a
+known type and length, and a blank line before the body.
+
+--message-file checks a message file instead, so that the same rules can be
+applied when the mini-commits are integrated with git commit, from a commit-msg
+hook:
+
+  git mini-commit lint --message-file "$1"
+
+The rules are set with git config:
+
+  minicommit.lint                  check messages when mini-commits are created
+  minicommit.lintTypes             allowed types (default: feat, fix, docs, style,
+                                   refactor, perf, test, build, ci, chore, revert)
+  minicommit.lintScope             optional (default), required or forbidden
+  minicommit.lintScopes            allowed scopes (default: any)
+  minicommit.lintSubjectMaxLength  longest subject line (default 72, 0 for no limit)
+
+Exits with an error when a message breaks a rule.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		mode, err := outputMode(cmd)
+		if err != nil {
+			return err
+		}
+
+		file, _ := cmd.Flags().GetString("message-file")
+		if file != "" && len(args) > 0 {
+			return newCommandError(codeUsage, "--message-file cannot be used with mini-commits")
+		}
+
+		rules, _, err := lintRulesFromConfig()
+		if err != nil {
+			return err
+		}
+
+		// Collect the messages to check; the message file is cleaned up as git commit would
+		var results []lintResult
+		if file != "" {
+			content, err := os.ReadFile(file)
+			if err != nil {
+				return newCommandError(codeUsage, "could not read message file '%s': %v", file, err)
+			}
+			message := cleanupMessage(string(content), true)
+			results = append(results, lintResult{message: message, problems: rules.Check(message)})
+		} else {
+			store, err := openStore()
+			if err != nil {
+				return err
+			}
+			miniCommits, err := store.LoadMiniCommits()
+			if err != nil {
+				return storageError(err, "failed to load mini-commits")
+			}
+			selected := miniCommits
+			if len(args) > 0 {
+				selected = nil
+				for _, arg := range args {
+					mc, _, err := resolveMiniCommit(miniCommits, arg)
+					if err != nil {
+						return err
+					}
+					selected = append(selected, *mc)
+				}
+			}
+			for i := range selected {
+				results = append(results, lintResult{mc: &selected[i], message: selected[i].Message,
+					problems: rules.Check(selected[i].Message)})
+			}
+		}
+
+		failed := 0
+		for _, r := range results {
+			if len(r.problems) > 0 {
+				failed++
+			}
+		}
+		if err := writeLintResults(cmd.OutOrStdout(), mode, results); err != nil {
+			return err
+		}
+		if failed > 0 {
+			return newCommandError(codeLintFailed, "found problems in %d message%s", failed, pluralS(failed))
+		}
+		return nil
+	},
+}
+
+// lintResult is the outcome of checking one message; mc is nil for a message file
+type lintResult struct {
+	mc       *types.MiniCommit
+	message  string
+	problems []lint.Problem
+}
+
+// writeLintResults reports the messages that break a rule
+func writeLintResults(w io.Writer, mode string, results []lintResult) error {
+	switch mode {
+	case outputJSON:
+		docs := make([]jsonLintResult, 0, len(results))
+		for _, r := range results {
+			if len(r.problems) == 0 {
+				continue
+			}
+			doc := jsonLintResult{Subject: subject(r.message), Problems: make([]jsonLintProblem, 0, len(r.problems))}
+			if r.mc != nil {
+				doc.ID = r.mc.ID
+			}
+			for _, p := range r.problems {
+				doc.Problems = append(doc.Problems, jsonLintProblem{Rule: p.Rule, Message: p.Message})
+			}
+			docs = append(docs, doc)
+		}
+		return writeJSON(w, jsonDocument{Lint: &docs})
+	case outputPorcelainV1:
+		for _, r := range results {
+			id := "-"
+			if r.mc != nil {
+				id = r.mc.ID
+			}
+			for _, p := range r.problems {
+				fmt.Fprintf(w, "problem %s %s %s\n", p.Rule, id, p.Message)
+			}
+		}
+		return nil
+	}
+
+	found := false
+	for _, r := range results {
+		if len(r.problems) == 0 {
+			continue
+		}
+		found = true
+		if r.mc != nil {
+			fmt.Fprintf(w, "%s %s\n", shortID(r.mc.ID), subject(r.message))
+		} else {
+			fmt.Fprintln(w, subject(r.message))
+		}
+		for _, p := range r.problems {
+			fmt.Fprintf(w, "  %s\n", p)
+		}
+	}
+	if !found {
+		fmt.Fprintln(w, "No problems found")
+	}
+	return nil
+}
+
+// lintRulesFromConfig reads the commit message rules from the configuration
+// and whether minicommit.lint asks for them to be checked when creating mini-commits
+func lintRulesFromConfig() (lint.Rules, bool, error) {
+	rules := lint.Rules{
+		Types:  settings.List(config.LintTypes),
+		Scope:  settings.String(config.LintScope),
+		Scopes: settings.List(config.LintScopes),
+	}
+
+	enabled, err := settings.Bool(config.Lint)
+	if err != nil {
+		return rules, false, configError(err)
+	}
+	switch rules.Scope {
+	case lint.ScopeOptional, lint.ScopeRequired, lint.ScopeForbidden:
+	default:
+		return rules, false, newCommandError(codeUsage, "minicommit.lintScope: expected optional, required or forbidden, got '%s'", rules.Scope)
+	}
+	if rules.SubjectMaxLength, err = settings.Int(config.LintSubjectMaxLength); err != nil {
+		return rules, false, configError(err)
+	}
+
+	return rules, enabled, nil
+}
+
+// checkMessage returns a lint_failed error listing the rules message breaks
+func checkMessage(rules lint.Rules, message string) error {
+	problems := rules.Check(message)
+	if len(problems) == 0 {
+		return nil
+	}
+	lines := make([]string, 0, len(problems))
+	for _, p := range problems {
+		lines = append(lines, "  "+p.String())
+	}
+	return newCommandError(codeLintFailed, "message breaks the commit message rules:\n%s", strings.Join(lines, "\n"))
+}
+
+func init() {
+	lintCmd.Flags().String("message-file", "", "check the message in the file, e.g. from a commit-msg hook")
+	rootCmd.AddCommand(lintCmd)
+}
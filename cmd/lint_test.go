@@ -0,0 +1,162 @@
+package cmd
+
+import (
+	"encoding/json"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"git-mini-commit/testutils"
+)
+
+// gitConfig テスト用リポジトリで git config を実行する
+func gitConfig(t *testing.T, args ...string) {
+	t.Helper()
+	if out, err := exec.Command("git", append([]string{"config"}, args...)...).CombinedOutput(); err != nil {
+		t.Fatalf("git config %v failed: %v\n%s", args, err, out)
+	}
+}
+
+func TestCLILint(t *testing.T) {
+	repo := testutils.NewTestGitRepo(t)
+	defer repo.Cleanup()
+	cli := testutils.NewTestCLI(t)
+	cli.SetRepo(repo)
+
+	stage := func(name, content string) {
+		t.Helper()
+		repo.CreateTestFile(name, content)
+		if err := repo.StageFile(name); err != nil {
+			t.Fatalf("Failed to stage %s: %v", name, err)
+		}
+	}
+
+	// 1. minicommit.lint が無効なら、規則に合わないメッセージも保存できる
+	stage("a.txt", "a\n")
+	cli.AssertCommandSuccess(t, "-m", "Update a")
+
+	// 2. 有効にすると作成時に検査され、違反は終了ステータス 14 で拒否される
+	gitConfig(t, "minicommit.lint", "true")
+	stage("b.txt", "b\n")
+	output := cli.AssertExitCode(t, 14, "-m", "feature: add b")
+	cli.AssertOutputContains(t, output, "type-enum: type 'feature' is not one of")
+	cli.AssertCommandSuccess(t, "-m", "feat: add b")
+
+	// 3. 規則は git config で変えられる
+	gitConfig(t, "minicommit.lintScope", "required")
+	gitConfig(t, "minicommit.lintScopes", "ui, api")
+	stage("c.txt", "c\n")
+	output = cli.AssertExitCode(t, 14, "-m", "feat(db): add c")
+	cli.AssertOutputContains(t, output, "scope-enum")
+	cli.AssertCommandSuccess(t, "-m", "feat(ui): add c")
+	gitConfig(t, "minicommit.lintScope", "sometimes")
+	output = cli.AssertExitCode(t, 2, "lint")
+	cli.AssertOutputContains(t, output, "minicommit.lintScope")
+	gitConfig(t, "--unset", "minicommit.lintScope")
+	gitConfig(t, "--unset", "minicommit.lintScopes")
+
+	// 4. lint はスタックのメッセージを検査する
+	output = cli.AssertExitCode(t, 14, "lint")
+	cli.AssertOutputContains(t, output, "Update a")
+	cli.AssertOutputContains(t, output, "header-format")
+	cli.AssertOutputContains(t, output, "found problems in 1 message")
+
+	var doc struct {
+		Lint []jsonLintResult `json:"lint"`
+	}
+	stdout, _, _ := cli.RunCommand("lint", "--json")
+	if err := json.Unmarshal([]byte(stdout), &doc); err != nil {
+		t.Fatalf("Failed to parse JSON output: %v, output: %s", err, stdout)
+	}
+	if len(doc.Lint) != 1 || doc.Lint[0].Subject != "Update a" || doc.Lint[0].Problems[0].Rule != "header-format" {
+		t.Errorf("Unexpected lint results: %+v", doc.Lint)
+	}
+
+	// 5. --message-file は commit-msg フックのようにファイルを検査する（コメントは除く）
+	messageFile := filepath.Join(t.TempDir(), "COMMIT_EDITMSG")
+	os.WriteFile(messageFile, []byte("fix: typo\n\n# Please enter the commit message\n"), 0644)
+	output = cli.AssertCommandSuccess(t, "lint", "--message-file", messageFile)
+	cli.AssertOutputContains(t, output, "No problems found")
+	os.WriteFile(messageFile, []byte("fix: typo.\n"), 0644)
+	output = cli.AssertExitCode(t, 14, "lint", "--message-file", messageFile)
+	cli.AssertOutputContains(t, output, "subject-full-stop")
+}
+
+func TestCLITrailers(t *testing.T) {
+	repo := testutils.NewTestGitRepo(t)
+	defer repo.Cleanup()
+	cli := testutils.NewTestCLI(t)
+	cli.SetRepo(repo)
+
+	repo.CreateTestFile("a.txt", "a\n")
+	if err := repo.StageFile("a.txt"); err != nil {
+		t.Fatalf("Failed to stage a.txt: %v", err)
+	}
+
+	// 1. 不正なトレーラーは使い方の誤り
+	output := cli.AssertExitCode(t, 2, "-m", "Add a", "--trailer", "no value")
+	cli.AssertOutputContains(t, output, "invalid trailer 'no value'")
+
+	// 2. トレーラーはメッセージとは別に保存され、show で表示される
+	var doc jsonDocument
+	output = cli.AssertCommandSuccess(t, "-m", "Add a", "--trailer", "Refs=#12", "--trailer", "Reviewed-by: Alice", "--json")
+	if err := json.Unmarshal([]byte(output), &doc); err != nil || doc.MiniCommit == nil {
+		t.Fatalf("Failed to parse JSON output: %v, output: %s", err, output)
+	}
+	id := doc.MiniCommit.ShortID
+	output = cli.AssertCommandSuccess(t, "show", id, "--json")
+	if err := json.Unmarshal([]byte(output), &doc); err != nil || doc.MiniCommit == nil {
+		t.Fatalf("Failed to parse JSON output: %v, output: %s", err, output)
+	}
+	trailers := doc.MiniCommit.Trailers
+	if doc.MiniCommit.Message != "Add a" || len(trailers) != 2 ||
+		trailers[0].Key != "Refs" || trailers[0].Value != "#12" || trailers[1].Key != "Reviewed-by" {
+		t.Errorf("Unexpected mini-commit: %+v", doc.MiniCommit)
+	}
+	output = cli.AssertCommandSuccess(t, "show", id)
+	cli.AssertOutputContains(t, output, "Trailers:\n  Refs: #12\n  Reviewed-by: Alice")
+	// porcelain v1 の形式は変えない
+	output = cli.AssertCommandSuccess(t, "show", id, "--porcelain")
+	if strings.Contains(output, "trailer") || !strings.HasPrefix(output, "id "+doc.MiniCommit.ID+"\ncreated ") ||
+		!strings.Contains(output, "\nstats 1 1 0\nmessage 5\nAdd a\npatch ") {
+		t.Errorf("Expected the porcelain v1 format without trailers, got:\n%s", output)
+	}
+}
+
+func TestCLIMessageTemplate(t *testing.T) {
+	repo := testutils.NewTestGitRepo(t)
+	defer repo.Cleanup()
+	cli := testutils.NewTestCLI(t)
+	cli.SetRepo(repo)
+
+	repo.CreateTestFile("a.txt", "a\n")
+	if err := repo.StageFile("a.txt"); err != nil {
+		t.Fatalf("Failed to stage a.txt: %v", err)
+	}
+	dir := t.TempDir()
+	template := filepath.Join(dir, "template.txt")
+	os.WriteFile(template, []byte("feat: \n\n# Why is this change needed?\n"), 0644)
+	gitConfig(t, "minicommit.template", template)
+
+	// 1. テンプレートを編集しなければ中止する
+	t.Setenv("GIT_EDITOR", ":")
+	output := cli.AssertExitCode(t, 2)
+	cli.AssertOutputContains(t, output, "the template was not edited")
+
+	// 2. エディタはテンプレートから始まる
+	script := filepath.Join(dir, "editor.sh")
+	os.WriteFile(script, []byte(`sed -i '1s/$/add a/' "$1"`), 0755)
+	t.Setenv("GIT_EDITOR", "sh "+script)
+	if got := createdMessage(t, cli); got != "feat: add a" {
+		t.Errorf("Expected the edited template, got %q", got)
+	}
+
+	// 3. 読めないテンプレートはエラー
+	gitConfig(t, "minicommit.template", filepath.Join(dir, "missing"))
+	repo.CreateTestFile("b.txt", "b\n")
+	repo.StageFile("b.txt")
+	output = cli.AssertExitCode(t, 2)
+	cli.AssertOutputContains(t, output, "could not read template file")
+}
@@ -2,7 +2,9 @@ package cmd
 
 import (
 	"fmt"
+	"time"
 
+	"git-mini-commit/internal/config"
 	"git-mini-commit/internal/git"
 	"git-mini-commit/internal/storage"
 
@@ -12,44 +14,169 @@ import (
 var listCmd = &cobra.Command{
 	Use:   "list",
 	Short: "List saved mini-commits",
-	Long:  `Display a list of all saved mini-commits.`,
-	Args:  cobra.ExactArgs(0),
+	Long: `Display a list of all saved mini-commits, oldest first.
+
+The layout can be changed with --oneline or --format, which accepts "default",
+"oneline" or a Go text/template. Templates can use the fields .Index, .ID,
+.ShortID, .Message, .Subject, .CreatedAt, .Date, .Stats (.Files, .Insertions,
+.Deletions), the recorded .Branch, .Author and .Base, which are empty when not
+recorded, and .Trailers (.Key, .Value), and the functions "relative" and
+"date", e.g.
+
+  git mini-commit list --format '{{.ShortID}} {{.Subject}} ({{relative .CreatedAt}}, +{{.Stats.Insertions}}/-{{.Stats.Deletions}})'
+
+The default format and date mode can be set with the git config keys
+minicommit.listFormat and minicommit.listDate.
+
+Filters narrow the list: --path (touched files), --grep (message regex),
+-G (regex on added/removed lines), -S (change in occurrence count of a string),
+--since/--until, --branch and --author. Metadata filters are evaluated before
+patch contents are scanned.`,
+	Args: exactArgs(0),
 	RunE: func(cmd *cobra.Command, args []string) error {
+		mode, err := outputMode(cmd)
+		if err != nil {
+			return err
+		}
+
+		limit, _ := cmd.Flags().GetInt("limit")
+		if limit < 0 {
+			return newCommandError(codeUsage, "--limit must not be negative")
+		}
+		reverse, _ := cmd.Flags().GetBool("reverse")
+		filter, err := newFilterFromFlags(cmd, time.Now())
+		if err != nil {
+			return err
+		}
+
 		// Check if it's a Git repository
-		if !git.IsGitRepository() {
-			return fmt.Errorf("not a git repository")
+		if err := git.CheckRepository(); err != nil {
+			return gitError(err)
 		}
 
 		// Initialize storage
 		storage, err := storage.NewStorage()
 		if err != nil {
-			return fmt.Errorf("failed to initialize storage: %v", err)
+			return storageError(err, "failed to initialize storage")
 		}
 
 		// Get mini-commit list
 		miniCommits, err := storage.LoadMiniCommits()
 		if err != nil {
-			return fmt.Errorf("failed to load mini-commits: %v", err)
+			return storageError(err, "failed to load mini-commits")
+		}
+
+		// Select entries: filters first, then --limit keeps the most recent ones
+		// and --reverse shows newest first. Indexes keep the position in the full stack.
+		// Patches are only read for entries left by the metadata criteria.
+		var indexes []int
+		selected := miniCommits[:0]
+		for i := range miniCommits {
+			mc := &miniCommits[i]
+			if !filter.matchMetadata(mc) {
+				continue
+			}
+			if filter.needsPatch() {
+				if err := loadPatches(storage, miniCommits[i:i+1]); err != nil {
+					return err
+				}
+				matched := filter.matchPatch(mc.Patch)
+				// Only one patch is held at a time; the index has the stats shown
+				mc.Patch = ""
+				if !matched {
+					continue
+				}
+			}
+			selected = append(selected, *mc)
+			indexes = append(indexes, i+1)
+		}
+		miniCommits = selected
+		if limit > 0 && limit < len(miniCommits) {
+			miniCommits = miniCommits[len(miniCommits)-limit:]
+			indexes = indexes[len(indexes)-limit:]
+		}
+		if reverse {
+			for i, j := 0, len(miniCommits)-1; i < j; i, j = i+1, j-1 {
+				miniCommits[i], miniCommits[j] = miniCommits[j], miniCommits[i]
+				indexes[i], indexes[j] = indexes[j], indexes[i]
+			}
+		}
+
+		out := cmd.OutOrStdout()
+		switch mode {
+		case outputJSON:
+			docs := make([]jsonMiniCommit, 0, len(miniCommits))
+			for i := range miniCommits {
+				docs = append(docs, toJSON(&miniCommits[i], false))
+			}
+			return writeJSON(out, jsonDocument{MiniCommits: &docs})
+		case outputPorcelainV1:
+			for i := range miniCommits {
+				writePorcelainEntry(out, &miniCommits[i])
+			}
+			return nil
+		}
+
+		formatter, err := listFormatterFromFlags(cmd)
+		if err != nil {
+			return err
 		}
 
 		if len(miniCommits) == 0 {
-			fmt.Println("No mini-commits found")
+			fmt.Fprintln(out, "No mini-commits found")
 			return nil
 		}
 
-		// Display list
-		fmt.Printf("Mini-commits (%d):\n\n", len(miniCommits))
-		for i, mc := range miniCommits {
-			fmt.Printf("%d. ID: %s\n", i+1, mc.ID[:8])
-			fmt.Printf("   Message: %s\n", mc.Message)
-			fmt.Printf("   Created: %s\n", mc.CreatedAt.Format("2006-01-02 15:04:05"))
-			fmt.Println()
+		if formatter.color, err = colorEnabled(cmd, ""); err != nil {
+			return err
 		}
+		stopPager, err := startPager(cmd)
+		if err != nil {
+			return err
+		}
+		defer stopPager()
+		out = cmd.OutOrStdout()
 
-		return nil
+		// Display list
+		return formatter.write(out, miniCommits, indexes)
 	},
 }
 
+// listFormatterFromFlags resolves the list format from flags, falling back to git config
+func listFormatterFromFlags(cmd *cobra.Command) (*listFormatter, error) {
+	format := flagSetting(cmd, "format", config.ListFormat)
+	if oneline, _ := cmd.Flags().GetBool("oneline"); oneline {
+		if cmd.Flags().Changed("format") {
+			return nil, newCommandError(codeUsage, "--oneline and --format cannot be used together")
+		}
+		format = listFormatOneline
+	}
+	dateFormat := flagSetting(cmd, "date", config.ListDate)
+
+	formatter, err := newListFormatter(format, dateFormat, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	formatter.withStat, _ = cmd.Flags().GetBool("stat")
+	return formatter, nil
+}
+
 func init() {
+	listCmd.Flags().Bool("oneline", false, "show each mini-commit on a single line")
+	listCmd.Flags().String("format", "", "output format: default, oneline or a Go text/template")
+	listCmd.Flags().String("date", "", "date format: default, relative, iso, short or unix")
+	listCmd.Flags().Bool("reverse", false, "show the newest mini-commits first")
+	listCmd.Flags().Bool("stat", false, "show the number of changed files and lines")
+	listCmd.Flags().IntP("limit", "n", 0, "show only the most recent <n> mini-commits")
+	listCmd.Flags().StringArray("path", nil, "only mini-commits touching the given pathspec (repeatable)")
+	listCmd.Flags().StringArray("grep", nil, "only mini-commits whose message matches the regex (repeatable)")
+	listCmd.Flags().StringP("diff-regex", "G", "", "only mini-commits with added/removed lines matching the regex")
+	listCmd.Flags().StringP("pickaxe", "S", "", "only mini-commits changing the number of occurrences of the string")
+	listCmd.Flags().Bool("pickaxe-regex", false, "treat the -S argument as a regex")
+	listCmd.Flags().BoolP("regexp-ignore-case", "i", false, "match --grep, --author, -G and -S case-insensitively")
+	listCmd.Flags().String("since", "", "only mini-commits created after the date (e.g. \"2 days ago\", 2025-01-31)")
+	listCmd.Flags().String("until", "", "only mini-commits created before the date")
+	listCmd.Flags().StringArray("branch", nil, "only mini-commits created on the branch (repeatable)")
+	listCmd.Flags().String("author", "", "only mini-commits whose author matches the regex")
 	rootCmd.AddCommand(listCmd)
 }
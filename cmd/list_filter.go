@@ -0,0 +1,251 @@
+package cmd
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"git-mini-commit/internal/patch"
+	"git-mini-commit/internal/types"
+
+	"github.com/spf13/cobra"
+)
+
+// miniCommitFilter selects mini-commits for list. Metadata criteria are checked
+// before the patch is scanned so that cheap filters short-circuit expensive ones.
+type miniCommitFilter struct {
+	since    time.Time
+	until    time.Time
+	branches []string
+	author   *regexp.Regexp
+	grep     []*regexp.Regexp
+	paths    []string
+	diffG    *regexp.Regexp
+	pickaxe  string
+	pickRe   *regexp.Regexp
+}
+
+// newFilterFromFlags builds a filter from the list flags
+func newFilterFromFlags(cmd *cobra.Command, now time.Time) (*miniCommitFilter, error) {
+	flags := cmd.Flags()
+	f := &miniCommitFilter{}
+	var err error
+
+	if value, _ := flags.GetString("since"); value != "" {
+		if f.since, err = parseApproxDate(value, now); err != nil {
+			return nil, newCommandError(codeUsage, "invalid --since: %v", err)
+		}
+	}
+	if value, _ := flags.GetString("until"); value != "" {
+		if f.until, err = parseApproxDate(value, now); err != nil {
+			return nil, newCommandError(codeUsage, "invalid --until: %v", err)
+		}
+	}
+
+	f.branches, _ = flags.GetStringArray("branch")
+	f.paths, _ = flags.GetStringArray("path")
+	for i, p := range f.paths {
+		f.paths[i] = strings.TrimSuffix(path.Clean(p), "/")
+	}
+
+	ignoreCase, _ := flags.GetBool("regexp-ignore-case")
+	compile := func(flag, expr string) (*regexp.Regexp, error) {
+		if ignoreCase {
+			expr = "(?i)" + expr
+		}
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			return nil, newCommandError(codeUsage, "invalid %s pattern: %v", flag, err)
+		}
+		return re, nil
+	}
+
+	if value, _ := flags.GetString("author"); value != "" {
+		if f.author, err = compile("--author", value); err != nil {
+			return nil, err
+		}
+	}
+	patterns, _ := flags.GetStringArray("grep")
+	for _, p := range patterns {
+		re, err := compile("--grep", p)
+		if err != nil {
+			return nil, err
+		}
+		f.grep = append(f.grep, re)
+	}
+	if value, _ := flags.GetString("diff-regex"); value != "" {
+		if f.diffG, err = compile("-G", value); err != nil {
+			return nil, err
+		}
+	}
+	if value, _ := flags.GetString("pickaxe"); value != "" {
+		f.pickaxe = value
+		if pickaxeRegex, _ := flags.GetBool("pickaxe-regex"); pickaxeRegex {
+			if f.pickRe, err = compile("-S", value); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return f, nil
+}
+
+// needsPatch reports whether matching requires the patch body
+func (f *miniCommitFilter) needsPatch() bool {
+	return len(f.paths) > 0 || f.diffG != nil || f.pickaxe != ""
+}
+
+// matchMetadata checks the criteria that only need the index entry
+func (f *miniCommitFilter) matchMetadata(mc *types.MiniCommit) bool {
+	if !f.since.IsZero() && mc.CreatedAt.Before(f.since) {
+		return false
+	}
+	if !f.until.IsZero() && mc.CreatedAt.After(f.until) {
+		return false
+	}
+	if len(f.branches) > 0 && !containsString(f.branches, mc.Branch) {
+		return false
+	}
+	if f.author != nil && !f.author.MatchString(mc.Author) {
+		return false
+	}
+	// Like git log, several --grep patterns match when any of them matches
+	if len(f.grep) > 0 {
+		matched := false
+		for _, re := range f.grep {
+			if re.MatchString(mc.Message) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+	return true
+}
+
+// matchPatch checks the criteria that need the patch body
+func (f *miniCommitFilter) matchPatch(p string) bool {
+	if !f.needsPatch() {
+		return true
+	}
+	if len(f.paths) > 0 && !f.matchPaths(patch.ChangedPaths(p)) {
+		return false
+	}
+	if f.diffG == nil && f.pickaxe == "" {
+		return true
+	}
+
+	// -G looks for a matching added/removed line; -S compares occurrence counts
+	foundG := false
+	removed, added := 0, 0
+	patch.ForEachChange(p, func(op byte, line string) {
+		if f.diffG != nil && !foundG && f.diffG.MatchString(line) {
+			foundG = true
+		}
+		if f.pickaxe != "" {
+			if op == '+' {
+				added += f.countPickaxe(line)
+			} else {
+				removed += f.countPickaxe(line)
+			}
+		}
+	})
+
+	if f.diffG != nil && !foundG {
+		return false
+	}
+	if f.pickaxe != "" && added == removed {
+		return false
+	}
+	return true
+}
+
+// countPickaxe counts occurrences of the -S string (or regex) in a line
+func (f *miniCommitFilter) countPickaxe(line string) int {
+	if f.pickRe != nil {
+		return len(f.pickRe.FindAllStringIndex(line, -1))
+	}
+	return strings.Count(line, f.pickaxe)
+}
+
+// matchPaths reports whether any changed path matches a --path pathspec
+func (f *miniCommitFilter) matchPaths(changed []string) bool {
+	for _, spec := range f.paths {
+		for _, p := range changed {
+			if spec == "." || p == spec || strings.HasPrefix(p, spec+"/") {
+				return true
+			}
+			if ok, _ := path.Match(spec, p); ok {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// containsString reports whether list contains s
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+// relativeDate matches expressions such as "3 days ago" or "2.weeks.ago"
+var relativeDate = regexp.MustCompile(`^(\d+)[ .]*(second|minute|hour|day|week|month|year)s?([ .]*ago)?$`)
+
+// parseApproxDate parses the date formats accepted by --since/--until:
+// absolute dates, Go durations ("36h"), "<n> <unit> ago", "now", "today" and "yesterday"
+func parseApproxDate(value string, now time.Time) (time.Time, error) {
+	value = strings.TrimSpace(value)
+
+	switch strings.ToLower(value) {
+	case "now":
+		return now, nil
+	case "today":
+		y, m, d := now.Date()
+		return time.Date(y, m, d, 0, 0, 0, 0, now.Location()), nil
+	case "yesterday":
+		y, m, d := now.AddDate(0, 0, -1).Date()
+		return time.Date(y, m, d, 0, 0, 0, 0, now.Location()), nil
+	}
+
+	for _, layout := range []string{time.RFC3339, "2006-01-02 15:04:05", "2006-01-02T15:04:05", "2006-01-02 15:04", "2006-01-02"} {
+		if t, err := time.ParseInLocation(layout, value, now.Location()); err == nil {
+			return t, nil
+		}
+	}
+
+	if d, err := time.ParseDuration(value); err == nil {
+		return now.Add(-d), nil
+	}
+
+	if m := relativeDate.FindStringSubmatch(strings.ToLower(value)); m != nil {
+		n, _ := strconv.Atoi(m[1])
+		switch m[2] {
+		case "second":
+			return now.Add(-time.Duration(n) * time.Second), nil
+		case "minute":
+			return now.Add(-time.Duration(n) * time.Minute), nil
+		case "hour":
+			return now.Add(-time.Duration(n) * time.Hour), nil
+		case "day":
+			return now.AddDate(0, 0, -n), nil
+		case "week":
+			return now.AddDate(0, 0, -7*n), nil
+		case "month":
+			return now.AddDate(0, -n, 0), nil
+		case "year":
+			return now.AddDate(-n, 0, 0), nil
+		}
+	}
+
+	return time.Time{}, fmt.Errorf("unrecognized date '%s'", value)
+}
@@ -0,0 +1,135 @@
+package cmd
+
+import (
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+
+	"git-mini-commit/testutils"
+)
+
+func TestParseApproxDate(t *testing.T) {
+	now := time.Date(2025, 1, 31, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		value    string
+		expected time.Time
+	}{
+		{value: "2025-01-02", expected: time.Date(2025, 1, 2, 0, 0, 0, 0, time.UTC)},
+		{value: "2025-01-02T03:04:05Z", expected: time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)},
+		{value: "36h", expected: now.Add(-36 * time.Hour)},
+		{value: "3 days ago", expected: now.AddDate(0, 0, -3)},
+		{value: "2.weeks.ago", expected: now.AddDate(0, 0, -14)},
+		{value: "yesterday", expected: time.Date(2025, 1, 30, 0, 0, 0, 0, time.UTC)},
+	}
+
+	for _, tt := range tests {
+		got, err := parseApproxDate(tt.value, now)
+		if err != nil {
+			t.Errorf("parseApproxDate(%q) error = %v", tt.value, err)
+			continue
+		}
+		if !got.Equal(tt.expected) {
+			t.Errorf("parseApproxDate(%q) = %v, want %v", tt.value, got, tt.expected)
+		}
+	}
+
+	if _, err := parseApproxDate("sometime", now); err == nil {
+		t.Errorf("Expected error for unrecognized date")
+	}
+}
+
+func TestFilterMatchPatch(t *testing.T) {
+	p := "diff --git a/src/app.go b/src/app.go\nindex 1..2 100644\n--- a/src/app.go\n+++ b/src/app.go\n@@ -1,2 +1,2 @@\n-oldName()\n+newName()\n keep()\n"
+
+	tests := []struct {
+		name     string
+		filter   miniCommitFilter
+		expected bool
+	}{
+		{name: "path directory", filter: miniCommitFilter{paths: []string{"src"}}, expected: true},
+		{name: "path glob", filter: miniCommitFilter{paths: []string{"src/*.go"}}, expected: true},
+		{name: "path miss", filter: miniCommitFilter{paths: []string{"docs"}}, expected: false},
+		{name: "pickaxe added", filter: miniCommitFilter{pickaxe: "newName"}, expected: true},
+		{name: "pickaxe context only", filter: miniCommitFilter{pickaxe: "keep"}, expected: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.filter.matchPatch(p); got != tt.expected {
+				t.Errorf("matchPatch() = %v, want %v", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestCLIListFilters(t *testing.T) {
+	// テスト用Gitリポジトリを作成
+	repo := testutils.NewTestGitRepo(t)
+	defer repo.Cleanup()
+
+	// テスト用CLIを作成
+	cli := testutils.NewTestCLI(t)
+	cli.SetRepo(repo)
+
+	// 1. 最初のコミットを作成してブランチを固定
+	if err := repo.CreateTestFile("README.md", "readme\n"); err != nil {
+		t.Fatalf("Failed to create file: %v", err)
+	}
+	repo.StageFile("README.md")
+	repo.CommitFile("initial")
+	if err := exec.Command("git", "checkout", "-q", "-b", "feature").Run(); err != nil {
+		t.Fatalf("Failed to create branch: %v", err)
+	}
+
+	// 2. docs と src のmini-commitを作成
+	if err := repo.CreateTestFile("docs.txt", "documentation\n"); err != nil {
+		t.Fatalf("Failed to create file: %v", err)
+	}
+	repo.StageFile("docs.txt")
+	cli.AssertCommandSuccess(t, "-m", "docs: describe usage")
+
+	if err := exec.Command("git", "reset", "-q").Run(); err != nil {
+		t.Fatalf("Failed to reset index: %v", err)
+	}
+	if err := repo.CreateTestFile("code.go", "package main // TODO\n"); err != nil {
+		t.Fatalf("Failed to create file: %v", err)
+	}
+	repo.StageFile("code.go")
+	cli.AssertCommandSuccess(t, "-m", "feat: add code")
+
+	// --grep
+	output := cli.AssertCommandSuccess(t, "list", "--oneline", "--grep", "^docs")
+	if !strings.Contains(output, "docs: describe usage") || strings.Contains(output, "feat: add code") {
+		t.Errorf("Unexpected --grep output: %q", output)
+	}
+
+	// --path
+	output = cli.AssertCommandSuccess(t, "list", "--oneline", "--path", "code.go")
+	if strings.Contains(output, "docs:") || !strings.Contains(output, "feat: add code") {
+		t.Errorf("Unexpected --path output: %q", output)
+	}
+
+	// -G
+	output = cli.AssertCommandSuccess(t, "list", "--oneline", "-G", "TODO")
+	if strings.TrimSpace(output) == "" || strings.Contains(output, "docs:") {
+		t.Errorf("Unexpected -G output: %q", output)
+	}
+
+	// --branch と --author
+	output = cli.AssertCommandSuccess(t, "list", "--oneline", "--branch", "feature", "--author", "Test User")
+	if len(strings.Split(strings.TrimSpace(output), "\n")) != 2 {
+		t.Errorf("Expected 2 mini-commits on branch feature, but got: %q", output)
+	}
+	output = cli.AssertCommandSuccess(t, "list", "--branch", "main-does-not-exist")
+	if !strings.Contains(output, "No mini-commits found") {
+		t.Errorf("Expected no mini-commits for unknown branch, but got: %q", output)
+	}
+
+	// --since
+	output = cli.AssertCommandSuccess(t, "list", "--oneline", "--since", "1 hour ago", "--until", "now")
+	if len(strings.Split(strings.TrimSpace(output), "\n")) != 2 {
+		t.Errorf("Expected 2 recent mini-commits, but got: %q", output)
+	}
+}
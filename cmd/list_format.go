@@ -0,0 +1,208 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"text/template"
+	"time"
+
+	"git-mini-commit/internal/color"
+	"git-mini-commit/internal/patch"
+	"git-mini-commit/internal/types"
+)
+
+// Built-in list formats
+const (
+	listFormatDefault = "default"
+	listFormatOneline = "oneline"
+)
+
+// Date formats accepted by --date
+const (
+	dateDefault  = "default"
+	dateRelative = "relative"
+	dateISO      = "iso"
+	dateShort    = "short"
+	dateUnix     = "unix"
+)
+
+// listEntry is the data available to --format templates. Branch, Author and
+// Base are empty when they were not recorded.
+type listEntry struct {
+	Index     int
+	ID        string
+	ShortID   string
+	Message   string
+	Subject   string
+	CreatedAt time.Time
+	Date      string
+	Branch    string
+	Author    string
+	Base      string
+	Trailers  []types.Trailer
+	Stats     patch.Stats
+}
+
+// listFormatter renders mini-commits for the human-readable list output
+type listFormatter struct {
+	format     string
+	dateFormat string
+	withStat   bool
+	color      bool
+	tmpl       *template.Template
+	now        time.Time
+}
+
+// newListFormatter validates the format and date settings and compiles templates
+func newListFormatter(format, dateFormat string, now time.Time) (*listFormatter, error) {
+	switch dateFormat {
+	case "":
+		dateFormat = dateDefault
+	case dateDefault, dateRelative, dateISO, dateShort, dateUnix:
+	default:
+		return nil, newCommandError(codeUsage, "unknown date format '%s' (supported: default, relative, iso, short, unix)", dateFormat)
+	}
+
+	f := &listFormatter{format: format, dateFormat: dateFormat, now: now}
+	switch format {
+	case "", listFormatDefault:
+		f.format = listFormatDefault
+	case listFormatOneline:
+	default:
+		tmpl, err := template.New("format").Funcs(template.FuncMap{
+			"relative": func(t time.Time) string { return formatRelative(t, now) },
+			"date":     func(layout string, t time.Time) string { return t.Format(layout) },
+		}).Parse(format)
+		if err != nil {
+			return nil, newCommandError(codeUsage, "invalid format template: %v", err)
+		}
+		f.tmpl = tmpl
+	}
+	return f, nil
+}
+
+// formatDate formats a timestamp according to the selected --date mode
+func (f *listFormatter) formatDate(t time.Time) string {
+	switch f.dateFormat {
+	case dateRelative:
+		return formatRelative(t, f.now)
+	case dateISO:
+		return t.Format("2006-01-02 15:04:05 -0700")
+	case dateShort:
+		return t.Format("2006-01-02")
+	case dateUnix:
+		return fmt.Sprintf("%d", t.Unix())
+	}
+	return t.Format("2006-01-02 15:04:05")
+}
+
+// write renders the given mini-commits; index is the 1-based position shown to the user
+func (f *listFormatter) write(w io.Writer, miniCommits []types.MiniCommit, indexes []int) error {
+	if f.format == listFormatDefault {
+		fmt.Fprintf(w, "Mini-commits (%d):\n\n", len(miniCommits))
+	}
+
+	for i := range miniCommits {
+		mc := &miniCommits[i]
+		entry := listEntry{
+			Index:     indexes[i],
+			ID:        mc.ID,
+			ShortID:   shortID(mc.ID),
+			Message:   mc.Message,
+			Subject:   subject(mc.Message),
+			CreatedAt: mc.CreatedAt,
+			Date:      f.formatDate(mc.CreatedAt),
+			Branch:    mc.Branch,
+			Author:    mc.Author,
+			Base:      mc.Base,
+			Trailers:  mc.Trailers,
+		}
+
+		// Stats are recorded in the index, so showing them does not read any patch
+		if f.withStat || f.tmpl != nil {
+			entry.Stats = miniCommitStats(mc)
+		}
+
+		shortID := entry.ShortID
+		if f.color {
+			shortID = color.Wrap(color.Yellow, shortID)
+		}
+
+		switch f.format {
+		case listFormatDefault:
+			fmt.Fprintf(w, "%d. ID: %s\n", entry.Index, shortID)
+			fmt.Fprintf(w, "   Message: %s\n", entry.Message)
+			fmt.Fprintf(w, "   Created: %s\n", entry.Date)
+			if f.withStat {
+				fmt.Fprintf(w, "   Stat:%s\n", patch.SummaryLine(entry.Stats))
+			}
+			fmt.Fprintln(w)
+		case listFormatOneline:
+			if f.withStat {
+				fmt.Fprintf(w, "%s %s %s\n", shortID, statColumn(entry.Stats), entry.Subject)
+			} else {
+				fmt.Fprintf(w, "%s %s\n", shortID, entry.Subject)
+			}
+		default:
+			var sb strings.Builder
+			if err := f.tmpl.Execute(&sb, entry); err != nil {
+				return newCommandError(codeUsage, "failed to render format template: %v", err)
+			}
+			fmt.Fprintln(w, sb.String())
+		}
+	}
+
+	return nil
+}
+
+// statColumn formats stats as a fixed-width column for the oneline format
+func statColumn(stats patch.Stats) string {
+	files := fmt.Sprintf("%d file%s", stats.Files, pluralS(stats.Files))
+	return fmt.Sprintf("%-9s %6s %6s", files, fmt.Sprintf("+%d", stats.Insertions), fmt.Sprintf("-%d", stats.Deletions))
+}
+
+// pluralS returns "s" unless n is 1
+func pluralS(n int) string {
+	if n == 1 {
+		return ""
+	}
+	return "s"
+}
+
+// formatRelative describes how long ago t was, in the style of git's --date=relative
+func formatRelative(t, now time.Time) string {
+	d := now.Sub(t)
+	if d < 0 {
+		return "in the future"
+	}
+
+	seconds := int(d / time.Second)
+	switch {
+	case seconds < 90:
+		return plural(seconds, "second") + " ago"
+	case seconds < 90*60:
+		return plural((seconds+30)/60, "minute") + " ago"
+	case seconds < 36*3600:
+		return plural((seconds+1800)/3600, "hour") + " ago"
+	}
+
+	days := (seconds + 43200) / 86400
+	switch {
+	case days < 14:
+		return plural(days, "day") + " ago"
+	case days < 70:
+		return plural((days+3)/7, "week") + " ago"
+	case days < 365:
+		return plural((days+15)/30, "month") + " ago"
+	}
+	return plural((days+183)/365, "year") + " ago"
+}
+
+// plural formats a count with a singular or plural unit
+func plural(n int, unit string) string {
+	if n == 1 {
+		return fmt.Sprintf("%d %s", n, unit)
+	}
+	return fmt.Sprintf("%d %ss", n, unit)
+}
@@ -0,0 +1,132 @@
+package cmd
+
+import (
+	"bytes"
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+
+	"git-mini-commit/internal/types"
+	"git-mini-commit/testutils"
+)
+
+func TestFormatRelative(t *testing.T) {
+	now := time.Date(2025, 1, 31, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		ago      time.Duration
+		expected string
+	}{
+		{ago: 5 * time.Second, expected: "5 seconds ago"},
+		{ago: 10 * time.Minute, expected: "10 minutes ago"},
+		{ago: 2 * time.Hour, expected: "2 hours ago"},
+		{ago: 3 * 24 * time.Hour, expected: "3 days ago"},
+		{ago: 21 * 24 * time.Hour, expected: "3 weeks ago"},
+		{ago: 120 * 24 * time.Hour, expected: "4 months ago"},
+		{ago: 800 * 24 * time.Hour, expected: "2 years ago"},
+		{ago: -time.Minute, expected: "in the future"},
+	}
+
+	for _, tt := range tests {
+		if got := formatRelative(now.Add(-tt.ago), now); got != tt.expected {
+			t.Errorf("formatRelative(-%v) = %q, want %q", tt.ago, got, tt.expected)
+		}
+	}
+}
+
+func TestListFormatterTemplate(t *testing.T) {
+	now := time.Date(2025, 1, 31, 12, 0, 0, 0, time.UTC)
+	miniCommits := []types.MiniCommit{
+		{
+			ID:        "0123456789abcdef0123456789abcdef01234567",
+			Message:   "Subject line\n\nBody",
+			CreatedAt: now.Add(-2 * time.Hour),
+			Patch:     "diff --git a/a.txt b/a.txt\n--- a/a.txt\n+++ b/a.txt\n@@ -1 +1,2 @@\n-old\n+new\n+more\n",
+		},
+	}
+
+	formatter, err := newListFormatter("{{.Index}} {{.ShortID}} {{.Subject}} {{relative .CreatedAt}} +{{.Stats.Insertions}}/-{{.Stats.Deletions}}", "", now)
+	if err != nil {
+		t.Fatalf("newListFormatter() error = %v", err)
+	}
+
+	var out bytes.Buffer
+	if err := formatter.write(&out, miniCommits, []int{3}); err != nil {
+		t.Fatalf("write() error = %v", err)
+	}
+
+	expected := "3 01234567 Subject line 2 hours ago +2/-1\n"
+	if out.String() != expected {
+		t.Errorf("Expected %q, but got %q", expected, out.String())
+	}
+
+	// 作成時のブランチ・作成者・ベースとトレーラー
+	miniCommits[0].Branch = "main"
+	miniCommits[0].Author = "Test User <test@example.com>"
+	miniCommits[0].Base = "89abcdef"
+	miniCommits[0].Trailers = []types.Trailer{{Key: "Refs", Value: "#12"}, {Key: "Reviewed-by", Value: "Alice"}}
+	formatter, err = newListFormatter("{{.Branch}} {{.Author}} {{.Base}}{{range .Trailers}} {{.Key}}={{.Value}}{{end}}", "", now)
+	if err != nil {
+		t.Fatalf("newListFormatter() error = %v", err)
+	}
+	out.Reset()
+	if err := formatter.write(&out, miniCommits, []int{1}); err != nil {
+		t.Fatalf("write() error = %v", err)
+	}
+	expected = "main Test User <test@example.com> 89abcdef Refs=#12 Reviewed-by=Alice\n"
+	if out.String() != expected {
+		t.Errorf("Expected %q, but got %q", expected, out.String())
+	}
+
+	// 不正なテンプレートと日付形式はエラー
+	if _, err := newListFormatter("{{.Unclosed", "", now); err == nil {
+		t.Errorf("Expected error for invalid template")
+	}
+	if _, err := newListFormatter("", "fortnightly", now); err == nil {
+		t.Errorf("Expected error for unknown date format")
+	}
+}
+
+func TestCLIListFormatting(t *testing.T) {
+	// テスト用Gitリポジトリを作成
+	repo := testutils.NewTestGitRepo(t)
+	defer repo.Cleanup()
+
+	// テスト用CLIを作成
+	cli := testutils.NewTestCLI(t)
+	cli.SetRepo(repo)
+
+	// 3つのmini-commitを作成
+	for i, name := range []string{"first", "second", "third"} {
+		if err := repo.CreateTestFile(name+".txt", name+"\n"); err != nil {
+			t.Fatalf("Failed to create file %d: %v", i, err)
+		}
+		if err := repo.StageFile(name + ".txt"); err != nil {
+			t.Fatalf("Failed to stage file %d: %v", i, err)
+		}
+		cli.AssertCommandSuccess(t, "-m", name+" commit")
+	}
+
+	// --oneline
+	output := cli.AssertCommandSuccess(t, "list", "--oneline")
+	lines := strings.Split(strings.TrimSpace(output), "\n")
+	if len(lines) != 3 || !strings.HasSuffix(lines[0], " first commit") {
+		t.Errorf("Unexpected --oneline output: %q", output)
+	}
+
+	// --reverse と --limit
+	output = cli.AssertCommandSuccess(t, "list", "--format", "{{.Index}}:{{.Subject}}", "--reverse", "-n", "2")
+	if output != "3:third commit\n2:second commit\n" {
+		t.Errorf("Unexpected --reverse --limit output: %q", output)
+	}
+
+	// git configで既定の形式を設定
+	if err := exec.Command("git", "config", "minicommit.listFormat", "oneline").Run(); err != nil {
+		t.Fatalf("Failed to set config: %v", err)
+	}
+	output = cli.AssertCommandSuccess(t, "list")
+	if strings.Contains(output, "Mini-commits (3)") || len(strings.Split(strings.TrimSpace(output), "\n")) != 3 {
+		t.Errorf("Expected oneline output from config, but got: %q", output)
+	}
+}
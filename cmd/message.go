@@ -0,0 +1,214 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"unicode"
+
+	"git-mini-commit/internal/color"
+	"git-mini-commit/internal/config"
+	"git-mini-commit/internal/editor"
+	"git-mini-commit/internal/git"
+	"git-mini-commit/internal/patch"
+	"git-mini-commit/internal/types"
+
+	"github.com/spf13/cobra"
+)
+
+// editMessageFile is where the message is written in the editor, like git's COMMIT_EDITMSG
+const editMessageFile = ".git/MINI_COMMIT_EDITMSG"
+
+// statusNames are the words git status uses for the file statuses
+var statusNames = map[byte]string{
+	patch.StatusAdded:    "new file",
+	patch.StatusDeleted:  "deleted",
+	patch.StatusModified: "modified",
+	patch.StatusRenamed:  "renamed",
+	patch.StatusCopied:   "copied",
+}
+
+// trailerKey matches the keys git interpret-trailers accepts
+var trailerKey = regexp.MustCompile(`^[A-Za-z0-9-]+$`)
+
+// messageFromFlags returns the message given with -m, whose values are joined as
+// paragraphs, or read from the -F file. It returns "" when neither is given, in
+// which case the message is written in the editor.
+func messageFromFlags(cmd *cobra.Command) (string, error) {
+	messages, _ := cmd.Flags().GetStringArray("message")
+	file, _ := cmd.Flags().GetString("file")
+	given := cmd.Flags().Changed("message")
+
+	var message string
+	switch {
+	case given && file != "":
+		return "", newCommandError(codeUsage, "-m and -F cannot be used together")
+	case given:
+		message = strings.Join(messages, "\n\n")
+	case file != "":
+		var content []byte
+		var err error
+		if file == "-" {
+			content, err = io.ReadAll(cmd.InOrStdin())
+		} else {
+			content, err = os.ReadFile(file)
+		}
+		if err != nil {
+			return "", newCommandError(codeUsage, "could not read message file '%s': %v", file, err)
+		}
+		message = string(content)
+	default:
+		return "", nil
+	}
+
+	if message = cleanupMessage(message, false); message == "" {
+		return "", newCommandError(codeMissingMessage, "message is required: aborting mini-commit due to empty message")
+	}
+	return message, nil
+}
+
+// parseTrailers parses the --trailer values, given as "key=value" or "key: value"
+func parseTrailers(values []string) ([]types.Trailer, error) {
+	var trailers []types.Trailer
+	for _, v := range values {
+		i := strings.IndexAny(v, "=:")
+		if i < 0 {
+			return nil, newCommandError(codeUsage, "invalid trailer '%s': expected key=value", v)
+		}
+		key, value := strings.TrimSpace(v[:i]), strings.TrimSpace(v[i+1:])
+		if !trailerKey.MatchString(key) || value == "" || strings.ContainsAny(value, "\r\n") {
+			return nil, newCommandError(codeUsage, "invalid trailer '%s': expected key=value", v)
+		}
+		trailers = append(trailers, types.Trailer{Key: key, Value: value})
+	}
+	return trailers, nil
+}
+
+// readTemplate returns the contents of the minicommit.template file, or "" when it is not set
+func readTemplate() (string, error) {
+	path := settings.String(config.Template)
+	if path == "" {
+		return "", nil
+	}
+	if rest, found := strings.CutPrefix(path, "~/"); found {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", newCommandError(codeUsage, "could not read template file '%s': %v", path, err)
+		}
+		path = filepath.Join(home, rest)
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return "", newCommandError(codeUsage, "could not read template file '%s': %v", path, err)
+	}
+	return string(content), nil
+}
+
+// editMessage opens the editor on a template listing the staged changes and
+// returns what was written, without the comment lines. The template starts with
+// the minicommit.template file when one is set; leaving it unchanged aborts.
+func editMessage(branch string) (string, error) {
+	command, err := editor.Resolve(git.GetConfig, color.IsTerminal(os.Stdin))
+	if err != nil {
+		return "", gitError(err)
+	}
+	if command == "" {
+		return "", newCommandError(codeMissingMessage, "message is required (-m or -F option) and no editor is available")
+	}
+
+	template, err := readTemplate()
+	if err != nil {
+		return "", err
+	}
+
+	// The template needs the changed files, so the staged changes are read in full here
+	staged, err := git.GetStagedChanges()
+	if err != nil {
+		return "", gitError(err)
+	}
+	if err := os.WriteFile(editMessageFile, messageTemplate(template, branch, patch.Parse(staged)), 0644); err != nil {
+		return "", newCommandError(codeError, "failed to write %s: %v", editMessageFile, err)
+	}
+	if err := editor.Edit(command, editMessageFile); err != nil {
+		return "", newCommandError(codeError, "%v", err)
+	}
+
+	content, err := os.ReadFile(editMessageFile)
+	if err != nil {
+		return "", newCommandError(codeError, "failed to read %s: %v", editMessageFile, err)
+	}
+	message := cleanupMessage(string(content), true)
+	if message == "" {
+		return "", newCommandError(codeMissingMessage, "message is required: aborting mini-commit due to empty message")
+	}
+	if template != "" && message == cleanupMessage(template, true) {
+		return "", newCommandError(codeMissingMessage, "message is required: aborting mini-commit; the template was not edited")
+	}
+	return message, nil
+}
+
+// messageTemplate returns the text the editor starts with: the template, or an
+// empty line for the message, then the branch, the staged files and their
+// diffstat as comments
+func messageTemplate(template, branch string, files []*patch.File) []byte {
+	var b bytes.Buffer
+	if template == "" {
+		template = "\n"
+	} else if !strings.HasSuffix(template, "\n") {
+		template += "\n"
+	}
+	b.WriteString(template)
+	b.WriteString("# Please enter the message for your mini-commit. Lines starting\n")
+	b.WriteString("# with '#' will be ignored, and an empty message aborts the mini-commit.\n")
+	b.WriteString("#\n")
+	if branch != "" {
+		fmt.Fprintf(&b, "# On branch %s\n", branch)
+	}
+	b.WriteString("# Changes to be saved:\n")
+	for _, f := range files {
+		path := f.Path()
+		if f.Status == patch.StatusRenamed || f.Status == patch.StatusCopied {
+			path = f.OldPath + " -> " + f.NewPath
+		}
+		fmt.Fprintf(&b, "#\t%-11s %s\n", statusNames[f.Status]+":", path)
+	}
+	b.WriteString("#\n")
+
+	var stat bytes.Buffer
+	patch.WriteStat(&stat, files, patch.DefaultStatWidth)
+	for _, line := range strings.SplitAfter(stat.String(), "\n") {
+		if line != "" {
+			b.WriteString("#" + line)
+		}
+	}
+	return b.Bytes()
+}
+
+// cleanupMessage tidies a message the way git does: trailing whitespace is
+// removed, runs of blank lines are squashed into one and leading and trailing
+// blank lines are dropped. With stripComments, lines starting with '#' are
+// removed first.
+func cleanupMessage(message string, stripComments bool) string {
+	var lines []string
+	blank := false
+	for _, line := range strings.Split(message, "\n") {
+		if stripComments && strings.HasPrefix(line, "#") {
+			continue
+		}
+		line = strings.TrimRightFunc(line, unicode.IsSpace)
+		if line == "" {
+			blank = true
+			continue
+		}
+		if blank && len(lines) > 0 {
+			lines = append(lines, "")
+		}
+		blank = false
+		lines = append(lines, line)
+	}
+	return strings.Join(lines, "\n")
+}
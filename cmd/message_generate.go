@@ -0,0 +1,148 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"git-mini-commit/internal/patch"
+	"git-mini-commit/internal/summary"
+	"git-mini-commit/internal/types"
+
+	"github.com/spf13/cobra"
+)
+
+var messageCmd = &cobra.Command{
+	Use:   "message [<mini-commit>...]",
+	Short: "Generate a commit message summarizing the mini-commits",
+	Long: `Generate the message for the commit that integrates the given mini-commits,
+or the whole stack: a subject line, then the mini-commit subjects as a bullet
+list under one heading per conventional commit type (feat, fix, ...) or, with
+--group-by directory, per changed directory.
+
+--message-file writes the message at the top of a message file that has no
+message yet, so that git commit starts with it from a prepare-commit-msg hook:
+
+  [ -z "$2" ] && git mini-commit message --message-file "$1"
+
+The order of the types and the subject length follow minicommit.lintTypes and
+minicommit.lintSubjectMaxLength.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		mode, err := outputMode(cmd)
+		if err != nil {
+			return err
+		}
+
+		groupBy, _ := cmd.Flags().GetString("group-by")
+		if groupBy != summary.GroupByType && groupBy != summary.GroupByDirectory {
+			return newCommandError(codeUsage, "invalid --group-by '%s': expected type or directory", groupBy)
+		}
+		depth, _ := cmd.Flags().GetInt("depth")
+		if depth < 0 {
+			return newCommandError(codeUsage, "invalid --depth %d", depth)
+		}
+		file, _ := cmd.Flags().GetString("message-file")
+
+		store, err := openStore()
+		if err != nil {
+			return err
+		}
+		rules, _, err := lintRulesFromConfig()
+		if err != nil {
+			return err
+		}
+		miniCommits, err := store.LoadMiniCommits()
+		if err != nil {
+			return storageError(err, "failed to load mini-commits")
+		}
+		selected := miniCommits
+		if len(args) > 0 {
+			selected = nil
+			for _, arg := range args {
+				mc, _, err := resolveMiniCommit(miniCommits, arg)
+				if err != nil {
+					return err
+				}
+				selected = append(selected, *mc)
+			}
+		}
+
+		// Only grouping by directory needs the changed files
+		entries := make([]summary.Entry, len(selected))
+		for i := range selected {
+			entries[i].Message = selected[i].Message
+			if groupBy == summary.GroupByDirectory {
+				if err := store.LoadPatch(&selected[i]); err != nil {
+					return storageError(err, "failed to load patch")
+				}
+				entries[i].Files = patch.Parse(selected[i].Patch)
+			}
+		}
+		message := summary.Generate(entries, summary.Options{
+			GroupBy:          groupBy,
+			Depth:            depth,
+			Types:            rules.Types,
+			SubjectMaxLength: rules.SubjectMaxLength,
+		})
+
+		if file != "" {
+			return prependMessage(file, message.String())
+		}
+		return writeGeneratedMessage(cmd, mode, message, selected)
+	},
+}
+
+// prependMessage writes message before the content of a message file, unless
+// the file already has a message; git's comments are left in place
+func prependMessage(file, message string) error {
+	content, err := os.ReadFile(file)
+	if err != nil {
+		return newCommandError(codeUsage, "could not read message file '%s': %v", file, err)
+	}
+	if message == "" || cleanupMessage(string(content), true) != "" {
+		return nil
+	}
+	if err := os.WriteFile(file, append([]byte(message+"\n"), content...), 0644); err != nil {
+		return newCommandError(codeError, "failed to write message file '%s': %v", file, err)
+	}
+	return nil
+}
+
+// writeGeneratedMessage prints the generated message; nothing is printed for an empty stack
+func writeGeneratedMessage(cmd *cobra.Command, mode string, message summary.Message, selected types.MiniCommitList) error {
+	out := cmd.OutOrStdout()
+	text := message.String()
+	switch mode {
+	case outputJSON:
+		doc := jsonDocument{}
+		if text != "" {
+			generated := jsonGeneratedMessage{Subject: message.Subject, Body: message.Body,
+				Groups: make([]jsonMessageGroup, 0, len(message.Groups))}
+			for _, g := range message.Groups {
+				group := jsonMessageGroup{Title: g.Title, IDs: make([]string, 0, len(g.Entries))}
+				for _, i := range g.Entries {
+					group.IDs = append(group.IDs, selected[i].ID)
+				}
+				generated.Groups = append(generated.Groups, group)
+			}
+			doc.Message = &generated
+		}
+		return writeJSON(out, doc)
+	case outputPorcelainV1:
+		if text != "" {
+			fmt.Fprintf(out, "message %d\n%s\n", len(text), text)
+		}
+		return nil
+	}
+
+	if text != "" {
+		fmt.Fprintln(out, text)
+	}
+	return nil
+}
+
+func init() {
+	messageCmd.Flags().String("group-by", summary.GroupByType, "group the mini-commits by type or directory")
+	messageCmd.Flags().Int("depth", 1, "path components that name a directory with --group-by directory; 0 for the whole directory")
+	messageCmd.Flags().String("message-file", "", "write the message into the file, e.g. from a prepare-commit-msg hook")
+	rootCmd.AddCommand(messageCmd)
+}
@@ -0,0 +1,77 @@
+package cmd
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"git-mini-commit/testutils"
+)
+
+func TestCLIMessageGenerate(t *testing.T) {
+	repo := testutils.NewTestGitRepo(t)
+	defer repo.Cleanup()
+	cli := testutils.NewTestCLI(t)
+	cli.SetRepo(repo)
+
+	// 1. 空のスタックでは何も出力しない
+	if output := cli.AssertCommandSuccess(t, "message"); output != "" {
+		t.Errorf("Expected no output for an empty stack, got %q", output)
+	}
+
+	for _, c := range []struct{ file, message string }{
+		{"cmd/a.go", "feat(cli): add a"},
+		{"internal/b.go", "fix: handle b"},
+		{"cmd/c.go", "Update c"},
+	} {
+		os.MkdirAll(filepath.Dir(c.file), 0755)
+		repo.CreateTestFile(c.file, c.message+"\n")
+		if err := repo.StageFile(c.file); err != nil {
+			t.Fatalf("Failed to stage %s: %v", c.file, err)
+		}
+		cli.AssertCommandSuccess(t, "-m", c.message)
+	}
+
+	// 2. 種類ごとにまとめる
+	output := cli.AssertCommandSuccess(t, "message")
+	want := "feat: cli: add a, handle b, Update c\n\n" +
+		"Features:\n- cli: add a\n\nBug Fixes:\n- handle b\n\nOther Changes:\n- Update c\n"
+	if output != want {
+		t.Errorf("Unexpected message:\n%s\nwant\n%s", output, want)
+	}
+
+	// 3. ディレクトリごとにまとめる（指定したmini-commitのみ）
+	output = cli.AssertCommandSuccess(t, "message", "--group-by", "directory", "@1", "@3")
+	if output != "feat(cli): add a, Update c\n\ncmd:\n- feat(cli): add a\n- Update c\n" {
+		t.Errorf("Unexpected message:\n%s", output)
+	}
+	output = cli.AssertExitCode(t, 2, "message", "--group-by", "author")
+	cli.AssertOutputContains(t, output, "invalid --group-by")
+
+	// 4. JSON にはグループごとのIDが含まれる
+	var doc jsonDocument
+	output = cli.AssertCommandSuccess(t, "message", "--json")
+	if err := json.Unmarshal([]byte(output), &doc); err != nil || doc.Message == nil {
+		t.Fatalf("Failed to parse JSON output: %v, output: %s", err, output)
+	}
+	if len(doc.Message.Groups) != 3 || doc.Message.Groups[1].Title != "Bug Fixes" || len(doc.Message.Groups[1].IDs) != 1 {
+		t.Errorf("Unexpected groups: %+v", doc.Message.Groups)
+	}
+
+	// 5. --message-file はメッセージのないファイルにだけ書き込む
+	messageFile := filepath.Join(t.TempDir(), "COMMIT_EDITMSG")
+	os.WriteFile(messageFile, []byte("\n# Please enter the commit message\n"), 0644)
+	cli.AssertCommandSuccess(t, "message", "--message-file", messageFile)
+	content, _ := os.ReadFile(messageFile)
+	if !strings.HasPrefix(string(content), "feat: cli: add a, handle b, Update c\n\nFeatures:\n") ||
+		!strings.HasSuffix(string(content), "- Update c\n\n# Please enter the commit message\n") {
+		t.Errorf("Unexpected message file:\n%s", content)
+	}
+	os.WriteFile(messageFile, []byte("Written by hand\n"), 0644)
+	cli.AssertCommandSuccess(t, "message", "--message-file", messageFile)
+	if content, _ := os.ReadFile(messageFile); string(content) != "Written by hand\n" {
+		t.Errorf("Expected the message file to be left alone, got %q", content)
+	}
+}
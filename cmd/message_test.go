@@ -0,0 +1,107 @@
+package cmd
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"git-mini-commit/testutils"
+)
+
+// createdMessage --json で作成し、保存されたメッセージを返す
+func createdMessage(t *testing.T, cli *testutils.TestCLI, args ...string) string {
+	t.Helper()
+	var doc jsonDocument
+	output := cli.AssertCommandSuccess(t, append(args, "--json")...)
+	if err := json.Unmarshal([]byte(output), &doc); err != nil || doc.MiniCommit == nil {
+		t.Fatalf("Failed to parse JSON output: %v, output: %s", err, output)
+	}
+	return doc.MiniCommit.Message
+}
+
+func TestCLIMessageSources(t *testing.T) {
+	repo := testutils.NewTestGitRepo(t)
+	defer repo.Cleanup()
+	cli := testutils.NewTestCLI(t)
+	cli.SetRepo(repo)
+
+	stage := func(name, content string) {
+		t.Helper()
+		repo.CreateTestFile(name, content)
+		if err := repo.StageFile(name); err != nil {
+			t.Fatalf("Failed to stage %s: %v", name, err)
+		}
+	}
+
+	// 1. 複数の -m は段落として結合される
+	stage("a.txt", "a\n")
+	if got := createdMessage(t, cli, "-m", "Subject", "-m", "Body  "); got != "Subject\n\nBody" {
+		t.Errorf("Expected the -m values as paragraphs, got %q", got)
+	}
+
+	// 2. -F はファイルから、-F - は標準入力から読む
+	stage("b.txt", "b\n")
+	messageFile := filepath.Join(t.TempDir(), "message.txt")
+	os.WriteFile(messageFile, []byte("\nFrom file\n\n\n# kept\n"), 0644)
+	if got := createdMessage(t, cli, "-F", messageFile); got != "From file\n\n# kept" {
+		t.Errorf("Expected the message from the file, got %q", got)
+	}
+	stage("c.txt", "c\n")
+	cli.SetStdin("From stdin\n")
+	if got := createdMessage(t, cli, "-F", "-"); got != "From stdin" {
+		t.Errorf("Expected the message from stdin, got %q", got)
+	}
+	cli.SetStdin("")
+
+	// 3. -m と -F は同時に使えない
+	output := cli.AssertExitCode(t, 2, "-m", "x", "-F", messageFile)
+	cli.AssertOutputContains(t, output, "cannot be used together")
+
+	// 4. エディタ: テンプレートにステージング済みのファイルと統計が入り、コメントは除かれる
+	stage("d.txt", "d\n")
+	dir := t.TempDir()
+	template := filepath.Join(dir, "template")
+	script := filepath.Join(dir, "editor.sh")
+	os.WriteFile(script, []byte(`cp "$1" "`+template+`"
+printf 'Edited subject\n\nBody line   \n' | cat - "$1" > "$1.new" && mv "$1.new" "$1"
+`), 0755)
+	t.Setenv("GIT_EDITOR", "sh "+script)
+	if got := createdMessage(t, cli); got != "Edited subject\n\nBody line" {
+		t.Errorf("Expected the edited message, got %q", got)
+	}
+	content, err := os.ReadFile(template)
+	if err != nil {
+		t.Fatalf("Expected the editor to be run: %v", err)
+	}
+	for _, want := range []string{"# Changes to be saved:", "#\tnew file:   d.txt", "# 4 files changed, 4 insertions(+)"} {
+		cli.AssertOutputContains(t, string(content), want)
+	}
+
+	// 5. 空のメッセージは中止され、何も保存されない
+	stage("e.txt", "e\n")
+	t.Setenv("GIT_EDITOR", ":")
+	output = cli.AssertExitCode(t, 2)
+	cli.AssertOutputContains(t, output, "aborting mini-commit due to empty message")
+	output = cli.AssertExitCode(t, 2, "-m", "  ")
+	cli.AssertOutputContains(t, output, "message is required")
+
+	// 6. エディタが失敗したら保存しない
+	t.Setenv("GIT_EDITOR", "false")
+	output = cli.AssertCommandFailure(t)
+	cli.AssertOutputContains(t, output, "problem with the editor")
+	if list := cli.AssertCommandSuccess(t, "list", "--porcelain"); strings.Count(list, "\n") != 4 {
+		t.Errorf("Expected 4 mini-commits, got:\n%s", list)
+	}
+}
+
+func TestCleanupMessage(t *testing.T) {
+	input := "\n\n  \nSubject  \n\n\n\nBody\n# comment\n\t\n"
+	if got := cleanupMessage(input, false); got != "Subject\n\nBody\n# comment" {
+		t.Errorf("cleanupMessage() = %q", got)
+	}
+	if got := cleanupMessage(input, true); got != "Subject\n\nBody" {
+		t.Errorf("cleanupMessage() with comments stripped = %q", got)
+	}
+}
@@ -0,0 +1,317 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"git-mini-commit/internal/patch"
+	"git-mini-commit/internal/types"
+
+	"github.com/spf13/cobra"
+)
+
+// Output modes
+const (
+	outputHuman       = "human"
+	outputJSON        = "json"
+	outputPorcelainV1 = "porcelain-v1"
+)
+
+// schemaVersion is the version of the JSON document layout
+const schemaVersion = 1
+
+// jsonMiniCommit is the JSON representation of a mini-commit
+type jsonMiniCommit struct {
+	ID        string          `json:"id"`
+	ShortID   string          `json:"shortId"`
+	Message   string          `json:"message"`
+	CreatedAt time.Time       `json:"createdAt"`
+	Stats     patch.Stats     `json:"stats"`
+	Files     []jsonFile      `json:"files,omitempty"`
+	Trailers  []types.Trailer `json:"trailers,omitempty"`
+	Patch     *string         `json:"patch,omitempty"`
+}
+
+// jsonFile is the JSON representation of one file changed by a mini-commit
+type jsonFile struct {
+	Path       string `json:"path"`
+	OldPath    string `json:"oldPath,omitempty"`
+	Status     string `json:"status"`
+	Insertions int    `json:"insertions"`
+	Deletions  int    `json:"deletions"`
+	Binary     bool   `json:"binary,omitempty"`
+}
+
+// jsonDocument is the top-level JSON object written by every command
+type jsonDocument struct {
+	SchemaVersion int                   `json:"schemaVersion"`
+	Action        string                `json:"action,omitempty"`
+	MiniCommit    *jsonMiniCommit       `json:"miniCommit,omitempty"`
+	MiniCommits   *[]jsonMiniCommit     `json:"miniCommits,omitempty"`
+	Diff          *jsonDiff             `json:"diff,omitempty"`
+	RangeDiff     *[]jsonRangePair      `json:"rangeDiff,omitempty"`
+	Check         *jsonCheck            `json:"check,omitempty"`
+	Operations    *[]jsonOperation      `json:"operations,omitempty"`
+	Trash         *[]jsonTrashEntry     `json:"trash,omitempty"`
+	Purged        *[]jsonTrashEntry     `json:"purged,omitempty"`
+	Expired       *int                  `json:"expired,omitempty"`
+	Pruned        *jsonPruned           `json:"pruned,omitempty"`
+	Problems      *[]jsonProblem        `json:"problems,omitempty"`
+	Lint          *[]jsonLintResult     `json:"lint,omitempty"`
+	Message       *jsonGeneratedMessage `json:"message,omitempty"`
+	Settings      *[]jsonSetting        `json:"settings,omitempty"`
+	Error         *jsonError            `json:"error,omitempty"`
+}
+
+// jsonProblem is an inconsistency found by fsck; Repair is set after fsck --repair
+type jsonProblem struct {
+	Kind   string `json:"kind"`
+	ID     string `json:"id,omitempty"`
+	Detail string `json:"detail"`
+	Repair string `json:"repair,omitempty"`
+}
+
+// jsonSetting is the effective value of a setting and where it comes from
+type jsonSetting struct {
+	Key    string `json:"key"`
+	Value  string `json:"value"`
+	Source string `json:"source"`
+	Origin string `json:"origin,omitempty"`
+}
+
+// jsonGeneratedMessage is the commit message generated by the message command
+type jsonGeneratedMessage struct {
+	Subject string             `json:"subject"`
+	Body    string             `json:"body"`
+	Groups  []jsonMessageGroup `json:"groups"`
+}
+
+// jsonMessageGroup is a heading of a generated message and the mini-commits under it
+type jsonMessageGroup struct {
+	Title string   `json:"title"`
+	IDs   []string `json:"ids"`
+}
+
+// jsonLintResult is a message that breaks the commit message rules; ID is empty for a message file
+type jsonLintResult struct {
+	ID       string            `json:"id,omitempty"`
+	Subject  string            `json:"subject"`
+	Problems []jsonLintProblem `json:"problems"`
+}
+
+// jsonLintProblem is a rule a message breaks
+type jsonLintProblem struct {
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
+
+// jsonDiff is the JSON representation of the difference between two mini-commits
+type jsonDiff struct {
+	From  string      `json:"from"`
+	To    string      `json:"to"`
+	Stats patch.Stats `json:"stats"`
+	Files []jsonFile  `json:"files"`
+	Patch string      `json:"patch"`
+}
+
+// jsonRangePair is one line of range-diff output; Old or New is nil for unmatched mini-commits
+type jsonRangePair struct {
+	Status    string          `json:"status"`
+	Old       *jsonRangeEntry `json:"old,omitempty"`
+	New       *jsonRangeEntry `json:"new,omitempty"`
+	Subject   string          `json:"subject"`
+	Interdiff string          `json:"interdiff,omitempty"`
+}
+
+// jsonRangeEntry identifies a mini-commit and its 1-based position within its range
+type jsonRangeEntry struct {
+	Index int    `json:"index"`
+	ID    string `json:"id"`
+}
+
+// jsonCheck is the JSON representation of a diff --check result
+type jsonCheck struct {
+	ID     string          `json:"id"`
+	Target string          `json:"target"`
+	Status string          `json:"status"`
+	Files  []jsonFileCheck `json:"files"`
+}
+
+// jsonFileCheck is the JSON representation of one file of a diff --check result
+type jsonFileCheck struct {
+	Path   string `json:"path"`
+	Status string `json:"status"`
+}
+
+// jsonOperation is the JSON representation of an operation log entry
+type jsonOperation struct {
+	Index   int       `json:"index"`
+	Kind    string    `json:"kind"`
+	Time    time.Time `json:"time"`
+	Summary string    `json:"summary"`
+	Before  []string  `json:"before"`
+	After   []string  `json:"after"`
+	Removed []string  `json:"removed,omitempty"`
+}
+
+// jsonTrashEntry is the JSON representation of a trashed mini-commit
+type jsonTrashEntry struct {
+	jsonMiniCommit
+	DeletedAt time.Time `json:"deletedAt"`
+	Reason    string    `json:"reason"`
+}
+
+// jsonPruned is the JSON representation of the patch objects deleted by gc or trash empty
+type jsonPruned struct {
+	Objects int   `json:"objects"`
+	Bytes   int64 `json:"bytes"`
+}
+
+// jsonError is the JSON representation of a failed command
+type jsonError struct {
+	Code     string `json:"code"`
+	Message  string `json:"message"`
+	Hint     string `json:"hint,omitempty"`
+	ExitCode int    `json:"exitCode"`
+}
+
+// outputMode returns the output mode selected by --json / --porcelain
+func outputMode(cmd *cobra.Command) (string, error) {
+	flags := cmd.Root().PersistentFlags()
+	asJSON, _ := flags.GetBool("json")
+	porcelain, _ := flags.GetString("porcelain")
+
+	switch {
+	case asJSON && porcelain != "":
+		return "", newCommandError(codeUsage, "--json and --porcelain cannot be used together")
+	case asJSON:
+		return outputJSON, nil
+	case porcelain == "v1":
+		return outputPorcelainV1, nil
+	case porcelain != "":
+		return "", newCommandError(codeUsage, "unsupported porcelain version '%s' (supported: v1)", porcelain)
+	}
+	return outputHuman, nil
+}
+
+// shortID returns the abbreviated form of a mini-commit ID used in human output
+func shortID(id string) string {
+	if len(id) > 8 {
+		return id[:8]
+	}
+	return id
+}
+
+// subject returns the first line of a message
+func subject(message string) string {
+	line, _, _ := strings.Cut(message, "\n")
+	return line
+}
+
+// miniCommitStats returns the stats recorded in the index, computing them from
+// the patch for mini-commits that have none
+func miniCommitStats(mc *types.MiniCommit) patch.Stats {
+	if mc.Stats != nil {
+		return *mc.Stats
+	}
+	return patch.ComputeStats(mc.Patch)
+}
+
+// toJSON converts a mini-commit for JSON output, optionally including the file list and patch
+func toJSON(mc *types.MiniCommit, withPatch bool) jsonMiniCommit {
+	out := jsonMiniCommit{
+		ID:        mc.ID,
+		ShortID:   shortID(mc.ID),
+		Message:   mc.Message,
+		CreatedAt: mc.CreatedAt,
+		Stats:     miniCommitStats(mc),
+		Trailers:  mc.Trailers,
+	}
+	if withPatch {
+		out.Files = toJSONFiles(patch.Parse(mc.Patch))
+		p := mc.Patch
+		out.Patch = &p
+	}
+	return out
+}
+
+// toJSONFiles converts parsed patch files for JSON output
+func toJSONFiles(files []*patch.File) []jsonFile {
+	out := make([]jsonFile, 0, len(files))
+	for _, f := range files {
+		jf := jsonFile{
+			Path:       f.Path(),
+			Status:     string(f.Status),
+			Insertions: f.Insertions,
+			Deletions:  f.Deletions,
+			Binary:     f.Binary,
+		}
+		if f.OldPath != f.Path() {
+			jf.OldPath = f.OldPath
+		}
+		out = append(out, jf)
+	}
+	return out
+}
+
+// writeJSON writes a JSON document followed by a newline
+func writeJSON(w io.Writer, doc jsonDocument) error {
+	doc.SchemaVersion = schemaVersion
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(doc)
+}
+
+// writePorcelainAction writes the single-line porcelain result of a mutating command
+func writePorcelainAction(w io.Writer, action string, mc *types.MiniCommit) {
+	fmt.Fprintf(w, "%s %s\n", action, mc.ID)
+}
+
+// writePorcelainEntry writes one mini-commit as a porcelain list line:
+// <id> SP <created-unix> SP <files> SP <insertions> SP <deletions> SP <subject>
+func writePorcelainEntry(w io.Writer, mc *types.MiniCommit) {
+	stats := miniCommitStats(mc)
+	fmt.Fprintf(w, "%s %d %d %d %d %s\n", mc.ID, mc.CreatedAt.Unix(),
+		stats.Files, stats.Insertions, stats.Deletions, subject(mc.Message))
+}
+
+// writePorcelainDetail writes a mini-commit with length-prefixed message and patch sections
+func writePorcelainDetail(w io.Writer, mc *types.MiniCommit) {
+	stats := miniCommitStats(mc)
+	fmt.Fprintf(w, "id %s\n", mc.ID)
+	fmt.Fprintf(w, "created %d\n", mc.CreatedAt.Unix())
+	fmt.Fprintf(w, "stats %d %d %d\n", stats.Files, stats.Insertions, stats.Deletions)
+	fmt.Fprintf(w, "message %d\n%s\n", len(mc.Message), mc.Message)
+	fmt.Fprintf(w, "patch %d\n%s", len(mc.Patch), mc.Patch)
+}
+
+// oneLine joins the lines of a message for the porcelain format
+var oneLine = strings.NewReplacer("\r\n", " ", "\n", " ", "\r", " ")
+
+// writeError reports err on w in the given output mode
+func writeError(w io.Writer, mode string, err error) {
+	code := errorCode(err)
+	hint := errorHints[code]
+	switch mode {
+	case outputJSON:
+		_ = writeJSON(w, jsonDocument{Error: &jsonError{Code: code, Message: err.Error(), Hint: hint,
+			ExitCode: exitStatus(code)}})
+	case outputPorcelainV1:
+		// git's stderr can span lines; the error stays on one
+		fmt.Fprintf(w, "error %s %s\n", code, oneLine.Replace(err.Error()))
+	default:
+		fmt.Fprintln(w, err)
+		if hint != "" {
+			fmt.Fprintf(w, "hint: %s\n", hint)
+		}
+	}
+}
+
+func init() {
+	rootCmd.PersistentFlags().Bool("json", false, "print machine-readable JSON output")
+	rootCmd.PersistentFlags().String("porcelain", "", "print stable, script-friendly output (format: v1)")
+	rootCmd.PersistentFlags().Lookup("porcelain").NoOptDefVal = "v1"
+}
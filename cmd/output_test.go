@@ -0,0 +1,141 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"git-mini-commit/internal/git"
+	"git-mini-commit/testutils"
+)
+
+func TestCLIJSONOutput(t *testing.T) {
+	// テスト用Gitリポジトリを作成
+	repo := testutils.NewTestGitRepo(t)
+	defer repo.Cleanup()
+
+	// テスト用CLIを作成
+	cli := testutils.NewTestCLI(t)
+	cli.SetRepo(repo)
+
+	// 1. ファイルを作成してステージング
+	if err := repo.CreateTestFile("test.txt", "Hello, World!\n"); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+	if err := repo.StageFile("test.txt"); err != nil {
+		t.Fatalf("Failed to stage file: %v", err)
+	}
+
+	// 2. JSON出力でmini-commitを作成
+	var created jsonDocument
+	output := cli.AssertCommandSuccess(t, "-m", "Test commit", "--json")
+	if err := json.Unmarshal([]byte(output), &created); err != nil {
+		t.Fatalf("Failed to parse JSON output: %v, output: %s", err, output)
+	}
+	if created.Action != "create" || created.MiniCommit == nil {
+		t.Fatalf("Expected create document, but got: %s", output)
+	}
+	if created.MiniCommit.Stats.Files != 1 || created.MiniCommit.Stats.Insertions != 1 {
+		t.Errorf("Unexpected stats: %+v", created.MiniCommit.Stats)
+	}
+
+	// 3. JSON出力で一覧を表示
+	var listed jsonDocument
+	output = cli.AssertCommandSuccess(t, "list", "--json")
+	if err := json.Unmarshal([]byte(output), &listed); err != nil {
+		t.Fatalf("Failed to parse JSON output: %v, output: %s", err, output)
+	}
+	if listed.MiniCommits == nil || len(*listed.MiniCommits) != 1 {
+		t.Fatalf("Expected 1 mini-commit in JSON list, but got: %s", output)
+	}
+	if (*listed.MiniCommits)[0].ID != created.MiniCommit.ID {
+		t.Errorf("Expected ID %s, but got %s", created.MiniCommit.ID, (*listed.MiniCommits)[0].ID)
+	}
+
+	// 4. JSON出力で差分を表示
+	var shown jsonDocument
+	output = cli.AssertCommandSuccess(t, "show", created.MiniCommit.ID, "--json")
+	if err := json.Unmarshal([]byte(output), &shown); err != nil {
+		t.Fatalf("Failed to parse JSON output: %v, output: %s", err, output)
+	}
+	if shown.MiniCommit == nil || shown.MiniCommit.Patch == nil || !strings.Contains(*shown.MiniCommit.Patch, "Hello, World!") {
+		t.Errorf("Expected patch in JSON show output, but got: %s", output)
+	}
+
+	// 5. エラーはコード付きのJSONで出力される
+	output = cli.AssertCommandFailure(t, "show", "nonexistent-id", "--json")
+	var failed jsonDocument
+	if err := json.Unmarshal([]byte(output), &failed); err != nil {
+		t.Fatalf("Failed to parse JSON error: %v, output: %s", err, output)
+	}
+	if failed.Error == nil || failed.Error.Code != codeNotFound {
+		t.Errorf("Expected '%s' error code, but got: %s", codeNotFound, output)
+	}
+}
+
+func TestCLIPorcelainOutput(t *testing.T) {
+	// テスト用Gitリポジトリを作成
+	repo := testutils.NewTestGitRepo(t)
+	defer repo.Cleanup()
+
+	// テスト用CLIを作成
+	cli := testutils.NewTestCLI(t)
+	cli.SetRepo(repo)
+
+	// 1. ファイルを作成してステージング
+	if err := repo.CreateTestFile("test.txt", "Hello, World!\n"); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+	if err := repo.StageFile("test.txt"); err != nil {
+		t.Fatalf("Failed to stage file: %v", err)
+	}
+
+	// 2. porcelain出力でmini-commitを作成
+	output := cli.AssertCommandSuccess(t, "-m", "Porcelain commit", "--porcelain")
+	fields := strings.Fields(output)
+	if len(fields) != 2 || fields[0] != "created" || len(fields[1]) != 40 {
+		t.Fatalf("Unexpected porcelain create output: %q", output)
+	}
+	id := fields[1]
+
+	// 3. porcelain出力で一覧を表示
+	output = cli.AssertCommandSuccess(t, "list", "--porcelain=v1")
+	expectedPrefix := id + " "
+	if !strings.HasPrefix(output, expectedPrefix) || !strings.HasSuffix(output, " 1 1 0 Porcelain commit\n") {
+		t.Errorf("Unexpected porcelain list output: %q", output)
+	}
+
+	// 4. 未対応のporcelainバージョンはエラー
+	output = cli.AssertCommandFailure(t, "list", "--porcelain=v2")
+	if !strings.Contains(output, "unsupported porcelain version") {
+		t.Errorf("Expected 'unsupported porcelain version' in output, but got: %s", output)
+	}
+
+	// 5. エラーはコード付きで出力される
+	output = cli.AssertCommandFailure(t, "drop", "nonexistent-id", "--porcelain")
+	if !strings.HasPrefix(output, "error "+codeNotFound+" ") {
+		t.Errorf("Expected porcelain error line, but got: %q", output)
+	}
+}
+
+func TestWriteErrorPorcelainOneLine(t *testing.T) {
+	// gitの複数行のstderrを含むエラーも1行で出力する
+	err := gitError(&git.Error{
+		Args:     []string{"apply", "--cached"},
+		ExitCode: 1,
+		Stderr:   "error: patch failed: test.txt:1\r\nerror: test.txt: patch does not apply\n",
+		Err:      errors.New("exit status 1"),
+	})
+	var buf bytes.Buffer
+	writeError(&buf, outputPorcelainV1, err)
+
+	output := buf.String()
+	if strings.Count(output, "\n") != 1 || strings.ContainsRune(output, '\r') {
+		t.Fatalf("Expected a single porcelain error line, got: %q", output)
+	}
+	if !strings.HasPrefix(output, "error "+codeGit+" ") || !strings.Contains(output, "test.txt:1 error: test.txt: patch does not apply") {
+		t.Errorf("Unexpected porcelain error line: %q", output)
+	}
+}
@@ -0,0 +1,93 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"git-mini-commit/internal/color"
+	"git-mini-commit/internal/git"
+	"git-mini-commit/internal/pager"
+
+	"github.com/spf13/cobra"
+)
+
+// pagerName is the command name used for the pager.<name> git config key
+const pagerName = "mini-commit"
+
+// colorEnabled decides whether human-readable output is colored: --color wins,
+// then NO_COLOR, then the given config key (e.g. color.diff), then color.ui, then auto
+func colorEnabled(cmd *cobra.Command, configKey string) (bool, error) {
+	isTerminal := color.IsTerminal(os.Stdout)
+
+	if flag := cmd.Root().PersistentFlags().Lookup("color"); flag.Changed {
+		mode, err := color.ParseMode(flag.Value.String())
+		if err != nil {
+			return false, newCommandError(codeUsage, "%v", err)
+		}
+		return color.Enabled(mode, isTerminal), nil
+	}
+
+	if os.Getenv("NO_COLOR") != "" {
+		return false, nil
+	}
+
+	for _, key := range []string{configKey, "color.ui"} {
+		if key == "" {
+			continue
+		}
+		value, ok, err := git.GetConfig(key)
+		if err != nil {
+			return false, gitError(err)
+		}
+		if !ok {
+			continue
+		}
+		mode, err := color.ParseMode(value)
+		if err != nil {
+			return false, newCommandError(codeUsage, "%s: %v", key, err)
+		}
+		return color.Enabled(mode, isTerminal), nil
+	}
+
+	return color.Enabled(color.ModeAuto, isTerminal), nil
+}
+
+// startPager redirects the command output to the configured pager when stdout is a
+// terminal. The returned function must be called once all output has been written.
+func startPager(cmd *cobra.Command) (func(), error) {
+	noop := func() {}
+
+	if noPager, _ := cmd.Root().PersistentFlags().GetBool("no-pager"); noPager {
+		return noop, nil
+	}
+	if !color.IsTerminal(os.Stdout) {
+		return noop, nil
+	}
+
+	command, err := pager.Resolve(pagerName, git.GetConfig)
+	if err != nil {
+		return noop, gitError(err)
+	}
+	if command == "" {
+		return noop, nil
+	}
+
+	p, err := pager.Start(command, os.Stdout, os.Stderr)
+	if err != nil {
+		// Fall back to plain output rather than failing the command
+		fmt.Fprintln(os.Stderr, err)
+		return noop, nil
+	}
+
+	cmd.SetOut(p)
+	return func() {
+		_ = p.Close()
+		cmd.SetOut(nil)
+	}, nil
+}
+
+func init() {
+	rootCmd.PersistentFlags().String("color", color.ModeAuto, "colorize output: auto, always or never")
+	rootCmd.PersistentFlags().Lookup("color").NoOptDefVal = color.ModeAlways
+	rootCmd.PersistentFlags().Bool("no-pager", false, "do not pipe output into a pager")
+}
@@ -4,7 +4,9 @@ import (
 	"fmt"
 
 	"git-mini-commit/internal/git"
+	"git-mini-commit/internal/hooks"
 	"git-mini-commit/internal/storage"
+	"git-mini-commit/internal/types"
 
 	"github.com/spf13/cobra"
 )
@@ -12,40 +14,86 @@ import (
 var popCmd = &cobra.Command{
 	Use:   "pop <hash>",
 	Short: "Apply mini-commit content back to staging",
-	Long:  `Apply the content of the mini-commit with the specified ID to the staging area.`,
-	Args:  cobra.ExactArgs(1),
+	Long: `Apply the content of the mini-commit with the specified ID to the staging area.
+
+The pre-pop hook runs first and stops the pop when it fails; --no-verify skips it.
+The post-pop hook runs once the patch is staged.`,
+	Args: exactArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) error {
 		hash := args[0]
 
+		mode, err := outputMode(cmd)
+		if err != nil {
+			return err
+		}
+
 		// Check if it's a Git repository
-		if !git.IsGitRepository() {
-			return fmt.Errorf("not a git repository")
+		if err := git.CheckRepository(); err != nil {
+			return gitError(err)
 		}
 
 		// Initialize storage
 		storage, err := storage.NewStorage()
 		if err != nil {
-			return fmt.Errorf("failed to initialize storage: %v", err)
+			return storageError(err, "failed to initialize storage")
 		}
 
 		// Get mini-commit
-		mc, err := storage.GetMiniCommit(hash)
+		mc, err := storage.FindMiniCommit(hash)
 		if err != nil {
-			return fmt.Errorf("failed to get mini-commit: %v", err)
+			return storageError(err, "failed to get mini-commit")
+		}
+
+		noVerify, _ := cmd.Flags().GetBool("no-verify")
+		if err := popMiniCommit(cmd, storage, mc, noVerify); err != nil {
+			return err
 		}
 
-		// Apply patch to staging area
-		if err := git.ApplyPatch(mc.Patch); err != nil {
-			return fmt.Errorf("failed to apply patch: %v", err)
+		out := cmd.OutOrStdout()
+		switch mode {
+		case outputJSON:
+			doc := toJSON(mc, false)
+			return writeJSON(out, jsonDocument{Action: "pop", MiniCommit: &doc})
+		case outputPorcelainV1:
+			writePorcelainAction(out, "applied", mc)
+			return nil
 		}
 
-		fmt.Printf("Applied mini-commit '%s' to staging area\n", mc.ID[:8])
-		fmt.Printf("Message: %s\n", mc.Message)
+		fmt.Fprintf(out, "Applied mini-commit '%s' to staging area\n", shortID(mc.ID))
+		fmt.Fprintf(out, "Message: %s\n", mc.Message)
 
 		return nil
 	},
 }
 
+// popMiniCommit applies the patch of mc to the staging area between the pop
+// hooks, which noVerify skips
+func popMiniCommit(cmd *cobra.Command, store *storage.Storage, mc *types.MiniCommit, noVerify bool) error {
+	if !noVerify {
+		if err := runHook(cmd, hooks.PrePop, mc); err != nil {
+			return err
+		}
+	}
+
+	// Stream the patch into the staging area
+	patch, err := store.OpenPatch(mc)
+	if err != nil {
+		return storageError(err, "failed to get mini-commit")
+	}
+	defer patch.Close()
+	if err := git.ApplyPatchFrom(patch); err != nil {
+		return newCommandError(codeApplyFailed, "failed to apply patch: %w", err)
+	}
+
+	// The patch is already staged, so a logging failure is only reported
+	if err := store.RecordPop(mc); err != nil {
+		fmt.Fprintf(cmd.ErrOrStderr(), "warning: %v\n", err)
+	}
+	runPostHook(cmd, hooks.PostPop, mc)
+	return nil
+}
+
 func init() {
+	popCmd.Flags().BoolP("no-verify", "n", false, "skip the pre-pop hook")
 	rootCmd.AddCommand(popCmd)
 }
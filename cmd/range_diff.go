@@ -0,0 +1,331 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+
+	"git-mini-commit/internal/color"
+	"git-mini-commit/internal/git"
+	"git-mini-commit/internal/linediff"
+	"git-mini-commit/internal/storage"
+	"git-mini-commit/internal/types"
+
+	"github.com/spf13/cobra"
+)
+
+// Range-diff pair statuses, as shown by git range-diff
+const (
+	rangeEqual    = "="
+	rangeModified = "!"
+	rangeRemoved  = "<"
+	rangeAdded    = ">"
+)
+
+// rangeDiffSimilarity is the minimum similarity for pairing mini-commits whose subjects differ
+const rangeDiffSimilarity = 0.5
+
+// rangeDiffContext is the number of context lines in interdiffs
+const rangeDiffContext = 3
+
+// rangePair is one line of range-diff output; old or new is -1 for unmatched mini-commits
+type rangePair struct {
+	status    string
+	old, new  int
+	interdiff string
+}
+
+// hunkHeaderNumbers matches the line numbers of a hunk header, which differ after restacking
+var hunkHeaderNumbers = regexp.MustCompile(`^@@ -\d+(,\d+)? \+\d+(,\d+)? @@`)
+
+var rangeDiffCmd = &cobra.Command{
+	Use:   "range-diff <old-range> <new-range>",
+	Short: "Compare two versions of a stack of mini-commits",
+	Long: `Compare two ranges of mini-commits, for example a stack before and after it
+was rebuilt, and pair up the mini-commits that correspond to each other.
+
+A range is written <a>..<b> and includes both ends; mini-commits can be named by
+ID or by stack position (@N, @-N) as in diff:
+
+  git mini-commit range-diff @1..@3 @4..@6
+
+A range can also be taken from the stack as it was before an operation listed
+by reflog: @{N} is that whole stack and @{N}:<a>..<b> a range of it, with
+positions and IDs resolved in that stack. This compares the stack with how it
+was before it was rebuilt, including mini-commits dropped since:
+
+  git mini-commit range-diff @{2} @1..@-1
+
+Each line shows the position and ID in the old range, a status and the position
+and ID in the new range:
+
+  =  the message and patch are unchanged
+  !  the mini-commit was changed; the difference between the two versions of
+     the message and patch follows
+  <  the mini-commit only exists in the old range
+  >  the mini-commit only exists in the new range
+
+Mini-commits are paired by identical content first, then by subject and finally
+by similarity of their patches. Line numbers in hunk headers and index lines are
+ignored, so mini-commits that only moved are reported as unchanged.`,
+	Args: exactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		mode, err := outputMode(cmd)
+		if err != nil {
+			return err
+		}
+
+		// Check if it's a Git repository
+		if err := git.CheckRepository(); err != nil {
+			return gitError(err)
+		}
+
+		// Initialize storage
+		storage, err := storage.NewStorage()
+		if err != nil {
+			return storageError(err, "failed to initialize storage")
+		}
+
+		miniCommits, err := storage.LoadMiniCommits()
+		if err != nil {
+			return storageError(err, "failed to load mini-commits")
+		}
+		oldRange, err := resolveStackRange(storage, miniCommits, args[0])
+		if err != nil {
+			return err
+		}
+		newRange, err := resolveStackRange(storage, miniCommits, args[1])
+		if err != nil {
+			return err
+		}
+		if err := loadPatches(storage, oldRange); err != nil {
+			return err
+		}
+		if err := loadPatches(storage, newRange); err != nil {
+			return err
+		}
+
+		pairs := pairRanges(oldRange, newRange)
+
+		out := cmd.OutOrStdout()
+		switch mode {
+		case outputJSON:
+			docs := make([]jsonRangePair, 0, len(pairs))
+			for _, p := range pairs {
+				doc := jsonRangePair{Status: p.status, Interdiff: p.interdiff}
+				if p.old >= 0 {
+					doc.Old = &jsonRangeEntry{Index: p.old + 1, ID: oldRange[p.old].ID}
+					doc.Subject = subject(oldRange[p.old].Message)
+				}
+				if p.new >= 0 {
+					doc.New = &jsonRangeEntry{Index: p.new + 1, ID: newRange[p.new].ID}
+					doc.Subject = subject(newRange[p.new].Message)
+				}
+				docs = append(docs, doc)
+			}
+			return writeJSON(out, jsonDocument{RangeDiff: &docs})
+		case outputPorcelainV1:
+			writePorcelainRangeDiff(out, pairs, oldRange, newRange)
+			return nil
+		}
+
+		useColor, err := colorEnabled(cmd, "color.diff")
+		if err != nil {
+			return err
+		}
+		stopPager, err := startPager(cmd)
+		if err != nil {
+			return err
+		}
+		defer stopPager()
+
+		writeRangeDiff(cmd.OutOrStdout(), pairs, oldRange, newRange, useColor)
+		return nil
+	},
+}
+
+// rangeDiffText is the text compared between two versions of a mini-commit:
+// its message and its patch without line numbers and blob IDs
+func rangeDiffText(mc *types.MiniCommit) []string {
+	lines := linediff.Lines(mc.Message)
+	lines = append(lines, "")
+	for _, line := range linediff.Lines(mc.Patch) {
+		if strings.HasPrefix(line, "index ") {
+			continue
+		}
+		lines = append(lines, hunkHeaderNumbers.ReplaceAllString(line, "@@"))
+	}
+	return lines
+}
+
+// pairRanges matches the mini-commits of two ranges and orders the result like
+// git range-diff: following the new range, with removed mini-commits shown
+// where they used to be
+func pairRanges(oldRange, newRange types.MiniCommitList) []rangePair {
+	oldText := make([][]string, len(oldRange))
+	for i := range oldRange {
+		oldText[i] = rangeDiffText(&oldRange[i])
+	}
+	newText := make([][]string, len(newRange))
+	for j := range newRange {
+		newText[j] = rangeDiffText(&newRange[j])
+	}
+
+	oldMatch := make([]int, len(oldRange))
+	newMatch := make([]int, len(newRange))
+	for i := range oldMatch {
+		oldMatch[i] = -1
+	}
+	for j := range newMatch {
+		newMatch[j] = -1
+	}
+	link := func(i, j int) {
+		oldMatch[i], newMatch[j] = j, i
+	}
+
+	// Identical content, then identical subjects, then the most similar patch
+	for j := range newRange {
+		for i := range oldRange {
+			if oldMatch[i] < 0 && strings.Join(oldText[i], "\n") == strings.Join(newText[j], "\n") {
+				link(i, j)
+				break
+			}
+		}
+	}
+	for j := range newRange {
+		if newMatch[j] >= 0 {
+			continue
+		}
+		for i := range oldRange {
+			if oldMatch[i] < 0 && subject(oldRange[i].Message) == subject(newRange[j].Message) {
+				link(i, j)
+				break
+			}
+		}
+	}
+	for j := range newRange {
+		if newMatch[j] >= 0 {
+			continue
+		}
+		best, bestScore := -1, rangeDiffSimilarity
+		for i := range oldRange {
+			if oldMatch[i] >= 0 {
+				continue
+			}
+			if score := linediff.Similarity(linediff.Diff(oldText[i], newText[j])); score >= bestScore {
+				best, bestScore = i, score
+			}
+		}
+		if best >= 0 {
+			link(best, j)
+		}
+	}
+
+	var pairs []rangePair
+	shown := make([]bool, len(oldRange))
+	i, j := 0, 0
+	for i < len(oldRange) || j < len(newRange) {
+		// Skip old mini-commits already shown next to their new version
+		if i < len(oldRange) && shown[i] {
+			i++
+			continue
+		}
+		if i < len(oldRange) && oldMatch[i] < 0 {
+			pairs = append(pairs, rangePair{status: rangeRemoved, old: i, new: -1})
+			shown[i] = true
+			i++
+			continue
+		}
+		if j >= len(newRange) {
+			break
+		}
+
+		if newMatch[j] < 0 {
+			pairs = append(pairs, rangePair{status: rangeAdded, old: -1, new: j})
+		} else {
+			old := newMatch[j]
+			edits := linediff.Diff(oldText[old], newText[j])
+			pair := rangePair{status: rangeEqual, old: old, new: j}
+			if !linediff.Equal(edits) {
+				pair.status = rangeModified
+				pair.interdiff = linediff.Unified(edits, rangeDiffContext)
+			}
+			pairs = append(pairs, pair)
+			shown[old] = true
+		}
+		j++
+	}
+
+	return pairs
+}
+
+// writeRangeDiff writes the human-readable range-diff
+func writeRangeDiff(w io.Writer, pairs []rangePair, oldRange, newRange types.MiniCommitList, useColor bool) {
+	width := len(fmt.Sprint(len(oldRange)))
+	if n := len(fmt.Sprint(len(newRange))); n > width {
+		width = n
+	}
+	side := func(pos int, miniCommits types.MiniCommitList) string {
+		if pos < 0 {
+			return fmt.Sprintf("%*s:  %s", width, "-", strings.Repeat("-", 8))
+		}
+		return fmt.Sprintf("%*d:  %s", width, pos+1, shortID(miniCommits[pos].ID))
+	}
+
+	for _, p := range pairs {
+		mc := &oldRange[max(p.old, 0)]
+		if p.new >= 0 {
+			mc = &newRange[p.new]
+		}
+		line := fmt.Sprintf("%s %s %s %s", side(p.old, oldRange), p.status, side(p.new, newRange), subject(mc.Message))
+		if useColor {
+			switch p.status {
+			case rangeRemoved:
+				line = color.Wrap(color.Red, line)
+			case rangeAdded:
+				line = color.Wrap(color.Green, line)
+			case rangeModified:
+				line = color.Wrap(color.Yellow, line)
+			}
+		}
+		fmt.Fprintln(w, line)
+
+		if p.interdiff != "" {
+			interdiff := p.interdiff
+			if useColor {
+				interdiff = color.Diff(interdiff)
+			}
+			for _, l := range linediff.Lines(interdiff) {
+				fmt.Fprintf(w, "    %s\n", l)
+			}
+		}
+	}
+}
+
+// writePorcelainRangeDiff writes one line per pair:
+// <status> SP <old-index> SP <old-id> SP <new-index> SP <new-id> SP <subject>
+// with "-" for a missing side, followed by a length-prefixed interdiff for changed pairs
+func writePorcelainRangeDiff(w io.Writer, pairs []rangePair, oldRange, newRange types.MiniCommitList) {
+	side := func(pos int, miniCommits types.MiniCommitList) string {
+		if pos < 0 {
+			return "- -"
+		}
+		return fmt.Sprintf("%d %s", pos+1, miniCommits[pos].ID)
+	}
+
+	for _, p := range pairs {
+		mc := &oldRange[max(p.old, 0)]
+		if p.new >= 0 {
+			mc = &newRange[p.new]
+		}
+		fmt.Fprintf(w, "%s %s %s %s\n", p.status, side(p.old, oldRange), side(p.new, newRange), subject(mc.Message))
+		if p.status == rangeModified {
+			fmt.Fprintf(w, "interdiff %d\n%s", len(p.interdiff), p.interdiff)
+		}
+	}
+}
+
+func init() {
+	rootCmd.AddCommand(rangeDiffCmd)
+}
@@ -0,0 +1,96 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"git-mini-commit/internal/types"
+)
+
+// rangeEntry file に行を追加するパッチを持つ mini-commit を作る。start はハンクの開始行
+func rangeEntry(id, message, file, line string, start int) types.MiniCommit {
+	patch := fmt.Sprintf("diff --git a/%s b/%s\nindex 1111111..2222222 100644\n--- a/%s\n+++ b/%s\n@@ -%d,0 +%d @@\n+%s\n",
+		file, file, file, file, start, start+1, line)
+	return types.MiniCommit{ID: id, Message: message, Patch: patch}
+}
+
+// pairsString pairRanges の結果を "<status><old>:<new>" の並びにする（位置は1から、ない側は "-"）
+func pairsString(pairs []rangePair) string {
+	side := func(pos int) string {
+		if pos < 0 {
+			return "-"
+		}
+		return fmt.Sprint(pos + 1)
+	}
+	var parts []string
+	for _, p := range pairs {
+		parts = append(parts, p.status+side(p.old)+":"+side(p.new))
+	}
+	return strings.Join(parts, " ")
+}
+
+func TestPairRangesMoved(t *testing.T) {
+	oldRange := types.MiniCommitList{
+		rangeEntry("a1", "Add a", "a.txt", "a", 1),
+		rangeEntry("b1", "Add b", "b.txt", "b", 1),
+		rangeEntry("c1", "Add c", "c.txt", "c", 1),
+	}
+	// 並べ替えで行番号とIDが変わっても、内容が同じなら変更なし
+	newRange := types.MiniCommitList{
+		rangeEntry("c2", "Add c", "c.txt", "c", 7),
+		rangeEntry("a2", "Add a", "a.txt", "a", 3),
+		rangeEntry("b2", "Add b", "b.txt", "b", 1),
+	}
+
+	pairs := pairRanges(oldRange, newRange)
+	if got := pairsString(pairs); got != "=3:1 =1:2 =2:3" {
+		t.Errorf("pairRanges() = %s", got)
+	}
+	for _, p := range pairs {
+		if p.interdiff != "" {
+			t.Errorf("Expected no interdiff for a moved mini-commit, got:\n%s", p.interdiff)
+		}
+	}
+}
+
+func TestPairRangesReworded(t *testing.T) {
+	oldRange := types.MiniCommitList{
+		rangeEntry("a1", "Add a", "a.txt", "a", 1),
+		rangeEntry("b1", "Add b", "b.txt", "b", 1),
+	}
+	// 件名が変わってもパッチが同じなら対応付け、メッセージの差分を示す
+	newRange := types.MiniCommitList{
+		rangeEntry("a1", "Add a", "a.txt", "a", 1),
+		rangeEntry("b2", "feat: add b", "b.txt", "b", 1),
+	}
+
+	pairs := pairRanges(oldRange, newRange)
+	if got := pairsString(pairs); got != "=1:1 !2:2" {
+		t.Fatalf("pairRanges() = %s", got)
+	}
+	if !strings.Contains(pairs[1].interdiff, "-Add b\n+feat: add b\n") {
+		t.Errorf("Expected the message change in the interdiff, got:\n%s", pairs[1].interdiff)
+	}
+}
+
+func TestPairRangesRemoved(t *testing.T) {
+	oldRange := types.MiniCommitList{
+		rangeEntry("a1", "Add a", "a.txt", "a", 1),
+		rangeEntry("b1", "Add b", "b.txt", "b", 1),
+		rangeEntry("c1", "Add c", "c.txt", "c", 1),
+	}
+	// 削除されたものは元の位置に、追加されたものは新しい範囲の位置に表示する
+	newRange := types.MiniCommitList{
+		rangeEntry("a1", "Add a", "a.txt", "a", 1),
+		rangeEntry("c1", "Add c", "c.txt", "c", 1),
+		rangeEntry("d1", "Add d", "d.txt", "d", 1),
+	}
+
+	if got := pairsString(pairRanges(oldRange, newRange)); got != "=1:1 <2:- =3:2 >-:3" {
+		t.Errorf("pairRanges() = %s", got)
+	}
+	if got := pairsString(pairRanges(oldRange, nil)); got != "<1:- <2:- <3:-" {
+		t.Errorf("pairRanges() against an empty range = %s", got)
+	}
+}
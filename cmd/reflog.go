@@ -0,0 +1,118 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+
+	"git-mini-commit/internal/color"
+	"git-mini-commit/internal/git"
+	"git-mini-commit/internal/storage"
+
+	"github.com/spf13/cobra"
+)
+
+var reflogCmd = &cobra.Command{
+	Use:   "reflog",
+	Short: "Show the log of operations on the mini-commit store",
+	Long: `Show every create, drop, pop, edit, clear, restore and undo recorded in the
+operation log, newest first. @{0} is the latest operation; "git mini-commit
+undo N" reverts @{0} through @{N-1}.`,
+	Args: exactArgs(0),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		mode, err := outputMode(cmd)
+		if err != nil {
+			return err
+		}
+
+		limit, _ := cmd.Flags().GetInt("limit")
+		if limit < 0 {
+			return newCommandError(codeUsage, "--limit must not be negative")
+		}
+
+		// Check if it's a Git repository
+		if err := git.CheckRepository(); err != nil {
+			return gitError(err)
+		}
+
+		// Initialize storage
+		store, err := storage.NewStorage()
+		if err != nil {
+			return storageError(err, "failed to initialize storage")
+		}
+
+		ops, err := store.Operations()
+		if err != nil {
+			return storageError(err, "failed to read operation log")
+		}
+
+		// Newest first, as @{0}, @{1}, ...
+		var entries []storage.Operation
+		for i := len(ops) - 1; i >= 0; i-- {
+			entries = append(entries, ops[i])
+		}
+		if limit > 0 && limit < len(entries) {
+			entries = entries[:limit]
+		}
+
+		out := cmd.OutOrStdout()
+		switch mode {
+		case outputJSON:
+			docs := make([]jsonOperation, 0, len(entries))
+			for i, op := range entries {
+				doc := jsonOperation{
+					Index:   i,
+					Kind:    op.Kind,
+					Time:    op.Time,
+					Summary: op.Summary,
+					Before:  op.Before,
+					After:   op.After,
+				}
+				for _, mc := range op.Removed {
+					doc.Removed = append(doc.Removed, mc.ID)
+				}
+				docs = append(docs, doc)
+			}
+			return writeJSON(out, jsonDocument{Operations: &docs})
+		case outputPorcelainV1:
+			for i, op := range entries {
+				fmt.Fprintf(out, "%d %d %s %d %d %s\n", i, op.Time.Unix(), op.Kind, len(op.Before), len(op.After), op.Summary)
+			}
+			return nil
+		}
+
+		if len(entries) == 0 {
+			fmt.Fprintln(out, "No operations recorded")
+			return nil
+		}
+
+		useColor, err := colorEnabled(cmd, "")
+		if err != nil {
+			return err
+		}
+		stopPager, err := startPager(cmd)
+		if err != nil {
+			return err
+		}
+		defer stopPager()
+
+		writeReflog(cmd.OutOrStdout(), entries, useColor)
+		return nil
+	},
+}
+
+// writeReflog writes one line per operation: "@{N} <time> <kind>: <summary>"
+func writeReflog(w io.Writer, entries []storage.Operation, useColor bool) {
+	for i, op := range entries {
+		ref := fmt.Sprintf("@{%d}", i)
+		if useColor {
+			ref = color.Wrap(color.Yellow, ref)
+		}
+		fmt.Fprintf(w, "%s %s %s: %s (%d -> %d)\n", ref, op.Time.Format("2006-01-02 15:04:05"),
+			op.Kind, op.Summary, len(op.Before), len(op.After))
+	}
+}
+
+func init() {
+	reflogCmd.Flags().IntP("limit", "n", 0, "show only the N most recent operations")
+	rootCmd.AddCommand(reflogCmd)
+}
@@ -0,0 +1,64 @@
+package cmd
+
+import (
+	"os/exec"
+	"strings"
+	"testing"
+
+	"git-mini-commit/testutils"
+)
+
+func TestCLIReflogAndUndo(t *testing.T) {
+	// テスト用Gitリポジトリを作成
+	repo := testutils.NewTestGitRepo(t)
+	defer repo.Cleanup()
+
+	// テスト用CLIを作成
+	cli := testutils.NewTestCLI(t)
+	cli.SetRepo(repo)
+
+	// 1. 2つのmini-commitを作成し、1つを誤って削除
+	first := saveMiniCommit(t, repo, cli, "a.txt", "a\n", "First")
+	second := saveMiniCommit(t, repo, cli, "b.txt", "b\n", "Second")
+	cli.AssertCommandSuccess(t, "drop", first)
+	exec.Command("git", "rm", "-q", "--cached", "a.txt", "b.txt").Run()
+	cli.AssertCommandSuccess(t, "pop", second)
+
+	// 2. reflog は新しい順に表示
+	output := cli.AssertCommandSuccess(t, "reflog")
+	lines := strings.Split(strings.TrimSpace(output), "\n")
+	if len(lines) != 4 {
+		t.Fatalf("Expected 4 operations, but got: %s", output)
+	}
+	if !strings.HasPrefix(lines[0], "@{0} ") || !strings.Contains(lines[0], " pop: ") {
+		t.Errorf("Expected the pop first, but got: %s", lines[0])
+	}
+	if !strings.Contains(lines[1], " drop: "+first[:8]+" First (2 -> 1)") {
+		t.Errorf("Unexpected drop entry: %s", lines[1])
+	}
+
+	// 3. pop と drop を取り消すと削除したmini-commitが戻る
+	output = cli.AssertCommandSuccess(t, "undo", "2")
+	cli.AssertOutputContains(t, output, "Undid 2 operations")
+	cli.AssertOutputContains(t, output, "Note: changes applied by pop are still staged")
+	output = cli.AssertCommandSuccess(t, "show", first)
+	cli.AssertOutputContains(t, output, "Message: First")
+
+	// 4. undo の取り消し（redo）
+	output = cli.AssertCommandSuccess(t, "undo", "--porcelain")
+	if output != "undone 1 1\n" {
+		t.Errorf("Unexpected porcelain output: %q", output)
+	}
+	cli.AssertCommandFailure(t, "show", first)
+
+	output = cli.AssertCommandSuccess(t, "reflog", "-n", "1", "--porcelain")
+	if !strings.HasPrefix(output, "0 ") || !strings.Contains(output, " undo 2 1 ") {
+		t.Errorf("Unexpected porcelain reflog: %q", output)
+	}
+
+	// 不正な引数
+	output = cli.AssertCommandFailure(t, "undo", "zero")
+	cli.AssertOutputContains(t, output, "invalid number of operations")
+	output = cli.AssertCommandFailure(t, "undo", "100")
+	cli.AssertOutputContains(t, output, "cannot undo 100 operation(s)")
+}
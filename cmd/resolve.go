@@ -0,0 +1,129 @@
+package cmd
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"git-mini-commit/internal/git"
+	"git-mini-commit/internal/storage"
+	"git-mini-commit/internal/types"
+)
+
+// stackPosition matches "@N" (N-th mini-commit as numbered by list) and "@-N" (N-th newest)
+var stackPosition = regexp.MustCompile(`^@(-?\d+)$`)
+
+// resolveMiniCommit finds the mini-commit named by ref, which is either an ID, a
+// unique prefix of one or a stack position, and returns it with its 0-based position in miniCommits
+func resolveMiniCommit(miniCommits types.MiniCommitList, ref string) (*types.MiniCommit, int, error) {
+	if m := stackPosition.FindStringSubmatch(ref); m != nil {
+		n, _ := strconv.Atoi(m[1])
+		pos := n - 1
+		if n < 0 {
+			pos = len(miniCommits) + n
+		}
+		if n == 0 || pos < 0 || pos >= len(miniCommits) {
+			return nil, 0, newCommandError(codeNotFound, "no mini-commit at position %s (stack has %d)", ref, len(miniCommits))
+		}
+		return &miniCommits[pos], pos, nil
+	}
+
+	ids := make([]string, len(miniCommits))
+	for i := range miniCommits {
+		ids[i] = miniCommits[i].ID
+	}
+	i, err := storage.MatchID(ids, ref)
+	if err != nil {
+		return nil, 0, storageError(err, "failed to get mini-commit")
+	}
+	return &miniCommits[i], i, nil
+}
+
+// resolveRange resolves "<a>..<b>" to the mini-commits from a to b inclusive, in stack order
+func resolveRange(miniCommits types.MiniCommitList, ref string) (types.MiniCommitList, error) {
+	from, to, ok := strings.Cut(ref, "..")
+	if !ok || from == "" || to == "" {
+		return nil, newCommandError(codeUsage, "invalid range '%s' (expected <a>..<b>)", ref)
+	}
+
+	_, start, err := resolveMiniCommit(miniCommits, from)
+	if err != nil {
+		return nil, err
+	}
+	_, end, err := resolveMiniCommit(miniCommits, to)
+	if err != nil {
+		return nil, err
+	}
+	if start > end {
+		return nil, newCommandError(codeUsage, "invalid range '%s': %s comes after %s in the stack", ref, from, to)
+	}
+	return miniCommits[start : end+1], nil
+}
+
+// pastStack matches "@{N}", the stack as it was before operation @{N} of
+// reflog, optionally followed by ":<a>..<b>" to take a range of it
+var pastStack = regexp.MustCompile(`^@\{(\d+)\}(?::(.*))?$`)
+
+// resolveStackRange resolves a range of range-diff: "<a>..<b>" in the current
+// stack, or "@{N}" or "@{N}:<a>..<b>" in the stack as it was before operation
+// @{N}, which may hold mini-commits since dropped or in a different version
+func resolveStackRange(store *storage.Storage, miniCommits types.MiniCommitList, ref string) (types.MiniCommitList, error) {
+	m := pastStack.FindStringSubmatch(ref)
+	if m == nil {
+		return resolveRange(miniCommits, ref)
+	}
+	n, err := strconv.Atoi(m[1])
+	if err != nil {
+		return nil, newCommandError(codeUsage, "invalid operation '%s'", ref)
+	}
+	ops, err := store.Operations()
+	if err != nil {
+		return nil, storageError(err, "failed to read operation log")
+	}
+	if n >= len(ops) {
+		return nil, newCommandError(codeNotFound, "no operation @{%d} (the log has %d)", n, len(ops))
+	}
+	past, err := store.StackBefore(n + 1)
+	if err != nil {
+		return nil, storageError(err, fmt.Sprintf("failed to rebuild the stack before @{%d}", n))
+	}
+	if m[2] == "" {
+		return past, nil
+	}
+	return resolveRange(past, m[2])
+}
+
+// loadPatches reads the patches of miniCommits, which the index does not hold
+func loadPatches(store *storage.Storage, miniCommits types.MiniCommitList) error {
+	for i := range miniCommits {
+		if miniCommits[i].Patch != "" {
+			continue
+		}
+		if err := store.LoadPatch(&miniCommits[i]); err != nil {
+			return storageError(err, "failed to load mini-commit")
+		}
+	}
+	return nil
+}
+
+// stateAfter rebuilds the tree the repository had once mc was staged: its patch
+// applied on top of its recorded base commit
+func stateAfter(mc *types.MiniCommit) (string, error) {
+	tree, err := git.TreeWithPatch(mc.Base, mc.Patch)
+	if err == nil || mc.Base != "" {
+		if err != nil {
+			return "", newCommandError(codeGit, "cannot rebuild the state after mini-commit %s: %v", shortID(mc.ID), err)
+		}
+		return tree, nil
+	}
+
+	// Mini-commits saved before bases were recorded have no base; try the current HEAD
+	head, headErr := git.HeadCommit()
+	if headErr == nil && head != "" {
+		if tree, headErr = git.TreeWithPatch(head, mc.Patch); headErr == nil {
+			return tree, nil
+		}
+	}
+	return "", newCommandError(codeGit, "cannot rebuild the state after mini-commit %s: %v", shortID(mc.ID), err)
+}
@@ -1,13 +1,21 @@
 package cmd
 
 import (
+	"bufio"
+	"context"
 	"fmt"
 	"os"
+	"os/signal"
+	"strings"
+	"syscall"
 	"time"
 
+	"git-mini-commit/internal/color"
 	"git-mini-commit/internal/git"
+	"git-mini-commit/internal/hooks"
 	"git-mini-commit/internal/storage"
 	"git-mini-commit/internal/types"
+	"git-mini-commit/internal/version"
 
 	"github.com/spf13/cobra"
 )
@@ -21,74 +29,201 @@ The staging area content is saved in small units and managed locally only.
 
 Usage:
   git mini-commit -m "message"      # Create mini-commit
+  git mini-commit                   # Create mini-commit, writing the message in the editor
   git mini-commit list              # List mini-commits
   git mini-commit show <hash>       # Show mini-commit diff
   git mini-commit pop <hash>        # Apply mini-commit to staging
   git mini-commit drop <hash>       # Delete mini-commit
-  git commit -m "message"          # Integration commit (standard Git command)`,
-	Args: cobra.ExactArgs(0),
+  git mini-commit watch             # Checkpoint the working tree automatically
+  git commit -m "message"          # Integration commit (standard Git command)
+
+Add --json or --porcelain to any command for machine-readable output.`,
+	Args: exactArgs(0),
 	RunE: func(cmd *cobra.Command, args []string) error {
-		message, _ := cmd.Flags().GetString("message")
-		if message == "" {
-			return fmt.Errorf("message is required (-m option)")
+		mode, err := outputMode(cmd)
+		if err != nil {
+			return err
+		}
+
+		// Without -m or -F the message is written in the editor once the
+		// staged changes are known
+		message, err := messageFromFlags(cmd)
+		if err != nil {
+			return err
+		}
+		trailerValues, _ := cmd.Flags().GetStringArray("trailer")
+		trailers, err := parseTrailers(trailerValues)
+		if err != nil {
+			return err
 		}
 
 		// Check if it's a Git repository
-		if !git.IsGitRepository() {
-			return fmt.Errorf("not a git repository")
+		if err := git.CheckRepository(); err != nil {
+			return gitError(err)
+		}
+		rules, lintEnabled, err := lintRulesFromConfig()
+		if err != nil {
+			return err
 		}
 
 		// Check if there are staged changes
 		hasChanges, err := git.HasStagedChanges()
 		if err != nil {
-			return fmt.Errorf("failed to check staging status: %v", err)
+			return gitError(err)
 		}
 		if !hasChanges {
-			return fmt.Errorf("no staged changes")
+			return gitError(git.ErrNoStagedChanges)
 		}
 
-		// Get staged changes
-		patch, err := git.GetStagedChanges()
+		// Record where and by whom the mini-commit was created
+		branch, err := git.CurrentBranch()
+		if err != nil {
+			return gitError(err)
+		}
+		// A missing user identity should not prevent checkpointing, so the author is optional
+		author, _ := git.AuthorIdent()
+		// The base commit lets the state after the mini-commit be rebuilt later
+		base, err := git.HeadCommit()
 		if err != nil {
-			return fmt.Errorf("failed to get staged changes: %v", err)
+			return gitError(err)
+		}
+		if message == "" {
+			if message, err = editMessage(branch); err != nil {
+				return err
+			}
+		}
+		// --no-verify skips the checks, like git commit skips its pre-commit and commit-msg hooks
+		noVerify, _ := cmd.Flags().GetBool("no-verify")
+		if lintEnabled && !noVerify {
+			if err := checkMessage(rules, message); err != nil {
+				return err
+			}
 		}
 
 		// Initialize storage
-		storage, err := storage.NewStorage()
+		store, err := storage.NewStorage()
 		if err != nil {
-			return fmt.Errorf("failed to initialize storage: %v", err)
+			return storageError(err, "failed to initialize storage")
 		}
 
 		// Create mini-commit
-		now := time.Now()
 		mc := &types.MiniCommit{
-			ID:        storage.GenerateID(patch, now),
 			Message:   message,
-			CreatedAt: now,
-			Patch:     patch,
+			CreatedAt: time.Now(),
+			Branch:    branch,
+			Author:    author,
+			Base:      base,
+			Trailers:  trailers,
 		}
 
-		// Save
-		if err := storage.SaveMiniCommit(mc); err != nil {
-			return fmt.Errorf("failed to save mini-commit: %v", err)
+		// The hook may change the index, for example to stage formatted files,
+		// so the staged changes are read once it has run
+		if !noVerify {
+			if err := runHook(cmd, hooks.PreMiniCommit, mc); err != nil {
+				return err
+			}
+			if hasChanges, err := git.HasStagedChanges(); err != nil {
+				return gitError(err)
+			} else if !hasChanges {
+				return gitError(git.ErrNoStagedChanges)
+			}
 		}
 
-		fmt.Printf("Created mini-commit: %s\n", mc.ID[:8])
-		fmt.Printf("Message: %s\n", mc.Message)
-		fmt.Printf("Created at: %s\n", mc.CreatedAt.Format("2006-01-02 15:04:05"))
+		// Stream the staged changes from git into the store, which computes the ID
+		staged, err := git.StagedChanges()
+		if err != nil {
+			return gitError(err)
+		}
+		defer staged.Close()
+		source := &storage.SourceReader{R: staged}
+		if err := store.CreateMiniCommit(mc, source); err != nil {
+			if source.Err != nil {
+				return gitError(source.Err)
+			}
+			return storageError(err, "failed to save mini-commit")
+		}
+
+		runPostHook(cmd, hooks.PostMiniCommit, mc)
+
+		out := cmd.OutOrStdout()
+		switch mode {
+		case outputJSON:
+			doc := toJSON(mc, false)
+			return writeJSON(out, jsonDocument{Action: "create", MiniCommit: &doc})
+		case outputPorcelainV1:
+			writePorcelainAction(out, "created", mc)
+			return nil
+		}
+
+		fmt.Fprintf(out, "Created mini-commit: %s\n", shortID(mc.ID))
+		fmt.Fprintf(out, "Message: %s\n", mc.Message)
+		fmt.Fprintf(out, "Created at: %s\n", mc.CreatedAt.Format("2006-01-02 15:04:05"))
 
 		return nil
 	},
 }
 
 func init() {
-	rootCmd.Flags().StringP("message", "m", "", "mini-commit message")
+	rootCmd.Version = version.Version
+	rootCmd.Flags().StringArrayP("message", "m", nil, "mini-commit message; several -m are joined as paragraphs")
+	rootCmd.Flags().StringP("file", "F", "", "take the message from the file, or stdin with -")
+	rootCmd.Flags().StringArray("trailer", nil, "record a key=value trailer with the mini-commit; can be repeated")
+	rootCmd.Flags().BoolP("no-verify", "n", false, "skip the pre-mini-commit hook and the message checks")
+	rootCmd.PersistentPreRunE = func(cmd *cobra.Command, args []string) error {
+		if err := applySettings(); err != nil {
+			return err
+		}
+		// A stale lock is only offered for removal to a user at a terminal, and
+		// not while the tui or watch is running
+		mode, err := outputMode(cmd)
+		if err == nil && mode == outputHuman && cmd != tuiCmd && cmd != watchCmd &&
+			color.IsTerminal(os.Stdin) && color.IsTerminal(os.Stderr) {
+			storage.SetStaleLockHandler(confirmBreakLock)
+		}
+		return nil
+	}
+	rootCmd.SetFlagErrorFunc(func(cmd *cobra.Command, err error) error {
+		return &commandError{code: codeUsage, err: err}
+	})
 }
 
 // Execute runs the command
 func Execute() {
-	if err := rootCmd.Execute(); err != nil {
-		fmt.Fprintln(os.Stderr, err)
-		os.Exit(1)
+	rootCmd.SilenceErrors = true
+	rootCmd.SilenceUsage = true
+
+	// An interrupt or a termination request stops the running git command and
+	// lets the command fail cleanly, or watch stop; a second one terminates at once
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	go func() {
+		<-ctx.Done()
+		stop()
+	}()
+	git.SetContext(ctx)
+
+	c, err := rootCmd.ExecuteContextC(ctx)
+	if err == nil {
+		return
+	}
+
+	mode, modeErr := outputMode(rootCmd)
+	if modeErr != nil {
+		mode = outputHuman
 	}
+	writeError(os.Stderr, mode, err)
+	if mode == outputHuman && errorCode(err) == codeUsage {
+		fmt.Fprint(os.Stderr, "\n"+c.UsageString())
+	}
+	os.Exit(exitStatus(errorCode(err)))
+}
+
+// confirmBreakLock asks whether to remove a lock left behind by a process that
+// is no longer running
+func confirmBreakLock(lockErr *storage.LockError) bool {
+	fmt.Fprintf(os.Stderr, "%s is left behind by process %d, which is no longer running.\nRemove it and continue? [y/N] ",
+		lockErr.Path, lockErr.PID)
+	answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes"
 }
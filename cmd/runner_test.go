@@ -0,0 +1,157 @@
+package cmd
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+
+	"git-mini-commit/internal/git"
+	"git-mini-commit/internal/storage"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// fakeGitPatch 偽のgitが返すステージング差分
+const fakeGitPatch = "diff --git a/a.txt b/a.txt\nnew file mode 100644\n--- /dev/null\n+++ b/a.txt\n@@ -0,0 +1 @@\n+fake\n"
+
+// newFakeGitDir は .git ディレクトリだけを持つ一時ディレクトリに移動する。gitは実行しない
+func newFakeGitDir(t *testing.T) {
+	t.Helper()
+	dir := t.TempDir()
+	if err := os.Mkdir(dir+"/.git", 0755); err != nil {
+		t.Fatalf("Failed to create .git: %v", err)
+	}
+	original, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Failed to get current directory: %v", err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatalf("Failed to change directory: %v", err)
+	}
+	t.Cleanup(func() { os.Chdir(original) })
+}
+
+// newFakeGit はmini-commitの作成に必要なgitコマンドに応答する
+func newFakeGit() *git.FakeRunner {
+	fake := git.NewFakeRunner()
+	fake.On("rev-parse", "--git-dir").Stdout = ".git\n"
+	fake.On("diff", "--cached", "--quiet").ExitCode = 1
+	fake.On("config").ExitCode = 1
+	fake.On("symbolic-ref").Stdout = "main\n"
+	fake.On("var", "GIT_AUTHOR_IDENT").Stdout = "Fake User <fake@example.com> 1700000000 +0000\n"
+	fake.On("rev-parse", "--verify").ExitCode = 1
+	fake.On("rev-parse", "--show-toplevel").Stdout = ".\n.git/hooks\n"
+	fake.On("diff", "--cached", "--no-color").Stdout = fakeGitPatch
+	return fake
+}
+
+// runWithFakeGit はgitの代わりにfakeを使い、コマンドをプロセス内で実行する
+func runWithFakeGit(t *testing.T, fake *git.FakeRunner, args ...string) (string, error) {
+	t.Helper()
+	defer git.SetRunner(fake)()
+	defer resetFlags(rootCmd)
+
+	var out bytes.Buffer
+	rootCmd.SetOut(&out)
+	rootCmd.SetErr(&out)
+	rootCmd.SetArgs(args)
+	defer func() {
+		rootCmd.SetOut(nil)
+		rootCmd.SetErr(nil)
+		rootCmd.SetArgs(nil)
+	}()
+	_, err := rootCmd.ExecuteC()
+	return out.String(), err
+}
+
+// resetFlags はプロセス内で続けて実行できるようにフラグを既定値に戻す
+func resetFlags(c *cobra.Command) {
+	reset := func(f *pflag.Flag) {
+		if slice, ok := f.Value.(pflag.SliceValue); ok {
+			slice.Replace(nil)
+		} else {
+			f.Value.Set(f.DefValue)
+		}
+		f.Changed = false
+	}
+	c.Flags().VisitAll(reset)
+	c.PersistentFlags().VisitAll(reset)
+	for _, sub := range c.Commands() {
+		resetFlags(sub)
+	}
+}
+
+func TestCommandsWithFakeGit(t *testing.T) {
+	newFakeGitDir(t)
+	fake := newFakeGit()
+
+	// 1. 作成: 偽のgitの出力からmini-commitを作成する
+	output, err := runWithFakeGit(t, fake, "-m", "Fake change")
+	if err != nil || !strings.Contains(output, "Created mini-commit") {
+		t.Fatalf("Expected the mini-commit to be created, got %q (%v)", output, err)
+	}
+	store, err := storage.NewStorage()
+	if err != nil {
+		t.Fatalf("NewStorage() error = %v", err)
+	}
+	list, _ := store.LoadMiniCommits()
+	if len(list) != 1 || list[0].Branch != "main" || list[0].Author != "Fake User <fake@example.com>" || list[0].Base != "" {
+		t.Fatalf("Unexpected mini-commits: %+v", list)
+	}
+
+	// 2. git diff の失敗はgitのエラーとして報告され、何も保存されない
+	failing := newFakeGit()
+	diff := failing.On("diff", "--cached", "--no-color")
+	diff.ExitCode = 128
+	diff.Stderr = "fatal: index file corrupt"
+	_, err = runWithFakeGit(t, failing, "-m", "Broken")
+	if errorCode(err) != codeGit || !strings.Contains(err.Error(), "index file corrupt") {
+		t.Errorf("Expected a git error, got %v", err)
+	}
+	if list, _ := store.LoadMiniCommits(); len(list) != 1 {
+		t.Errorf("Expected no mini-commit saved after git failed, got %d", len(list))
+	}
+
+	// 3. pop: パッチは git apply --cached の標準入力に渡される
+	fake.On("apply", "--cached")
+	if _, err := runWithFakeGit(t, fake, "pop", list[0].ID); err != nil {
+		t.Fatalf("pop error = %v", err)
+	}
+	var applied *git.FakeCall
+	calls := fake.Calls()
+	for i := range calls {
+		if strings.Join(calls[i].Args, " ") == "apply --cached" {
+			applied = &calls[i]
+		}
+	}
+	if applied == nil || applied.Stdin != fakeGitPatch {
+		t.Errorf("Expected the patch to be applied from stdin, got %+v", applied)
+	}
+
+	// 4. git apply の失敗は適用失敗として報告される
+	apply := fake.On("apply", "--cached")
+	apply.ExitCode = 1
+	apply.Stderr = "error: patch failed"
+	_, err = runWithFakeGit(t, fake, "pop", list[0].ID)
+	if errorCode(err) != codeApplyFailed || !strings.Contains(err.Error(), "patch failed") {
+		t.Errorf("Expected an apply failure, got %v", err)
+	}
+	// ヒントは適用先（ステージング・作業ツリー）を問わない
+	var report strings.Builder
+	writeError(&report, outputHuman, err)
+	if hint := report.String(); !strings.Contains(hint, "hint: the patch conflicts with the changes it was applied to") ||
+		strings.Contains(hint, "index") {
+		t.Errorf("Expected a hint that fits any target, got %q", hint)
+	}
+
+	// 5. リポジトリ外では not_a_repository（終了コード3）になる
+	outside := git.NewFakeRunner()
+	outside.On("config").ExitCode = 1
+	outside.On("rev-parse", "--git-dir").ExitCode = 128
+	_, err = runWithFakeGit(t, outside, "list")
+	if errorCode(err) != codeNotRepository || exitStatus(errorCode(err)) != 3 {
+		t.Errorf("Expected not_a_repository, got %v (%s)", err, errorCode(err))
+	}
+}
@@ -2,7 +2,9 @@ package cmd
 
 import (
 	"fmt"
+	"io"
 
+	"git-mini-commit/internal/color"
 	"git-mini-commit/internal/git"
 	"git-mini-commit/internal/storage"
 
@@ -12,40 +14,114 @@ import (
 var showCmd = &cobra.Command{
 	Use:   "show <hash>",
 	Short: "Show diff of specified mini-commit",
-	Long:  `Display the diff (patch) of the mini-commit with the specified ID.`,
-	Args:  cobra.ExactArgs(1),
+	Long: `Display the diff (patch) of the mini-commit with the specified ID.
+
+--stat, --numstat, --summary, --name-only and --name-status show a summary of
+the changed files instead of the diff; add --patch to show the diff as well.`,
+	Args: exactArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) error {
 		hash := args[0]
 
+		mode, err := outputMode(cmd)
+		if err != nil {
+			return err
+		}
+
 		// Check if it's a Git repository
-		if !git.IsGitRepository() {
-			return fmt.Errorf("not a git repository")
+		if err := git.CheckRepository(); err != nil {
+			return gitError(err)
 		}
 
 		// Initialize storage
 		storage, err := storage.NewStorage()
 		if err != nil {
-			return fmt.Errorf("failed to initialize storage: %v", err)
+			return storageError(err, "failed to initialize storage")
 		}
 
-		// Get mini-commit
-		mc, err := storage.GetMiniCommit(hash)
+		// Get mini-commit; its patch is loaded only when it is not streamed
+		mc, err := storage.FindMiniCommit(hash)
 		if err != nil {
-			return fmt.Errorf("failed to get mini-commit: %v", err)
+			return storageError(err, "failed to get mini-commit")
+		}
+
+		out := cmd.OutOrStdout()
+		if mode != outputHuman {
+			if err := storage.LoadPatch(mc); err != nil {
+				return storageError(err, "failed to get mini-commit")
+			}
+		}
+		switch mode {
+		case outputJSON:
+			doc := toJSON(mc, true)
+			return writeJSON(out, jsonDocument{MiniCommit: &doc})
+		case outputPorcelainV1:
+			writePorcelainDetail(out, mc)
+			return nil
+		}
+
+		useColor, err := colorEnabled(cmd, "color.diff")
+		if err != nil {
+			return err
+		}
+		// Colored output and the file summaries need the whole patch; plain output streams it
+		var patch io.ReadCloser
+		if useColor || diffFormatsRequested(cmd) {
+			err = storage.LoadPatch(mc)
+		} else {
+			patch, err = storage.OpenPatch(mc)
+		}
+		if err != nil {
+			return storageError(err, "failed to get mini-commit")
+		}
+		if patch != nil {
+			defer patch.Close()
+		}
+		stopPager, err := startPager(cmd)
+		if err != nil {
+			return err
+		}
+		defer stopPager()
+		out = cmd.OutOrStdout()
+		paint := func(code, s string) string {
+			if useColor {
+				return color.Wrap(code, s)
+			}
+			return s
 		}
 
 		// Display information
-		fmt.Printf("Mini-commit: %s\n", mc.ID[:8])
-		fmt.Printf("Message: %s\n", mc.Message)
-		fmt.Printf("Created: %s\n", mc.CreatedAt.Format("2006-01-02 15:04:05"))
-		fmt.Println("\nDiff:")
-		fmt.Println("---")
-		fmt.Print(mc.Patch)
+		fmt.Fprintf(out, "Mini-commit: %s\n", paint(color.Yellow, shortID(mc.ID)))
+		fmt.Fprintf(out, "Message: %s\n", mc.Message)
+		fmt.Fprintf(out, "Created: %s\n", mc.CreatedAt.Format("2006-01-02 15:04:05"))
+		if len(mc.Trailers) > 0 {
+			fmt.Fprintln(out, "Trailers:")
+			for _, t := range mc.Trailers {
+				fmt.Fprintf(out, "  %s: %s\n", t.Key, t.Value)
+			}
+		}
+
+		if diffFormatsRequested(cmd) {
+			fmt.Fprintln(out)
+		}
+		if !writeDiffFormats(cmd, out, mc.Patch, useColor) {
+			return nil
+		}
+
+		fmt.Fprintln(out, "\nDiff:")
+		fmt.Fprintln(out, "---")
+		if patch != nil {
+			if _, err := io.Copy(out, patch); err != nil {
+				return storageError(err, "failed to read patch")
+			}
+			return nil
+		}
+		writePatch(out, mc.Patch, useColor)
 
 		return nil
 	},
 }
 
 func init() {
+	addDiffFormatFlags(showCmd)
 	rootCmd.AddCommand(showCmd)
 }
@@ -0,0 +1,101 @@
+package cmd
+
+import (
+	"os/exec"
+	"strings"
+	"testing"
+
+	"git-mini-commit/testutils"
+)
+
+func TestCLIShowStatModes(t *testing.T) {
+	// テスト用Gitリポジトリを作成
+	repo := testutils.NewTestGitRepo(t)
+	defer repo.Cleanup()
+
+	// テスト用CLIを作成
+	cli := testutils.NewTestCLI(t)
+	cli.SetRepo(repo)
+
+	// 1. 2つのファイルを作成してステージング
+	if err := repo.CreateTestFile("a.txt", "one\ntwo\n"); err != nil {
+		t.Fatalf("Failed to create file: %v", err)
+	}
+	if err := repo.CreateTestFile("b.txt", "three\n"); err != nil {
+		t.Fatalf("Failed to create file: %v", err)
+	}
+	repo.StageFile("a.txt")
+	repo.StageFile("b.txt")
+
+	output := cli.AssertCommandSuccess(t, "-m", "Two files", "--porcelain")
+	id := strings.TrimSpace(strings.TrimPrefix(output, "created "))
+
+	// --stat は差分の代わりに統計を表示
+	output = cli.AssertCommandSuccess(t, "show", id, "--stat")
+	if !strings.Contains(output, " a.txt | 2 ++\n") || !strings.Contains(output, " 2 files changed, 3 insertions(+)") {
+		t.Errorf("Unexpected --stat output: %s", output)
+	}
+	if strings.Contains(output, "Diff:") {
+		t.Errorf("Expected --stat to omit the diff, but got: %s", output)
+	}
+
+	// --name-status と --patch の併用
+	output = cli.AssertCommandSuccess(t, "show", id, "--name-status", "--patch")
+	if !strings.Contains(output, "A\ta.txt\nA\tb.txt\n") || !strings.Contains(output, "Diff:") {
+		t.Errorf("Unexpected --name-status --patch output: %s", output)
+	}
+
+	// --numstat
+	output = cli.AssertCommandSuccess(t, "show", id, "--numstat")
+	if !strings.Contains(output, "2\t0\ta.txt\n1\t0\tb.txt\n") {
+		t.Errorf("Unexpected --numstat output: %s", output)
+	}
+
+	// list --stat
+	output = cli.AssertCommandSuccess(t, "list", "--stat")
+	if !strings.Contains(output, "Stat: 2 files changed, 3 insertions(+)") {
+		t.Errorf("Unexpected list --stat output: %s", output)
+	}
+}
+
+func TestCLIShowColor(t *testing.T) {
+	// テスト用Gitリポジトリを作成
+	repo := testutils.NewTestGitRepo(t)
+	defer repo.Cleanup()
+
+	// テスト用CLIを作成
+	cli := testutils.NewTestCLI(t)
+	cli.SetRepo(repo)
+
+	if err := repo.CreateTestFile("a.txt", "one\n"); err != nil {
+		t.Fatalf("Failed to create file: %v", err)
+	}
+	repo.StageFile("a.txt")
+	output := cli.AssertCommandSuccess(t, "-m", "Color", "--porcelain")
+	id := strings.TrimSpace(strings.TrimPrefix(output, "created "))
+
+	// 端末でない場合は既定で色なし
+	output = cli.AssertCommandSuccess(t, "show", id)
+	if strings.Contains(output, "\033[") {
+		t.Errorf("Expected no color when not writing to a terminal, but got: %q", output)
+	}
+
+	// --color=always で色付き
+	output = cli.AssertCommandSuccess(t, "show", id, "--color=always")
+	if !strings.Contains(output, "\033[32m+one\033[m") {
+		t.Errorf("Expected colored diff with --color=always, but got: %q", output)
+	}
+
+	// --color=never は color.ui=always より優先
+	if err := exec.Command("git", "config", "color.ui", "always").Run(); err != nil {
+		t.Fatalf("Failed to set config: %v", err)
+	}
+	output = cli.AssertCommandSuccess(t, "show", id, "--color=never")
+	if strings.Contains(output, "\033[") {
+		t.Errorf("Expected no color with --color=never, but got: %q", output)
+	}
+	output = cli.AssertCommandSuccess(t, "show", id)
+	if !strings.Contains(output, "\033[") {
+		t.Errorf("Expected color from color.ui=always, but got: %q", output)
+	}
+}
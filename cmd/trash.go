@@ -0,0 +1,212 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"time"
+
+	"git-mini-commit/internal/git"
+	"git-mini-commit/internal/storage"
+
+	"github.com/spf13/cobra"
+)
+
+var trashCmd = &cobra.Command{
+	Use:   "trash",
+	Short: "List, restore or empty removed mini-commits",
+	Long: `drop, clear and undo move removed mini-commits to the trash instead of
+deleting them. They stay there until "trash empty" or "gc" purges them; gc
+purges entries older than minicommit.trashRetention (default 30.days).
+
+Without a subcommand, the trash is listed.`,
+	Args: exactArgs(0),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return trashListCmd.RunE(cmd, args)
+	},
+}
+
+var trashListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List trashed mini-commits",
+	Args:  exactArgs(0),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		mode, err := outputMode(cmd)
+		if err != nil {
+			return err
+		}
+
+		store, err := openStore()
+		if err != nil {
+			return err
+		}
+		trash, err := store.TrashedMiniCommits()
+		if err != nil {
+			return storageError(err, "failed to load trash")
+		}
+
+		out := cmd.OutOrStdout()
+		switch mode {
+		case outputJSON:
+			docs := toJSONTrash(trash)
+			return writeJSON(out, jsonDocument{Trash: &docs})
+		case outputPorcelainV1:
+			for i := range trash {
+				writePorcelainTrashEntry(out, &trash[i])
+			}
+			return nil
+		}
+
+		if len(trash) == 0 {
+			fmt.Fprintln(out, "Trash is empty")
+			return nil
+		}
+		fmt.Fprintf(out, "Trashed mini-commits (%d):\n\n", len(trash))
+		for _, entry := range trash {
+			fmt.Fprintf(out, "%s  %s  %-6s %s\n", shortID(entry.ID), entry.DeletedAt.Format("2006-01-02 15:04:05"),
+				entry.Reason, subject(entry.Message))
+		}
+		return nil
+	},
+}
+
+var trashRestoreCmd = &cobra.Command{
+	Use:   "restore <hash>...",
+	Short: "Move trashed mini-commits back into the stack",
+	Args:  minimumArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		mode, err := outputMode(cmd)
+		if err != nil {
+			return err
+		}
+
+		store, err := openStore()
+		if err != nil {
+			return err
+		}
+
+		out := cmd.OutOrStdout()
+		var docs []jsonMiniCommit
+		for _, id := range args {
+			mc, err := store.RestoreMiniCommit(id)
+			if err != nil {
+				return storageError(err, "failed to restore mini-commit")
+			}
+
+			switch mode {
+			case outputJSON:
+				docs = append(docs, toJSON(mc, false))
+			case outputPorcelainV1:
+				writePorcelainAction(out, "restored", mc)
+			default:
+				fmt.Fprintf(out, "Restored mini-commit '%s': %s\n", shortID(mc.ID), subject(mc.Message))
+			}
+		}
+
+		if mode == outputJSON {
+			return writeJSON(out, jsonDocument{Action: "restore", MiniCommits: &docs})
+		}
+		return nil
+	},
+}
+
+var trashEmptyCmd = &cobra.Command{
+	Use:   "empty",
+	Short: "Permanently remove all trashed mini-commits",
+	Long: `Permanently remove all trashed mini-commits. Their content is removed
+from the operation log as well, along with the patch objects nothing refers to
+any more, so "undo" cannot bring them back.`,
+	Args: exactArgs(0),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		mode, err := outputMode(cmd)
+		if err != nil {
+			return err
+		}
+
+		store, err := openStore()
+		if err != nil {
+			return err
+		}
+		purged, err := store.PurgeTrash(time.Now())
+		if err != nil {
+			return storageError(err, "failed to empty trash")
+		}
+		pruned, err := store.PruneObjects()
+		if err != nil {
+			return storageError(err, "failed to prune patch objects")
+		}
+
+		return writePurged(cmd.OutOrStdout(), mode, "trash-empty", purged, nil, pruned,
+			fmt.Sprintf("Purged %d mini-commit%s from the trash", len(purged), pluralS(len(purged))))
+	},
+}
+
+// openStore checks for a repository and opens the mini-commit store
+func openStore() (*storage.Storage, error) {
+	// Check if it's a Git repository
+	if err := git.CheckRepository(); err != nil {
+		return nil, gitError(err)
+	}
+
+	// Initialize storage
+	store, err := storage.NewStorage()
+	if err != nil {
+		return nil, storageError(err, "failed to initialize storage")
+	}
+	return store, nil
+}
+
+// toJSONTrash converts trash entries for JSON output
+func toJSONTrash(trash []storage.TrashEntry) []jsonTrashEntry {
+	docs := make([]jsonTrashEntry, 0, len(trash))
+	for i := range trash {
+		docs = append(docs, jsonTrashEntry{
+			jsonMiniCommit: toJSON(&trash[i].MiniCommit, false),
+			DeletedAt:      trash[i].DeletedAt,
+			Reason:         trash[i].Reason,
+		})
+	}
+	return docs
+}
+
+// writePorcelainTrashEntry writes one trashed mini-commit:
+// <id> SP <deleted-unix> SP <reason> SP <subject>
+func writePorcelainTrashEntry(w io.Writer, entry *storage.TrashEntry) {
+	fmt.Fprintf(w, "%s %d %s %s\n", entry.ID, entry.DeletedAt.Unix(), entry.Reason, subject(entry.Message))
+}
+
+// writePurged reports the mini-commits removed from the trash by "trash empty"
+// or gc, the number of operations gc expired from the reflog when expired is
+// not nil, and the patch objects deleted along with them
+func writePurged(w io.Writer, mode, action string, purged []storage.TrashEntry, expired *int,
+	pruned *storage.PruneResult, summary string) error {
+	switch mode {
+	case outputJSON:
+		docs := toJSONTrash(purged)
+		return writeJSON(w, jsonDocument{Action: action, Purged: &docs, Expired: expired,
+			Pruned: &jsonPruned{Objects: pruned.Objects, Bytes: pruned.Bytes}})
+	case outputPorcelainV1:
+		for _, entry := range purged {
+			fmt.Fprintf(w, "purged %s\n", entry.ID)
+		}
+		if expired != nil {
+			fmt.Fprintf(w, "expired %d\n", *expired)
+		}
+		fmt.Fprintf(w, "pruned %d %d\n", pruned.Objects, pruned.Bytes)
+		return nil
+	}
+
+	fmt.Fprintln(w, summary)
+	if expired != nil {
+		fmt.Fprintf(w, "Expired %d operation%s from the reflog\n", *expired, pluralS(*expired))
+	}
+	fmt.Fprintf(w, "Deleted %d unused patch object%s, reclaimed %s\n", pruned.Objects, pluralS(pruned.Objects),
+		formatBytes(pruned.Bytes))
+	return nil
+}
+
+func init() {
+	trashCmd.AddCommand(trashListCmd)
+	trashCmd.AddCommand(trashRestoreCmd)
+	trashCmd.AddCommand(trashEmptyCmd)
+	rootCmd.AddCommand(trashCmd)
+}
@@ -0,0 +1,87 @@
+package cmd
+
+import (
+	"os/exec"
+	"strings"
+	"testing"
+
+	"git-mini-commit/testutils"
+)
+
+func TestCLITrashAndGC(t *testing.T) {
+	// テスト用Gitリポジトリを作成
+	repo := testutils.NewTestGitRepo(t)
+	defer repo.Cleanup()
+
+	// テスト用CLIを作成
+	cli := testutils.NewTestCLI(t)
+	cli.SetRepo(repo)
+
+	first := saveMiniCommit(t, repo, cli, "a.txt", "a\n", "First")
+	second := saveMiniCommit(t, repo, cli, "b.txt", "b\n", "Second")
+
+	// 1. drop したmini-commitはゴミ箱に残る
+	cli.AssertCommandSuccess(t, "drop", first)
+	output := cli.AssertCommandSuccess(t, "trash")
+	cli.AssertOutputContains(t, output, "Trashed mini-commits (1):")
+	cli.AssertOutputContains(t, output, first[:8])
+	cli.AssertOutputContains(t, output, "drop   First")
+
+	output = cli.AssertCommandSuccess(t, "trash", "list", "--porcelain")
+	if !strings.HasPrefix(output, first+" ") || !strings.HasSuffix(output, " drop First\n") {
+		t.Errorf("Unexpected porcelain output: %q", output)
+	}
+
+	// 2. 復元すると元の位置に戻る
+	output = cli.AssertCommandSuccess(t, "trash", "restore", first)
+	cli.AssertOutputContains(t, output, "Restored mini-commit '"+first[:8]+"': First")
+	output = cli.AssertCommandSuccess(t, "list", "--oneline")
+	if output != first[:8]+" First\n"+second[:8]+" Second\n" {
+		t.Errorf("Unexpected list after restore: %q", output)
+	}
+	output = cli.AssertCommandFailure(t, "trash", "restore", first)
+	cli.AssertOutputContains(t, output, "not found")
+
+	// 3. gc は保持期間内のものを残す
+	cli.AssertCommandSuccess(t, "drop", second)
+	output = cli.AssertCommandSuccess(t, "gc")
+	cli.AssertOutputContains(t, output, "Purged 0 trashed mini-commits")
+	cli.AssertOutputContains(t, output, "Deleted 0 unused patch objects, reclaimed 0 B")
+
+	exec.Command("git", "config", "minicommit.trashRetention", "never").Run()
+	output = cli.AssertCommandSuccess(t, "gc", "--prune", "now", "--porcelain")
+	if !strings.HasPrefix(output, "purged "+second+"\nexpired 0\npruned 1 ") {
+		t.Errorf("Expected --prune to override the retention and the patch object to be deleted, but got: %q", output)
+	}
+
+	exec.Command("git", "config", "minicommit.trashRetention", "soon").Run()
+	output = cli.AssertCommandFailure(t, "gc")
+	cli.AssertOutputContains(t, output, "invalid trash retention")
+
+	// 4. trash empty は操作ログの内容と使われなくなったパッチオブジェクトも削除し、undo で戻せない
+	cli.AssertCommandSuccess(t, "drop", first)
+	output = cli.AssertCommandSuccess(t, "trash", "empty")
+	cli.AssertOutputContains(t, output, "Purged 1 mini-commit from the trash")
+	cli.AssertOutputContains(t, output, "Deleted 1 unused patch object")
+	output = cli.AssertCommandSuccess(t, "trash")
+	cli.AssertOutputContains(t, output, "Trash is empty")
+	output = cli.AssertCommandFailure(t, "undo")
+	cli.AssertOutputContains(t, output, "has been purged")
+
+	// 5. その後の gc で削除するものはない
+	output = cli.AssertCommandSuccess(t, "gc", "--prune", "never", "--json")
+	cli.AssertOutputContains(t, output, `"objects": 0`)
+	output = cli.AssertCommandSuccess(t, "trash", "empty", "--porcelain")
+	if output != "pruned 0 0\n" {
+		t.Errorf("Unexpected porcelain output: %q", output)
+	}
+
+	// 6. 保持期間を過ぎた操作ログを削除する（最新の操作は残る）
+	output = cli.AssertCommandSuccess(t, "gc", "--prune", "never", "--expire-reflog", "now")
+	cli.AssertOutputContains(t, output, "Expired 5 operations from the reflog")
+	if output := cli.AssertCommandSuccess(t, "reflog", "--porcelain"); strings.Count(output, "\n") != 1 {
+		t.Errorf("Expected one operation to be left, got %q", output)
+	}
+	output = cli.AssertCommandFailure(t, "gc", "--prune", "never", "--expire-reflog", "soon")
+	cli.AssertOutputContains(t, output, "invalid reflog retention")
+}
@@ -0,0 +1,627 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"git-mini-commit/internal/color"
+	"git-mini-commit/internal/lint"
+	"git-mini-commit/internal/patch"
+	"git-mini-commit/internal/storage"
+	"git-mini-commit/internal/tui"
+	"git-mini-commit/internal/types"
+
+	"github.com/spf13/cobra"
+)
+
+var tuiCmd = &cobra.Command{
+	Use:   "tui",
+	Short: "Browse and manage the mini-commits in a full-screen terminal UI",
+	Long: `Show the stack on the left, with the ID, size and subject of each mini-commit,
+and the details and diff of the selected one on the right.
+
+Keys:
+  j, k, up, down   select the next or previous mini-commit
+  g, G, home, end  select the oldest or newest mini-commit
+  J, K             scroll the diff by a line
+  space, b         scroll the diff by a page
+  p                pop: apply the mini-commit to the staging area
+  a                apply the mini-commit to the working tree
+  d                drop the mini-commit
+  r                reword the message in the editor
+  s                squash the mini-commit into the one before it
+  S                split the mini-commit into one per file
+  ctrl-l           reload the stack
+  ?                show or hide the keys
+  q, esc           quit
+
+Drop, squash and split ask for confirmation; all of them can be undone with
+git mini-commit undo. The pop hooks and the message checks run as in the other
+commands, unless --no-verify is given.
+
+With --script, the keys are read from a file ("-" for stdin), one or more per
+line, and every screen is written to stdout as text instead of a terminal.
+Keys are named as typed ("j", "?") or by name ("enter", "space", "ctrl-l").`,
+	Args: exactArgs(0),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		mode, err := outputMode(cmd)
+		if err != nil {
+			return err
+		}
+		if mode != outputHuman {
+			return newCommandError(codeUsage, "tui has no machine-readable output")
+		}
+
+		store, err := openStore()
+		if err != nil {
+			return err
+		}
+		rules, lintEnabled, err := lintRulesFromConfig()
+		if err != nil {
+			return err
+		}
+		useColor, err := colorEnabled(cmd, "color.diff")
+		if err != nil {
+			return err
+		}
+		noVerify, _ := cmd.Flags().GetBool("no-verify")
+		app := &tuiApp{cmd: cmd, store: store, useColor: useColor, noVerify: noVerify, rules: rules,
+			lintEnabled: lintEnabled, selected: -1}
+
+		// Hooks and warnings would draw over the screen, so what they write is
+		// shown in the status line, then printed once the screen is restored
+		stderr := cmd.ErrOrStderr()
+		cmd.SetErr(&app.output)
+		defer func() {
+			cmd.SetErr(stderr)
+			stderr.Write(app.output.Bytes())
+		}()
+		if err := app.reload(); err != nil {
+			return err
+		}
+
+		if scriptPath, _ := cmd.Flags().GetString("script"); scriptPath != "" {
+			keys, err := readScript(cmd, scriptPath)
+			if err != nil {
+				return err
+			}
+			size, _ := cmd.Flags().GetString("size")
+			width, height, err := parseSize(size)
+			if err != nil {
+				return err
+			}
+			app.run = func(fn func() error) error { return fn() }
+			return tui.Run(app, tui.NewScript(keys), tui.NewTextScreen(cmd.OutOrStdout(), width, height))
+		}
+
+		if !color.IsTerminal(os.Stdin) || !color.IsTerminal(os.Stdout) {
+			return newCommandError(codeUsage, "tui needs a terminal; use --script to drive it from a file")
+		}
+		term, err := tui.OpenTerminal(cmd.Context(), os.Stdin, os.Stdout)
+		if err != nil {
+			return newCommandError(codeError, "%v", err)
+		}
+		app.interactive = true
+		app.run = term.Suspend
+		err = tui.Run(app, term, term)
+		if closeErr := term.Close(); err == nil {
+			err = closeErr
+		}
+		// An interrupt or a termination request ends the UI like q does
+		if errors.Is(err, context.Canceled) {
+			return nil
+		}
+		return err
+	},
+}
+
+// tuiApp is the state of the terminal UI
+type tuiApp struct {
+	cmd         *cobra.Command
+	store       *storage.Storage
+	useColor    bool
+	noVerify    bool
+	rules       lint.Rules
+	lintEnabled bool
+	interactive bool
+	// run runs fn, e.g. the editor, with the terminal restored
+	run func(fn func() error) error
+
+	stack    types.MiniCommitList
+	selected int
+	top      int // first mini-commit shown in the stack pane
+	scroll   int // first line shown in the detail pane
+	page     int // height of the panes when last drawn
+	help     bool
+	confirm  *tuiConfirm
+	status   string
+	follow   string // mini-commit to select once the stack is reloaded
+
+	detail    []string // lines of the detail pane, for detailKey
+	detailKey string
+
+	output bytes.Buffer // what hooks and warnings wrote
+	shown  int          // length of output already shown in the status line
+}
+
+// tuiConfirm is an action waiting for the user to answer y
+type tuiConfirm struct {
+	question string
+	action   func() (string, error)
+}
+
+// tuiHints sum up the keys in the status line
+const tuiHints = "j/k select  space/b scroll  p pop  a apply  d drop  r reword  s squash  S split  ? help  q quit"
+
+// tuiHelp lists the keys in the detail pane
+var tuiHelp = []string{
+	"Keys",
+	"",
+	"  j, k, up, down   select the next or previous mini-commit",
+	"  g, G, home, end  select the oldest or newest mini-commit",
+	"  J, K             scroll the diff by a line",
+	"  space, b         scroll the diff by a page",
+	"  p                pop: apply the mini-commit to the staging area",
+	"  a                apply the mini-commit to the working tree",
+	"  d                drop the mini-commit",
+	"  r                reword the message in the editor",
+	"  s                squash the mini-commit into the one before it",
+	"  S                split the mini-commit into one per file",
+	"  ctrl-l           reload the stack",
+	"  ?                show or hide the keys",
+	"  q, esc           quit",
+}
+
+// reload reads the stack again, keeping the selection on the same mini-commit
+// when it is still there, or else at the same position. The newest one is
+// selected at first.
+func (a *tuiApp) reload() error {
+	var id string
+	if a.selected >= 0 && a.selected < len(a.stack) {
+		id = a.stack[a.selected].ID
+	}
+	return a.reloadSelecting(id)
+}
+
+// reloadSelecting reads the stack again and selects the mini-commit id
+func (a *tuiApp) reloadSelecting(id string) error {
+	stack, err := a.store.LoadMiniCommits()
+	if err != nil {
+		return storageError(err, "failed to load mini-commits")
+	}
+	position := a.selected
+	if position < 0 {
+		position = len(stack) - 1
+	}
+	for i := range stack {
+		if stack[i].ID == id {
+			position = i
+		}
+	}
+	a.stack = stack
+	a.selected = position
+	a.clampSelection()
+	a.detailKey = ""
+	return nil
+}
+
+// clampSelection keeps the selection inside the stack
+func (a *tuiApp) clampSelection() {
+	if a.selected >= len(a.stack) {
+		a.selected = len(a.stack) - 1
+	}
+	if a.selected < 0 {
+		a.selected = 0
+	}
+}
+
+// current returns the selected mini-commit, or nil for an empty stack
+func (a *tuiApp) current() *types.MiniCommit {
+	if len(a.stack) == 0 {
+		return nil
+	}
+	return &a.stack[a.selected]
+}
+
+// Update handles a key press
+func (a *tuiApp) Update(key tui.Key) bool {
+	if a.confirm != nil {
+		confirm := a.confirm
+		a.confirm = nil
+		if key == "y" || key == "Y" {
+			a.do(confirm.action)
+		} else {
+			a.status = "Cancelled"
+		}
+		return false
+	}
+
+	a.status = ""
+	previous := a.selected
+	switch key {
+	case "q", tui.KeyCtrlC:
+		return true
+	case tui.KeyEsc:
+		if !a.help {
+			return true
+		}
+		a.help = false
+	case "?":
+		a.help = !a.help
+	case "j", tui.KeyDown:
+		a.selected++
+	case "k", tui.KeyUp:
+		a.selected--
+	case "g", tui.KeyHome:
+		a.selected = 0
+	case "G", tui.KeyEnd:
+		a.selected = len(a.stack) - 1
+	case "J":
+		a.scroll++
+	case "K":
+		a.scroll--
+	case tui.KeySpace, tui.KeyPageDown:
+		a.scroll += a.page
+	case "b", tui.KeyPageUp:
+		a.scroll -= a.page
+	case "ctrl-l":
+		if err := a.reload(); err != nil {
+			a.status = "error: " + err.Error()
+		}
+	case "p":
+		a.withCurrent(a.pop)
+	case "a":
+		a.withCurrent(a.apply)
+	case "d":
+		a.withCurrent(func(mc *types.MiniCommit) (string, error) {
+			a.ask(fmt.Sprintf("Drop '%s'?", shortID(mc.ID)), func() (string, error) { return a.drop(mc) })
+			return "", nil
+		})
+	case "r":
+		a.withCurrent(a.reword)
+	case "s":
+		a.withCurrent(func(mc *types.MiniCommit) (string, error) {
+			if a.selected == 0 {
+				return "", fmt.Errorf("'%s' is the oldest mini-commit: there is nothing to squash it into", shortID(mc.ID))
+			}
+			older := &a.stack[a.selected-1]
+			a.ask(fmt.Sprintf("Squash '%s' into '%s'?", shortID(mc.ID), shortID(older.ID)),
+				func() (string, error) { return a.squash(older, mc) })
+			return "", nil
+		})
+	case "S":
+		a.withCurrent(func(mc *types.MiniCommit) (string, error) {
+			a.ask(fmt.Sprintf("Split '%s' into one mini-commit per file?", shortID(mc.ID)),
+				func() (string, error) { return a.split(mc) })
+			return "", nil
+		})
+	}
+
+	a.clampSelection()
+	if a.selected != previous {
+		a.scroll = 0
+	}
+	return false
+}
+
+// ask waits for the user to confirm action
+func (a *tuiApp) ask(question string, action func() (string, error)) {
+	a.confirm = &tuiConfirm{question: question + " [y/N]", action: action}
+}
+
+// withCurrent runs action on the selected mini-commit
+func (a *tuiApp) withCurrent(action func(mc *types.MiniCommit) (string, error)) {
+	mc := a.current()
+	if mc == nil {
+		a.status = "There are no mini-commits"
+		return
+	}
+	a.do(func() (string, error) { return action(mc) })
+}
+
+// do runs action and reports its result in the status line, with the last
+// line written by the hooks it ran, then reloads the stack
+func (a *tuiApp) do(action func() (string, error)) {
+	message, err := action()
+	if err != nil {
+		message = "error: " + err.Error()
+	}
+	if written := strings.TrimSpace(a.output.String()[a.shown:]); written != "" {
+		lines := strings.Split(written, "\n")
+		message = strings.TrimPrefix(message+" | "+lines[len(lines)-1], " | ")
+	}
+	a.shown = a.output.Len()
+	if message != "" {
+		a.status = message
+	}
+
+	reload := a.reload
+	if a.follow != "" {
+		id := a.follow
+		reload = func() error { return a.reloadSelecting(id) }
+		a.follow = ""
+	}
+	if err := reload(); err != nil {
+		a.status = "error: " + err.Error()
+	}
+}
+
+func (a *tuiApp) pop(mc *types.MiniCommit) (string, error) {
+	if err := popMiniCommit(a.cmd, a.store, mc, a.noVerify); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("Applied '%s' to the staging area", shortID(mc.ID)), nil
+}
+
+func (a *tuiApp) apply(mc *types.MiniCommit) (string, error) {
+	if err := applyToWorktree(a.store, mc); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("Applied '%s' to the working tree", shortID(mc.ID)), nil
+}
+
+func (a *tuiApp) drop(mc *types.MiniCommit) (string, error) {
+	if err := dropMiniCommit(a.cmd, a.store, mc); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("Dropped '%s'", shortID(mc.ID)), nil
+}
+
+func (a *tuiApp) reword(mc *types.MiniCommit) (string, error) {
+	message, err := rewordMessage(mc, a.interactive, a.run)
+	if err != nil {
+		return "", err
+	}
+	if message == mc.Message {
+		return "Message unchanged", nil
+	}
+	if a.lintEnabled && !a.noVerify {
+		if err := checkMessage(a.rules, message); err != nil {
+			return "", err
+		}
+	}
+
+	reworded := *mc
+	reworded.Message = message
+	if err := a.store.UpdateMiniCommit(&reworded); err != nil {
+		return "", storageError(err, "failed to reword mini-commit")
+	}
+	return fmt.Sprintf("Reworded '%s'", shortID(mc.ID)), nil
+}
+
+func (a *tuiApp) squash(older, newer *types.MiniCommit) (string, error) {
+	pair := []types.MiniCommit{*older, *newer}
+	for i := range pair {
+		if err := a.store.LoadPatch(&pair[i]); err != nil {
+			return "", storageError(err, "failed to get mini-commit")
+		}
+	}
+	squashed, err := squashMiniCommits(a.store, &pair[0], &pair[1])
+	if err != nil {
+		return "", err
+	}
+	if err := a.store.ReplaceMiniCommits(storage.OpSquash, []string{older.ID, newer.ID},
+		types.MiniCommitList{*squashed}); err != nil {
+		return "", storageError(err, "failed to squash mini-commits")
+	}
+	a.follow = squashed.ID
+	return fmt.Sprintf("Squashed '%s' into '%s' as '%s'", shortID(newer.ID), shortID(older.ID), shortID(squashed.ID)), nil
+}
+
+func (a *tuiApp) split(mc *types.MiniCommit) (string, error) {
+	whole := *mc
+	if err := a.store.LoadPatch(&whole); err != nil {
+		return "", storageError(err, "failed to get mini-commit")
+	}
+	pieces, err := splitMiniCommit(a.store, &whole)
+	if err != nil {
+		return "", err
+	}
+	if err := a.store.ReplaceMiniCommits(storage.OpSplit, []string{mc.ID}, pieces); err != nil {
+		return "", storageError(err, "failed to split mini-commit")
+	}
+	a.follow = pieces[0].ID
+	return fmt.Sprintf("Split '%s' into %d mini-commits", shortID(mc.ID), len(pieces)), nil
+}
+
+// View draws the title, the stack and detail panes side by side, and the status line
+func (a *tuiApp) View(width, height int) []string {
+	if width < 40 || height < 5 {
+		lines := make([]string, height)
+		for i := range lines {
+			lines[i] = tui.Fit("", width)
+		}
+		lines[0] = tui.Fit("The terminal is too small", width)
+		return lines
+	}
+
+	a.page = height - 2
+	stackWidth := width / 3
+	if stackWidth > 48 {
+		stackWidth = 48
+	}
+	detailWidth := width - stackWidth - 1
+
+	lines := make([]string, 0, height)
+	title := fmt.Sprintf(" %d mini-commit%s", len(a.stack), pluralS(len(a.stack)))
+	lines = append(lines, a.paint("\033[7m", tui.Fit(title, width)))
+	stackLines := a.stackPane(stackWidth, a.page)
+	detailLines := a.detailPane(detailWidth, a.page)
+	for i := 0; i < a.page; i++ {
+		lines = append(lines, stackLines[i]+"│"+detailLines[i])
+	}
+
+	status := tuiHints
+	switch {
+	case a.confirm != nil:
+		status = a.confirm.question
+	case a.status != "":
+		status = a.status
+	}
+	return append(lines, tui.Fit(status, width))
+}
+
+// stackPane lists the mini-commits, scrolled to keep the selected one in sight
+func (a *tuiApp) stackPane(width, height int) []string {
+	lines := make([]string, 0, height)
+	if len(a.stack) == 0 {
+		lines = append(lines, tui.Fit(" (no mini-commits)", width))
+	}
+
+	if a.selected < a.top {
+		a.top = a.selected
+	}
+	if a.selected >= a.top+height {
+		a.top = a.selected - height + 1
+	}
+	for i := a.top; i < len(a.stack) && len(lines) < height; i++ {
+		mc := &a.stack[i]
+		stats := miniCommitStats(mc)
+		marker := " "
+		if i == a.selected {
+			marker = ">"
+		}
+		counts := fmt.Sprintf("+%d -%d", stats.Insertions, stats.Deletions)
+		line := fmt.Sprintf("%s %s %s %s", marker, shortID(mc.ID), counts, subject(mc.Message))
+		switch {
+		case i == a.selected:
+			line = a.paint("\033[7m", tui.Fit(line, width))
+		case a.useColor:
+			line = fmt.Sprintf("%s %s %s %s %s", marker, color.Wrap(color.Yellow, shortID(mc.ID)),
+				color.Wrap(color.Green, fmt.Sprintf("+%d", stats.Insertions)),
+				color.Wrap(color.Red, fmt.Sprintf("-%d", stats.Deletions)), subject(mc.Message))
+		}
+		lines = append(lines, tui.Fit(line, width))
+	}
+	for len(lines) < height {
+		lines = append(lines, tui.Fit("", width))
+	}
+	return lines
+}
+
+// detailPane shows the keys, or the selected mini-commit from the scroll position
+func (a *tuiApp) detailPane(width, height int) []string {
+	content := tuiHelp
+	if !a.help {
+		content = a.details(width)
+	}
+
+	if a.scroll > len(content)-height {
+		a.scroll = len(content) - height
+	}
+	if a.scroll < 0 || a.help {
+		a.scroll = 0
+	}
+	lines := make([]string, 0, height)
+	for i := a.scroll; i < len(content) && len(lines) < height; i++ {
+		lines = append(lines, tui.Fit(" "+content[i], width))
+	}
+	for len(lines) < height {
+		lines = append(lines, tui.Fit("", width))
+	}
+	return lines
+}
+
+// details returns the lines describing the selected mini-commit, like show
+// does, followed by its diffstat and diff. They are kept until the selection,
+// the stack or the width changes.
+func (a *tuiApp) details(width int) []string {
+	mc := a.current()
+	if mc == nil {
+		return []string{"Save the staged changes with git mini-commit -m <message>,", "or start git mini-commit watch."}
+	}
+	key := mc.ID + "/" + strconv.Itoa(width)
+	if key == a.detailKey {
+		return a.detail
+	}
+
+	full := *mc
+	if err := a.store.LoadPatch(&full); err != nil {
+		return []string{"error: " + err.Error()}
+	}
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "Mini-commit: %s\n", a.paint(color.Yellow, full.ID))
+	message := strings.Split(full.Message, "\n")
+	fmt.Fprintf(&b, "Message: %s\n", message[0])
+	for _, line := range message[1:] {
+		fmt.Fprintf(&b, "    %s\n", line)
+	}
+	fmt.Fprintf(&b, "Created: %s (%s)\n", full.CreatedAt.Format("2006-01-02 15:04:05"), formatRelative(full.CreatedAt, time.Now()))
+	if full.Author != "" {
+		fmt.Fprintf(&b, "Author: %s\n", full.Author)
+	}
+	if full.Branch != "" {
+		fmt.Fprintf(&b, "Branch: %s\n", full.Branch)
+	}
+	for _, t := range full.Trailers {
+		fmt.Fprintf(&b, "%s: %s\n", t.Key, t.Value)
+	}
+
+	statWidth := width - 2
+	if statWidth > patch.DefaultStatWidth {
+		statWidth = patch.DefaultStatWidth
+	}
+	var stat bytes.Buffer
+	patch.WriteStat(&stat, patch.Parse(full.Patch), statWidth)
+	statText, diff := stat.String(), full.Patch
+	if a.useColor {
+		statText, diff = color.Stat(statText), color.Diff(diff)
+	}
+	fmt.Fprintf(&b, "\n%s\n%s", statText, diff)
+
+	a.detail = strings.Split(strings.TrimSuffix(b.String(), "\n"), "\n")
+	a.detailKey = key
+	return a.detail
+}
+
+// paint colors s when colors are on
+func (a *tuiApp) paint(code, s string) string {
+	if !a.useColor {
+		return s
+	}
+	return color.Wrap(code, s)
+}
+
+// readScript reads the keys of a --script file, or of stdin for "-"
+func readScript(cmd *cobra.Command, path string) ([]tui.Key, error) {
+	var content []byte
+	var err error
+	if path == "-" {
+		content, err = io.ReadAll(cmd.InOrStdin())
+	} else {
+		content, err = os.ReadFile(path)
+	}
+	if err != nil {
+		return nil, newCommandError(codeUsage, "could not read script '%s': %v", path, err)
+	}
+	keys, err := tui.ParseScript(string(content))
+	if err != nil {
+		return nil, newCommandError(codeUsage, "invalid script '%s': %v", path, err)
+	}
+	return keys, nil
+}
+
+// parseSize parses a --size value such as "100x30"
+func parseSize(value string) (int, int, error) {
+	w, h, ok := strings.Cut(value, "x")
+	width, errW := strconv.Atoi(w)
+	height, errH := strconv.Atoi(h)
+	if !ok || errW != nil || errH != nil || width <= 0 || height <= 0 {
+		return 0, 0, newCommandError(codeUsage, "invalid size '%s' (expected <columns>x<lines>, e.g. 100x30)", value)
+	}
+	return width, height, nil
+}
+
+func init() {
+	tuiCmd.Flags().BoolP("no-verify", "n", false, "skip the pop hooks and the message checks")
+	tuiCmd.Flags().String("script", "", "read the keys from this file (- for stdin) and write the screens as text")
+	tuiCmd.Flags().String("size", "80x24", "screen size for --script, as <columns>x<lines>")
+	rootCmd.AddCommand(tuiCmd)
+}
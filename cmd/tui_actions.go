@@ -0,0 +1,148 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"git-mini-commit/internal/editor"
+	"git-mini-commit/internal/git"
+	"git-mini-commit/internal/patch"
+	"git-mini-commit/internal/storage"
+	"git-mini-commit/internal/types"
+)
+
+// applyToWorktree applies the patch of mc to the working tree, leaving the
+// index and the stack as they are
+func applyToWorktree(store *storage.Storage, mc *types.MiniCommit) error {
+	p, err := store.OpenPatch(mc)
+	if err != nil {
+		return storageError(err, "failed to get mini-commit")
+	}
+	defer p.Close()
+	if err := git.ApplyPatchToWorktreeFrom(p); err != nil {
+		return newCommandError(codeApplyFailed, "failed to apply patch to the working tree: %w", err)
+	}
+	return nil
+}
+
+// rewordMessage opens the editor on the message of mc and returns what was
+// written, without the comment lines. run runs the editor, so that the caller
+// can hand it the terminal.
+func rewordMessage(mc *types.MiniCommit, interactive bool, run func(func() error) error) (string, error) {
+	command, err := editor.Resolve(git.GetConfig, interactive)
+	if err != nil {
+		return "", gitError(err)
+	}
+	if command == "" {
+		return "", newCommandError(codeMissingMessage, "no editor is available to reword the message")
+	}
+
+	content := mc.Message + "\n\n" +
+		"# Please enter the new message for the mini-commit. Lines starting\n" +
+		"# with '#' will be ignored, and an empty message aborts the reword.\n"
+	if err := os.WriteFile(editMessageFile, []byte(content), 0644); err != nil {
+		return "", newCommandError(codeError, "failed to write %s: %v", editMessageFile, err)
+	}
+	if err := run(func() error { return editor.Edit(command, editMessageFile) }); err != nil {
+		return "", newCommandError(codeError, "%v", err)
+	}
+
+	edited, err := os.ReadFile(editMessageFile)
+	if err != nil {
+		return "", newCommandError(codeError, "failed to read %s: %v", editMessageFile, err)
+	}
+	message := cleanupMessage(string(edited), true)
+	if message == "" {
+		return "", newCommandError(codeAborted, "aborting the reword due to empty message")
+	}
+	return message, nil
+}
+
+// squashMiniCommits combines newer with older, the mini-commit before it in
+// the stack. When newer already holds the changes of older, as successive
+// checkpoints of the same staged changes do, its patch is kept; otherwise it
+// is applied on top of older. Both patches must be loaded.
+func squashMiniCommits(store *storage.Storage, older, newer *types.MiniCommit) (*types.MiniCommit, error) {
+	base, changes := newer.Base, newer.Patch
+
+	newerTree, err := git.TreeWithPatch(newer.Base, newer.Patch)
+	if err != nil {
+		return nil, gitError(err)
+	}
+	err = git.CheckPatchAgainst(newerTree, older.Patch, true)
+	if err != nil && !errors.Is(err, git.ErrConflict) {
+		return nil, gitError(err)
+	}
+	if err != nil {
+		olderTree, err := git.TreeWithPatch(older.Base, older.Patch)
+		if err != nil {
+			return nil, gitError(err)
+		}
+		tree, err := git.TreeWithPatch(olderTree, newer.Patch)
+		if errors.Is(err, git.ErrConflict) {
+			return nil, newCommandError(codeApplyFailed, "cannot squash: '%s' does not apply on top of '%s'",
+				shortID(newer.ID), shortID(older.ID))
+		}
+		if err != nil {
+			return nil, gitError(err)
+		}
+		baseTree, err := git.TreeWithPatch(older.Base, "")
+		if err != nil {
+			return nil, gitError(err)
+		}
+		if changes, err = git.DiffTrees(baseTree, tree); err != nil {
+			return nil, gitError(err)
+		}
+		base = older.Base
+	}
+
+	now := time.Now()
+	return &types.MiniCommit{
+		ID:        store.GenerateID(changes, now),
+		Message:   older.Message + "\n\n" + newer.Message,
+		CreatedAt: now,
+		Branch:    newer.Branch,
+		Author:    older.Author,
+		Base:      base,
+		Trailers:  append(append([]types.Trailer(nil), older.Trailers...), newer.Trailers...),
+		Patch:     changes,
+	}, nil
+}
+
+// splitMiniCommit splits mc into one mini-commit per file, in patch order,
+// whose subjects name the file. Its patch must be loaded.
+func splitMiniCommit(store *storage.Storage, mc *types.MiniCommit) (types.MiniCommitList, error) {
+	parsed, err := patch.ParsePatch(mc.Patch)
+	if err != nil {
+		return nil, newCommandError(codeCorrupt, "cannot split '%s': %v", shortID(mc.ID), err)
+	}
+	if len(parsed.Files) < 2 {
+		return nil, newCommandError(codeUsage, "cannot split '%s': it changes a single file", shortID(mc.ID))
+	}
+
+	subjectLine, body, _ := strings.Cut(mc.Message, "\n")
+	body = strings.TrimLeft(body, "\n")
+	now := time.Now()
+	pieces := make(types.MiniCommitList, 0, len(parsed.Files))
+	for _, f := range parsed.Files {
+		message := fmt.Sprintf("%s (%s)", subjectLine, f.Path())
+		if body != "" {
+			message += "\n\n" + body
+		}
+		content := f.String()
+		pieces = append(pieces, types.MiniCommit{
+			ID:        store.GenerateID(content, now),
+			Message:   message,
+			CreatedAt: now,
+			Branch:    mc.Branch,
+			Author:    mc.Author,
+			Base:      mc.Base,
+			Trailers:  mc.Trailers,
+			Patch:     content,
+		})
+	}
+	return pieces, nil
+}
@@ -0,0 +1,153 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"git-mini-commit/testutils"
+)
+
+// lastFrame --script の出力から最後の画面を返す
+func lastFrame(output string) string {
+	if i := strings.LastIndex(output, "--- frame "); i >= 0 {
+		return output[i:]
+	}
+	return output
+}
+
+// listSubjects list --porcelain の件名を古い順に返す
+func listSubjects(t *testing.T, cli *testutils.TestCLI) []string {
+	t.Helper()
+	var subjects []string
+	for _, line := range strings.Split(strings.TrimSpace(cli.AssertCommandSuccess(t, "list", "--porcelain")), "\n") {
+		if fields := strings.SplitN(line, " ", 6); len(fields) == 6 {
+			subjects = append(subjects, fields[5])
+		}
+	}
+	return subjects
+}
+
+func TestCLITui(t *testing.T) {
+	repo := testutils.NewTestGitRepo(t)
+	defer repo.Cleanup()
+	cli := testutils.NewTestCLI(t)
+	cli.SetRepo(repo)
+
+	repo.CreateTestFile("a.txt", "a\n")
+	repo.StageFile("a.txt")
+	if err := repo.CommitFile("Initial commit"); err != nil {
+		t.Fatalf("Failed to commit: %v", err)
+	}
+	older := saveMiniCommit(t, repo, cli, "b.txt", "b\n", "Add b")
+	newer := saveMiniCommit(t, repo, cli, "c.txt", "c\n", "Add c")
+	gitOutput(t, "reset", "-q", "--hard")
+
+	// 1. 最新のmini-commitを選択して開き、キーで選択と表示を切り替える
+	cli.SetStdin("k\n?\n")
+	output := cli.AssertCommandSuccess(t, "tui", "--script", "-", "--size", "100x16")
+	frames := strings.Split(output, "--- frame ")
+	if len(frames) != 4 {
+		t.Fatalf("Expected 3 frames, got:\n%s", output)
+	}
+	for _, want := range []string{"2 mini-commits", "> " + newer[:8], "Message: Add c", "c.txt | 1 +"} {
+		cli.AssertOutputContains(t, frames[1], want)
+	}
+	cli.AssertOutputContains(t, frames[2], "> "+older[:8])
+	cli.AssertOutputContains(t, frames[2], "Message: Add b")
+	cli.AssertOutputContains(t, frames[3], "Keys")
+	for _, frame := range frames[1:] {
+		if lines := strings.Count(frame, "\n"); lines != 17 {
+			t.Errorf("Expected 16 lines per frame, got %d:\n%s", lines-1, frame)
+		}
+	}
+
+	// 2. a は作業ツリーにだけ適用し、スタックはそのまま
+	cli.SetStdin("a\n")
+	output = cli.AssertCommandSuccess(t, "tui", "--script", "-")
+	cli.AssertOutputContains(t, lastFrame(output), "Applied '"+newer[:8]+"' to the working tree")
+	if _, err := os.Stat(filepath.Join(repo.RepoPath, "c.txt")); err != nil {
+		t.Errorf("Expected c.txt in the working tree: %v", err)
+	}
+	if staged := gitOutput(t, "diff", "--cached", "--name-only"); staged != "" {
+		t.Errorf("Expected nothing staged, got %q", staged)
+	}
+	if got := listSubjects(t, cli); len(got) != 2 {
+		t.Errorf("Expected the stack to be untouched, got %v", got)
+	}
+	os.Remove(filepath.Join(repo.RepoPath, "b.txt"))
+	os.Remove(filepath.Join(repo.RepoPath, "c.txt"))
+
+	// 3. 確認で拒否すると削除しない。S はファイルごとに分割する
+	cli.SetStdin("d n S y\n")
+	output = cli.AssertCommandSuccess(t, "tui", "--script", "-")
+	cli.AssertOutputContains(t, output, "Drop '"+newer[:8]+"'? [y/N]")
+	cli.AssertOutputContains(t, lastFrame(output), "Split '"+newer[:8]+"' into 2 mini-commits")
+	want := []string{"Add b", "Add c (b.txt)", "Add c (c.txt)"}
+	if got := listSubjects(t, cli); strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Errorf("Expected %v after the split, got %v", want, got)
+	}
+
+	// 4. 1ファイルだけのmini-commitは分割できず、最古のmini-commitはまとめられない
+	cli.SetStdin("G S y g s\n")
+	output = cli.AssertCommandSuccess(t, "tui", "--script", "-")
+	cli.AssertOutputContains(t, output, "error: cannot split")
+	cli.AssertOutputContains(t, lastFrame(output), "error:")
+
+	// 5. s は直前のmini-commitにまとめ、元に戻せる
+	cli.SetStdin("G s y\n")
+	output = cli.AssertCommandSuccess(t, "tui", "--script", "-")
+	cli.AssertOutputContains(t, lastFrame(output), "Squashed")
+	want = []string{"Add b", "Add c (b.txt)"}
+	if got := listSubjects(t, cli); strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Errorf("Expected %v after the squash, got %v", want, got)
+	}
+	list := strings.Fields(strings.Split(cli.AssertCommandSuccess(t, "list", "--porcelain"), "\n")[1])
+	show := cli.AssertCommandSuccess(t, "show", list[0])
+	for _, want := range []string{"Add c (c.txt)", "b.txt", "c.txt"} {
+		cli.AssertOutputContains(t, show, want)
+	}
+	cli.AssertCommandSuccess(t, "undo")
+	if got := listSubjects(t, cli); len(got) != 3 {
+		t.Errorf("Expected the squash to be undone, got %v", got)
+	}
+	cli.AssertCommandSuccess(t, "undo", "3")
+	if got := listSubjects(t, cli); strings.Join(got, "|") != "Add b|Add c" {
+		t.Errorf("Expected the split to be undone, got %v", got)
+	}
+
+	// 6. r はエディタでメッセージを書き換える
+	script := filepath.Join(t.TempDir(), "editor.sh")
+	os.WriteFile(script, []byte(`printf 'Reworded subject\n\n# comment\n' > "$1"`+"\n"), 0755)
+	t.Setenv("GIT_EDITOR", "sh "+script)
+	cli.SetStdin("r\n")
+	output = cli.AssertCommandSuccess(t, "tui", "--script", "-")
+	cli.AssertOutputContains(t, lastFrame(output), "Message: Reworded subject")
+	if got := listSubjects(t, cli); strings.Join(got, "|") != "Add b|Reworded subject" {
+		t.Errorf("Expected the newest mini-commit to be reworded, got %v", got)
+	}
+
+	// 7. p はステージングエリアに適用し、d y は削除する
+	cli.SetStdin("p d y\n")
+	output = cli.AssertCommandSuccess(t, "tui", "--script", "-")
+	cli.AssertOutputContains(t, output, "to the staging area")
+	cli.AssertOutputContains(t, lastFrame(output), "1 mini-commit\n")
+	if staged := gitOutput(t, "diff", "--cached", "--name-only"); staged != "b.txt\nc.txt\n" {
+		t.Errorf("Expected b.txt and c.txt to be staged, got %q", staged)
+	}
+	if got := listSubjects(t, cli); strings.Join(got, "|") != "Add b" {
+		t.Errorf("Expected the reworded mini-commit to be dropped, got %v", got)
+	}
+
+	// 8. 使い方の誤り
+	cli.SetStdin("")
+	cli.AssertExitCode(t, 2, "tui", "--json")
+	cli.AssertExitCode(t, 2, "tui")
+	cli.AssertExitCode(t, 2, "tui", "--script", "missing.keys")
+	cli.SetStdin("frobnicate\n")
+	output = cli.AssertExitCode(t, 2, "tui", "--script", "-")
+	cli.AssertOutputContains(t, output, "invalid script")
+	cli.SetStdin("q\n")
+	cli.AssertExitCode(t, 2, "tui", "--script", "-", "--size", "wide")
+}
@@ -0,0 +1,92 @@
+package cmd
+
+import (
+	"fmt"
+	"strconv"
+
+	"git-mini-commit/internal/git"
+	"git-mini-commit/internal/storage"
+
+	"github.com/spf13/cobra"
+)
+
+var undoCmd = &cobra.Command{
+	Use:   "undo [n]",
+	Short: "Restore the mini-commit store to its state before the last n operations",
+	Long: `Undo the last n operations (default 1) recorded in the operation log, restoring
+dropped, cleared and edited mini-commits and moving created ones to the trash.
+
+The undo is recorded as an operation itself, so running "undo" again redoes what
+was undone. Undoing a pop leaves the staging area unchanged; only the store is
+restored. See "git mini-commit reflog" for the recorded operations.`,
+	Args: rangeArgs(0, 1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		mode, err := outputMode(cmd)
+		if err != nil {
+			return err
+		}
+
+		n := 1
+		if len(args) == 1 {
+			n, err = strconv.Atoi(args[0])
+			if err != nil || n < 1 {
+				return newCommandError(codeUsage, "invalid number of operations '%s'", args[0])
+			}
+		}
+
+		// Check if it's a Git repository
+		if err := git.CheckRepository(); err != nil {
+			return gitError(err)
+		}
+
+		// Initialize storage
+		store, err := storage.NewStorage()
+		if err != nil {
+			return storageError(err, "failed to initialize storage")
+		}
+
+		ops, err := store.Operations()
+		if err != nil {
+			return storageError(err, "failed to read operation log")
+		}
+		if n > len(ops) {
+			return newCommandError(codeUsage, "cannot undo %d operation(s): only %d recorded", n, len(ops))
+		}
+		undone := ops[len(ops)-n:]
+
+		miniCommits, err := store.Undo(n)
+		if err != nil {
+			return storageError(err, "failed to undo")
+		}
+
+		out := cmd.OutOrStdout()
+		switch mode {
+		case outputJSON:
+			docs := make([]jsonMiniCommit, 0, len(miniCommits))
+			for i := range miniCommits {
+				docs = append(docs, toJSON(&miniCommits[i], false))
+			}
+			return writeJSON(out, jsonDocument{Action: "undo", MiniCommits: &docs})
+		case outputPorcelainV1:
+			fmt.Fprintf(out, "undone %d %d\n", n, len(miniCommits))
+			return nil
+		}
+
+		fmt.Fprintf(out, "Undid %d operation%s:\n", n, pluralS(n))
+		popped := false
+		for i := len(undone) - 1; i >= 0; i-- {
+			fmt.Fprintf(out, "  %s: %s\n", undone[i].Kind, undone[i].Summary)
+			popped = popped || undone[i].Kind == storage.OpPop
+		}
+		fmt.Fprintf(out, "Mini-commits: %d\n", len(miniCommits))
+		if popped {
+			fmt.Fprintln(out, "Note: changes applied by pop are still staged")
+		}
+
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(undoCmd)
+}
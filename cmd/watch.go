@@ -0,0 +1,280 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"git-mini-commit/internal/config"
+	"git-mini-commit/internal/git"
+	"git-mini-commit/internal/hooks"
+	"git-mini-commit/internal/patch"
+	"git-mini-commit/internal/storage"
+	"git-mini-commit/internal/types"
+
+	"github.com/spf13/cobra"
+)
+
+// checkpointPaths is the number of paths named in a generated message
+const checkpointPaths = 3
+
+var watchCmd = &cobra.Command{
+	Use:   "watch",
+	Short: "Checkpoint the working tree automatically while you work",
+	Long: `Watch the working tree and save a mini-commit of it whenever it has stopped
+changing for a while, so that a long refactoring can be rewound to any pause.
+
+The working tree is polled every --interval (minicommit.watchInterval, default
+2s). Once it has not changed for --quiet-period (minicommit.watchQuietPeriod,
+default 30s), its changes from HEAD are saved as "git add -A" would stage them,
+untracked files included unless ignored. The index is left untouched.
+
+The message names the files changed since the previous checkpoint. Nothing is
+saved when the working tree matches HEAD or the newest mini-commit. The hooks
+run as when creating a mini-commit, unless --no-verify is given; a failing
+pre-mini-commit hook skips that checkpoint only.
+
+Watching stops after --max-count checkpoints, or on Ctrl-C or SIGTERM, without
+leaving a checkpoint half-saved.`,
+	Args: exactArgs(0),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		mode, err := outputMode(cmd)
+		if err != nil {
+			return err
+		}
+		maxCount, _ := cmd.Flags().GetInt("max-count")
+		if maxCount < 0 {
+			return newCommandError(codeUsage, "--max-count must not be negative")
+		}
+
+		store, err := openStore()
+		if err != nil {
+			return err
+		}
+
+		flagSetting(cmd, "interval", config.WatchInterval)
+		interval, err := settings.Duration(config.WatchInterval)
+		if err != nil {
+			return configError(err)
+		}
+		if interval == 0 {
+			return newCommandError(codeUsage, "the watch interval must be longer than 0")
+		}
+		flagSetting(cmd, "quiet-period", config.WatchQuietPeriod)
+		quiet, err := settings.Duration(config.WatchQuietPeriod)
+		if err != nil {
+			return configError(err)
+		}
+
+		noVerify, _ := cmd.Flags().GetBool("no-verify")
+		w := &watcher{cmd: cmd, mode: mode, store: store, noVerify: noVerify}
+		fmt.Fprintf(cmd.ErrOrStderr(), "Watching the working tree every %s, checkpointing after %s without changes. Press Ctrl-C to stop.\n",
+			interval, quiet)
+		err = w.run(cmd.Context(), interval, quiet, maxCount)
+		fmt.Fprintf(cmd.ErrOrStderr(), "Stopped watching after %d checkpoint%s\n", w.count, pluralS(w.count))
+		return err
+	},
+}
+
+// watcher saves checkpoints of the working tree
+type watcher struct {
+	cmd      *cobra.Command
+	mode     string
+	store    *storage.Storage
+	noVerify bool
+
+	last  string // tree of the previous checkpoint, to name the files changed since
+	count int
+}
+
+// run polls the working tree until ctx is done or maxCount checkpoints (0 for
+// no limit) are saved. Stopping is not an error, even when it interrupts git.
+func (w *watcher) run(ctx context.Context, interval, quiet time.Duration, maxCount int) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	// The working tree found at start counts as a change, so that it is saved
+	// too unless the newest mini-commit already has it
+	var (
+		seen      string
+		changedAt time.Time
+		pending   bool
+	)
+	for {
+		tree, err := git.WorktreeTree()
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return gitError(err)
+		}
+		if tree != seen {
+			seen, changedAt, pending = tree, time.Now(), true
+		}
+
+		if pending && time.Since(changedAt) >= quiet {
+			pending = false
+			if err := w.checkpoint(tree); err != nil {
+				if ctx.Err() != nil {
+					return nil
+				}
+				return err
+			}
+			if maxCount > 0 && w.count >= maxCount {
+				return nil
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// checkpoint saves the changes from HEAD to tree, unless there are none or
+// they are those of the newest mini-commit
+func (w *watcher) checkpoint(tree string) error {
+	changes, message, err := w.pending(tree)
+	if err != nil || changes == "" {
+		w.last = tree
+		return err
+	}
+	branch, err := git.CurrentBranch()
+	if err != nil {
+		return gitError(err)
+	}
+	// A missing user identity should not prevent checkpointing, so the author is optional
+	author, _ := git.AuthorIdent()
+	base, err := git.HeadCommit()
+	if err != nil {
+		return gitError(err)
+	}
+	mc := &types.MiniCommit{
+		Message:   message,
+		CreatedAt: time.Now(),
+		Branch:    branch,
+		Author:    author,
+		Base:      base,
+	}
+
+	// A failing hook only skips this checkpoint. The hook may change the
+	// working tree, for example to format files, so it is read again and
+	// checked and described like the tree it replaces.
+	if !w.noVerify {
+		if err := runHook(w.cmd, hooks.PreMiniCommit, mc); err != nil {
+			if errorCode(err) != codeHookFailed {
+				return err
+			}
+			fmt.Fprintf(w.cmd.ErrOrStderr(), "warning: %v; checkpoint skipped\n", err)
+			return nil
+		}
+		rewritten, err := git.WorktreeTree()
+		if err != nil {
+			return gitError(err)
+		}
+		if rewritten != tree {
+			tree = rewritten
+			if changes, mc.Message, err = w.pending(tree); err != nil || changes == "" {
+				w.last = tree
+				return err
+			}
+		}
+	}
+
+	if err := w.store.CreateMiniCommit(mc, strings.NewReader(changes)); err != nil {
+		return storageError(err, "failed to save mini-commit")
+	}
+	w.last = tree
+	w.count++
+
+	runPostHook(w.cmd, hooks.PostMiniCommit, mc)
+
+	out := w.cmd.OutOrStdout()
+	switch w.mode {
+	case outputJSON:
+		doc := toJSON(mc, false)
+		return writeJSON(out, jsonDocument{Action: "create", MiniCommit: &doc})
+	case outputPorcelainV1:
+		writePorcelainAction(out, "created", mc)
+		return nil
+	}
+	fmt.Fprintf(out, "Created mini-commit %s: %s\n", shortID(mc.ID), mc.Message)
+	return nil
+}
+
+// pending returns the changes from HEAD to tree and the message to save them
+// with, or no changes when there are none or they are those of the newest
+// mini-commit
+func (w *watcher) pending(tree string) (changes, message string, err error) {
+	changes, err = git.TreeChanges(tree)
+	if err != nil {
+		return "", "", gitError(err)
+	}
+	if changes == "" {
+		return "", "", nil
+	}
+	if same, err := w.sameAsNewest(changes); err != nil || same {
+		return "", "", err
+	}
+	message, err = w.message(tree, changes)
+	if err != nil {
+		return "", "", err
+	}
+	return changes, message, nil
+}
+
+// sameAsNewest reports whether changes are the patch of the newest mini-commit
+func (w *watcher) sameAsNewest(changes string) (bool, error) {
+	miniCommits, err := w.store.LoadMiniCommits()
+	if err != nil {
+		return false, storageError(err, "failed to load mini-commits")
+	}
+	if len(miniCommits) == 0 {
+		return false, nil
+	}
+	newest := &miniCommits[len(miniCommits)-1]
+	if err := w.store.LoadPatch(newest); err != nil {
+		return false, storageError(err, "failed to load patch")
+	}
+	return newest.Patch == changes, nil
+}
+
+// message names the files changed since the previous checkpoint, or from HEAD
+// for the first one
+func (w *watcher) message(tree, changes string) (string, error) {
+	since := changes
+	if w.last != "" {
+		var err error
+		if since, err = git.DiffTrees(w.last, tree); err != nil {
+			return "", gitError(err)
+		}
+	}
+	return checkpointMessage(patch.ChangedPaths(since)), nil
+}
+
+// checkpointMessage is "Checkpoint: a, b and c", or "Checkpoint: a, b and 3
+// more files" when there are too many paths to name
+func checkpointMessage(paths []string) string {
+	if len(paths) == 0 {
+		return "Checkpoint"
+	}
+	if len(paths) > checkpointPaths {
+		more := len(paths) - checkpointPaths + 1
+		paths = append(paths[:checkpointPaths-1:checkpointPaths-1], fmt.Sprintf("%d more files", more))
+	}
+	if len(paths) == 1 {
+		return "Checkpoint: " + paths[0]
+	}
+	return "Checkpoint: " + strings.Join(paths[:len(paths)-1], ", ") + " and " + paths[len(paths)-1]
+}
+
+func init() {
+	watchCmd.Flags().String("interval", "", "how often to look for changes, e.g. 500ms (default 2s)")
+	watchCmd.Flags().String("quiet-period", "", "how long the working tree must stay unchanged before a checkpoint (default 30s)")
+	watchCmd.Flags().Int("max-count", 0, "stop after this many checkpoints; 0 for no limit")
+	watchCmd.Flags().BoolP("no-verify", "n", false, "skip the pre-mini-commit hook")
+	rootCmd.AddCommand(watchCmd)
+}
@@ -0,0 +1,117 @@
+package cmd
+
+import (
+	"bufio"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"git-mini-commit/testutils"
+)
+
+func TestCheckpointMessage(t *testing.T) {
+	tests := []struct {
+		paths []string
+		want  string
+	}{
+		{nil, "Checkpoint"},
+		{[]string{"a.go"}, "Checkpoint: a.go"},
+		{[]string{"a.go", "b.go", "c.go"}, "Checkpoint: a.go, b.go and c.go"},
+		{[]string{"a.go", "b.go", "c.go", "d.go", "e.go"}, "Checkpoint: a.go, b.go and 3 more files"},
+	}
+	for _, tt := range tests {
+		if got := checkpointMessage(tt.paths); got != tt.want {
+			t.Errorf("checkpointMessage(%v) = %q, want %q", tt.paths, got, tt.want)
+		}
+	}
+}
+
+func TestCLIWatch(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("watch is stopped with a signal")
+	}
+	projectDir := os.Getenv("GIT_MINI_COMMIT_PROJECT_DIR")
+	if projectDir == "" {
+		t.Skip("GIT_MINI_COMMIT_PROJECT_DIR is not set")
+	}
+	repo := testutils.NewTestGitRepo(t)
+	defer repo.Cleanup()
+	cli := testutils.NewTestCLI(t)
+	cli.SetRepo(repo)
+
+	// 1. 追跡されていないファイルも保存し、インデックスは変更しない
+	repo.CreateTestFile("a.txt", "a\n")
+	repo.CreateTestFile("b.txt", "b\n")
+	output := cli.AssertCommandSuccess(t, "watch", "--interval", "10ms", "--quiet-period", "0", "--max-count", "1")
+	cli.AssertOutputContains(t, output, "Checkpoint: a.txt and b.txt")
+	if staged := gitOutput(t, "diff", "--cached", "--name-only"); staged != "" {
+		t.Errorf("Expected the index to be untouched, got %q", staged)
+	}
+
+	// 2. 不正な間隔は使い方の誤り
+	cli.AssertExitCode(t, 2, "watch", "--interval", "0")
+	cli.AssertExitCode(t, 2, "watch", "--quiet-period", "soon")
+
+	// 3. 最新のmini-commitと同じ内容は保存せず、変更が落ち着いてから保存する。シグナルで正常に終了する
+	watch := exec.Command(filepath.Join(projectDir, "git-mini-commit"), "watch", "--porcelain",
+		"--interval", "20ms", "--quiet-period", "200ms")
+	stdout, err := watch.StdoutPipe()
+	if err != nil {
+		t.Fatalf("Failed to create pipe: %v", err)
+	}
+	var stderr strings.Builder
+	watch.Stderr = &stderr
+	if err := watch.Start(); err != nil {
+		t.Fatalf("Failed to start watch: %v", err)
+	}
+	lines := make(chan string)
+	go func() {
+		scanner := bufio.NewScanner(stdout)
+		for scanner.Scan() {
+			lines <- scanner.Text()
+		}
+		close(lines)
+	}()
+
+	select {
+	case line := <-lines:
+		t.Errorf("Expected nothing saved for an unchanged working tree, got %q", line)
+	case <-time.After(500 * time.Millisecond):
+	}
+	repo.CreateTestFile("b.txt", "b\nc\n")
+	select {
+	case line := <-lines:
+		if !strings.HasPrefix(line, "created ") {
+			t.Errorf("Unexpected output: %q", line)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatalf("Expected a checkpoint of the change")
+	}
+
+	watch.Process.Signal(os.Interrupt)
+	for range lines {
+	}
+	if err := watch.Wait(); err != nil {
+		t.Errorf("Expected watch to stop cleanly, got %v: %s", err, stderr.String())
+	}
+	cli.AssertOutputContains(t, stderr.String(), "Stopped watching after 1 checkpoint\n")
+
+	list := cli.AssertCommandSuccess(t, "list", "--format", "{{.Subject}}")
+	if list != "Checkpoint: a.txt and b.txt\nCheckpoint: b.txt\n" {
+		t.Errorf("Unexpected checkpoints:\n%s", list)
+	}
+
+	// 4. フックが作業ツリーを書き換えたら、書き換え後の変更からメッセージを作る
+	writeHook(t, filepath.Join(".git", "hooks"), "pre-mini-commit", "rm -f d.txt\n")
+	repo.CreateTestFile("c.txt", "c\n")
+	repo.CreateTestFile("d.txt", "d\n")
+	output = cli.AssertCommandSuccess(t, "watch", "--interval", "10ms", "--quiet-period", "0", "--max-count", "1")
+	cli.AssertOutputContains(t, output, "Checkpoint: a.txt, b.txt and c.txt\n")
+	newest := cli.AssertCommandSuccess(t, "list", "--reverse", "-n", "1", "--format", "{{.ID}}")
+	show := cli.AssertCommandSuccess(t, "show", strings.TrimSpace(newest), "--name-only")
+	cli.AssertOutputNotContains(t, show, "d.txt")
+}
@@ -0,0 +1,131 @@
+package color
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// ANSI escape sequences, matching git's default diff colors
+const (
+	Reset  = "\033[m"
+	Bold   = "\033[1m"
+	Red    = "\033[31m"
+	Green  = "\033[32m"
+	Yellow = "\033[33m"
+	Cyan   = "\033[36m"
+)
+
+// Color modes accepted by --color and the color.* git config keys
+const (
+	ModeAuto   = "auto"
+	ModeAlways = "always"
+	ModeNever  = "never"
+)
+
+// ParseMode normalises a --color value or git config color setting.
+// git accepts true/false and always/never/auto for color.ui and color.diff.
+func ParseMode(value string) (string, error) {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "", "auto", "true", "yes", "on", "1":
+		return ModeAuto, nil
+	case "always":
+		return ModeAlways, nil
+	case "never", "false", "no", "off", "0":
+		return ModeNever, nil
+	}
+	return "", fmt.Errorf("invalid color mode '%s' (supported: auto, always, never)", value)
+}
+
+// Enabled resolves a mode to on/off; auto means "when writing to a terminal"
+func Enabled(mode string, isTerminal bool) bool {
+	switch mode {
+	case ModeAlways:
+		return true
+	case ModeNever:
+		return false
+	}
+	return isTerminal && os.Getenv("TERM") != "dumb"
+}
+
+// IsTerminal reports whether f is connected to a terminal. The null device is a
+// character device too, but never a terminal.
+func IsTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	if err != nil || info.Mode()&os.ModeCharDevice == 0 {
+		return false
+	}
+	if null, err := os.Stat(os.DevNull); err == nil && os.SameFile(info, null) {
+		return false
+	}
+	return true
+}
+
+// Wrap surrounds s with the given color, leaving empty strings untouched
+func Wrap(code, s string) string {
+	if s == "" {
+		return s
+	}
+	return code + s + Reset
+}
+
+// Diff colors a git-style unified diff line by line
+func Diff(p string) string {
+	lines := strings.SplitAfter(p, "\n")
+	var sb strings.Builder
+	sb.Grow(len(p) + len(lines)*8)
+
+	inHunk := false
+	for _, line := range lines {
+		body := strings.TrimSuffix(line, "\n")
+		newline := line[len(body):]
+
+		switch {
+		case strings.HasPrefix(body, "diff --git "):
+			inHunk = false
+			sb.WriteString(Wrap(Bold, body))
+		case strings.HasPrefix(body, "@@"):
+			inHunk = true
+			sb.WriteString(colorHunkHeader(body))
+		case !inHunk:
+			sb.WriteString(Wrap(Bold, body))
+		case strings.HasPrefix(body, "+"):
+			sb.WriteString(Wrap(Green, body))
+		case strings.HasPrefix(body, "-"):
+			sb.WriteString(Wrap(Red, body))
+		default:
+			sb.WriteString(body)
+		}
+		sb.WriteString(newline)
+	}
+	return sb.String()
+}
+
+// colorHunkHeader colors "@@ -a,b +c,d @@" cyan while leaving the function context plain
+func colorHunkHeader(line string) string {
+	end := strings.Index(line[2:], "@@")
+	if end < 0 {
+		return Wrap(Cyan, line)
+	}
+	end += 4
+	return Wrap(Cyan, line[:end]) + line[end:]
+}
+
+// statGraph matches the "+++--" graph at the end of a diffstat line
+var statGraph = regexp.MustCompile(`^(.* \| +\d+ )(\+*)(-*)$`)
+
+// Stat colors the graph of diffstat output
+func Stat(s string) string {
+	lines := strings.SplitAfter(s, "\n")
+	var sb strings.Builder
+	for _, line := range lines {
+		body := strings.TrimSuffix(line, "\n")
+		if m := statGraph.FindStringSubmatch(body); m != nil {
+			body = m[1] + Wrap(Green, m[2]) + Wrap(Red, m[3])
+		}
+		sb.WriteString(body)
+		sb.WriteString(line[len(strings.TrimSuffix(line, "\n")):])
+	}
+	return sb.String()
+}
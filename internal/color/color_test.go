@@ -0,0 +1,96 @@
+package color
+
+import (
+	"os"
+	"testing"
+)
+
+func TestParseMode(t *testing.T) {
+	tests := map[string]string{
+		"":       ModeAuto,
+		"auto":   ModeAuto,
+		"true":   ModeAuto,
+		"always": ModeAlways,
+		"never":  ModeNever,
+		"false":  ModeNever,
+		"Never":  ModeNever,
+	}
+	for value, expected := range tests {
+		got, err := ParseMode(value)
+		if err != nil {
+			t.Errorf("ParseMode(%q) error = %v", value, err)
+			continue
+		}
+		if got != expected {
+			t.Errorf("ParseMode(%q) = %q, want %q", value, got, expected)
+		}
+	}
+
+	if _, err := ParseMode("sometimes"); err == nil {
+		t.Errorf("Expected error for invalid mode")
+	}
+}
+
+func TestEnabled(t *testing.T) {
+	t.Setenv("TERM", "xterm")
+
+	if !Enabled(ModeAlways, false) {
+		t.Errorf("Expected always to enable color without a terminal")
+	}
+	if Enabled(ModeNever, true) {
+		t.Errorf("Expected never to disable color on a terminal")
+	}
+	if Enabled(ModeAuto, false) || !Enabled(ModeAuto, true) {
+		t.Errorf("Expected auto to follow the terminal")
+	}
+
+	t.Setenv("TERM", "dumb")
+	if Enabled(ModeAuto, true) {
+		t.Errorf("Expected auto to disable color on a dumb terminal")
+	}
+}
+
+func TestDiff(t *testing.T) {
+	p := "diff --git a/a.txt b/a.txt\n--- a/a.txt\n+++ b/a.txt\n@@ -1 +1 @@ func main\n-old\n+new\n context\n"
+	expected := Bold + "diff --git a/a.txt b/a.txt" + Reset + "\n" +
+		Bold + "--- a/a.txt" + Reset + "\n" +
+		Bold + "+++ b/a.txt" + Reset + "\n" +
+		Cyan + "@@ -1 +1 @@" + Reset + " func main\n" +
+		Red + "-old" + Reset + "\n" +
+		Green + "+new" + Reset + "\n" +
+		" context\n"
+
+	if got := Diff(p); got != expected {
+		t.Errorf("Diff() = %q, want %q", got, expected)
+	}
+}
+
+func TestStat(t *testing.T) {
+	s := " a.txt | 3 ++-\n 1 file changed, 2 insertions(+), 1 deletion(-)\n"
+	expected := " a.txt | 3 " + Green + "++" + Reset + Red + "-" + Reset + "\n 1 file changed, 2 insertions(+), 1 deletion(-)\n"
+
+	if got := Stat(s); got != expected {
+		t.Errorf("Stat() = %q, want %q", got, expected)
+	}
+}
+
+func TestIsTerminal(t *testing.T) {
+	// /dev/null とファイルは端末ではない
+	null, err := os.Open(os.DevNull)
+	if err != nil {
+		t.Fatalf("Failed to open %s: %v", os.DevNull, err)
+	}
+	defer null.Close()
+	if IsTerminal(null) {
+		t.Errorf("Expected %s not to be a terminal", os.DevNull)
+	}
+
+	file, err := os.CreateTemp(t.TempDir(), "out")
+	if err != nil {
+		t.Fatalf("Failed to create file: %v", err)
+	}
+	defer file.Close()
+	if IsTerminal(file) {
+		t.Errorf("Expected a file not to be a terminal")
+	}
+}
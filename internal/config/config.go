@@ -0,0 +1,233 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"git-mini-commit/internal/git"
+	"git-mini-commit/internal/lint"
+)
+
+// Keys of the settings, read from git config under the minicommit section
+const (
+	Lint                 = "minicommit.lint"
+	LintTypes            = "minicommit.lintTypes"
+	LintScope            = "minicommit.lintScope"
+	LintScopes           = "minicommit.lintScopes"
+	LintSubjectMaxLength = "minicommit.lintSubjectMaxLength"
+	Template             = "minicommit.template"
+	ListFormat           = "minicommit.listFormat"
+	ListDate             = "minicommit.listDate"
+	TrashRetention       = "minicommit.trashRetention"
+	ReflogRetention      = "minicommit.reflogRetention"
+	LockTimeout          = "minicommit.lockTimeout"
+	GitTimeout           = "minicommit.gitTimeout"
+	WatchInterval        = "minicommit.watchInterval"
+	WatchQuietPeriod     = "minicommit.watchQuietPeriod"
+)
+
+// Sources of a setting, from the lowest to the highest precedence. The git
+// config scopes are those git reports and are ordered by git itself.
+const (
+	SourceDefault  = "default"
+	SourceSystem   = "system"
+	SourceGlobal   = "global"
+	SourceLocal    = "local"
+	SourceWorktree = "worktree"
+	SourceCommand  = "command"
+	SourceEnv      = "env"
+	SourceFlag     = "flag"
+)
+
+// envPrefix starts the environment variables that override the settings
+const envPrefix = "GIT_MINI_COMMIT_"
+
+// Definition is a known setting
+type Definition struct {
+	Key         string
+	Default     string
+	Description string
+}
+
+// Definitions are the known settings, in the order they are listed
+var Definitions = []Definition{
+	{Lint, "false", "check messages against the lint rules when creating mini-commits"},
+	{LintTypes, strings.Join(lint.DefaultTypes, " "), "allowed conventional commit types"},
+	{LintScope, lint.ScopeOptional, "optional, required or forbidden"},
+	{LintScopes, "", "allowed scopes; empty allows any"},
+	{LintSubjectMaxLength, strconv.Itoa(lint.DefaultSubjectMaxLength), "longest subject line; 0 for no limit"},
+	{Template, "", "file the editor starts with when writing a message"},
+	{ListFormat, "", "default list format: default, oneline or a Go text/template"},
+	{ListDate, "", "default list date format"},
+	{TrashRetention, "30.days", "how long gc keeps trashed mini-commits; never to keep them"},
+	{ReflogRetention, "90.days", "how long gc keeps operations in the reflog; never to keep them"},
+	{LockTimeout, "10s", "how long to wait for another process to release the store lock"},
+	{GitTimeout, "5m", "how long a git command may run before it is stopped; 0 for no limit (reading and applying patches are not limited)"},
+	{WatchInterval, "2s", "how often watch looks for changes in the working tree"},
+	{WatchQuietPeriod, "30s", "how long the working tree must stay unchanged before watch takes a checkpoint"},
+}
+
+// Setting is the effective value of a key and where it comes from. Origin is
+// the config file, environment variable or flag that set it.
+type Setting struct {
+	Key    string
+	Value  string
+	Source string
+	Origin string
+}
+
+// Config is the effective configuration: git config overridden by the
+// environment, then by flags, falling back to the defaults
+type Config struct {
+	settings map[string]*Setting
+	order    []string
+}
+
+// Load reads the minicommit settings from git config and the environment
+func Load() (*Config, error) {
+	entries, err := git.ListConfig(`^minicommit\.`)
+	if err != nil {
+		return nil, err
+	}
+	return New(entries, os.LookupEnv), nil
+}
+
+// New builds the configuration from git config entries, in the order git read
+// them, and the environment
+func New(entries []git.ConfigEntry, lookupEnv func(string) (string, bool)) *Config {
+	c := &Config{settings: map[string]*Setting{}}
+	for _, d := range Definitions {
+		c.set(d.Key, d.Default, SourceDefault, "")
+	}
+	for _, e := range entries {
+		c.set(e.Key, e.Value, e.Scope, e.Origin)
+	}
+	for _, d := range Definitions {
+		if value, ok := lookupEnv(EnvName(d.Key)); ok {
+			c.set(d.Key, value, SourceEnv, EnvName(d.Key))
+		}
+	}
+	return c
+}
+
+// EnvName returns the environment variable that overrides a key, e.g.
+// GIT_MINI_COMMIT_LINT_SUBJECT_MAX_LENGTH for minicommit.lintSubjectMaxLength
+func EnvName(key string) string {
+	_, name, _ := strings.Cut(key, ".")
+	var b strings.Builder
+	b.WriteString(envPrefix)
+	for i, r := range name {
+		if unicode.IsUpper(r) && i > 0 {
+			b.WriteByte('_')
+		}
+		b.WriteRune(unicode.ToUpper(r))
+	}
+	return b.String()
+}
+
+// set records a value; keys are case-insensitive, and the first spelling is kept
+func (c *Config) set(key, value, source, origin string) {
+	id := strings.ToLower(key)
+	if s, ok := c.settings[id]; ok {
+		s.Value, s.Source, s.Origin = value, source, origin
+		return
+	}
+	c.settings[id] = &Setting{Key: key, Value: value, Source: source, Origin: origin}
+	c.order = append(c.order, id)
+}
+
+// SetFlag overrides a key with the value of a command-line flag
+func (c *Config) SetFlag(key, value, flag string) {
+	c.set(key, value, SourceFlag, flag)
+}
+
+// Lookup returns the setting of a key; ok is false for an unknown key that is not set
+func (c *Config) Lookup(key string) (Setting, bool) {
+	s, ok := c.settings[strings.ToLower(key)]
+	if !ok {
+		return Setting{Key: key}, false
+	}
+	return *s, true
+}
+
+// Settings returns the known settings followed by the other minicommit keys that are set
+func (c *Config) Settings() []Setting {
+	out := make([]Setting, 0, len(c.order))
+	for _, id := range c.order {
+		out = append(out, *c.settings[id])
+	}
+	return out
+}
+
+// IsSet reports whether a key was set rather than left at its default
+func (c *Config) IsSet(key string) bool {
+	s, ok := c.Lookup(key)
+	return ok && s.Source != SourceDefault
+}
+
+// String returns the value of a key, "" when it is unknown and not set
+func (c *Config) String(key string) string {
+	s, _ := c.Lookup(key)
+	return s.Value
+}
+
+// Bool returns the value of a key as a git boolean: true, yes, on, 1 or a key
+// set without a value, and false, no, off or 0
+func (c *Config) Bool(key string) (bool, error) {
+	s, _ := c.Lookup(key)
+	switch strings.ToLower(s.Value) {
+	case "true", "yes", "on", "1":
+		return true, nil
+	case "false", "no", "off", "0":
+		return false, nil
+	case "":
+		// A key written without "=" in a config file is true; an empty
+		// environment variable is treated as unset
+		return c.IsSet(key) && s.Source != SourceEnv, nil
+	}
+	return false, s.invalid("boolean")
+}
+
+// Int returns the value of a key as a non-negative integer
+func (c *Config) Int(key string) (int, error) {
+	s, _ := c.Lookup(key)
+	n, err := strconv.Atoi(strings.TrimSpace(s.Value))
+	if err != nil || n < 0 {
+		return 0, s.invalid("number")
+	}
+	return n, nil
+}
+
+// Duration returns the value of a key as a duration such as "10s" or "1m30s"
+func (c *Config) Duration(key string) (time.Duration, error) {
+	s, _ := c.Lookup(key)
+	d, err := time.ParseDuration(strings.TrimSpace(s.Value))
+	if err != nil || d < 0 {
+		return 0, s.invalid("duration")
+	}
+	return d, nil
+}
+
+// List returns the value of a key split into words at commas and spaces
+func (c *Config) List(key string) []string {
+	return strings.FieldsFunc(c.String(key), func(r rune) bool {
+		return r == ',' || unicode.IsSpace(r)
+	})
+}
+
+// invalid returns the error for a value that is not of the expected kind
+func (s Setting) invalid(kind string) error {
+	return fmt.Errorf("%s: invalid %s '%s'%s", s.Key, kind, s.Value, s.from())
+}
+
+// from describes where a setting comes from for error messages
+func (s Setting) from() string {
+	if s.Origin == "" {
+		return ""
+	}
+	return " (from " + s.Origin + ")"
+}
@@ -0,0 +1,104 @@
+package config
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"git-mini-commit/internal/git"
+)
+
+// env テスト用の環境変数
+func env(vars map[string]string) func(string) (string, bool) {
+	return func(name string) (string, bool) {
+		value, ok := vars[name]
+		return value, ok
+	}
+}
+
+func TestPrecedence(t *testing.T) {
+	entries := []git.ConfigEntry{
+		{Scope: SourceGlobal, Origin: "file:/home/me/.gitconfig", Key: "minicommit.listformat", Value: "oneline"},
+		{Scope: SourceGlobal, Origin: "file:/home/me/.gitconfig", Key: "minicommit.lint", Value: "true"},
+		{Scope: SourceLocal, Origin: "file:.git/config", Key: "minicommit.lint", Value: "false"},
+		{Scope: SourceLocal, Origin: "file:.git/config", Key: "minicommit.unknown", Value: "x"},
+	}
+	c := New(entries, env(map[string]string{"GIT_MINI_COMMIT_LIST_FORMAT": "default"}))
+
+	tests := []struct {
+		key    string
+		value  string
+		source string
+		origin string
+	}{
+		{Lint, "false", SourceLocal, "file:.git/config"},
+		{ListFormat, "default", SourceEnv, "GIT_MINI_COMMIT_LIST_FORMAT"},
+		{TrashRetention, "30.days", SourceDefault, ""},
+		{"minicommit.unknown", "x", SourceLocal, "file:.git/config"},
+	}
+	for _, tt := range tests {
+		s, ok := c.Lookup(tt.key)
+		if !ok || s.Value != tt.value || s.Source != tt.source || s.Origin != tt.origin {
+			t.Errorf("Lookup(%s) = %+v, %v", tt.key, s, ok)
+		}
+	}
+
+	// フラグは環境変数より優先され、キーの表記は定義のものが残る
+	c.SetFlag("minicommit.LISTFORMAT", "{{.ID}}", "--format")
+	if s, _ := c.Lookup(ListFormat); s.Key != ListFormat || s.Value != "{{.ID}}" || s.Source != SourceFlag {
+		t.Errorf("Expected the flag to win, got %+v", s)
+	}
+
+	// 一覧は定義順、続いて未知のキー
+	settings := c.Settings()
+	if len(settings) != len(Definitions)+1 || settings[0].Key != Lint || settings[len(settings)-1].Key != "minicommit.unknown" {
+		t.Errorf("Unexpected settings: %+v", settings)
+	}
+	if _, ok := c.Lookup("minicommit.notSet"); ok {
+		t.Errorf("Expected an unknown key to be missing")
+	}
+}
+
+func TestTypedAccessors(t *testing.T) {
+	c := New([]git.ConfigEntry{
+		{Scope: SourceLocal, Origin: "file:.git/config", Key: "minicommit.lint"},
+		{Scope: SourceLocal, Origin: "file:.git/config", Key: "minicommit.lintsubjectmaxlength", Value: "fifty"},
+		{Scope: SourceLocal, Origin: "file:.git/config", Key: "minicommit.lintscopes", Value: "ui, api  db"},
+	}, env(map[string]string{"GIT_MINI_COMMIT_LOCK_TIMEOUT": "1m30s"}))
+
+	// 値のないキーは true
+	if b, err := c.Bool(Lint); err != nil || !b {
+		t.Errorf("Bool(%s) = %v, %v", Lint, b, err)
+	}
+	if d, err := c.Duration(LockTimeout); err != nil || d != 90*time.Second {
+		t.Errorf("Duration(%s) = %v, %v", LockTimeout, d, err)
+	}
+	if got := strings.Join(c.List(LintScopes), ","); got != "ui,api,db" {
+		t.Errorf("List(%s) = %s", LintScopes, got)
+	}
+	if got := len(c.List(LintTypes)); got != 11 {
+		t.Errorf("Expected the default types, got %d", got)
+	}
+
+	// 不正な値はキーと設定元を示すエラー
+	_, err := c.Int(LintSubjectMaxLength)
+	if err == nil || err.Error() != "minicommit.lintSubjectMaxLength: invalid number 'fifty' (from file:.git/config)" {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	c.SetFlag(Lint, "maybe", "--lint")
+	if _, err := c.Bool(Lint); err == nil || !strings.Contains(err.Error(), "(from --lint)") {
+		t.Errorf("Unexpected error: %v", err)
+	}
+}
+
+func TestEnvName(t *testing.T) {
+	for key, want := range map[string]string{
+		Lint:                 "GIT_MINI_COMMIT_LINT",
+		LintSubjectMaxLength: "GIT_MINI_COMMIT_LINT_SUBJECT_MAX_LENGTH",
+		TrashRetention:       "GIT_MINI_COMMIT_TRASH_RETENTION",
+	} {
+		if got := EnvName(key); got != want {
+			t.Errorf("EnvName(%s) = %s, want %s", key, got, want)
+		}
+	}
+}
@@ -0,0 +1,68 @@
+package editor
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+// DefaultEditor is used when neither the environment nor git config names an editor
+const DefaultEditor = "vi"
+
+// Resolve picks the editor command the way git does: GIT_EDITOR, then core.editor,
+// then VISUAL unless the terminal is dumb, then EDITOR, then "vi". getConfig reads
+// a git config key and reports whether it is set. The default editor needs a
+// terminal, so it is only used when interactive is set and the terminal is not
+// dumb; an empty result means there is no editor to run.
+func Resolve(getConfig func(key string) (string, bool, error), interactive bool) (string, error) {
+	if value := os.Getenv("GIT_EDITOR"); value != "" {
+		return value, nil
+	}
+
+	value, ok, err := getConfig("core.editor")
+	if err != nil {
+		return "", err
+	}
+	if ok && value != "" {
+		return value, nil
+	}
+
+	dumb := os.Getenv("TERM") == "" || os.Getenv("TERM") == "dumb"
+	if value := os.Getenv("VISUAL"); value != "" && !dumb {
+		return value, nil
+	}
+	if value := os.Getenv("EDITOR"); value != "" {
+		return value, nil
+	}
+
+	if !interactive || dumb {
+		return "", nil
+	}
+	return DefaultEditor, nil
+}
+
+// Edit runs the editor command through the shell on the file at path and waits
+// for it to exit. Like in git, the command ":" leaves the file as it is.
+func Edit(command, path string) error {
+	if strings.TrimSpace(command) == ":" {
+		return nil
+	}
+
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.Command("cmd", "/C", command+` "`+path+`"`)
+	} else {
+		// "$@" passes the path as is, whatever characters it contains
+		cmd = exec.Command("sh", "-c", command+` "$@"`, command, path)
+	}
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("there was a problem with the editor '%s': %v", command, err)
+	}
+	return nil
+}
@@ -0,0 +1,120 @@
+package editor
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// fakeConfig テスト用のgit config
+func fakeConfig(values map[string]string) func(string) (string, bool, error) {
+	return func(key string) (string, bool, error) {
+		value, ok := values[key]
+		return value, ok, nil
+	}
+}
+
+func TestResolve(t *testing.T) {
+	tests := []struct {
+		name        string
+		env         map[string]string
+		config      map[string]string
+		interactive bool
+		expected    string
+	}{
+		{
+			name:        "default on a terminal",
+			env:         map[string]string{"TERM": "xterm"},
+			interactive: true,
+			expected:    DefaultEditor,
+		},
+		{
+			name:     "no default without a terminal",
+			env:      map[string]string{"TERM": "xterm"},
+			expected: "",
+		},
+		{
+			name:        "no default on a dumb terminal",
+			env:         map[string]string{"TERM": "dumb"},
+			interactive: true,
+			expected:    "",
+		},
+		{
+			name:     "EDITOR",
+			env:      map[string]string{"EDITOR": "nano"},
+			expected: "nano",
+		},
+		{
+			name:     "VISUAL over EDITOR",
+			env:      map[string]string{"TERM": "xterm", "VISUAL": "code --wait", "EDITOR": "nano"},
+			expected: "code --wait",
+		},
+		{
+			name:     "VISUAL is skipped on a dumb terminal",
+			env:      map[string]string{"TERM": "dumb", "VISUAL": "code --wait", "EDITOR": "nano"},
+			expected: "nano",
+		},
+		{
+			name:     "core.editor over VISUAL",
+			env:      map[string]string{"TERM": "xterm", "VISUAL": "code --wait"},
+			config:   map[string]string{"core.editor": "emacs"},
+			expected: "emacs",
+		},
+		{
+			name:     "GIT_EDITOR wins",
+			env:      map[string]string{"GIT_EDITOR": "ed", "EDITOR": "nano"},
+			config:   map[string]string{"core.editor": "emacs"},
+			expected: "ed",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// 環境変数を未設定にする（t.Setenvがテスト終了時に元の値へ戻す）
+			for _, key := range []string{"GIT_EDITOR", "VISUAL", "EDITOR", "TERM"} {
+				t.Setenv(key, "")
+				os.Unsetenv(key)
+			}
+			for key, value := range tt.env {
+				t.Setenv(key, value)
+			}
+
+			got, err := Resolve(fakeConfig(tt.config), tt.interactive)
+			if err != nil {
+				t.Fatalf("Resolve() error = %v", err)
+			}
+			if got != tt.expected {
+				t.Errorf("Resolve() = %q, want %q", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestEdit(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Skipping shell editor test on Windows")
+	}
+
+	// パスに空白があってもそのまま渡される
+	path := filepath.Join(t.TempDir(), "EDIT MSG")
+	os.WriteFile(path, []byte("# template\n"), 0644)
+	if err := Edit(`sed -i -e 's/template/edited/'`, path); err != nil {
+		t.Fatalf("Edit() error = %v", err)
+	}
+	if content, _ := os.ReadFile(path); string(content) != "# edited\n" {
+		t.Errorf("Expected the editor to change the file, got %q", content)
+	}
+
+	// ":" は何もしない
+	if err := Edit(":", filepath.Join(t.TempDir(), "missing")); err != nil {
+		t.Errorf("Expected ':' to do nothing, got %v", err)
+	}
+
+	// 失敗したエディタはエラー
+	err := Edit("false", path)
+	if err == nil || !strings.Contains(err.Error(), "problem with the editor 'false'") {
+		t.Errorf("Expected an editor error, got %v", err)
+	}
+}
@@ -0,0 +1,44 @@
+package git
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Errors matched with errors.Is to tell failures apart
+var (
+	// ErrNotRepository is returned when the current directory is not in a git repository
+	ErrNotRepository = errors.New("not a git repository")
+	// ErrNoStagedChanges is returned when a mini-commit is requested without staged changes
+	ErrNoStagedChanges = errors.New("no staged changes")
+	// ErrConflict is returned when a patch does not apply to the index or the working tree
+	ErrConflict = errors.New("patch does not apply")
+)
+
+// Error reports a git command that failed, with its exit code and what it wrote to stderr
+type Error struct {
+	Args []string
+	// ExitCode is -1 when git could not be started or was stopped by a signal,
+	// e.g. on timeout or cancellation
+	ExitCode int
+	Stderr   string
+	Err      error
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%v, stderr: %s", e.Err, e.Stderr)
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// ExitCode returns the exit code of the git command that caused err, or -1
+// when err does not come from a git command that ran to completion
+func ExitCode(err error) int {
+	var gitErr *Error
+	if errors.As(err, &gitErr) {
+		return gitErr.ExitCode
+	}
+	return -1
+}
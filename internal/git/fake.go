@@ -0,0 +1,121 @@
+package git
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+)
+
+// FakeRunner is a scriptable Runner for tests. Each command is answered by the
+// most recently added response whose arguments start the command line; a
+// command without a response fails. Every command run is recorded.
+type FakeRunner struct {
+	mu        sync.Mutex
+	responses []*FakeResponse
+	calls     []FakeCall
+}
+
+// FakeResponse is what FakeRunner answers to the commands it matches
+type FakeResponse struct {
+	args []string
+	// Stdout is the output of the command
+	Stdout string
+	// ExitCode other than zero makes the command fail with an *Error
+	ExitCode int
+	Stderr   string
+}
+
+// FakeCall is a command run by a FakeRunner, with the input it was given
+type FakeCall struct {
+	Args  []string
+	Env   []string
+	Stdin string
+}
+
+// NewFakeRunner returns a FakeRunner without responses
+func NewFakeRunner() *FakeRunner {
+	return &FakeRunner{}
+}
+
+// On adds a response, successful and without output until it is filled in,
+// for the commands whose arguments start with args
+func (f *FakeRunner) On(args ...string) *FakeResponse {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	resp := &FakeResponse{args: args}
+	f.responses = append(f.responses, resp)
+	return resp
+}
+
+// Calls returns the commands run so far
+func (f *FakeRunner) Calls() []FakeCall {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]FakeCall(nil), f.calls...)
+}
+
+// Ran reports whether a command starting with args was run
+func (f *FakeRunner) Ran(args ...string) bool {
+	for _, call := range f.Calls() {
+		if hasPrefix(call.Args, args) {
+			return true
+		}
+	}
+	return false
+}
+
+// Run answers c with its response, reading its input like git would
+func (f *FakeRunner) Run(ctx context.Context, c Command) (string, error) {
+	call := FakeCall{Args: c.Args, Env: c.Env}
+	if c.Stdin != nil {
+		data, err := io.ReadAll(c.Stdin)
+		if err != nil {
+			return "", &Error{Args: c.Args, ExitCode: -1, Err: err}
+		}
+		call.Stdin = string(data)
+	}
+
+	f.mu.Lock()
+	f.calls = append(f.calls, call)
+	var resp *FakeResponse
+	for i := len(f.responses) - 1; i >= 0; i-- {
+		if hasPrefix(c.Args, f.responses[i].args) {
+			resp = f.responses[i]
+			break
+		}
+	}
+	f.mu.Unlock()
+
+	if err := ctx.Err(); err != nil {
+		return "", &Error{Args: c.Args, ExitCode: -1, Err: err}
+	}
+	if resp == nil {
+		return "", &Error{Args: c.Args, ExitCode: -1, Err: fmt.Errorf("unexpected command: %s", argsString(c.Args))}
+	}
+	if resp.ExitCode != 0 {
+		return "", &Error{Args: c.Args, ExitCode: resp.ExitCode, Stderr: resp.Stderr,
+			Err: fmt.Errorf("exit status %d", resp.ExitCode)}
+	}
+	if c.Stdout != nil {
+		if _, err := io.Copy(c.Stdout, strings.NewReader(resp.Stdout)); err != nil {
+			return "", &Error{Args: c.Args, ExitCode: -1, Err: err}
+		}
+		return "", nil
+	}
+	return resp.Stdout, nil
+}
+
+// hasPrefix reports whether args starts with prefix
+func hasPrefix(args, prefix []string) bool {
+	if len(prefix) > len(args) {
+		return false
+	}
+	for i := range prefix {
+		if args[i] != prefix[i] {
+			return false
+		}
+	}
+	return true
+}
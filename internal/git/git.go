@@ -1,9 +1,10 @@
 package git
 
 import (
-	"bytes"
 	"fmt"
-	"os/exec"
+	"io"
+	"os"
+	"path/filepath"
 	"strings"
 )
 
@@ -11,29 +12,42 @@ import (
 
 // GetStagedChanges gets staged changes in patch format
 func GetStagedChanges() (string, error) {
-	cmd := exec.Command("git", "diff", "--cached")
-	var stdout, stderr bytes.Buffer
-	cmd.Stdout = &stdout
-	cmd.Stderr = &stderr
+	r, err := StagedChanges()
+	if err != nil {
+		return "", err
+	}
+	defer r.Close()
 
-	if err := cmd.Run(); err != nil {
-		return "", fmt.Errorf("failed to get staged changes: %v, stderr: %s", err, stderr.String())
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return "", err
 	}
+	return string(data), nil
+}
 
-	return stdout.String(), nil
+// StagedChanges streams the staged changes in patch format, so that large
+// patches need not fit in memory. A failure of git is returned by Read in place
+// of io.EOF, before the output can be mistaken for a complete patch.
+func StagedChanges() (io.ReadCloser, error) {
+	return stream(Command{Args: stagedDiffArgs, Patch: true, NoTimeout: true}, "failed to get staged changes"), nil
 }
 
+// stagedDiffArgs produce the patch of the staged changes. The user's diff
+// settings must not change it: colors, external and textconv drivers or other
+// prefixes would make it impossible to apply.
+var stagedDiffArgs = []string{"diff", "--cached", "--no-color", "--no-ext-diff", "--no-textconv",
+	"--src-prefix=a/", "--dst-prefix=b/"}
+
 // HasStagedChanges checks if there are staged changes
 func HasStagedChanges() (bool, error) {
-	cmd := exec.Command("git", "diff", "--cached", "--quiet")
-	err := cmd.Run()
+	_, err := runOutput("diff", "--cached", "--quiet")
 
 	// exit code 0: no changes, 1: has changes
 	if err != nil {
-		if exitError, ok := err.(*exec.ExitError); ok && exitError.ExitCode() == 1 {
+		if isExit(err, 1) {
 			return true, nil
 		}
-		return false, fmt.Errorf("failed to check staging status: %v", err)
+		return false, fmt.Errorf("failed to check staging status: %w", err)
 	}
 
 	return false, nil
@@ -41,24 +55,322 @@ func HasStagedChanges() (bool, error) {
 
 // ApplyPatch applies patch to staging area
 func ApplyPatch(patch string) error {
-	cmd := exec.Command("git", "apply", "--cached")
-	cmd.Stdin = strings.NewReader(patch)
+	return ApplyPatchFrom(strings.NewReader(patch))
+}
+
+// ApplyPatchFrom applies the patch read from r to staging area
+func ApplyPatchFrom(r io.Reader) error {
+	if _, err := run(Command{Args: []string{"apply", "--cached"}, Stdin: r, Patch: true, NoTimeout: true}); err != nil {
+		return conflictError(err)
+	}
+	return nil
+}
+
+// ApplyPatchToWorktreeFrom applies the patch read from r to the working tree,
+// leaving the index as it is
+func ApplyPatchToWorktreeFrom(r io.Reader) error {
+	if _, err := run(Command{Args: []string{"apply"}, Stdin: r, Patch: true, NoTimeout: true}); err != nil {
+		return conflictError(err)
+	}
+	return nil
+}
 
-	var stderr bytes.Buffer
-	cmd.Stderr = &stderr
+// GetConfig reads a git config value; the second result is false when the key is unset
+func GetConfig(key string) (string, bool, error) {
+	out, err := run(Command{Args: []string{"config", "--get", key}})
+	if err != nil {
+		// exit code 1: the key is not set
+		if isExit(err, 1) {
+			return "", false, nil
+		}
+		return "", false, fmt.Errorf("failed to read config '%s': %w", key, err)
+	}
+
+	return strings.TrimRight(out, "\n"), true, nil
+}
+
+// ConfigEntry is a git config value and where it was set
+type ConfigEntry struct {
+	Scope  string // system, global, local, worktree or command
+	Origin string // e.g. "file:.git/config"
+	Key    string // lower-cased except for the subsection, as git prints it
+	Value  string
+}
 
-	if err := cmd.Run(); err != nil {
-		return fmt.Errorf("failed to apply patch: %v, stderr: %s", err, stderr.String())
+// ListConfig reads the config values whose keys match the regular expression,
+// in the order git reads them, so that a later value overrides an earlier one
+func ListConfig(pattern string) ([]ConfigEntry, error) {
+	out, err := run(Command{Args: []string{"config", "--show-scope", "--show-origin", "-z", "--get-regexp", pattern}})
+	if err != nil {
+		// exit code 1: no key matches
+		if isExit(err, 1) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read config: %w", err)
 	}
 
+	// Each entry is "scope NUL origin NUL key LF value NUL"; a key set without
+	// "=" has no LF and no value
+	fields := strings.Split(out, "\x00")
+	var entries []ConfigEntry
+	for i := 0; i+2 < len(fields); i += 3 {
+		key, value, _ := strings.Cut(fields[i+2], "\n")
+		entries = append(entries, ConfigEntry{Scope: fields[i], Origin: fields[i+1], Key: key, Value: value})
+	}
+	return entries, nil
+}
+
+// CurrentBranch returns the short name of the checked-out branch, or "" on a detached HEAD
+func CurrentBranch() (string, error) {
+	out, err := runOutput("symbolic-ref", "--quiet", "--short", "HEAD")
+	if err != nil {
+		// exit code 1: HEAD is detached
+		if isExit(err, 1) {
+			return "", nil
+		}
+		return "", fmt.Errorf("failed to get current branch: %w", err)
+	}
+
+	return strings.TrimSpace(out), nil
+}
+
+// AuthorIdent returns the configured author as "Name <email>"
+func AuthorIdent() (string, error) {
+	out, err := run(Command{Args: []string{"var", "GIT_AUTHOR_IDENT"}})
+	if err != nil {
+		return "", fmt.Errorf("failed to get author identity: %w", err)
+	}
+
+	// The ident ends with "<timestamp> <timezone>", which is recorded separately
+	ident := strings.TrimSpace(out)
+	if end := strings.LastIndex(ident, ">"); end >= 0 {
+		ident = ident[:end+1]
+	}
+	return ident, nil
+}
+
+// HeadCommit returns the commit HEAD points to, or "" before the first commit
+func HeadCommit() (string, error) {
+	out, err := runOutput("rev-parse", "--verify", "--quiet", "HEAD^{commit}")
+	if err != nil {
+		// exit code 1: HEAD does not point to a commit yet
+		if isExit(err, 1) {
+			return "", nil
+		}
+		return "", fmt.Errorf("failed to resolve HEAD: %w", err)
+	}
+
+	return strings.TrimSpace(out), nil
+}
+
+// TreeWithPatch returns the tree obtained by applying patch on top of base
+// (a commit or tree, or "" for the empty tree). A temporary index is used so
+// the user's index and working tree are left untouched.
+func TreeWithPatch(base, patch string) (string, error) {
+	var tree string
+	err := withTempIndex(base, func(env []string) error {
+		if patch != "" {
+			if _, err := runPatch(env, patch, "apply", "--cached"); err != nil {
+				return fmt.Errorf("failed to apply patch: %w", conflictError(err))
+			}
+		}
+		out, err := runWithEnv(env, "", "write-tree")
+		if err != nil {
+			return fmt.Errorf("failed to write tree: %w", err)
+		}
+		tree = strings.TrimSpace(out)
+		return nil
+	})
+	return tree, err
+}
+
+// CheckPatchAgainst reports whether patch applies to base (or could be reverted
+// from it, with reverse) without touching the user's index
+func CheckPatchAgainst(base, patch string, reverse bool) error {
+	return CheckPatchAgainstFrom(base, strings.NewReader(patch), reverse)
+}
+
+// CheckPatchAgainstFrom is CheckPatchAgainst for the patch read from r
+func CheckPatchAgainstFrom(base string, r io.Reader, reverse bool) error {
+	return withTempIndex(base, func(env []string) error {
+		args := []string{"apply", "--cached", "--check"}
+		if reverse {
+			args = append(args, "-R")
+		}
+		if _, err := run(Command{Args: args, Env: env, Stdin: r, Patch: true, NoTimeout: true}); err != nil {
+			return conflictError(err)
+		}
+		return nil
+	})
+}
+
+// withTempIndex runs fn with GIT_INDEX_FILE pointing to a temporary index
+// holding base ("" for the empty tree)
+func withTempIndex(base string, fn func(env []string) error) error {
+	dir, err := os.MkdirTemp("", "mini-commit-index-")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary index: %w", err)
+	}
+	defer os.RemoveAll(dir)
+	env := []string{"GIT_INDEX_FILE=" + filepath.Join(dir, "index")}
+
+	readTree := []string{"read-tree", "--empty"}
+	if base != "" {
+		readTree = []string{"read-tree", base}
+	}
+	if _, err := runWithEnv(env, "", readTree...); err != nil {
+		return fmt.Errorf("failed to read base tree '%s': %w", base, err)
+	}
+	return fn(env)
+}
+
+// WorktreeTree returns the tree the working tree would be staged as by
+// "git add -A", with the untracked files that are not ignored. A copy of the
+// index is used, so the user's staged changes are left untouched and the files
+// git already knows to be unchanged are not read again.
+func WorktreeTree() (string, error) {
+	out, err := runOutput("rev-parse", "--git-path", "index")
+	if err != nil {
+		return "", fmt.Errorf("failed to find the index: %w", err)
+	}
+	index, err := os.ReadFile(strings.TrimSpace(out))
+	if err != nil && !os.IsNotExist(err) {
+		return "", fmt.Errorf("failed to read the index: %w", err)
+	}
+
+	dir, err := os.MkdirTemp("", "mini-commit-index-")
+	if err != nil {
+		return "", fmt.Errorf("failed to create temporary index: %w", err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "index")
+	// Without an index yet, git starts from an empty one
+	if index != nil {
+		if err := os.WriteFile(path, index, 0644); err != nil {
+			return "", fmt.Errorf("failed to create temporary index: %w", err)
+		}
+	}
+	env := []string{"GIT_INDEX_FILE=" + path}
+
+	// The user's excludes decide which untracked files are left out
+	if _, err := run(Command{Args: []string{"add", "-A"}, Env: env}); err != nil {
+		return "", fmt.Errorf("failed to add the working tree: %w", err)
+	}
+	out, err = runWithEnv(env, "", "write-tree")
+	if err != nil {
+		return "", fmt.Errorf("failed to write tree: %w", err)
+	}
+	return strings.TrimSpace(out), nil
+}
+
+// TreeChanges returns the changes from HEAD to tree, in the same format as
+// StagedChanges
+func TreeChanges(tree string) (string, error) {
+	var changes string
+	err := withTempIndex(tree, func(env []string) error {
+		out, err := runPatch(env, "", stagedDiffArgs...)
+		if err != nil {
+			return fmt.Errorf("failed to get changes: %w", err)
+		}
+		changes = out
+		return nil
+	})
+	return changes, err
+}
+
+// DiffTrees returns the patch between two trees
+func DiffTrees(from, to string) (string, error) {
+	out, err := runPatch(nil, "", "diff-tree", "-p", "--binary", "--no-color", "--no-ext-diff", from, to)
+	if err != nil {
+		return "", fmt.Errorf("failed to diff trees: %w", err)
+	}
+	return out, nil
+}
+
+// DiffAgainstTree returns the patch that turns the index (cached) or the working
+// tree into tree, limited to paths
+func DiffAgainstTree(tree string, cached bool, paths []string) (string, error) {
+	// -R swaps the prefixes as well, so swap them back to get the usual a/ and b/
+	args := []string{"diff", "--no-color", "--no-ext-diff", "--binary", "-R", "--src-prefix=b/", "--dst-prefix=a/"}
+	if cached {
+		args = append(args, "--cached")
+	}
+	args = append(args, tree, "--")
+	args = append(args, paths...)
+
+	out, err := runPatch(nil, "", args...)
+	if err != nil {
+		return "", fmt.Errorf("failed to diff against tree: %w", err)
+	}
+	return out, nil
+}
+
+// CheckPatch reports whether patch applies to the index (cached) or the working
+// tree without changing either; reverse checks whether it could be reverted
+func CheckPatch(patch string, cached, reverse bool) error {
+	args := []string{"apply", "--check"}
+	if cached {
+		args = append(args, "--cached")
+	}
+	if reverse {
+		args = append(args, "-R")
+	}
+
+	if _, err := runPatch(nil, patch, args...); err != nil {
+		return conflictError(err)
+	}
 	return nil
 }
 
+// runWithEnv runs git with env added to its environment and stdin as its input
+func runWithEnv(env []string, stdin string, args ...string) (string, error) {
+	return run(Command{Args: args, Env: env, Stdin: strings.NewReader(stdin)})
+}
+
+// runPatch is runWithEnv for a command producing or applying a patch, which
+// does not read the user's configuration
+func runPatch(env []string, stdin string, args ...string) (string, error) {
+	return run(Command{Args: args, Env: env, Stdin: strings.NewReader(stdin), Patch: true})
+}
+
+// HookPaths returns the absolute path of the hooks directory, which
+// core.hooksPath can move, and the top of the working tree hooks run in
+func HookPaths() (string, string, error) {
+	out, err := run(Command{Args: []string{"rev-parse", "--show-toplevel", "--git-path", "hooks"}})
+	if err != nil {
+		return "", "", fmt.Errorf("failed to find the hooks directory: %w", err)
+	}
+	workTree, hooksPath, _ := strings.Cut(strings.TrimRight(out, "\n"), "\n")
+	dir, err := filepath.Abs(hooksPath)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to find the hooks directory: %w", err)
+	}
+	return dir, workTree, nil
+}
 
 // IsGitRepository checks if current directory is a Git repository
 func IsGitRepository() bool {
-	cmd := exec.Command("git", "rev-parse", "--git-dir")
-	err := cmd.Run()
-	return err == nil
+	return CheckRepository() == nil
 }
 
+// CheckRepository returns an error matching ErrNotRepository when the current
+// directory is not in a git repository, or the failure of git itself
+func CheckRepository() error {
+	_, err := runOutput("rev-parse", "--git-dir")
+	if isExit(err, 128) {
+		return ErrNotRepository
+	}
+	if err != nil {
+		return fmt.Errorf("failed to find the git repository: %w", err)
+	}
+	return nil
+}
+
+// conflictError reports err, a failure of git apply, as ErrConflict when git
+// found that the patch does not apply (exit code 1)
+func conflictError(err error) error {
+	if isExit(err, 1) {
+		return fmt.Errorf("%w: %v", ErrConflict, err)
+	}
+	return err
+}
@@ -1,8 +1,11 @@
 package git
 
 import (
+	"bytes"
+	"io"
 	"os"
 	"os/exec"
+	"path/filepath"
 	"strings"
 	"testing"
 
@@ -162,8 +165,59 @@ index 0000000..3b18e51
 	}
 }
 
+func TestStagedChangesStream(t *testing.T) {
+	repo := testutils.NewTestGitRepo(t)
+	defer repo.Cleanup()
+
+	content := strings.Repeat("streamed line\n", 10000)
+	if err := repo.CreateTestFile("large.txt", content); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+	if err := repo.StageFile("large.txt"); err != nil {
+		t.Fatalf("Failed to stage file: %v", err)
+	}
+	expected, err := GetStagedChanges()
+	if err != nil {
+		t.Fatalf("GetStagedChanges() error = %v", err)
+	}
 
+	// 1. ストリームで読んだ内容は GetStagedChanges と同じ
+	r, err := StagedChanges()
+	if err != nil {
+		t.Fatalf("StagedChanges() error = %v", err)
+	}
+	var buf bytes.Buffer
+	if _, err := io.Copy(&buf, r); err != nil {
+		t.Fatalf("Failed to read staged changes: %v", err)
+	}
+	if err := r.Close(); err != nil {
+		t.Errorf("Close() error = %v", err)
+	}
+	if buf.String() != expected {
+		t.Errorf("Expected the streamed patch to match GetStagedChanges")
+	}
+
+	// 2. 途中で閉じてもgitは終了する
+	r, err = StagedChanges()
+	if err != nil {
+		t.Fatalf("StagedChanges() error = %v", err)
+	}
+	r.Read(make([]byte, 16))
+	if err := r.Close(); err != nil {
+		t.Errorf("Close() error = %v", err)
+	}
 
+	// 3. ステージングを戻してからストリームのパッチを適用する
+	if err := exec.Command("git", "reset", "-q").Run(); err != nil {
+		t.Fatalf("Failed to reset: %v", err)
+	}
+	if err := ApplyPatchFrom(strings.NewReader(expected)); err != nil {
+		t.Fatalf("ApplyPatchFrom() error = %v", err)
+	}
+	if patch, _ := GetStagedChanges(); patch != expected {
+		t.Errorf("Expected the applied patch to be staged again")
+	}
+}
 
 func TestGitOperationsIntegration(t *testing.T) {
 	repo := testutils.NewTestGitRepo(t)
@@ -244,6 +298,18 @@ func TestGitErrorHandling(t *testing.T) {
 		}
 	})
 
+	t.Run("StagedChanges in non-git directory", func(t *testing.T) {
+		r, err := StagedChanges()
+		if err != nil {
+			return
+		}
+		defer r.Close()
+		// gitの失敗はEOFの代わりにReadが返す
+		if _, err := io.ReadAll(r); err == nil {
+			t.Errorf("Expected error reading StagedChanges in non-git directory")
+		}
+	})
+
 	t.Run("HasStagedChanges in non-git directory", func(t *testing.T) {
 		_, err := HasStagedChanges()
 		if err == nil {
@@ -252,3 +318,253 @@ func TestGitErrorHandling(t *testing.T) {
 	})
 
 }
+
+func TestGlobalConfigIgnoredForPatches(t *testing.T) {
+	repo := testutils.NewTestGitRepo(t)
+	defer repo.Cleanup()
+
+	// ~/.gitconfig の diff.context や diff.noprefix はパッチを変えない
+	global := filepath.Join(t.TempDir(), "gitconfig")
+	if err := os.WriteFile(global, []byte("[diff]\n\tcontext = 0\n\tnoprefix = true\n[minicommit]\n\tlint = true\n"), 0644); err != nil {
+		t.Fatalf("Failed to write global config: %v", err)
+	}
+	t.Setenv("GIT_CONFIG_GLOBAL", global)
+
+	repo.CreateTestFile("test.txt", "1\n2\n3\n")
+	repo.StageFile("test.txt")
+	repo.CommitFile("Initial commit")
+	repo.CreateTestFile("test.txt", "1\ntwo\n3\n")
+	repo.StageFile("test.txt")
+
+	patch, err := GetStagedChanges()
+	if err != nil {
+		t.Fatalf("GetStagedChanges() error = %v", err)
+	}
+	if !strings.Contains(patch, "+++ b/test.txt") || !strings.Contains(patch, "@@ -1,3 +1,3 @@") {
+		t.Errorf("Expected the patch to ignore the global config, got:\n%s", patch)
+	}
+
+	// 設定を読むコマンドはグローバルな設定も使う
+	if value, ok, err := GetConfig("minicommit.lint"); err != nil || !ok || value != "true" {
+		t.Errorf("GetConfig() = %q, %v, %v; want the global value", value, ok, err)
+	}
+}
+
+func TestSafeDirectoryFromGlobalConfig(t *testing.T) {
+	if os.Geteuid() != 0 {
+		t.Skip("changing the owner of the repository needs root")
+	}
+	repo := testutils.NewTestGitRepo(t)
+	defer repo.Cleanup()
+
+	repo.CreateTestFile("test.txt", "1\n")
+	repo.StageFile("test.txt")
+	repo.CommitFile("Initial commit")
+	repo.CreateTestFile("test.txt", "one\n")
+	repo.StageFile("test.txt")
+
+	// 他のユーザーが所有するリポジトリを ~/.gitconfig の safe.directory で信頼する
+	if out, err := exec.Command("chown", "-R", "nobody", repo.RepoPath).CombinedOutput(); err != nil {
+		t.Skipf("chown failed: %v, %s", err, out)
+	}
+	global := filepath.Join(t.TempDir(), "gitconfig")
+	if err := os.WriteFile(global, []byte("[safe]\n\tdirectory = "+repo.RepoPath+"\n"), 0644); err != nil {
+		t.Fatalf("Failed to write global config: %v", err)
+	}
+	t.Setenv("GIT_CONFIG_GLOBAL", global)
+
+	if err := CheckRepository(); err != nil {
+		t.Fatalf("CheckRepository() error = %v", err)
+	}
+	// グローバルな設定を読まないパッチのコマンドも safe.directory は守る
+	patch, err := GetStagedChanges()
+	if err != nil {
+		t.Fatalf("GetStagedChanges() error = %v", err)
+	}
+	if !strings.Contains(patch, "+one") {
+		t.Errorf("Expected the staged change, got:\n%s", patch)
+	}
+}
+
+func TestGetConfig(t *testing.T) {
+	repo := testutils.NewTestGitRepo(t)
+	defer repo.Cleanup()
+
+	// 設定済みのキー
+	value, ok, err := GetConfig("user.name")
+	if err != nil {
+		t.Fatalf("GetConfig() error = %v", err)
+	}
+	if !ok || value != "Test User" {
+		t.Errorf("Expected 'Test User', but got %q (ok=%v)", value, ok)
+	}
+
+	// 未設定のキー
+	_, ok, err = GetConfig("minicommit.doesNotExist")
+	if err != nil {
+		t.Fatalf("GetConfig() error = %v", err)
+	}
+	if ok {
+		t.Errorf("Expected unset key to report ok=false")
+	}
+}
+
+func TestListConfig(t *testing.T) {
+	repo := testutils.NewTestGitRepo(t)
+	defer repo.Cleanup()
+
+	exec.Command("git", "config", "minicommit.listFormat", "oneline").Run()
+	exec.Command("git", "config", "--add", "minicommit.listFormat", "default").Run()
+
+	entries, err := ListConfig(`^minicommit\.`)
+	if err != nil {
+		t.Fatalf("ListConfig() error = %v", err)
+	}
+	// 同じキーは読まれた順に並び、後のものが優先される
+	if len(entries) != 2 || entries[1].Key != "minicommit.listformat" || entries[1].Value != "default" ||
+		entries[1].Scope != "local" || entries[1].Origin != "file:.git/config" {
+		t.Errorf("Unexpected entries: %+v", entries)
+	}
+
+	entries, err = ListConfig(`^minicommit\.doesNotExist$`)
+	if err != nil || len(entries) != 0 {
+		t.Errorf("Expected no entries, got %+v (%v)", entries, err)
+	}
+}
+
+func TestCurrentBranchAndAuthor(t *testing.T) {
+	repo := testutils.NewTestGitRepo(t)
+	defer repo.Cleanup()
+
+	if err := exec.Command("git", "checkout", "-q", "-b", "topic").Run(); err != nil {
+		t.Fatalf("Failed to create branch: %v", err)
+	}
+
+	branch, err := CurrentBranch()
+	if err != nil {
+		t.Fatalf("CurrentBranch() error = %v", err)
+	}
+	if branch != "topic" {
+		t.Errorf("Expected branch 'topic', but got %q", branch)
+	}
+
+	author, err := AuthorIdent()
+	if err != nil {
+		t.Fatalf("AuthorIdent() error = %v", err)
+	}
+	if author != "Test User <test@example.com>" {
+		t.Errorf("Expected 'Test User <test@example.com>', but got %q", author)
+	}
+}
+
+func TestTreeWithPatchAndDiffTrees(t *testing.T) {
+	repo := testutils.NewTestGitRepo(t)
+	defer repo.Cleanup()
+
+	// 初回コミット前はHEADが空
+	head, err := HeadCommit()
+	if err != nil {
+		t.Fatalf("HeadCommit() error = %v", err)
+	}
+	if head != "" {
+		t.Errorf("Expected empty HEAD before the first commit, but got %q", head)
+	}
+
+	repo.CreateTestFile("a.txt", "one\n")
+	repo.StageFile("a.txt")
+	repo.CommitFile("initial")
+	head, err = HeadCommit()
+	if err != nil || head == "" {
+		t.Fatalf("HeadCommit() = %q, %v", head, err)
+	}
+
+	repo.CreateTestFile("a.txt", "one\ntwo\n")
+	repo.StageFile("a.txt")
+	patch, err := GetStagedChanges()
+	if err != nil {
+		t.Fatalf("GetStagedChanges() error = %v", err)
+	}
+
+	before, err := TreeWithPatch(head, "")
+	if err != nil {
+		t.Fatalf("TreeWithPatch() error = %v", err)
+	}
+	after, err := TreeWithPatch(head, patch)
+	if err != nil {
+		t.Fatalf("TreeWithPatch() error = %v", err)
+	}
+
+	diff, err := DiffTrees(before, after)
+	if err != nil {
+		t.Fatalf("DiffTrees() error = %v", err)
+	}
+	if !strings.Contains(diff, "+two") {
+		t.Errorf("Expected diff to add 'two', but got:\n%s", diff)
+	}
+
+	// 一時インデックスを使うため、ユーザーのインデックスは変更されない
+	staged, _ := GetStagedChanges()
+	if staged != patch {
+		t.Errorf("Expected the index to be untouched")
+	}
+
+	// 適用できないパッチはエラー
+	if _, err := TreeWithPatch("", patch); err == nil {
+		t.Errorf("Expected an error when the patch does not apply")
+	}
+
+	// HEADに対しては適用できるが、まだ取り込まれていない
+	if err := CheckPatchAgainst(head, patch, false); err != nil {
+		t.Errorf("Expected the patch to apply to HEAD: %v", err)
+	}
+	if err := CheckPatchAgainst(head, patch, true); err == nil {
+		t.Errorf("Expected the patch not to be integrated into HEAD")
+	}
+	if err := CheckPatchAgainst(after, patch, true); err != nil {
+		t.Errorf("Expected the patch to be revertible from the tree that contains it: %v", err)
+	}
+}
+
+func TestWorktreeTreeAndTreeChanges(t *testing.T) {
+	repo := testutils.NewTestGitRepo(t)
+	defer repo.Cleanup()
+
+	// 1. インデックスがなくても、追跡されていないファイルを含む
+	repo.CreateTestFile("a.txt", "a\n")
+	repo.CreateTestFile("ignored.log", "log\n")
+	repo.CreateTestFile(".gitignore", "*.log\n")
+	tree, err := WorktreeTree()
+	if err != nil {
+		t.Fatalf("WorktreeTree() error = %v", err)
+	}
+	changes, err := TreeChanges(tree)
+	if err != nil {
+		t.Fatalf("TreeChanges() error = %v", err)
+	}
+	if !strings.Contains(changes, "+++ b/a.txt") || strings.Contains(changes, "ignored.log") {
+		t.Errorf("Unexpected changes:\n%s", changes)
+	}
+
+	// 2. ステージングした変更と同じ形式で、ユーザーのインデックスは変更されない
+	repo.StageFile("a.txt")
+	repo.StageFile(".gitignore")
+	staged, _ := GetStagedChanges()
+	if changes != staged {
+		t.Errorf("Expected the format of the staged changes:\n%s\nwant:\n%s", changes, staged)
+	}
+	repo.CreateTestFile("a.txt", "a\nb\n")
+	if again, err := WorktreeTree(); err != nil || again == tree {
+		t.Errorf("Expected the tree to follow the working tree, got %q, %v", again, err)
+	}
+	if after, _ := GetStagedChanges(); after != staged {
+		t.Errorf("Expected the index to be untouched")
+	}
+
+	// 3. 作業ツリーがHEADと同じなら変更はない
+	repo.StageFile("a.txt")
+	repo.CommitFile("initial")
+	tree, _ = WorktreeTree()
+	if changes, err := TreeChanges(tree); err != nil || changes != "" {
+		t.Errorf("Expected no changes, got %q, %v", changes, err)
+	}
+}
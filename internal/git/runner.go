@@ -0,0 +1,232 @@
+package git
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Command is one invocation of git
+type Command struct {
+	Args []string
+	// Env is added to the environment git runs with, e.g. GIT_INDEX_FILE
+	Env []string
+	// Stdin is given to git as its input; nil means no input
+	Stdin io.Reader
+	// Stdout receives the output of git as it is produced. When nil, the output
+	// is collected and returned by Run.
+	Stdout io.Writer
+	// Patch runs git without the system-wide and global configuration, for
+	// commands whose output is parsed as a patch or that apply one, so that
+	// aliases and diff, apply or color settings cannot change them. Other
+	// commands, such as finding the repository, read the user's configuration.
+	Patch bool
+	// NoTimeout exempts a command streaming a patch, which runs as long as the
+	// patch is large, from the timeout of the runner; only the context stops it
+	NoTimeout bool
+}
+
+// Runner runs git commands. The functions of this package use the runner set
+// with SetRunner, an ExecRunner unless tests replace it with a FakeRunner.
+type Runner interface {
+	// Run runs c and returns its output, unless c.Stdout is set. A command that
+	// fails or cannot be started is reported with an *Error.
+	Run(ctx context.Context, c Command) (string, error)
+}
+
+// ExecRunner runs the git executable found in PATH
+type ExecRunner struct {
+	// Timeout stops a command that runs longer; zero means no limit
+	Timeout time.Duration
+}
+
+// sanitizedEnv keeps the output of git independent of the user's locale and,
+// for patches, of the system-wide and global configuration, so that ~/.gitconfig
+// cannot change the patches produced or how they apply. The configuration of
+// the repository is kept, and so are the safeDirs taken from the configuration
+// left out: git also honours safe.directory on the command line, which
+// GIT_CONFIG_COUNT adds to, so repositories the user trusts stay usable.
+func sanitizedEnv(patch bool, safeDirs []string) []string {
+	env := append(os.Environ(), "LC_ALL=C", "GIT_TERMINAL_PROMPT=0")
+	if !patch {
+		return env
+	}
+	env = append(env, "GIT_CONFIG_NOSYSTEM=1", "GIT_CONFIG_GLOBAL="+os.DevNull)
+	if len(safeDirs) > 0 {
+		count, _ := strconv.Atoi(os.Getenv("GIT_CONFIG_COUNT"))
+		for i, dir := range safeDirs {
+			env = append(env, fmt.Sprintf("GIT_CONFIG_KEY_%d=safe.directory", count+i),
+				fmt.Sprintf("GIT_CONFIG_VALUE_%d=%s", count+i, dir))
+		}
+		env = append(env, fmt.Sprintf("GIT_CONFIG_COUNT=%d", count+len(safeDirs)))
+	}
+	return env
+}
+
+// safeDirectories returns the safe.directory entries of the system-wide and
+// global configuration, the scopes patch commands do not read. Entries of the
+// repository's own configuration are never trusted by git and are left out.
+func safeDirectories(ctx context.Context) []string {
+	cmd := exec.CommandContext(ctx, "git", "config", "-z", "--show-scope", "--get-all", "safe.directory")
+	cmd.Env = sanitizedEnv(false, nil)
+	// git config exits with 1 when the key is unset
+	out, err := cmd.Output()
+	if err != nil {
+		return nil
+	}
+	var dirs []string
+	fields := strings.Split(string(out), "\x00")
+	for i := 0; i+1 < len(fields); i += 2 {
+		if fields[i] == "system" || fields[i] == "global" {
+			dirs = append(dirs, fields[i+1])
+		}
+	}
+	return dirs
+}
+
+// Run runs c with exec, stopping git when ctx is done or, unless c is exempt
+// from it, the timeout expires
+func (r *ExecRunner) Run(ctx context.Context, c Command) (string, error) {
+	timeout := r.Timeout
+	if c.NoTimeout {
+		timeout = 0
+	}
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	var safeDirs []string
+	if c.Patch {
+		safeDirs = safeDirectories(ctx)
+	}
+	cmd := exec.CommandContext(ctx, "git", c.Args...)
+	cmd.Env = append(sanitizedEnv(c.Patch, safeDirs), c.Env...)
+	cmd.Stdin = c.Stdin
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	if c.Stdout != nil {
+		cmd.Stdout = c.Stdout
+	}
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		gitErr := &Error{Args: c.Args, ExitCode: -1, Stderr: stderr.String(), Err: err}
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			gitErr.ExitCode = exitErr.ExitCode()
+		}
+		if ctxErr := ctx.Err(); errors.Is(ctxErr, context.DeadlineExceeded) && timeout > 0 {
+			gitErr.Err = fmt.Errorf("timed out after %v: %w", timeout, ctxErr)
+		} else if ctxErr != nil && !errors.Is(err, ctxErr) {
+			gitErr.Err = fmt.Errorf("%v (%v)", err, ctxErr)
+		}
+		return "", gitErr
+	}
+	return stdout.String(), nil
+}
+
+var (
+	runnerMutex   sync.RWMutex
+	runner        Runner = &ExecRunner{}
+	runnerContext        = context.Background()
+)
+
+// SetRunner makes the functions of this package run git through r and returns
+// a function restoring the previous runner
+func SetRunner(r Runner) (restore func()) {
+	runnerMutex.Lock()
+	defer runnerMutex.Unlock()
+	previous := runner
+	runner = r
+	return func() { SetRunner(previous) }
+}
+
+// SetTimeout makes the ExecRunner of this package stop commands running longer
+// than timeout; zero means no limit. A runner set with SetRunner is left as is.
+func SetTimeout(timeout time.Duration) {
+	runnerMutex.Lock()
+	defer runnerMutex.Unlock()
+	if _, ok := runner.(*ExecRunner); ok {
+		runner = &ExecRunner{Timeout: timeout}
+	}
+}
+
+// SetContext makes the functions of this package run git under ctx, so that
+// cancelling it stops the running command, and returns a function restoring
+// the previous context
+func SetContext(ctx context.Context) (restore func()) {
+	runnerMutex.Lock()
+	defer runnerMutex.Unlock()
+	previous := runnerContext
+	runnerContext = ctx
+	return func() { SetContext(previous) }
+}
+
+// run runs c through the current runner
+func run(c Command) (string, error) {
+	runnerMutex.RLock()
+	r, ctx := runner, runnerContext
+	runnerMutex.RUnlock()
+	return r.Run(ctx, c)
+}
+
+// runOutput runs git with args and returns its output
+func runOutput(args ...string) (string, error) {
+	return run(Command{Args: args})
+}
+
+// stream runs c in the background and returns its output as a stream. The
+// error of the command, prefixed with action, is returned by Read in place of
+// io.EOF, so that truncated output is never mistaken for the whole. Close stops
+// the command if the output was not read to the end and waits for it.
+func stream(c Command, action string) io.ReadCloser {
+	pr, pw := io.Pipe()
+	s := &commandStream{pipe: pr, done: make(chan struct{})}
+	c.Stdout = pw
+	go func() {
+		defer close(s.done)
+		if _, err := run(c); err != nil {
+			pw.CloseWithError(fmt.Errorf("%s: %w", action, err))
+			return
+		}
+		pw.Close()
+	}()
+	return s
+}
+
+// commandStream is the output of a command started by stream
+type commandStream struct {
+	pipe *io.PipeReader
+	done chan struct{}
+}
+
+func (s *commandStream) Read(p []byte) (int, error) {
+	return s.pipe.Read(p)
+}
+
+func (s *commandStream) Close() error {
+	// Closing the pipe makes git fail to write, which stops it
+	s.pipe.CloseWithError(errors.New("output no longer read"))
+	<-s.done
+	return nil
+}
+
+// isExit reports whether err is git exiting with code
+func isExit(err error, code int) bool {
+	return err != nil && ExitCode(err) == code
+}
+
+// argsString formats git arguments for messages
+func argsString(args []string) string {
+	return "git " + strings.Join(args, " ")
+}
@@ -0,0 +1,148 @@
+package git
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"git-mini-commit/testutils"
+)
+
+func TestExecRunner(t *testing.T) {
+	repo := testutils.NewTestGitRepo(t)
+	defer repo.Cleanup()
+
+	runner := &ExecRunner{}
+
+	// 1. 出力を返す
+	out, err := runner.Run(context.Background(), Command{Args: []string{"hash-object", "--stdin"}, Stdin: strings.NewReader("hello\n")})
+	if err != nil || strings.TrimSpace(out) != "ce013625030ba8dba906f756967f9e9ca394464a" {
+		t.Errorf("Run() = %q, %v", out, err)
+	}
+
+	// 2. 失敗は終了コードとstderrを持つ *Error になる
+	_, err = runner.Run(context.Background(), Command{Args: []string{"cat-file", "-t", "deadbeef"}})
+	var gitErr *Error
+	if !errors.As(err, &gitErr) || gitErr.ExitCode != 128 || !strings.Contains(gitErr.Stderr, "deadbeef") {
+		t.Errorf("Expected an *Error with exit code 128 and stderr, got %#v", err)
+	}
+	if ExitCode(err) != 128 || ExitCode(errors.New("other")) != -1 {
+		t.Errorf("Unexpected ExitCode results")
+	}
+
+	// 3. タイムアウトすると終わらない入力を待っているgitを止める
+	blocked, writer, err := os.Pipe()
+	if err != nil {
+		t.Fatalf("os.Pipe() error = %v", err)
+	}
+	defer blocked.Close()
+	defer writer.Close()
+	runner.Timeout = 100 * time.Millisecond
+	start := time.Now()
+	_, err = runner.Run(context.Background(), Command{Args: []string{"hash-object", "--stdin"}, Stdin: blocked})
+	if ExitCode(err) != -1 || !strings.Contains(err.Error(), "deadline exceeded") {
+		t.Errorf("Expected the command to time out, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("Expected the timeout to stop git, took %v", elapsed)
+	}
+
+	// 4. キャンセルされたコンテキストでは実行しない
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := (&ExecRunner{}).Run(ctx, Command{Args: []string{"--version"}}); err == nil {
+		t.Errorf("Expected a cancelled context to stop git")
+	}
+}
+
+func TestSanitizedEnv(t *testing.T) {
+	t.Setenv("GIT_CONFIG_COUNT", "1")
+
+	env := strings.Join(sanitizedEnv(true, []string{"/srv/repo"}), "\n")
+	if !strings.Contains(env, "LC_ALL=C") || !strings.Contains(env, "GIT_CONFIG_NOSYSTEM=1") ||
+		!strings.Contains(env, "GIT_CONFIG_GLOBAL="+os.DevNull) {
+		t.Errorf("Expected a C locale without system and global config, got %s", env)
+	}
+	// 読まない設定の safe.directory は既存のコマンドライン設定の後ろに渡す
+	if !strings.Contains(env, "GIT_CONFIG_KEY_1=safe.directory\nGIT_CONFIG_VALUE_1=/srv/repo\nGIT_CONFIG_COUNT=2") {
+		t.Errorf("Expected safe.directory to be passed on the command line, got %s", env)
+	}
+	// パッチ以外のコマンドはシステム全体とグローバルな設定も使う
+	if env := strings.Join(sanitizedEnv(false, nil), "\n"); strings.Contains(env, "GIT_CONFIG_NOSYSTEM") ||
+		strings.Contains(env, "GIT_CONFIG_GLOBAL="+os.DevNull) {
+		t.Errorf("Expected the system and global config to be kept outside patches")
+	}
+}
+
+func TestSetTimeout(t *testing.T) {
+	defer SetRunner(&ExecRunner{})()
+
+	// 既定のExecRunnerにタイムアウトを設定する
+	SetTimeout(time.Minute)
+	if r, ok := runner.(*ExecRunner); !ok || r.Timeout != time.Minute {
+		t.Errorf("Expected an ExecRunner with a 1m timeout, got %#v", runner)
+	}
+
+	// テストが差し替えたランナーはそのまま
+	fake := NewFakeRunner()
+	SetRunner(fake)
+	SetTimeout(time.Second)
+	if runner != Runner(fake) {
+		t.Errorf("Expected the fake runner to be kept, got %#v", runner)
+	}
+}
+
+func TestFunctionsWithFakeRunner(t *testing.T) {
+	fake := NewFakeRunner()
+	defer SetRunner(fake)()
+
+	fake.On("diff", "--cached", "--quiet").ExitCode = 1
+	fake.On("rev-parse", "--verify").ExitCode = 1
+	fake.On("config", "--get", "color.diff").ExitCode = 1
+	fake.On("config", "--get", "user.name").Stdout = "Test User\n"
+	fake.On("apply", "--cached")
+
+	// 終了コード1は変更あり・コミットなし・未設定を意味する
+	if has, err := HasStagedChanges(); err != nil || !has {
+		t.Errorf("HasStagedChanges() = %v, %v", has, err)
+	}
+	if head, err := HeadCommit(); err != nil || head != "" {
+		t.Errorf("HeadCommit() = %q, %v", head, err)
+	}
+	if _, ok, err := GetConfig("color.diff"); err != nil || ok {
+		t.Errorf("GetConfig(color.diff) = %v, %v", ok, err)
+	}
+	if value, ok, err := GetConfig("user.name"); err != nil || !ok || value != "Test User" {
+		t.Errorf("GetConfig(user.name) = %q, %v, %v", value, ok, err)
+	}
+
+	// パッチは標準入力で渡される
+	if err := ApplyPatch("patch content\n"); err != nil {
+		t.Fatalf("ApplyPatch() error = %v", err)
+	}
+	calls := fake.Calls()
+	if last := calls[len(calls)-1]; last.Stdin != "patch content\n" {
+		t.Errorf("Expected the patch on stdin, got %+v", last)
+	}
+
+	// gitの失敗はストリームの最後に型付きエラーとして返る
+	fake.On("diff", "--cached", "--no-color").ExitCode = 128
+	r, err := StagedChanges()
+	if err != nil {
+		t.Fatalf("StagedChanges() error = %v", err)
+	}
+	_, err = io.ReadAll(r)
+	r.Close()
+	if ExitCode(err) != 128 || !strings.Contains(err.Error(), "failed to get staged changes") {
+		t.Errorf("Expected the failure of git diff, got %v", err)
+	}
+
+	// 応答のないコマンドは失敗する
+	if IsGitRepository() || !fake.Ran("rev-parse", "--git-dir") {
+		t.Errorf("Expected an unscripted command to fail")
+	}
+}
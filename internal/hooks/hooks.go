@@ -0,0 +1,71 @@
+package hooks
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+)
+
+// Names of the hooks, looked up in the hooks directory of the repository
+const (
+	PreMiniCommit  = "pre-mini-commit"
+	PostMiniCommit = "post-mini-commit"
+	PrePop         = "pre-pop"
+	PostPop        = "post-pop"
+	PostDrop       = "post-drop"
+)
+
+// ErrNotExecutable is returned by Find for a hook file that cannot be run,
+// which git also ignores
+var ErrNotExecutable = errors.New("hook is not executable")
+
+// Error is returned by Run when a hook exits with a non-zero status
+type Error struct {
+	Name     string
+	ExitCode int
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("the %s hook exited with status %d", e.Name, e.ExitCode)
+}
+
+// Find returns the path of the hook name in dir, or "" when there is none. A
+// file that is not executable is returned with ErrNotExecutable.
+func Find(dir, name string) (string, error) {
+	path := filepath.Join(dir, name)
+	info, err := os.Stat(path)
+	if err != nil || info.IsDir() {
+		return "", nil
+	}
+	if runtime.GOOS != "windows" && info.Mode()&0111 == 0 {
+		return path, ErrNotExecutable
+	}
+	return path, nil
+}
+
+// Run runs the hook at path in workDir, with env added to the environment and
+// stdin as its input. Its output goes to out; like git, hooks write to stderr
+// so that they cannot be mistaken for the output of the command.
+func Run(path, workDir string, env []string, stdin []byte, out io.Writer) error {
+	cmd := exec.Command(path)
+	cmd.Dir = workDir
+	cmd.Env = append(os.Environ(), env...)
+	cmd.Stdin = bytes.NewReader(stdin)
+	cmd.Stdout = out
+	cmd.Stderr = out
+
+	err := cmd.Run()
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return &Error{Name: filepath.Base(path), ExitCode: exitErr.ExitCode()}
+	}
+	if err != nil {
+		return fmt.Errorf("failed to run the %s hook: %w", filepath.Base(path), err)
+	}
+	return nil
+}
@@ -0,0 +1,54 @@
+package hooks
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func TestFind(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, PrePop), []byte("#!/bin/sh\n"), 0755)
+	os.WriteFile(filepath.Join(dir, PostPop), []byte("#!/bin/sh\n"), 0644)
+
+	if path, err := Find(dir, PrePop); err != nil || path != filepath.Join(dir, PrePop) {
+		t.Errorf("Find(%s) = %q, %v", PrePop, path, err)
+	}
+	if path, err := Find(dir, PostDrop); err != nil || path != "" {
+		t.Errorf("Expected no hook, got %q, %v", path, err)
+	}
+	if runtime.GOOS != "windows" {
+		if _, err := Find(dir, PostPop); !errors.Is(err, ErrNotExecutable) {
+			t.Errorf("Expected ErrNotExecutable, got %v", err)
+		}
+	}
+}
+
+func TestRun(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("hooks are shell scripts")
+	}
+	dir := t.TempDir()
+	hook := filepath.Join(dir, PreMiniCommit)
+
+	// 1. 環境変数と標準入力を受け取り、出力は out に書かれる
+	os.WriteFile(hook, []byte("#!/bin/sh\necho \"$MINI_COMMIT_HOOK $(pwd)\"\ncat\n"), 0755)
+	var out bytes.Buffer
+	if err := Run(hook, dir, []string{"MINI_COMMIT_HOOK=test"}, []byte("input\n"), &out); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if want := "test " + dir + "\ninput\n"; out.String() != want {
+		t.Errorf("Run() output = %q, want %q", out.String(), want)
+	}
+
+	// 2. 0以外の終了コードは Error になる
+	os.WriteFile(hook, []byte("#!/bin/sh\nexit 3\n"), 0755)
+	err := Run(hook, dir, nil, nil, &out)
+	var hookErr *Error
+	if !errors.As(err, &hookErr) || hookErr.ExitCode != 3 || err.Error() != "the pre-mini-commit hook exited with status 3" {
+		t.Errorf("Expected the exit status, got %v", err)
+	}
+}
@@ -0,0 +1,227 @@
+package linediff
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Edit operations
+const (
+	OpEqual  = ' '
+	OpDelete = '-'
+	OpInsert = '+'
+)
+
+// Edit is one line of an edit script
+type Edit struct {
+	Op   byte
+	Line string
+}
+
+// Diff computes a shortest edit script turning a into b (Myers' algorithm)
+func Diff(a, b []string) []Edit {
+	// Common prefix and suffix don't need the full search
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+
+	edits := make([]Edit, 0, len(a)+len(b))
+	for _, line := range a[:prefix] {
+		edits = append(edits, Edit{OpEqual, line})
+	}
+	edits = append(edits, myers(a[prefix:len(a)-suffix], b[prefix:len(b)-suffix])...)
+	for _, line := range a[len(a)-suffix:] {
+		edits = append(edits, Edit{OpEqual, line})
+	}
+	return edits
+}
+
+// myers returns the edit script for a and b, keeping one snapshot of the
+// furthest-reaching paths per edit distance for backtracking
+func myers(a, b []string) []Edit {
+	n, m := len(a), len(b)
+	max := n + m
+	if max == 0 {
+		return nil
+	}
+	offset := max + 1
+	v := make([]int, 2*max+3)
+	var trace [][]int
+
+	for d := 0; d <= max; d++ {
+		trace = append(trace, append([]int(nil), v...))
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+				x = v[offset+k+1]
+			} else {
+				x = v[offset+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			v[offset+k] = x
+			if x >= n && y >= m {
+				return backtrack(trace, a, b, offset)
+			}
+		}
+	}
+	return nil
+}
+
+// backtrack walks the snapshots from the end to recover the edit script
+func backtrack(trace [][]int, a, b []string, offset int) []Edit {
+	x, y := len(a), len(b)
+	var reversed []Edit
+
+	for d := len(trace) - 1; d > 0; d-- {
+		v := trace[d]
+		k := x - y
+		var prevK int
+		if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+			prevK = k + 1
+		} else {
+			prevK = k - 1
+		}
+		prevX := v[offset+prevK]
+		prevY := prevX - prevK
+
+		for x > prevX && y > prevY {
+			reversed = append(reversed, Edit{OpEqual, a[x-1]})
+			x--
+			y--
+		}
+		if x == prevX {
+			reversed = append(reversed, Edit{OpInsert, b[y-1]})
+			y--
+		} else {
+			reversed = append(reversed, Edit{OpDelete, a[x-1]})
+			x--
+		}
+	}
+	for x > 0 && y > 0 {
+		reversed = append(reversed, Edit{OpEqual, a[x-1]})
+		x--
+		y--
+	}
+
+	edits := make([]Edit, len(reversed))
+	for i, e := range reversed {
+		edits[len(reversed)-1-i] = e
+	}
+	return edits
+}
+
+// Equal reports whether an edit script contains no changes
+func Equal(edits []Edit) bool {
+	for _, e := range edits {
+		if e.Op != OpEqual {
+			return false
+		}
+	}
+	return true
+}
+
+// Similarity returns the share of lines both sides have in common, from 0 to 1
+func Similarity(edits []Edit) float64 {
+	common, total := 0, 0
+	for _, e := range edits {
+		if e.Op == OpEqual {
+			common += 2
+			total += 2
+		} else {
+			total++
+		}
+	}
+	if total == 0 {
+		return 1
+	}
+	return float64(common) / float64(total)
+}
+
+// Unified formats an edit script as unified diff hunks with the given number
+// of context lines. The result has no file headers.
+func Unified(edits []Edit, context int) string {
+	var sb strings.Builder
+
+	i := 0
+	oldLine, newLine := 1, 1
+	for i < len(edits) {
+		// Find the next change
+		start := i
+		for start < len(edits) && edits[start].Op == OpEqual {
+			start++
+		}
+		if start == len(edits) {
+			break
+		}
+		for ; i < start-context; i++ {
+			oldLine++
+			newLine++
+		}
+
+		// Extend the hunk while changes are close enough to share context
+		end := start
+		for j := start; j < len(edits); j++ {
+			if edits[j].Op != OpEqual {
+				end = j + 1
+				continue
+			}
+			if j-end >= 2*context {
+				break
+			}
+		}
+		end += context
+		if end > len(edits) {
+			end = len(edits)
+		}
+
+		oldCount, newCount := 0, 0
+		for _, e := range edits[i:end] {
+			if e.Op != OpInsert {
+				oldCount++
+			}
+			if e.Op != OpDelete {
+				newCount++
+			}
+		}
+		fmt.Fprintf(&sb, "@@ -%s +%s @@\n", hunkRange(oldLine, oldCount), hunkRange(newLine, newCount))
+		for _, e := range edits[i:end] {
+			sb.WriteByte(e.Op)
+			sb.WriteString(e.Line)
+			sb.WriteByte('\n')
+		}
+
+		oldLine += oldCount
+		newLine += newCount
+		i = end
+	}
+
+	return sb.String()
+}
+
+// hunkRange formats "start,count" the way diff does, using the previous line for empty ranges
+func hunkRange(start, count int) string {
+	switch count {
+	case 0:
+		return fmt.Sprintf("%d,0", start-1)
+	case 1:
+		return fmt.Sprint(start)
+	}
+	return fmt.Sprintf("%d,%d", start, count)
+}
+
+// Lines splits text into lines without their line terminators
+func Lines(text string) []string {
+	if text == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+}
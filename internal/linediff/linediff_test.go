@@ -0,0 +1,91 @@
+package linediff
+
+import (
+	"strings"
+	"testing"
+)
+
+// applyEdits 編集スクリプトから変更前後の行を復元
+func applyEdits(edits []Edit) (a, b []string) {
+	for _, e := range edits {
+		if e.Op != OpInsert {
+			a = append(a, e.Line)
+		}
+		if e.Op != OpDelete {
+			b = append(b, e.Line)
+		}
+	}
+	return a, b
+}
+
+func TestDiff(t *testing.T) {
+	tests := []struct {
+		name    string
+		a, b    string
+		changes int
+	}{
+		{"identical", "a\nb\nc\n", "a\nb\nc\n", 0},
+		{"insert", "a\nc\n", "a\nb\nc\n", 1},
+		{"delete", "a\nb\nc\n", "a\nc\n", 1},
+		{"replace", "a\nb\nc\n", "a\nx\nc\n", 2},
+		{"from empty", "", "a\nb\n", 2},
+		{"to empty", "a\nb\n", "", 2},
+		{"classic", "a\nb\nc\na\nb\nb\na\n", "c\nb\na\nb\na\nc\n", 5},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			edits := Diff(Lines(tt.a), Lines(tt.b))
+
+			a, b := applyEdits(edits)
+			if strings.Join(a, "\n") != strings.Join(Lines(tt.a), "\n") || strings.Join(b, "\n") != strings.Join(Lines(tt.b), "\n") {
+				t.Fatalf("Edit script does not reproduce the inputs: %v", edits)
+			}
+
+			changes := 0
+			for _, e := range edits {
+				if e.Op != OpEqual {
+					changes++
+				}
+			}
+			if changes != tt.changes {
+				t.Errorf("Expected %d changed lines, but got %d: %v", tt.changes, changes, edits)
+			}
+			if Equal(edits) != (tt.changes == 0) {
+				t.Errorf("Equal() = %v with %d changes", Equal(edits), changes)
+			}
+		})
+	}
+}
+
+func TestUnified(t *testing.T) {
+	a := Lines("1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n")
+	b := Lines("1\n2\nthree\n4\n5\n6\n7\n8\n9\n10\neleven\n")
+
+	expected := "@@ -2,3 +2,3 @@\n 2\n-3\n+three\n 4\n@@ -10 +10,2 @@\n 10\n+eleven\n"
+	if got := Unified(Diff(a, b), 1); got != expected {
+		t.Errorf("Unified() =\n%s\nexpected:\n%s", got, expected)
+	}
+
+	// 近い変更は1つのハンクにまとめる
+	expected = "@@ -1,10 +1,11 @@\n 1\n 2\n-3\n+three\n 4\n 5\n 6\n 7\n 8\n 9\n 10\n+eleven\n"
+	if got := Unified(Diff(a, b), 4); got != expected {
+		t.Errorf("Unified() =\n%s\nexpected:\n%s", got, expected)
+	}
+
+	if got := Unified(Diff(a, a), 3); got != "" {
+		t.Errorf("Expected no hunks for identical input, but got %q", got)
+	}
+}
+
+func TestSimilarity(t *testing.T) {
+	if s := Similarity(Diff(Lines("a\nb\n"), Lines("a\nb\n"))); s != 1 {
+		t.Errorf("Expected similarity 1, but got %v", s)
+	}
+	if s := Similarity(Diff(Lines("a\nb\n"), Lines("c\nd\n"))); s != 0 {
+		t.Errorf("Expected similarity 0, but got %v", s)
+	}
+	if s := Similarity(Diff(Lines("a\nb\n"), Lines("a\nc\n"))); s != 0.5 {
+		t.Errorf("Expected similarity 0.5, but got %v", s)
+	}
+}
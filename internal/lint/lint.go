@@ -0,0 +1,133 @@
+package lint
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// Rule names reported with each problem
+const (
+	RuleHeaderFormat     = "header-format"
+	RuleTypeEnum         = "type-enum"
+	RuleScopeRequired    = "scope-required"
+	RuleScopeForbidden   = "scope-forbidden"
+	RuleScopeEnum        = "scope-enum"
+	RuleSubjectEmpty     = "subject-empty"
+	RuleSubjectMaxLength = "subject-max-length"
+	RuleSubjectFullStop  = "subject-full-stop"
+	RuleBodyLeadingBlank = "body-leading-blank"
+)
+
+// Scope settings
+const (
+	ScopeOptional  = "optional"
+	ScopeRequired  = "required"
+	ScopeForbidden = "forbidden"
+)
+
+// DefaultTypes are the types of the conventional commits specification and its usual extensions
+var DefaultTypes = []string{"feat", "fix", "docs", "style", "refactor", "perf", "test", "build", "ci", "chore", "revert"}
+
+// DefaultSubjectMaxLength is the usual limit for the first line of a git commit message
+const DefaultSubjectMaxLength = 72
+
+// header matches the "type(scope)!: description" subject line of a conventional commit
+var header = regexp.MustCompile(`^([A-Za-z]+)(\(([^()]*)\))?(!)?:(.*)$`)
+
+// Rules configure the conventional commit checks. Empty Types or Scopes allow
+// any type or scope, and a SubjectMaxLength of 0 allows subject lines of any length.
+type Rules struct {
+	Types            []string
+	Scope            string
+	Scopes           []string
+	SubjectMaxLength int
+}
+
+// DefaultRules returns the rules used when nothing is configured
+func DefaultRules() Rules {
+	return Rules{Types: DefaultTypes, Scope: ScopeOptional, SubjectMaxLength: DefaultSubjectMaxLength}
+}
+
+// Header is the parsed subject line of a conventional commit
+type Header struct {
+	Type        string
+	Scope       string
+	Breaking    bool
+	Description string
+}
+
+// ParseHeader parses a "type(scope)!: description" subject line; ok is false
+// when the line does not follow the format
+func ParseHeader(subject string) (h Header, ok bool) {
+	m := header.FindStringSubmatch(subject)
+	if m == nil || !strings.HasPrefix(m[5], " ") || strings.TrimSpace(m[5]) == "" {
+		return Header{}, false
+	}
+	return Header{Type: m[1], Scope: m[3], Breaking: m[4] != "", Description: strings.TrimSpace(m[5])}, true
+}
+
+// Problem is a rule a message breaks
+type Problem struct {
+	Rule    string
+	Message string
+}
+
+func (p Problem) String() string {
+	return p.Rule + ": " + p.Message
+}
+
+// Check returns the rules message breaks, in the order they are checked
+func (r Rules) Check(message string) []Problem {
+	var problems []Problem
+	add := func(rule, format string, args ...any) {
+		problems = append(problems, Problem{Rule: rule, Message: fmt.Sprintf(format, args...)})
+	}
+
+	subject, body, hasBody := strings.Cut(message, "\n")
+	if n := utf8.RuneCountInString(subject); r.SubjectMaxLength > 0 && n > r.SubjectMaxLength {
+		add(RuleSubjectMaxLength, "the subject line has %d characters, more than %d", n, r.SubjectMaxLength)
+	}
+	if hasBody && !strings.HasPrefix(body, "\n") {
+		add(RuleBodyLeadingBlank, "the body must be separated from the subject line by a blank line")
+	}
+
+	m := header.FindStringSubmatch(subject)
+	if m == nil {
+		add(RuleHeaderFormat, "the subject line must look like \"type(scope): description\"")
+		return problems
+	}
+	typ, hasScope, scope, description := m[1], m[2] != "", m[3], m[5]
+
+	if len(r.Types) > 0 && !contains(r.Types, typ) {
+		add(RuleTypeEnum, "type '%s' is not one of %s", typ, strings.Join(r.Types, ", "))
+	}
+	switch {
+	case r.Scope == ScopeRequired && scope == "":
+		add(RuleScopeRequired, "a scope is required, as in \"%s(scope): ...\"", typ)
+	case r.Scope == ScopeForbidden && hasScope:
+		add(RuleScopeForbidden, "scopes are not allowed")
+	case scope != "" && len(r.Scopes) > 0 && !contains(r.Scopes, scope):
+		add(RuleScopeEnum, "scope '%s' is not one of %s", scope, strings.Join(r.Scopes, ", "))
+	}
+	switch {
+	case strings.TrimSpace(description) == "":
+		add(RuleSubjectEmpty, "the description after \"%s:\" is empty", m[1]+m[2]+m[4])
+	case !strings.HasPrefix(description, " "):
+		add(RuleHeaderFormat, "a space must follow the colon after \"%s\"", m[1]+m[2]+m[4])
+	case strings.HasSuffix(description, "."):
+		add(RuleSubjectFullStop, "the subject line must not end with a period")
+	}
+
+	return problems
+}
+
+func contains(values []string, s string) bool {
+	for _, v := range values {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,82 @@
+package lint
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCheck(t *testing.T) {
+	tests := []struct {
+		name     string
+		rules    Rules
+		message  string
+		expected []string
+	}{
+		{name: "valid", rules: DefaultRules(), message: "feat(parser): handle renames"},
+		{name: "valid with body and breaking change", rules: DefaultRules(), message: "fix!: drop the old flag\n\nBody"},
+		{name: "not conventional", rules: DefaultRules(), message: "Update parser", expected: []string{RuleHeaderFormat}},
+		{name: "unknown type", rules: DefaultRules(), message: "feature: x", expected: []string{RuleTypeEnum}},
+		{name: "any type", rules: Rules{}, message: "feature: x"},
+		{name: "scope required", rules: Rules{Scope: ScopeRequired}, message: "feat: x", expected: []string{RuleScopeRequired}},
+		{name: "empty scope", rules: Rules{Scope: ScopeRequired}, message: "feat(): x", expected: []string{RuleScopeRequired}},
+		{name: "scope forbidden", rules: Rules{Scope: ScopeForbidden}, message: "feat(ui): x", expected: []string{RuleScopeForbidden}},
+		{name: "scope not allowed", rules: Rules{Scopes: []string{"ui", "api"}}, message: "feat(db): x", expected: []string{RuleScopeEnum}},
+		{name: "scope allowed", rules: Rules{Scopes: []string{"ui", "api"}}, message: "feat(api): x"},
+		{name: "empty description", rules: DefaultRules(), message: "feat(ui):  ", expected: []string{RuleSubjectEmpty}},
+		{name: "no space after colon", rules: DefaultRules(), message: "fix:typo", expected: []string{RuleHeaderFormat}},
+		{name: "full stop", rules: DefaultRules(), message: "docs: fix typo.", expected: []string{RuleSubjectFullStop}},
+		{
+			name:     "too long and no blank line",
+			rules:    Rules{SubjectMaxLength: 10},
+			message:  "chore: update deps\nbody",
+			expected: []string{RuleSubjectMaxLength, RuleBodyLeadingBlank},
+		},
+		{name: "length counts characters", rules: Rules{SubjectMaxLength: 11}, message: "fix: 日本語の件名"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, p := range tt.rules.Check(tt.message) {
+				got = append(got, p.Rule)
+			}
+			if strings.Join(got, ",") != strings.Join(tt.expected, ",") {
+				t.Errorf("Check(%q) = %v, want %v", tt.message, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestProblemMessages(t *testing.T) {
+	problems := DefaultRules().Check("feature(ui):")
+	if len(problems) != 2 {
+		t.Fatalf("Expected 2 problems, got %v", problems)
+	}
+	if !strings.Contains(problems[0].String(), "type-enum: type 'feature' is not one of feat, fix") {
+		t.Errorf("Unexpected problem: %s", problems[0])
+	}
+	if problems[1].String() != `subject-empty: the description after "feature(ui):" is empty` {
+		t.Errorf("Unexpected problem: %s", problems[1])
+	}
+}
+
+func TestParseHeader(t *testing.T) {
+	tests := []struct {
+		subject string
+		want    Header
+		ok      bool
+	}{
+		{"feat(parser): handle renames", Header{Type: "feat", Scope: "parser", Description: "handle renames"}, true},
+		{"fix!: drop the old flag", Header{Type: "fix", Breaking: true, Description: "drop the old flag"}, true},
+		{"Update parser", Header{}, false},
+		{"fix:typo", Header{}, false},
+		{"feat: ", Header{}, false},
+	}
+
+	for _, tt := range tests {
+		got, ok := ParseHeader(tt.subject)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("ParseHeader(%q) = %+v, %v, want %+v, %v", tt.subject, got, ok, tt.want, tt.ok)
+		}
+	}
+}
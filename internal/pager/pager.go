@@ -0,0 +1,108 @@
+package pager
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+// DefaultPager is used when neither the environment nor git config names a pager
+const DefaultPager = "less"
+
+// Pager is a running pager process that output can be written to
+type Pager struct {
+	cmd   *exec.Cmd
+	stdin io.WriteCloser
+}
+
+// Resolve picks the pager command the way git does for a command called name:
+// GIT_PAGER, then pager.<name>, then core.pager, then PAGER, then "less".
+// getConfig reads a git config key and reports whether it is set.
+// An empty result means paging is disabled.
+func Resolve(name string, getConfig func(key string) (string, bool, error)) (string, error) {
+	if value, ok := os.LookupEnv("GIT_PAGER"); ok {
+		return normalize(value), nil
+	}
+
+	value, ok, err := getConfig("pager." + name)
+	if err != nil {
+		return "", err
+	}
+	if ok {
+		// pager.<cmd> may be a boolean or a command, like in git
+		switch strings.ToLower(value) {
+		case "false", "no", "off", "0":
+			return "", nil
+		case "true", "yes", "on", "1":
+		default:
+			return normalize(value), nil
+		}
+	}
+
+	if value, ok, err := getConfig("core.pager"); err != nil {
+		return "", err
+	} else if ok {
+		return normalize(value), nil
+	}
+
+	if value, ok := os.LookupEnv("PAGER"); ok {
+		return normalize(value), nil
+	}
+
+	return DefaultPager, nil
+}
+
+// normalize treats an empty pager and "cat" as "no pager"
+func normalize(command string) string {
+	command = strings.TrimSpace(command)
+	if command == "cat" {
+		return ""
+	}
+	return command
+}
+
+// Start runs command through the shell with its output on stdout and stderr
+func Start(command string, stdout, stderr io.Writer) (*Pager, error) {
+	shell, flag := "sh", "-c"
+	if runtime.GOOS == "windows" {
+		shell, flag = "cmd", "/C"
+	}
+
+	cmd := exec.Command(shell, flag, command)
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+	cmd.Env = os.Environ()
+	// Same defaults as git: quit if one screen, keep colors, don't clear the screen
+	if _, ok := os.LookupEnv("LESS"); !ok {
+		cmd.Env = append(cmd.Env, "LESS=FRX")
+	}
+	if _, ok := os.LookupEnv("LV"); !ok {
+		cmd.Env = append(cmd.Env, "LV=-c")
+	}
+
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create pager pipe: %v", err)
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("failed to start pager '%s': %v", command, err)
+	}
+
+	return &Pager{cmd: cmd, stdin: stdin}, nil
+}
+
+// Write sends output to the pager
+func (p *Pager) Write(b []byte) (int, error) {
+	return p.stdin.Write(b)
+}
+
+// Close signals end of output and waits for the user to quit the pager
+func (p *Pager) Close() error {
+	if err := p.stdin.Close(); err != nil {
+		return err
+	}
+	return p.cmd.Wait()
+}
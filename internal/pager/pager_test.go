@@ -0,0 +1,105 @@
+package pager
+
+import (
+	"bytes"
+	"os"
+	"runtime"
+	"testing"
+)
+
+// fakeConfig テスト用のgit config
+func fakeConfig(values map[string]string) func(string) (string, bool, error) {
+	return func(key string) (string, bool, error) {
+		value, ok := values[key]
+		return value, ok, nil
+	}
+}
+
+func TestResolve(t *testing.T) {
+	tests := []struct {
+		name     string
+		env      map[string]string
+		config   map[string]string
+		expected string
+	}{
+		{
+			name:     "default",
+			expected: DefaultPager,
+		},
+		{
+			name:     "PAGER",
+			env:      map[string]string{"PAGER": "more"},
+			expected: "more",
+		},
+		{
+			name:     "core.pager over PAGER",
+			env:      map[string]string{"PAGER": "more"},
+			config:   map[string]string{"core.pager": "less -S"},
+			expected: "less -S",
+		},
+		{
+			name:     "pager.mini-commit over core.pager",
+			config:   map[string]string{"core.pager": "less", "pager.mini-commit": "delta"},
+			expected: "delta",
+		},
+		{
+			name:     "pager.mini-commit=false disables paging",
+			config:   map[string]string{"core.pager": "less", "pager.mini-commit": "false"},
+			expected: "",
+		},
+		{
+			name:     "pager.mini-commit=true keeps core.pager",
+			config:   map[string]string{"core.pager": "most", "pager.mini-commit": "true"},
+			expected: "most",
+		},
+		{
+			name:     "GIT_PAGER wins",
+			env:      map[string]string{"GIT_PAGER": "cat", "PAGER": "more"},
+			config:   map[string]string{"pager.mini-commit": "delta"},
+			expected: "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// 環境変数を未設定にする（t.Setenvがテスト終了時に元の値へ戻す）
+			for _, key := range []string{"GIT_PAGER", "PAGER"} {
+				t.Setenv(key, "")
+				os.Unsetenv(key)
+			}
+			for key, value := range tt.env {
+				t.Setenv(key, value)
+			}
+
+			got, err := Resolve("mini-commit", fakeConfig(tt.config))
+			if err != nil {
+				t.Fatalf("Resolve() error = %v", err)
+			}
+			if got != tt.expected {
+				t.Errorf("Resolve() = %q, want %q", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestStart(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Skipping shell pager test on Windows")
+	}
+
+	var out bytes.Buffer
+	p, err := Start("tr a-z A-Z", &out, &out)
+	if err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	if _, err := p.Write([]byte("paged output\n")); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	if err := p.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	if out.String() != "PAGED OUTPUT\n" {
+		t.Errorf("Expected output to go through the pager, but got %q", out.String())
+	}
+}
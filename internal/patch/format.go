@@ -0,0 +1,150 @@
+package patch
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// DefaultStatWidth is the total width used by WriteStat, matching git's default
+const DefaultStatWidth = 80
+
+// maxStatNameWidth limits the width of the path column in WriteStat
+const maxStatNameWidth = 50
+
+// WriteStat writes a git-style diffstat ("path | 3 ++-") followed by a summary line
+func WriteStat(w io.Writer, files []*File, width int) {
+	if len(files) == 0 {
+		return
+	}
+	if width <= 0 {
+		width = DefaultStatWidth
+	}
+
+	nameWidth, maxChanges, hasBinary := 0, 0, false
+	for _, f := range files {
+		hasBinary = hasBinary || f.Binary
+		if n := len(f.DisplayPath()); n > nameWidth {
+			nameWidth = n
+		}
+		if n := f.Insertions + f.Deletions; n > maxChanges {
+			maxChanges = n
+		}
+	}
+	if nameWidth > maxStatNameWidth {
+		nameWidth = maxStatNameWidth
+	}
+	countWidth := len(fmt.Sprint(maxChanges))
+	if hasBinary && countWidth < 3 {
+		countWidth = 3 // room for "Bin"
+	}
+
+	// " <name> | <count> <graph>"
+	graphWidth := width - nameWidth - countWidth - 5
+	if graphWidth < 10 {
+		graphWidth = 10
+	}
+
+	for _, f := range files {
+		name := f.DisplayPath()
+		if len(name) > nameWidth {
+			name = "..." + name[len(name)-nameWidth+3:]
+		}
+		if f.Binary {
+			fmt.Fprintf(w, " %-*s | %*s\n", nameWidth, name, countWidth, "Bin")
+			continue
+		}
+
+		plus, minus := f.Insertions, f.Deletions
+		if maxChanges > graphWidth {
+			plus, minus = scaleGraph(plus, maxChanges, graphWidth), scaleGraph(minus, maxChanges, graphWidth)
+		}
+		line := fmt.Sprintf(" %-*s | %*d %s%s", nameWidth, name, countWidth, f.Insertions+f.Deletions,
+			strings.Repeat("+", plus), strings.Repeat("-", minus))
+		fmt.Fprintln(w, strings.TrimRight(line, " "))
+	}
+
+	fmt.Fprintln(w, SummaryLine(Summarize(files)))
+}
+
+// scaleGraph scales a change count to the graph width, keeping non-zero counts visible
+func scaleGraph(n, max, width int) int {
+	if n == 0 {
+		return 0
+	}
+	scaled := n * width / max
+	if scaled == 0 {
+		scaled = 1
+	}
+	return scaled
+}
+
+// SummaryLine formats stats like git: " 2 files changed, 3 insertions(+), 1 deletion(-)"
+func SummaryLine(stats Stats) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, " %d file%s changed", stats.Files, pluralS(stats.Files))
+	if stats.Insertions > 0 || stats.Deletions == 0 {
+		fmt.Fprintf(&sb, ", %d insertion%s(+)", stats.Insertions, pluralS(stats.Insertions))
+	}
+	if stats.Deletions > 0 || stats.Insertions == 0 {
+		fmt.Fprintf(&sb, ", %d deletion%s(-)", stats.Deletions, pluralS(stats.Deletions))
+	}
+	return sb.String()
+}
+
+// WriteNumstat writes "<insertions>\t<deletions>\t<path>" lines; binary files show "-"
+func WriteNumstat(w io.Writer, files []*File) {
+	for _, f := range files {
+		if f.Binary {
+			fmt.Fprintf(w, "-\t-\t%s\n", f.DisplayPath())
+			continue
+		}
+		fmt.Fprintf(w, "%d\t%d\t%s\n", f.Insertions, f.Deletions, f.DisplayPath())
+	}
+}
+
+// WriteNameOnly writes the path of every file
+func WriteNameOnly(w io.Writer, files []*File) {
+	for _, f := range files {
+		fmt.Fprintln(w, f.Path())
+	}
+}
+
+// WriteNameStatus writes "<status>\t<path>" lines, with similarity and both paths for renames and copies
+func WriteNameStatus(w io.Writer, files []*File) {
+	for _, f := range files {
+		switch f.Status {
+		case StatusRenamed, StatusCopied:
+			fmt.Fprintf(w, "%c%03d\t%s\t%s\n", f.Status, f.Similarity, f.OldPath, f.NewPath)
+		default:
+			fmt.Fprintf(w, "%c\t%s\n", f.Status, f.Path())
+		}
+	}
+}
+
+// WriteSummary writes the condensed summary of created, deleted, renamed and mode-changed files
+func WriteSummary(w io.Writer, files []*File) {
+	for _, f := range files {
+		switch f.Status {
+		case StatusAdded:
+			fmt.Fprintf(w, " create mode %s %s\n", f.NewMode, f.NewPath)
+		case StatusDeleted:
+			fmt.Fprintf(w, " delete mode %s %s\n", f.OldMode, f.OldPath)
+		case StatusRenamed:
+			fmt.Fprintf(w, " rename %s (%d%%)\n", f.DisplayPath(), f.Similarity)
+		case StatusCopied:
+			fmt.Fprintf(w, " copy %s (%d%%)\n", f.DisplayPath(), f.Similarity)
+		}
+		if f.Status != StatusAdded && f.Status != StatusDeleted && f.OldMode != "" && f.NewMode != "" && f.OldMode != f.NewMode {
+			fmt.Fprintf(w, " mode change %s => %s %s\n", f.OldMode, f.NewMode, f.Path())
+		}
+	}
+}
+
+// pluralS returns "s" unless n is 1
+func pluralS(n int) string {
+	if n == 1 {
+		return ""
+	}
+	return "s"
+}
@@ -0,0 +1,361 @@
+package patch
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Patch is a git-style unified diff parsed into files and hunks. Unlike Parse,
+// ParsePatch keeps every byte of its input: String returns the patch it was
+// parsed from.
+type Patch struct {
+	// Preamble is the text before the first "diff --git" line, such as a mail header
+	Preamble string
+	Files    []*FileDiff
+}
+
+// FileDiff is one file section of a patch. The embedded File holds what the
+// header says about the file and its change counts.
+type FileDiff struct {
+	File
+	// Header holds the lines from "diff --git" up to the first hunk, without their
+	// newlines: extended headers, "---" and "+++", and binary patch data
+	Header []string
+	Hunks  []*Hunk
+}
+
+// hunkHeader matches "@@ -a[,b] +c[,d] @@" with an optional section heading
+var hunkHeader = regexp.MustCompile(`^@@ -\d+(,\d+)? \+\d+(,\d+)? @@`)
+
+// Hunk is one "@@ -a,b +c,d @@" section of a file
+type Hunk struct {
+	OldStart int
+	OldLines int
+	NewStart int
+	NewLines int
+	// Section is the heading git prints after the ranges, usually the enclosing function
+	Section string
+	Lines   []Line
+}
+
+// Line is one line of a hunk. Op is ' ' for context, '+' for an insertion, '-' for
+// a deletion and '\\' for a "\ No newline at end of file" marker, which applies to
+// the line before it. Text excludes Op and the newline.
+type Line struct {
+	Op   byte
+	Text string
+}
+
+// ParsePatch parses a git-style unified diff into files and hunks. It accepts
+// what git diff writes and rejects anything it could not write back byte for
+// byte, such as a hunk whose length does not match its header.
+func ParsePatch(p string) (*Patch, error) {
+	ps := &parser{keep: true, strict: true}
+	if err := ps.parse(p); err != nil {
+		return nil, err
+	}
+	return &ps.patch, nil
+}
+
+// parser reads a patch one line at a time. It is the one place that knows where
+// hunks start and end: ParsePatch, Parse, ComputeStats, ForEachChange, Validate
+// and Writer all run it. The hunks and headers are only kept with keep; strict
+// rejects what ParsePatch could not write back, which the others take as git
+// apply does. Once it found an error, the rest of the input is ignored.
+type parser struct {
+	keep   bool
+	strict bool
+	// change is called for every added or removed line
+	change func(op byte, text string)
+
+	patch    Patch
+	preamble strings.Builder
+	leading  int
+	text     bool
+	file     *FileDiff
+	hunk     *Hunk
+	lastOp   byte
+	oldLeft  int
+	newLeft  int
+	lineNo   int
+	err      error
+}
+
+// parse reads all of p and returns the first error found
+func (p *parser) parse(input string) error {
+	for rest := input; rest != ""; {
+		line, tail, found := strings.Cut(rest, "\n")
+		p.feed(line)
+		if !found {
+			return p.finish(false)
+		}
+		rest = tail
+	}
+	return p.finish(true)
+}
+
+// feed reads one line without its newline
+func (p *parser) feed(line string) {
+	if p.err != nil {
+		return
+	}
+	p.lineNo++
+
+	// Inside a hunk every line belongs to the hunk until both sides are consumed
+	if p.inHunk() {
+		p.hunkLine(line)
+		return
+	}
+
+	switch {
+	case strings.HasPrefix(line, "diff --git "):
+		p.file = &FileDiff{File: *newFile(line)}
+		if p.keep {
+			p.file.Header = []string{line}
+		}
+		p.patch.Files = append(p.patch.Files, p.file)
+		p.hunk = nil
+	case p.file == nil:
+		p.leading++
+		p.text = p.text || strings.TrimSpace(line) != ""
+		if p.keep {
+			p.preamble.WriteString(line)
+			p.preamble.WriteByte('\n')
+		}
+	case strings.HasPrefix(line, "@@"):
+		h, err := parseHunkHeader(line, p.strict)
+		if err != nil {
+			p.err = fmt.Errorf("line %d: %w", p.lineNo, err)
+			return
+		}
+		p.hunk, p.lastOp = h, 0
+		if p.keep {
+			p.file.Hunks = append(p.file.Hunks, h)
+		}
+		p.oldLeft, p.newLeft = h.OldLines, h.NewLines
+	case strings.HasPrefix(line, `\`) && p.canMarkNoNewline():
+		// The marker of the last line follows the end of the hunk
+		p.addLine('\\', line[1:])
+	case p.hunk != nil && p.strict:
+		p.fail("unexpected line after hunk")
+	case p.hunk != nil:
+		// Text between the hunks of a file, which git apply skips
+	default:
+		if p.keep {
+			p.file.Header = append(p.file.Header, line)
+		}
+		p.file.parseHeaderLine(line)
+	}
+}
+
+// hunkLine reads a line of the current hunk. An empty line stands for an empty
+// context line, as some tools write it, except when strict.
+func (p *parser) hunkLine(line string) {
+	op, text := byte(' '), ""
+	if line != "" {
+		op, text = line[0], line[1:]
+	} else if p.strict {
+		p.fail("empty line inside hunk")
+		return
+	}
+
+	switch op {
+	case '+':
+		p.newLeft--
+		p.file.Insertions++
+	case '-':
+		p.oldLeft--
+		p.file.Deletions++
+	case ' ':
+		p.oldLeft--
+		p.newLeft--
+	case '\\':
+		if !p.canMarkNoNewline() {
+			if p.strict {
+				p.fail("misplaced \"\\ No newline\" marker")
+			}
+			return
+		}
+	default:
+		p.fail("unexpected line inside hunk")
+		return
+	}
+	if p.oldLeft < 0 || p.newLeft < 0 {
+		p.fail("hunk longer than its header announces")
+		return
+	}
+	p.addLine(op, text)
+}
+
+// addLine records a line of the current hunk
+func (p *parser) addLine(op byte, text string) {
+	p.lastOp = op
+	if p.keep {
+		p.hunk.Lines = append(p.hunk.Lines, Line{Op: op, Text: text})
+	}
+	if p.change != nil && (op == '+' || op == '-') {
+		p.change(op, text)
+	}
+}
+
+// finish checks the end of the input; complete tells whether it ended with a newline
+func (p *parser) finish(complete bool) error {
+	if p.err == nil && p.inHunk() {
+		p.err = fmt.Errorf("truncated hunk at end of patch")
+	}
+	if p.err == nil && !complete && p.strict {
+		p.err = fmt.Errorf("patch does not end with a newline")
+	}
+	p.patch.Preamble = p.preamble.String()
+	return p.err
+}
+
+// fail records an error at the current line
+func (p *parser) fail(message string) {
+	p.err = fmt.Errorf("line %d: %s", p.lineNo, message)
+}
+
+// inHunk reports whether the next line belongs to the current hunk
+func (p *parser) inHunk() bool {
+	return p.oldLeft > 0 || p.newLeft > 0
+}
+
+// canMarkNoNewline reports whether a "\ No newline" marker may follow the lines
+// read so far: it needs a line to apply to, and only one marker per line
+func (p *parser) canMarkNoNewline() bool {
+	return p.hunk != nil && p.lastOp != 0 && p.lastOp != '\\'
+}
+
+// files returns the files read, without their headers and hunks
+func (p *parser) files() []*File {
+	files := make([]*File, len(p.patch.Files))
+	for i, f := range p.patch.Files {
+		files[i] = &f.File
+	}
+	return files
+}
+
+// validate reports why the input is not a patch Validate accepts
+func (p *parser) validate() error {
+	switch {
+	case len(p.patch.Files) == 0 && !p.text:
+		return fmt.Errorf("empty patch")
+	case p.leading > 0 || len(p.patch.Files) == 0:
+		return fmt.Errorf("not a git diff: missing \"diff --git\" header")
+	}
+	return p.err
+}
+
+// parseHunkHeader parses "@@ -a[,b] +c[,d] @@[ section]". When strict, headers
+// git would not write this way, e.g. "-1,1" for "-1", are rejected so that
+// String reproduces them.
+func parseHunkHeader(line string, strict bool) (*Hunk, error) {
+	if !hunkHeader.MatchString(line) {
+		return nil, fmt.Errorf("malformed hunk header")
+	}
+	ranges, section, _ := strings.Cut(strings.TrimPrefix(line, "@@ "), " @@")
+	oldRange, newRange, _ := strings.Cut(ranges, " ")
+
+	h := &Hunk{Section: strings.TrimPrefix(section, " ")}
+	var err error
+	if h.OldStart, h.OldLines, err = parseRange(oldRange[1:]); err != nil {
+		return nil, err
+	}
+	if h.NewStart, h.NewLines, err = parseRange(newRange[1:]); err != nil {
+		return nil, err
+	}
+	if strict && h.header() != line {
+		return nil, fmt.Errorf("hunk header not in git's format")
+	}
+	return h, nil
+}
+
+// parseRange parses the "start[,count]" of a hunk range; the count defaults to 1
+func parseRange(r string) (int, int, error) {
+	start, count, found := strings.Cut(r, ",")
+	s, err := strconv.Atoi(start)
+	if err != nil {
+		return 0, 0, fmt.Errorf("malformed hunk header")
+	}
+	if !found {
+		return s, 1, nil
+	}
+	c, err := strconv.Atoi(count)
+	if err != nil {
+		return 0, 0, fmt.Errorf("malformed hunk header")
+	}
+	return s, c, nil
+}
+
+// formatRange writes a hunk range the way git does, leaving out a count of 1
+func formatRange(start, count int) string {
+	if count == 1 {
+		return strconv.Itoa(start)
+	}
+	return strconv.Itoa(start) + "," + strconv.Itoa(count)
+}
+
+// header returns the "@@" line of the hunk
+func (h *Hunk) header() string {
+	header := "@@ -" + formatRange(h.OldStart, h.OldLines) + " +" + formatRange(h.NewStart, h.NewLines) + " @@"
+	if h.Section != "" {
+		header += " " + h.Section
+	}
+	return header
+}
+
+// String returns the hunk as it appears in a patch, header included
+func (h *Hunk) String() string {
+	var b strings.Builder
+	h.write(&b)
+	return b.String()
+}
+
+func (h *Hunk) write(b *strings.Builder) {
+	b.WriteString(h.header())
+	b.WriteByte('\n')
+	for _, l := range h.Lines {
+		b.WriteByte(l.Op)
+		b.WriteString(l.Text)
+		b.WriteByte('\n')
+	}
+}
+
+// String returns the file section as it appears in a patch
+func (f *FileDiff) String() string {
+	var b strings.Builder
+	f.write(&b)
+	return b.String()
+}
+
+func (f *FileDiff) write(b *strings.Builder) {
+	for _, line := range f.Header {
+		b.WriteString(line)
+		b.WriteByte('\n')
+	}
+	for _, h := range f.Hunks {
+		h.write(b)
+	}
+}
+
+// String returns the patch in git's format, identical to the parsed input
+func (p *Patch) String() string {
+	var b strings.Builder
+	b.WriteString(p.Preamble)
+	for _, f := range p.Files {
+		f.write(&b)
+	}
+	return b.String()
+}
+
+// Stats returns the number of files and changed lines of the patch
+func (p *Patch) Stats() Stats {
+	stats := Stats{Files: len(p.Files)}
+	for _, f := range p.Files {
+		stats.Insertions += f.Insertions
+		stats.Deletions += f.Deletions
+	}
+	return stats
+}
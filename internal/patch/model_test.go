@@ -0,0 +1,236 @@
+package patch
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// noNewlinePatch 最終行に改行がないファイルの変更（見出し付きのハンク）
+const noNewlinePatch = `diff --git a/t.txt b/t.txt
+index 1111111..2222222 100644
+--- a/t.txt
++++ b/t.txt
+@@ -1,2 +1,2 @@ func main() {
+ a
+-b
+\ No newline at end of file
++c
+\ No newline at end of file
+`
+
+func TestParsePatch(t *testing.T) {
+	input := "From: someone\n\n" + mixedPatch + noNewlinePatch
+	p := checkRoundTrip(t, input)
+
+	if p.Preamble != "From: someone\n\n" {
+		t.Errorf("Preamble = %q", p.Preamble)
+	}
+	if len(p.Files) != 6 {
+		t.Fatalf("Expected 6 files, but got %d", len(p.Files))
+	}
+
+	// 1. ヘッダー行は "diff --git" から最初のハンクの前まで
+	f := p.Files[0]
+	if len(f.Header) != 6 || f.Header[5] != "+++ b/f.txt" {
+		t.Errorf("Unexpected header: %q", f.Header)
+	}
+	if len(f.Hunks) != 1 {
+		t.Fatalf("Expected 1 hunk, but got %d", len(f.Hunks))
+	}
+	h := f.Hunks[0]
+	if h.OldStart != 1 || h.OldLines != 3 || h.NewStart != 1 || h.NewLines != 4 || h.Section != "" {
+		t.Errorf("Unexpected hunk range: %+v", h)
+	}
+	var ops strings.Builder
+	for _, l := range h.Lines {
+		ops.WriteByte(l.Op)
+	}
+	if ops.String() != " -+ +" || h.Lines[2].Text != "B" {
+		t.Errorf("Unexpected hunk lines: %+v", h.Lines)
+	}
+
+	// 2. リネームとバイナリはハンクを持たない
+	if len(p.Files[2].Hunks) != 0 || len(p.Files[4].Hunks) != 0 {
+		t.Errorf("Expected no hunks for the rename and the binary file")
+	}
+
+	// 3. 改行なしの印と見出し
+	h = p.Files[5].Hunks[0]
+	if h.Section != "func main() {" || len(h.Lines) != 5 || h.Lines[2].Op != '\\' || h.Lines[4].Op != '\\' {
+		t.Errorf("Unexpected hunk: %+v", h)
+	}
+	if p.Stats() != (Stats{Files: 6, Insertions: 4, Deletions: 3}) {
+		t.Errorf("Unexpected stats: %+v", p.Stats())
+	}
+}
+
+func TestHunkString(t *testing.T) {
+	// 件数1は省略され、0は省略されない（git と同じ）
+	h := &Hunk{OldStart: 0, OldLines: 0, NewStart: 1, NewLines: 1, Lines: []Line{{Op: '+', Text: "new"}}}
+	if got := h.String(); got != "@@ -0,0 +1 @@\n+new\n" {
+		t.Errorf("Hunk.String() = %q", got)
+	}
+
+	// 変更したハンクも書き出せる
+	p, err := ParsePatch(noNewlinePatch)
+	if err != nil {
+		t.Fatalf("ParsePatch() error = %v", err)
+	}
+	p.Files[0].Hunks[0].Section = ""
+	if !strings.Contains(p.String(), "\n@@ -1,2 +1,2 @@\n a\n") {
+		t.Errorf("Expected the edited hunk header, got:\n%s", p.String())
+	}
+}
+
+func TestParsePatchErrors(t *testing.T) {
+	header := "diff --git a/x b/x\n--- a/x\n+++ b/x\n"
+
+	tests := []struct {
+		name  string
+		patch string
+		err   string
+	}{
+		{name: "no final newline", patch: header + "@@ -1 +1 @@\n-a\n+b", err: "does not end with a newline"},
+		{name: "truncated hunk", patch: header + "@@ -1,2 +1,2 @@\n a\n", err: "truncated hunk"},
+		{name: "empty line in hunk", patch: header + "@@ -1,2 +1,2 @@\n a\n\n", err: "line 6: empty line inside hunk"},
+		{name: "unexpected line in hunk", patch: header + "@@ -1 +1 @@\nxa\n", err: "line 5: unexpected line inside hunk"},
+		{name: "text after hunk", patch: header + "@@ -1 +1 @@\n-a\n+b\ntrailer\n", err: "line 7: unexpected line after hunk"},
+		{name: "malformed header", patch: header + "@@ -a +1 @@\n", err: "line 4: malformed hunk header"},
+		{name: "explicit count of 1", patch: header + "@@ -1,1 +1 @@\n-a\n+b\n", err: "not in git's format"},
+		{name: "leading marker", patch: header + "@@ -1 +1 @@\n\\ No newline at end of file\n", err: "misplaced"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParsePatch(tt.patch)
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("ParsePatch() error = %v, want %q", err, tt.err)
+			}
+		})
+	}
+
+	// 空のパッチはファイルなし
+	if p, err := ParsePatch(""); err != nil || len(p.Files) != 0 || p.String() != "" {
+		t.Errorf("Expected an empty patch, got %+v, %v", p, err)
+	}
+}
+
+// checkRoundTrip ParsePatchが入力をそのまま書き戻し、Parseと同じファイル情報を返すことを検証
+func checkRoundTrip(t *testing.T, input string) *Patch {
+	t.Helper()
+	p, err := ParsePatch(input)
+	if err != nil {
+		t.Fatalf("ParsePatch() error = %v\n%s", err, input)
+	}
+	if p.String() != input {
+		t.Fatalf("String() differs from the input:\n%q\n%q", input, p.String())
+	}
+	files := Parse(input)
+	if len(files) != len(p.Files) {
+		t.Fatalf("Expected %d files as Parse, got %d", len(files), len(p.Files))
+	}
+	for i, f := range p.Files {
+		if f.File != *files[i] {
+			t.Errorf("file %d = %+v, Parse gives %+v", i, f.File, *files[i])
+		}
+	}
+	if p.Stats() != ComputeStats(input) {
+		t.Errorf("Stats() = %+v, ComputeStats gives %+v", p.Stats(), ComputeStats(input))
+	}
+	return p
+}
+
+func FuzzParsePatch(f *testing.F) {
+	f.Add(mixedPatch)
+	f.Add(noNewlinePatch)
+	f.Add("preamble\n" + mixedPatch + noNewlinePatch)
+	f.Add("diff --git \"a/sp ace\" \"b/\\303\\274.txt\"\nsimilarity index 90%\nrename from sp ace\nrename to \"\\303\\274.txt\"\n")
+	f.Add("diff --git a/x b/x\n@@ -1 +1 @@\n-a\n+b\n\\ No newline at end of file\n")
+
+	f.Fuzz(func(t *testing.T, input string) {
+		// 受け付けたパッチは必ずそのまま書き戻せる
+		if _, err := ParsePatch(input); err != nil {
+			return
+		}
+		checkRoundTrip(t, input)
+	})
+}
+
+// FuzzParseGitDiff 実際の git diff の出力を解析する。
+// flags: 1=リネーム 2=実行権限 4=削除 8=バイナリ追加 16=コピー検出
+func FuzzParseGitDiff(f *testing.F) {
+	f.Add([]byte("a\nb\nc\n"), []byte("a\nB\nc\nd\n"), byte(0))
+	f.Add([]byte("no newline"), []byte("still none"), byte(0))
+	f.Add([]byte("1\n2\n3\n4\n5\n6\n7\n8\n"), []byte("1\n2\n3\n4\n5\n6\n7\nx\n"), byte(1))
+	f.Add([]byte("keep\n"), []byte("keep\n"), byte(2|4|8))
+	f.Add([]byte("same\nlines\nhere\n"), []byte("same\nlines\nhere\n"), byte(16))
+	f.Add([]byte(""), []byte("crlf\r\nline\r\n"), byte(0))
+	f.Add([]byte("bin\x00ary"), []byte("bin\x00ary\x01"), byte(1))
+
+	dir := f.TempDir()
+	git := func(t *testing.T, args ...string) string {
+		t.Helper()
+		cmd := exec.Command("git", append([]string{"-C", dir}, args...)...)
+		cmd.Env = append(os.Environ(), "GIT_CONFIG_NOSYSTEM=1", "GIT_CONFIG_GLOBAL="+os.DevNull, "LC_ALL=C")
+		out, err := cmd.Output()
+		if err != nil {
+			t.Fatalf("git %s: %v", strings.Join(args, " "), err)
+		}
+		return string(out)
+	}
+	write := func(t *testing.T, name string, content []byte, mode os.FileMode) {
+		t.Helper()
+		path := filepath.Join(dir, name)
+		os.MkdirAll(filepath.Dir(path), 0755)
+		if err := os.WriteFile(path, content, mode); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+		os.Chmod(path, mode)
+	}
+
+	f.Fuzz(func(t *testing.T, old, new []byte, flags byte) {
+		if _, err := os.Stat(filepath.Join(dir, ".git")); err != nil {
+			git(t, "init", "-q")
+		}
+		entries, _ := os.ReadDir(dir)
+		for _, e := range entries {
+			if e.Name() != ".git" {
+				os.RemoveAll(filepath.Join(dir, e.Name()))
+			}
+		}
+
+		// 変更前: a.txt と c.txt
+		write(t, "a.txt", old, 0644)
+		write(t, "c.txt", old, 0644)
+		git(t, "add", "-A")
+		before := strings.TrimSpace(git(t, "write-tree"))
+
+		// 変更後
+		target, mode := "a.txt", os.FileMode(0644)
+		if flags&1 != 0 {
+			os.Remove(filepath.Join(dir, "a.txt"))
+			target = "sub dir/ü.txt"
+		}
+		if flags&2 != 0 {
+			mode = 0755
+		}
+		write(t, target, new, mode)
+		if flags&4 != 0 {
+			os.Remove(filepath.Join(dir, "c.txt"))
+		}
+		if flags&8 != 0 {
+			write(t, "d.bin", append(new, 0), 0644)
+		}
+		git(t, "add", "-A")
+		after := strings.TrimSpace(git(t, "write-tree"))
+
+		args := []string{"diff", "--binary", "-M", "-C", before, after}
+		if flags&16 != 0 {
+			args = append(args, "--find-copies-harder")
+		}
+		checkRoundTrip(t, git(t, args...))
+	})
+}
@@ -0,0 +1,119 @@
+package patch
+
+import (
+	"strconv"
+	"strings"
+)
+
+// File status letters, as used by git diff --name-status
+const (
+	StatusAdded    = 'A'
+	StatusDeleted  = 'D'
+	StatusModified = 'M'
+	StatusRenamed  = 'R'
+	StatusCopied   = 'C'
+)
+
+// File is one file section of a git-style unified diff
+type File struct {
+	OldPath    string
+	NewPath    string
+	Status     byte
+	OldMode    string
+	NewMode    string
+	Similarity int
+	Binary     bool
+	Insertions int
+	Deletions  int
+}
+
+// Path returns the path a file is known by after the change
+func (f *File) Path() string {
+	if f.Status == StatusDeleted {
+		return f.OldPath
+	}
+	return f.NewPath
+}
+
+// DisplayPath returns the path shown in diffstats, "old => new" for renames and copies
+func (f *File) DisplayPath() string {
+	if (f.Status == StatusRenamed || f.Status == StatusCopied) && f.OldPath != f.NewPath {
+		return f.OldPath + " => " + f.NewPath
+	}
+	return f.Path()
+}
+
+// Parse splits a git-style unified diff into files with their change counts.
+// Text that does not belong to a "diff --git" section is ignored, and so is
+// everything from a malformed hunk on.
+func Parse(p string) []*File {
+	ps := &parser{}
+	_ = ps.parse(p)
+	return ps.files()
+}
+
+// newFile starts a file from its "diff --git" line
+func newFile(line string) *File {
+	oldPath, newPath := splitGitHeader(strings.TrimPrefix(line, "diff --git "))
+	return &File{OldPath: oldPath, NewPath: newPath, Status: StatusModified}
+}
+
+// parseHeaderLine records what an extended header line says about the file.
+// Lines it does not know, such as "---" and "+++", are ignored.
+func (f *File) parseHeaderLine(line string) {
+	switch {
+	case strings.HasPrefix(line, "new file mode "):
+		f.Status = StatusAdded
+		f.NewMode = strings.TrimPrefix(line, "new file mode ")
+	case strings.HasPrefix(line, "deleted file mode "):
+		f.Status = StatusDeleted
+		f.OldMode = strings.TrimPrefix(line, "deleted file mode ")
+	case strings.HasPrefix(line, "old mode "):
+		f.OldMode = strings.TrimPrefix(line, "old mode ")
+	case strings.HasPrefix(line, "new mode "):
+		f.NewMode = strings.TrimPrefix(line, "new mode ")
+	case strings.HasPrefix(line, "index "):
+		// "index abc..def 100644" carries the mode of unchanged-mode files
+		if fields := strings.Fields(line); len(fields) == 3 && f.OldMode == "" && f.NewMode == "" {
+			f.OldMode, f.NewMode = fields[2], fields[2]
+		}
+	case strings.HasPrefix(line, "similarity index "):
+		f.Similarity, _ = strconv.Atoi(strings.TrimSuffix(strings.TrimPrefix(line, "similarity index "), "%"))
+	case strings.HasPrefix(line, "rename from "):
+		f.Status = StatusRenamed
+		f.OldPath = unquote(strings.TrimPrefix(line, "rename from "))
+	case strings.HasPrefix(line, "rename to "):
+		f.Status = StatusRenamed
+		f.NewPath = unquote(strings.TrimPrefix(line, "rename to "))
+	case strings.HasPrefix(line, "copy from "):
+		f.Status = StatusCopied
+		f.OldPath = unquote(strings.TrimPrefix(line, "copy from "))
+	case strings.HasPrefix(line, "copy to "):
+		f.Status = StatusCopied
+		f.NewPath = unquote(strings.TrimPrefix(line, "copy to "))
+	case strings.HasPrefix(line, "Binary files ") || line == "GIT binary patch":
+		f.Binary = true
+	}
+}
+
+// SplitFiles splits a git-style unified diff into one patch per file, in patch order.
+// Text before the first "diff --git" line is dropped, like everything Parse ignores.
+func SplitFiles(p string) []string {
+	ps := &parser{keep: true}
+	_ = ps.parse(p)
+	parts := make([]string, len(ps.patch.Files))
+	for i, f := range ps.patch.Files {
+		parts[i] = f.String()
+	}
+	return parts
+}
+
+// Summarize adds up the change counts of files
+func Summarize(files []*File) Stats {
+	stats := Stats{Files: len(files)}
+	for _, f := range files {
+		stats.Insertions += f.Insertions
+		stats.Deletions += f.Deletions
+	}
+	return stats
+}
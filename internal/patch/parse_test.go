@@ -0,0 +1,148 @@
+package patch
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+// mixedPatch git diff --cached の出力（変更・モード変更・追加・リネーム・削除・バイナリ）
+const mixedPatch = `diff --git a/f.txt b/f.txt
+old mode 100644
+new mode 100755
+index de98044..0c8d6a8
+--- a/f.txt
++++ b/f.txt
+@@ -1,3 +1,4 @@
+ a
+-b
++B
+ c
++d
+diff --git a/n.txt b/n.txt
+new file mode 100644
+index 0000000..3e75765
+--- /dev/null
++++ b/n.txt
+@@ -0,0 +1 @@
++new
+diff --git a/old.txt b/new.txt
+similarity index 100%
+rename from old.txt
+rename to new.txt
+diff --git a/gone.txt b/gone.txt
+deleted file mode 100644
+index 587be6b..0000000
+--- a/gone.txt
++++ /dev/null
+@@ -1 +0,0 @@
+-x
+diff --git a/bin.dat b/bin.dat
+index 1234567..89abcde 100644
+Binary files a/bin.dat and b/bin.dat differ
+`
+
+func TestParse(t *testing.T) {
+	files := Parse(mixedPatch)
+	if len(files) != 5 {
+		t.Fatalf("Expected 5 files, but got %d", len(files))
+	}
+
+	expected := []File{
+		{OldPath: "f.txt", NewPath: "f.txt", Status: StatusModified, OldMode: "100644", NewMode: "100755", Insertions: 2, Deletions: 1},
+		{OldPath: "n.txt", NewPath: "n.txt", Status: StatusAdded, NewMode: "100644", Insertions: 1},
+		{OldPath: "old.txt", NewPath: "new.txt", Status: StatusRenamed, Similarity: 100},
+		{OldPath: "gone.txt", NewPath: "gone.txt", Status: StatusDeleted, OldMode: "100644", Deletions: 1},
+		{OldPath: "bin.dat", NewPath: "bin.dat", Status: StatusModified, OldMode: "100644", NewMode: "100644", Binary: true},
+	}
+	for i, want := range expected {
+		if *files[i] != want {
+			t.Errorf("file %d = %+v, want %+v", i, *files[i], want)
+		}
+	}
+}
+
+func TestWriteFormats(t *testing.T) {
+	files := Parse(mixedPatch)
+
+	tests := []struct {
+		name     string
+		write    func(*bytes.Buffer)
+		expected string
+	}{
+		{
+			name:  "stat",
+			write: func(b *bytes.Buffer) { WriteStat(b, files, DefaultStatWidth) },
+			expected: " f.txt              |   3 ++-\n" +
+				" n.txt              |   1 +\n" +
+				" old.txt => new.txt |   0\n" +
+				" gone.txt           |   1 -\n" +
+				" bin.dat            | Bin\n" +
+				" 5 files changed, 3 insertions(+), 2 deletions(-)\n",
+		},
+		{
+			name:     "numstat",
+			write:    func(b *bytes.Buffer) { WriteNumstat(b, files) },
+			expected: "2\t1\tf.txt\n1\t0\tn.txt\n0\t0\told.txt => new.txt\n0\t1\tgone.txt\n-\t-\tbin.dat\n",
+		},
+		{
+			name:     "name-only",
+			write:    func(b *bytes.Buffer) { WriteNameOnly(b, files) },
+			expected: "f.txt\nn.txt\nnew.txt\ngone.txt\nbin.dat\n",
+		},
+		{
+			name:     "name-status",
+			write:    func(b *bytes.Buffer) { WriteNameStatus(b, files) },
+			expected: "M\tf.txt\nA\tn.txt\nR100\told.txt\tnew.txt\nD\tgone.txt\nM\tbin.dat\n",
+		},
+		{
+			name:  "summary",
+			write: func(b *bytes.Buffer) { WriteSummary(b, files) },
+			expected: " mode change 100644 => 100755 f.txt\n" +
+				" create mode 100644 n.txt\n" +
+				" rename old.txt => new.txt (100%)\n" +
+				" delete mode 100644 gone.txt\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var b bytes.Buffer
+			tt.write(&b)
+			if b.String() != tt.expected {
+				t.Errorf("Expected:\n%s\nbut got:\n%s", tt.expected, b.String())
+			}
+		})
+	}
+}
+
+func TestWriteStatScalesGraph(t *testing.T) {
+	files := []*File{{OldPath: "big.txt", NewPath: "big.txt", Status: StatusModified, Insertions: 1000, Deletions: 500}}
+
+	var b bytes.Buffer
+	WriteStat(&b, files, DefaultStatWidth)
+	line, _, _ := strings.Cut(b.String(), "\n")
+	if len(line) > DefaultStatWidth {
+		t.Errorf("Expected graph to fit in %d columns, but got %d: %s", DefaultStatWidth, len(line), line)
+	}
+}
+
+func TestSplitFiles(t *testing.T) {
+	parts := SplitFiles("preamble\n" + mixedPatch)
+	if len(parts) != 5 {
+		t.Fatalf("Expected 5 parts, but got %d", len(parts))
+	}
+	if strings.Join(parts, "") != mixedPatch {
+		t.Errorf("Expected the parts to add up to the patch")
+	}
+	for i, part := range parts {
+		files := Parse(part)
+		if len(files) != 1 || files[0].Path() != Parse(mixedPatch)[i].Path() {
+			t.Errorf("part %d does not hold exactly file %d: %q", i, i, part)
+		}
+	}
+
+	if parts := SplitFiles(""); len(parts) != 0 {
+		t.Errorf("Expected no parts for an empty patch, but got %d", len(parts))
+	}
+}
@@ -0,0 +1,84 @@
+package patch
+
+import (
+	"strconv"
+	"strings"
+)
+
+// ChangedPaths returns the paths touched by a git-style unified diff, in patch order.
+// Renamed and copied files contribute both their old and new paths.
+func ChangedPaths(p string) []string {
+	var paths []string
+	seen := make(map[string]bool)
+	for _, f := range Parse(p) {
+		for _, path := range []string{f.OldPath, f.NewPath} {
+			if path != "" && !seen[path] {
+				seen[path] = true
+				paths = append(paths, path)
+			}
+		}
+	}
+	return paths
+}
+
+// splitGitHeader splits the "a/<old> b/<new>" part of a diff --git line into paths
+func splitGitHeader(rest string) (string, string) {
+	// Quoted paths are used when names contain special characters
+	if strings.HasPrefix(rest, `"`) {
+		old, remainder, ok := cutQuoted(rest)
+		if !ok {
+			return "", ""
+		}
+		return stripPrefix(old), stripPrefix(unquote(strings.TrimSpace(remainder)))
+	}
+	if strings.HasSuffix(rest, `"`) {
+		if i := strings.Index(rest, ` "`); i >= 0 {
+			return stripPrefix(rest[:i]), stripPrefix(unquote(rest[i+1:]))
+		}
+	}
+
+	// Unquoted: both names are the same length unless the file was renamed,
+	// in which case the rename lines provide the exact paths
+	if len(rest)%2 == 1 {
+		half := len(rest) / 2
+		if rest[half] == ' ' && stripPrefix(rest[:half]) == stripPrefix(rest[half+1:]) {
+			return stripPrefix(rest[:half]), stripPrefix(rest[half+1:])
+		}
+	}
+	if i := strings.Index(rest, " b/"); i >= 0 {
+		return stripPrefix(rest[:i]), stripPrefix(rest[i+1:])
+	}
+	return "", ""
+}
+
+// cutQuoted splits a leading C-style quoted string from the rest of s
+func cutQuoted(s string) (string, string, bool) {
+	for i := 1; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case '"':
+			return unquote(s[:i+1]), s[i+1:], true
+		}
+	}
+	return "", "", false
+}
+
+// unquote decodes a C-style quoted path as written by git; unquoted paths are returned as is
+func unquote(s string) string {
+	if len(s) < 2 || s[0] != '"' || s[len(s)-1] != '"' {
+		return s
+	}
+	if u, err := strconv.Unquote(s); err == nil {
+		return u
+	}
+	return s
+}
+
+// stripPrefix removes the "a/" or "b/" prefix git adds to paths
+func stripPrefix(path string) string {
+	if strings.HasPrefix(path, "a/") || strings.HasPrefix(path, "b/") {
+		return path[2:]
+	}
+	return path
+}
@@ -0,0 +1,43 @@
+package patch
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestChangedPaths(t *testing.T) {
+	tests := []struct {
+		name     string
+		patch    string
+		expected []string
+	}{
+		{
+			name:     "simple files",
+			patch:    "diff --git a/src/main.go b/src/main.go\nindex 1..2 100644\n--- a/src/main.go\n+++ b/src/main.go\n@@ -1 +1 @@\n-a\n+b\ndiff --git a/README.md b/README.md\nnew file mode 100644\n",
+			expected: []string{"src/main.go", "README.md"},
+		},
+		{
+			name:     "path with spaces",
+			patch:    "diff --git a/my file.txt b/my file.txt\nnew file mode 100644\n",
+			expected: []string{"my file.txt"},
+		},
+		{
+			name:     "rename",
+			patch:    "diff --git a/old.txt b/new.txt\nsimilarity index 100%\nrename from old.txt\nrename to new.txt\n",
+			expected: []string{"old.txt", "new.txt"},
+		},
+		{
+			name:     "quoted path",
+			patch:    "diff --git \"a/\\346\\227\\245.txt\" \"b/\\346\\227\\245.txt\"\nnew file mode 100644\n",
+			expected: []string{"日.txt"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ChangedPaths(tt.patch); !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("ChangedPaths() = %q, want %q", got, tt.expected)
+			}
+		})
+	}
+}
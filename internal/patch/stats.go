@@ -0,0 +1,83 @@
+package patch
+
+import "bytes"
+
+// Stats summarises the size of a patch
+type Stats struct {
+	Files      int `json:"files"`
+	Insertions int `json:"insertions"`
+	Deletions  int `json:"deletions"`
+}
+
+// ComputeStats counts changed files and added/removed lines in a git-style unified diff
+func ComputeStats(p string) Stats {
+	return Summarize(Parse(p))
+}
+
+// ForEachChange calls fn for every added ('+') or removed ('-') line inside the hunks
+// of a unified diff, passing the line content without its marker. File headers such
+// as "--- a/file" are never reported, even when they look like changed lines.
+func ForEachChange(p string, fn func(op byte, line string)) {
+	ps := &parser{change: fn}
+	_ = ps.parse(p)
+}
+
+// Writer parses a patch written to it like Parse, without holding it in memory:
+// it keeps the files with their change counts, but of each hunk line only the
+// first character, which tells what kind of line it is. Close must be called
+// once everything is written.
+type Writer struct {
+	p    parser
+	line []byte
+}
+
+// Write parses the complete lines of b and keeps the start of an unfinished one
+func (w *Writer) Write(b []byte) (int, error) {
+	n := len(b)
+	for len(b) > 0 {
+		end := bytes.IndexByte(b, '\n')
+		chunk := b
+		if end >= 0 {
+			chunk = b[:end]
+		}
+		// Headers are kept whole for their paths
+		if w.p.inHunk() {
+			chunk = chunk[:min(len(chunk), 1-len(w.line))]
+		}
+		w.line = append(w.line, chunk...)
+		if end < 0 {
+			break
+		}
+		w.p.feed(string(w.line))
+		w.line = w.line[:0]
+		b = b[end+1:]
+	}
+	return n, nil
+}
+
+// Close parses a last line without a newline. It never fails; Validate reports
+// what is wrong with the patch.
+func (w *Writer) Close() error {
+	complete := len(w.line) == 0
+	if !complete {
+		w.p.feed(string(w.line))
+		w.line = nil
+	}
+	_ = w.p.finish(complete)
+	return nil
+}
+
+// Files returns the files of the patch, as Parse does
+func (w *Writer) Files() []*File {
+	return w.p.files()
+}
+
+// Stats returns the stats of the patch, as ComputeStats does
+func (w *Writer) Stats() Stats {
+	return Summarize(w.Files())
+}
+
+// Validate returns the error Validate gives for the patch
+func (w *Writer) Validate() error {
+	return w.p.validate()
+}
@@ -0,0 +1,79 @@
+package patch
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+var statsTests = []struct {
+	name     string
+	patch    string
+	expected Stats
+}{
+	{
+		name:     "empty patch",
+		patch:    "",
+		expected: Stats{},
+	},
+	{
+		name:     "new file",
+		patch:    "diff --git a/test.txt b/test.txt\nnew file mode 100644\nindex 0000000..3b18e51\n--- /dev/null\n+++ b/test.txt\n@@ -0,0 +1 @@\n+Hello, World!\n",
+		expected: Stats{Files: 1, Insertions: 1},
+	},
+	{
+		name: "modified lines that look like headers",
+		patch: "diff --git a/a.txt b/a.txt\nindex 1111111..2222222 100644\n--- a/a.txt\n+++ b/a.txt\n@@ -1,3 +1,3 @@\n context\n---- removed\n+++ added\n context\n" +
+			"diff --git a/b.txt b/b.txt\ndeleted file mode 100644\nindex 3333333..0000000\n--- a/b.txt\n+++ /dev/null\n@@ -1,2 +0,0 @@\n-one\n-two\n\\ No newline at end of file\n",
+		expected: Stats{Files: 2, Insertions: 1, Deletions: 3},
+	},
+	{
+		name:     "binary file",
+		patch:    "diff --git a/bin.dat b/bin.dat\nnew file mode 100644\nindex 0000000..1234567\nBinary files /dev/null and b/bin.dat differ\n",
+		expected: Stats{Files: 1},
+	},
+}
+
+func TestComputeStats(t *testing.T) {
+	for _, tt := range statsTests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ComputeStats(tt.patch); got != tt.expected {
+				t.Errorf("ComputeStats() = %+v, want %+v", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestWriter(t *testing.T) {
+	longLine := "+" + strings.Repeat("x", 4096) + "\n"
+	tests := append(statsTests, struct {
+		name     string
+		patch    string
+		expected Stats
+	}{
+		name:     "long hunk lines",
+		patch:    "diff --git a/a.txt b/a.txt\n--- a/a.txt\n+++ b/a.txt\n@@ -1 +1,2 @@\n-" + longLine[1:] + longLine + longLine,
+		expected: Stats{Files: 1, Insertions: 2, Deletions: 1},
+	})
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// 行の途中で区切られた書き込みでも ComputeStats と同じ結果になる
+			for _, size := range []int{1, 7, len(tt.patch) + 1} {
+				w := &Writer{}
+				for rest := tt.patch; rest != ""; {
+					n := min(size, len(rest))
+					w.Write([]byte(rest[:n]))
+					rest = rest[n:]
+				}
+				w.Close()
+				if got := w.Stats(); got != tt.expected || got != ComputeStats(tt.patch) {
+					t.Errorf("Writer(%d-byte writes) = %+v, want %+v", size, got, tt.expected)
+				}
+				if got, want := fmt.Sprint(w.Validate()), fmt.Sprint(Validate(tt.patch)); got != want {
+					t.Errorf("Writer(%d-byte writes).Validate() = %s, want %s", size, got, want)
+				}
+			}
+		})
+	}
+}
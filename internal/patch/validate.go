@@ -0,0 +1,10 @@
+package patch
+
+// Validate checks that p is a well-formed git-style unified diff: it starts with a
+// "diff --git" header, every hunk header parses and every hunk has as many lines as
+// its header announces. Extended headers and binary data are not checked.
+func Validate(p string) error {
+	ps := &parser{}
+	_ = ps.parse(p)
+	return ps.validate()
+}
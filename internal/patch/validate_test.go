@@ -0,0 +1,44 @@
+package patch
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	// 正常なパッチ（変更・追加・リネーム・削除・バイナリ）
+	if err := Validate(mixedPatch); err != nil {
+		t.Errorf("Validate(mixedPatch) error = %v", err)
+	}
+
+	// ハンク内の空行は空のコンテキスト行として扱う
+	blank := "diff --git a/f b/f\n--- a/f\n+++ b/f\n@@ -1,3 +1,3 @@\n a\n\n-b\n+c\n"
+	if err := Validate(blank); err != nil {
+		t.Errorf("Validate() error = %v for an empty context line", err)
+	}
+	if got := ComputeStats(blank); got != (Stats{Files: 1, Insertions: 1, Deletions: 1}) {
+		t.Errorf("ComputeStats() = %+v for an empty context line", got)
+	}
+
+	tests := []struct {
+		name  string
+		patch string
+		want  string
+	}{
+		{"空", "", "empty patch"},
+		{"gitの差分でない", "hello\n", "not a git diff"},
+		{"前置きのある差分", "hello\n" + mixedPatch, "not a git diff"},
+		{"不正なハンクヘッダ", "diff --git a/f b/f\n--- a/f\n+++ b/f\n@@ -1 +x @@\n-a\n", "malformed hunk header"},
+		{"途中で切れたハンク", "diff --git a/f b/f\n--- a/f\n+++ b/f\n@@ -1,2 +1,2 @@\n a\n", "truncated hunk"},
+		{"ハンク内の不正な行", "diff --git a/f b/f\n--- a/f\n+++ b/f\n@@ -1 +1 @@\n?a\n", "unexpected line"},
+		{"ヘッダより長いハンク", "diff --git a/f b/f\n--- a/f\n+++ b/f\n@@ -1 +1 @@\n-a\n-b\n+c\n", "hunk longer"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Validate(tt.patch)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Validate() error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,119 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Errors matched with errors.Is to tell failures apart. ErrNewerFormat is
+// declared with the format versions.
+var (
+	// ErrNotFound is returned when no mini-commit matches the requested ID
+	ErrNotFound = errors.New("mini-commit not found")
+	// ErrAmbiguousID is returned when a short ID matches several mini-commits
+	ErrAmbiguousID = errors.New("short mini-commit ID is ambiguous")
+	// ErrLocked is returned when another process holds the store lock for too long
+	ErrLocked = errors.New("mini-commit store is locked")
+	// ErrCorrupt is matched by errors caused by damaged or inconsistent data,
+	// which fsck can find and repair
+	ErrCorrupt = errors.New("mini-commit store is corrupt")
+)
+
+// MinIDPrefix is the shortest prefix accepted in place of a full ID
+const MinIDPrefix = 4
+
+// NotFoundError reports the ID that could not be found; it matches ErrNotFound with errors.Is
+type NotFoundError struct {
+	ID string
+}
+
+func (e *NotFoundError) Error() string {
+	return fmt.Sprintf("mini-commit '%s' not found", e.ID)
+}
+
+// Is makes errors.Is(err, ErrNotFound) succeed
+func (e *NotFoundError) Is(target error) bool {
+	return target == ErrNotFound
+}
+
+// AmbiguousIDError reports a short ID and the IDs it matches; it matches
+// ErrAmbiguousID with errors.Is
+type AmbiguousIDError struct {
+	Prefix     string
+	Candidates []string
+}
+
+func (e *AmbiguousIDError) Error() string {
+	return fmt.Sprintf("short ID '%s' is ambiguous; it matches %s", e.Prefix, strings.Join(e.Candidates, ", "))
+}
+
+// Is makes errors.Is(err, ErrAmbiguousID) succeed
+func (e *AmbiguousIDError) Is(target error) bool {
+	return target == ErrAmbiguousID
+}
+
+// LockError reports the lock file another process holds; it matches ErrLocked
+// with errors.Is. Stale is set when the process that created the lock, whose
+// PID it records, is no longer running.
+type LockError struct {
+	Path  string
+	PID   int
+	Stale bool
+}
+
+func (e *LockError) Error() string {
+	if e.Stale {
+		return fmt.Sprintf("unable to lock '%s': it was left behind by process %d, which is no longer running", e.Path, e.PID)
+	}
+	return fmt.Sprintf("unable to lock '%s': another git-mini-commit process seems to be running", e.Path)
+}
+
+// Is makes errors.Is(err, ErrLocked) succeed
+func (e *LockError) Is(target error) bool {
+	return target == ErrLocked
+}
+
+// corruption marks an error as caused by damaged data; it matches ErrCorrupt with errors.Is
+type corruption struct {
+	error
+}
+
+func (e corruption) Unwrap() error {
+	return e.error
+}
+
+// Is makes errors.Is(err, ErrCorrupt) succeed
+func (e corruption) Is(target error) bool {
+	return target == ErrCorrupt
+}
+
+// MatchID returns the position in ids of the ID equal to ref or, failing
+// that, of the only one starting with ref. Prefixes shorter than MinIDPrefix
+// only match exactly.
+func MatchID(ids []string, ref string) (int, error) {
+	for i, id := range ids {
+		if id == ref {
+			return i, nil
+		}
+	}
+
+	match := -1
+	var candidates []string
+	if len(ref) >= MinIDPrefix {
+		for i, id := range ids {
+			if strings.HasPrefix(id, ref) {
+				match = i
+				candidates = append(candidates, id)
+			}
+		}
+	}
+	switch len(candidates) {
+	case 0:
+		return -1, &NotFoundError{ID: ref}
+	case 1:
+		return match, nil
+	default:
+		return -1, &AmbiguousIDError{Prefix: ref, Candidates: candidates}
+	}
+}
@@ -0,0 +1,98 @@
+package storage
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"git-mini-commit/testutils"
+)
+
+func TestMatchID(t *testing.T) {
+	ids := []string{"abcd1234", "abcd5678", "ef012345", "ef01"}
+
+	tests := []struct {
+		name     string
+		ref      string
+		expected int
+		err      error
+	}{
+		{name: "full ID", ref: "abcd5678", expected: 1},
+		{name: "unique prefix", ref: "ef012", expected: 2},
+		{name: "exact ID that prefixes another", ref: "ef01", expected: 3},
+		{name: "ambiguous prefix", ref: "abcd", err: ErrAmbiguousID},
+		{name: "too short", ref: "abc", err: ErrNotFound},
+		{name: "no match", ref: "9999", err: ErrNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := MatchID(ids, tt.ref)
+			if tt.err != nil {
+				if !errors.Is(err, tt.err) {
+					t.Errorf("MatchID(%q) error = %v, want %v", tt.ref, err, tt.err)
+				}
+				return
+			}
+			if err != nil || got != tt.expected {
+				t.Errorf("MatchID(%q) = %d, %v, want %d", tt.ref, got, err, tt.expected)
+			}
+		})
+	}
+
+	// 曖昧なIDは候補を持つ
+	_, err := MatchID(ids, "abcd")
+	var ambiguous *AmbiguousIDError
+	if !errors.As(err, &ambiguous) || len(ambiguous.Candidates) != 2 {
+		t.Errorf("Expected the candidates of an ambiguous ID, got %v", err)
+	}
+}
+
+func TestErrorKinds(t *testing.T) {
+	repo := testutils.NewTestGitRepo(t)
+	defer repo.Cleanup()
+
+	storage, err := NewStorage()
+	if err != nil {
+		t.Fatalf("NewStorage() error = %v", err)
+	}
+	mc := newTestMiniCommit(t, storage, "First", testPatch("a.txt", "a"))
+
+	// 1. 短いIDでも取得できる
+	if found, err := storage.GetMiniCommit(mc.ID[:MinIDPrefix]); err != nil || found.ID != mc.ID {
+		t.Errorf("Expected the mini-commit by its short ID, got %v", err)
+	}
+
+	// 2. ロックが解放されなければ ErrLocked
+	previous := lockTimeout
+	lockTimeout = 50 * time.Millisecond
+	defer func() { lockTimeout = previous }()
+	lockPath := filepath.Join(storage.basePath, LockFile)
+	os.WriteFile(lockPath, []byte("1\n"), 0644)
+	err = storage.DeleteMiniCommit(mc.ID)
+	var lockErr *LockError
+	if !errors.Is(err, ErrLocked) || !errors.As(err, &lockErr) || lockErr.Path != lockPath {
+		t.Errorf("Expected ErrLocked, got %v", err)
+	}
+	os.Remove(lockPath)
+
+	// 3. 壊れたオブジェクトと解析できないインデックスは ErrCorrupt
+	saved, err := storage.FindMiniCommit(mc.ID)
+	if err != nil {
+		t.Fatalf("FindMiniCommit() error = %v", err)
+	}
+	os.WriteFile(storage.objectPath(saved.PatchHash), []byte("not zlib"), 0644)
+	if _, err := storage.GetMiniCommit(mc.ID); !errors.Is(err, ErrCorrupt) {
+		t.Errorf("Expected ErrCorrupt for a damaged object, got %v", err)
+	}
+	os.Remove(storage.objectPath(saved.PatchHash))
+	if _, err := storage.GetMiniCommit(mc.ID); !errors.Is(err, ErrCorrupt) {
+		t.Errorf("Expected ErrCorrupt for a missing object, got %v", err)
+	}
+	os.WriteFile(filepath.Join(storage.basePath, IndexFile), []byte("invalid json"), 0644)
+	if _, err := storage.LoadMiniCommits(); !errors.Is(err, ErrCorrupt) {
+		t.Errorf("Expected ErrCorrupt for a damaged index, got %v", err)
+	}
+}
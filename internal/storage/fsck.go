@@ -0,0 +1,576 @@
+package storage
+
+import (
+	"crypto/sha1"
+	"encoding"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"git-mini-commit/internal/patch"
+	"git-mini-commit/internal/types"
+)
+
+// LostFoundDir receives data that Repair could not keep, one subdirectory per run
+const LostFoundDir = "lost-found"
+
+// OpRepair is logged when Repair changes the stack
+const OpRepair = "repair"
+
+// Problem kinds reported by Check and Repair
+const (
+	ProblemCorruptIndex  = "corrupt-index"
+	ProblemDuplicateID   = "duplicate-id"
+	ProblemMissingPatch  = "missing-patch"
+	ProblemOrphanPatch   = "orphan-patch"
+	ProblemPatchMismatch = "patch-mismatch"
+	ProblemIDMismatch    = "id-mismatch"
+	ProblemBadPatch      = "bad-patch"
+	ProblemBadObject     = "bad-object"
+)
+
+// Problem is an inconsistency found in the store. ID is the mini-commit
+// concerned, or the object name for an object no mini-commit refers to.
+// Repair describes what Repair did about it and is empty for Check.
+type Problem struct {
+	Kind   string
+	ID     string
+	Detail string
+	Repair string
+}
+
+// storeScan is the raw state of the store as found on disk
+type storeScan struct {
+	index    types.MiniCommitList
+	indexErr error
+	trash    []TrashEntry
+	objects  map[string]*scannedPatch
+	corrupt  map[string]error
+}
+
+// scannedPatch is what fsck needs to know about a patch, learnt by reading it
+// once as a stream: why it is malformed, if it is, its stats, and the state of
+// the ID hash after its content, from which its ID for any creation time follows
+type scannedPatch struct {
+	err     error
+	stats   patch.Stats
+	idState []byte
+	modTime time.Time
+}
+
+// scanPatch reads a patch from r. Errors are those of r.
+func scanPatch(r io.Reader) (*scannedPatch, error) {
+	id := sha1.New()
+	parsed := &patch.Writer{}
+	if _, err := io.Copy(io.MultiWriter(id, parsed), r); err != nil {
+		return nil, err
+	}
+	parsed.Close()
+	state, err := id.(encoding.BinaryMarshaler).MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	return &scannedPatch{err: parsed.Validate(), stats: parsed.Stats(), idState: state}, nil
+}
+
+// generateID returns the ID GenerateID gives the patch with timestamp
+func (p *scannedPatch) generateID(timestamp time.Time) string {
+	h := sha1.New()
+	// The state comes from the same kind of hash, so it is always restored
+	_ = h.(encoding.BinaryUnmarshaler).UnmarshalBinary(p.idState)
+	_, _ = io.WriteString(h, timestamp.Format(time.RFC3339Nano))
+	return fmt.Sprintf("%x", h.Sum(nil))
+}
+
+// Check verifies that the index and the patch objects agree and that every
+// patch is well-formed, without changing anything
+func (s *Storage) Check() ([]Problem, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	scan, err := s.scanStore()
+	if err != nil {
+		return nil, err
+	}
+	problems, _ := s.diagnose(scan)
+	return problems, nil
+}
+
+// Repair fixes the problems found by Check: the index is rebuilt from the
+// stack recorded in the operation log and the surviving patch objects, missing
+// patches are restored from the operation log where it has a copy, and data
+// that cannot be kept (a corrupt index, damaged objects, malformed patches) is
+// moved to lost-found. It returns the problems with what was done about each.
+func (s *Storage) Repair() ([]Problem, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	unlock, err := s.lockStore()
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	scan, err := s.scanStore()
+	if err != nil {
+		return nil, err
+	}
+	problems, plan := s.diagnose(scan)
+	if len(problems) == 0 {
+		return nil, nil
+	}
+
+	quarantine := filepath.Join(s.basePath, LostFoundDir, time.Now().Format("20060102-150405"))
+	quarantined := func(name string) string {
+		return filepath.ToSlash(filepath.Join(LostFoundDir, filepath.Base(quarantine), name))
+	}
+	// Data is copied as a stream, as a patch need not fit in memory
+	moveAside := func(name string, open func() (io.ReadCloser, error)) error {
+		if err := os.MkdirAll(quarantine, 0755); err != nil {
+			return fmt.Errorf("failed to create lost-found directory: %w", err)
+		}
+		r, err := open()
+		if err != nil {
+			return fmt.Errorf("failed to quarantine %s: %v", name, err)
+		}
+		defer r.Close()
+		f, err := os.OpenFile(filepath.Join(quarantine, name), os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0644)
+		if err != nil {
+			return fmt.Errorf("failed to quarantine %s: %v", name, err)
+		}
+		_, err = io.Copy(f, r)
+		if closeErr := f.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			return fmt.Errorf("failed to quarantine %s: %v", name, err)
+		}
+		return nil
+	}
+
+	if scan.indexErr != nil {
+		open := func() (io.ReadCloser, error) { return os.Open(filepath.Join(s.basePath, IndexFile)) }
+		if err := moveAside(IndexFile, open); err != nil {
+			return nil, err
+		}
+	}
+	for key, hash := range plan.dropped {
+		open := func() (io.ReadCloser, error) { return s.openObject(hash) }
+		if content, ok := plan.inline[hash]; ok {
+			open = func() (io.ReadCloser, error) { return io.NopCloser(strings.NewReader(content)), nil }
+		}
+		if err := moveAside(key+".patch", open); err != nil {
+			return nil, err
+		}
+	}
+
+	// Damaged objects are removed so that the rebuilt index can store their patch
+	// again, and malformed patches unless something that is kept still refers to them
+	var unwanted []string
+	for hash := range scan.corrupt {
+		open := func() (io.ReadCloser, error) { return os.Open(s.objectPath(hash)) }
+		if err := moveAside(hash, open); err != nil {
+			return nil, err
+		}
+		unwanted = append(unwanted, hash)
+	}
+	keep := make(map[string]bool)
+	for _, mc := range plan.rebuilt {
+		keep[mc.PatchHash] = true
+	}
+	for _, entry := range scan.trash {
+		keep[entry.PatchHash] = true
+	}
+	for _, hash := range plan.dropped {
+		if !keep[hash] {
+			unwanted = append(unwanted, hash)
+		}
+	}
+	for _, hash := range unwanted {
+		if err := os.Remove(s.objectPath(hash)); err != nil && !os.IsNotExist(err) {
+			return nil, fmt.Errorf("failed to delete patch object: %w", err)
+		}
+	}
+
+	if err := s.saveIndex(plan.rebuilt); err != nil {
+		return nil, fmt.Errorf("failed to save index: %w", err)
+	}
+
+	kept := idSet(plan.rebuilt)
+	indexed := idSet(scan.index)
+	for i := range problems {
+		p := &problems[i]
+		_, isDropped := plan.dropped[p.ID]
+		switch {
+		case p.Kind == ProblemCorruptIndex:
+			p.Repair = "moved to " + quarantined(IndexFile) + " and rebuilt the index from the operation log and the patch objects"
+		case p.Kind == ProblemBadObject:
+			p.Repair = "moved to " + quarantined(p.ID)
+		case isDropped && p.Kind == ProblemBadPatch:
+			p.Repair = "moved to " + quarantined(p.ID+".patch")
+		case p.Kind == ProblemOrphanPatch && plan.unrecorded[p.ID]:
+			p.Repair = "added back to the index (its message and creation time were not recorded)"
+		case p.Kind == ProblemOrphanPatch:
+			p.Repair = "added back to the index"
+		case p.Kind == ProblemMissingPatch && kept[p.ID]:
+			p.Repair = "restored the patch from the operation log"
+		case p.Kind == ProblemMissingPatch && indexed[p.ID]:
+			p.Repair = "removed the index entry"
+		case p.Kind == ProblemMissingPatch:
+			p.Repair = "left out of the rebuilt index"
+		case p.Kind == ProblemPatchMismatch:
+			p.Repair = "updated the stats in the index from the patch"
+		case p.Kind == ProblemDuplicateID:
+			p.Repair = "removed the duplicate index entry"
+		case p.Kind == ProblemIDMismatch:
+			p.Repair = "kept as is; the ID cannot be verified"
+		}
+	}
+
+	// Log the repair so that entries it removed can be brought back with undo
+	before := stackIDs(scan.index)
+	var removed []types.MiniCommit
+	for _, mc := range scan.index {
+		if hash, ok := plan.dropped[mc.ID]; ok && !kept[mc.ID] {
+			mc.PatchHash = hash
+			removed = append(removed, mc)
+		}
+	}
+	op := Operation{Kind: OpRepair, Summary: fmt.Sprintf("%d problem(s)", len(problems)), Before: before,
+		After: stackIDs(plan.rebuilt), Removed: logged(removed)}
+	if err := s.appendOperation(op); err != nil {
+		return nil, err
+	}
+	return problems, nil
+}
+
+// scanStore reads the index and the trash, and streams every patch object
+// through scanPatch, so that none is held in memory
+func (s *Storage) scanStore() (*storeScan, error) {
+	scan := &storeScan{objects: make(map[string]*scannedPatch), corrupt: make(map[string]error)}
+
+	data, err := os.ReadFile(filepath.Join(s.basePath, IndexFile))
+	switch {
+	case os.IsNotExist(err):
+	case err != nil:
+		return nil, fmt.Errorf("failed to read index file: %w", err)
+	default:
+		if index, err := decodeIndex(data); err != nil {
+			scan.indexErr = err
+		} else {
+			scan.index = index.MiniCommits
+		}
+	}
+
+	if scan.trash, err = s.loadTrash(); err != nil {
+		return nil, err
+	}
+
+	objects, err := s.listObjects()
+	if err != nil {
+		return nil, err
+	}
+	for hash := range objects {
+		scanned, err := s.scanObject(hash)
+		var objErr *ObjectError
+		switch {
+		case errors.As(err, &objErr):
+			scan.corrupt[hash] = objErr.Err
+			continue
+		case err != nil:
+			return nil, fmt.Errorf("failed to read patch object: %w", err)
+		}
+		if info, err := os.Stat(s.objectPath(hash)); err == nil {
+			scanned.modTime = info.ModTime()
+		}
+		scan.objects[hash] = scanned
+	}
+	return scan, nil
+}
+
+// scanObject streams the object named hash through scanPatch, reporting errors like readObject
+func (s *Storage) scanObject(hash string) (*scannedPatch, error) {
+	r, err := s.openObject(hash)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	return scanPatch(r)
+}
+
+// repairPlan is the stack Repair writes: the entries to keep, which refer to
+// the object chosen for each, the objects of malformed patches to quarantine by
+// mini-commit ID or object name, and the recovered entries whose metadata was
+// not recorded. Patches that only older releases' operation logs hold are kept
+// in inline by object name, to be stored again.
+type repairPlan struct {
+	rebuilt    types.MiniCommitList
+	dropped    map[string]string
+	unrecorded map[string]bool
+	inline     map[string]string
+}
+
+// addInline records a patch copied from the operation log and returns its object name
+func (p *repairPlan) addInline(content string) (string, *scannedPatch) {
+	hash := patchHash(content)
+	p.inline[hash] = content
+	// Reading from a string cannot fail
+	scanned, _ := scanPatch(strings.NewReader(content))
+	return hash, scanned
+}
+
+// diagnose lists the problems of scan and works out how Repair fixes them
+func (s *Storage) diagnose(scan *storeScan) ([]Problem, *repairPlan) {
+	var problems []Problem
+	report := func(kind, id, format string, args ...interface{}) {
+		problems = append(problems, Problem{Kind: kind, ID: id, Detail: fmt.Sprintf(format, args...)})
+	}
+	if scan.indexErr != nil {
+		report(ProblemCorruptIndex, "", "cannot parse %s: %v", IndexFile, scan.indexErr)
+	}
+	for _, hash := range sortedKeys(scan.corrupt) {
+		report(ProblemBadObject, hash, "cannot read patch object: %v", scan.corrupt[hash])
+	}
+
+	ops, _ := s.loadOperations()
+	plan := &repairPlan{rebuilt: types.MiniCommitList{}, dropped: make(map[string]string), unrecorded: make(map[string]bool),
+		inline: make(map[string]string)}
+	seen := make(map[string]bool)
+	trashed := make(map[string]bool)
+	referenced := make(map[string]bool)
+	for _, entry := range scan.trash {
+		trashed[entry.ID] = true
+		referenced[entry.PatchHash] = true
+	}
+	// The entries of the index keep their order; those recovered from the
+	// operation log are placed where it last recorded them, and only those
+	// with no recorded position are ordered by creation time, after the others
+	var logged, unplaced types.MiniCommitList
+	keep := func(list *types.MiniCommitList, mc types.MiniCommit, hash string, scanned *scannedPatch) {
+		if scanned.err != nil {
+			report(ProblemBadPatch, mc.ID, "%v", scanned.err)
+			plan.dropped[mc.ID] = hash
+			return
+		}
+		stats := scanned.stats
+		mc.PatchHash, mc.Stats, mc.Patch = hash, &stats, plan.inline[hash]
+		*list = append(*list, mc)
+	}
+
+	for _, mc := range scan.index {
+		if seen[mc.ID] {
+			report(ProblemDuplicateID, mc.ID, "listed more than once in the index")
+			continue
+		}
+		seen[mc.ID] = true
+		referenced[mc.PatchHash] = true
+
+		hash := mc.PatchHash
+		scanned, ok := scan.objects[hash]
+		if !ok {
+			reason := "patch object is missing"
+			if _, bad := scan.corrupt[hash]; bad {
+				reason = "patch object is damaged"
+			}
+			content := loggedPatch(ops, &mc)
+			if content == "" {
+				report(ProblemMissingPatch, mc.ID, "%s and the operation log has no copy", reason)
+				continue
+			}
+			report(ProblemMissingPatch, mc.ID, "%s", reason)
+			hash, scanned = plan.addInline(content)
+		}
+
+		if scanned.err == nil {
+			if mc.Stats != nil && *mc.Stats != scanned.stats {
+				report(ProblemPatchMismatch, mc.ID, "patch does not match the stats recorded in the index")
+			}
+			if scanned.generateID(mc.CreatedAt) != mc.ID {
+				report(ProblemIDMismatch, mc.ID, "ID does not match the patch and creation time")
+			}
+		}
+		keep(&plan.rebuilt, mc, hash, scanned)
+	}
+
+	// Mini-commits the operation log has in the stack but the index lacks, as
+	// after the index was lost, are recovered with their logged metadata
+	var lost []string
+	if len(ops) > 0 {
+		for _, id := range ops[len(ops)-1].After {
+			if seen[id] || trashed[id] {
+				continue
+			}
+			mc, ok := recoverLogged(ops, id, scan.objects)
+			if !ok {
+				lost = append(lost, id)
+				continue
+			}
+			hash, scanned := mc.PatchHash, scan.objects[mc.PatchHash]
+			if mc.Patch != "" {
+				hash, scanned = plan.addInline(mc.Patch)
+			}
+			seen[id] = true
+			referenced[hash] = true
+			report(ProblemOrphanPatch, id, "in the stack recorded by the operation log but not in the index")
+			keep(&logged, mc, hash, scanned)
+		}
+	}
+
+	// Objects nothing refers to whose patch the log never recorded as removed
+	// were saved by an operation that did not complete
+	for _, op := range ops {
+		for _, mc := range op.Removed {
+			referenced[loggedHash(mc)] = true
+		}
+	}
+	for _, hash := range sortedKeys(scan.objects) {
+		if referenced[hash] {
+			continue
+		}
+		scanned := scan.objects[hash]
+		if scanned.err != nil {
+			report(ProblemBadPatch, hash, "patch object is not in the index: %v", scanned.err)
+			plan.dropped[hash] = hash
+			continue
+		}
+		mc, recorded := recoverCreated(ops, hash, scanned)
+		if seen[mc.ID] || trashed[mc.ID] {
+			continue
+		}
+		seen[mc.ID] = true
+		if !recorded {
+			plan.unrecorded[mc.ID] = true
+		}
+		report(ProblemOrphanPatch, mc.ID, "patch object %s is not in the index", hash[:8])
+		keep(&unplaced, mc, hash, scanned)
+	}
+
+	for _, id := range lost {
+		if !seen[id] {
+			report(ProblemMissingPatch, id, "in the stack recorded by the operation log, but its patch is missing")
+		}
+	}
+
+	if len(logged) > 0 {
+		plan.rebuilt = placeLogged(plan.rebuilt, ops[len(ops)-1].After, logged)
+	}
+	sort.SliceStable(unplaced, func(i, j int) bool {
+		return unplaced[i].CreatedAt.Before(unplaced[j].CreatedAt)
+	})
+	plan.rebuilt = append(plan.rebuilt, unplaced...)
+	return problems, plan
+}
+
+// placeLogged inserts the entries recovered from the operation log into stack
+// at their position in after, the stack the log last recorded: right after the
+// closest entry before them that stack holds, or first if there is none
+func placeLogged(stack types.MiniCommitList, after []string, recovered types.MiniCommitList) types.MiniCommitList {
+	byID := make(map[string]types.MiniCommit, len(recovered))
+	for _, mc := range recovered {
+		byID[mc.ID] = mc
+	}
+	placed := make(types.MiniCommitList, 0, len(stack)+len(recovered))
+	placed = append(placed, stack...)
+	position := 0
+	for _, id := range after {
+		if mc, ok := byID[id]; ok {
+			placed = append(placed[:position], append(types.MiniCommitList{mc}, placed[position:]...)...)
+			position++
+			continue
+		}
+		for i := range placed {
+			if placed[i].ID == id {
+				position = i + 1
+				break
+			}
+		}
+	}
+	return placed
+}
+
+// loggedPatch returns a copy of the patch of mc from the operation log, or ""
+// if none was logged. Only older releases logged the patches themselves, and
+// only a copy that matches the ID is trusted.
+func loggedPatch(ops []Operation, mc *types.MiniCommit) string {
+	for i := len(ops) - 1; i >= 0; i-- {
+		for _, logged := range ops[i].Removed {
+			if logged.ID == mc.ID && logged.Patch != "" && generateID(logged.Patch, mc.CreatedAt) == mc.ID {
+				return logged.Patch
+			}
+		}
+	}
+	return ""
+}
+
+// recoverLogged rebuilds the mini-commit id from the operation log: the last
+// version it recorded whose patch is still there, or else the creation time and
+// subject logged when it was created together with the stored object that
+// matches them. The result refers to its object, unless it is a version logged
+// by an older release that carries its patch.
+func recoverLogged(ops []Operation, id string, objects map[string]*scannedPatch) (types.MiniCommit, bool) {
+	for i := len(ops) - 1; i >= 0; i-- {
+		for _, mc := range ops[i].Removed {
+			if mc.ID != id {
+				continue
+			}
+			if mc.Patch != "" {
+				if generateID(mc.Patch, mc.CreatedAt) == id {
+					return mc, true
+				}
+				continue
+			}
+			if scanned, ok := objects[mc.PatchHash]; ok && scanned.generateID(mc.CreatedAt) == id {
+				return mc, true
+			}
+		}
+	}
+
+	for _, op := range ops {
+		if op.Kind != OpCreate || len(op.After) == 0 || op.After[len(op.After)-1] != id {
+			continue
+		}
+		for _, hash := range sortedKeys(objects) {
+			if objects[hash].generateID(op.Time) == id {
+				return createdMiniCommit(op, hash), true
+			}
+		}
+	}
+	return types.MiniCommit{}, false
+}
+
+// recoverCreated rebuilds the index entry of the orphan patch object hash from
+// the creation it matches in the operation log. Without one, the object's
+// modification time stands in for the creation time and the entry gets a new ID.
+func recoverCreated(ops []Operation, hash string, scanned *scannedPatch) (types.MiniCommit, bool) {
+	for _, op := range ops {
+		if op.Kind == OpCreate && len(op.After) > 0 && scanned.generateID(op.Time) == op.After[len(op.After)-1] {
+			return createdMiniCommit(op, hash), true
+		}
+	}
+	return types.MiniCommit{ID: scanned.generateID(scanned.modTime), CreatedAt: scanned.modTime, PatchHash: hash,
+		Message: "Recovered mini-commit"}, false
+}
+
+// createdMiniCommit is the mini-commit logged by the create operation op, with
+// the subject of its message and the patch object hash
+func createdMiniCommit(op Operation, hash string) types.MiniCommit {
+	mc := types.MiniCommit{ID: op.After[len(op.After)-1], CreatedAt: op.Time, PatchHash: hash, Message: "Recovered mini-commit"}
+	if _, subject, ok := strings.Cut(op.Summary, " "); ok {
+		mc.Message = subject
+	}
+	return mc
+}
+
+// sortedKeys returns the keys of m in order, for reproducible reports
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,196 @@
+package storage
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"git-mini-commit/internal/patch"
+	"git-mini-commit/internal/types"
+	"git-mini-commit/testutils"
+)
+
+// testPatch ステージング差分の例
+func testPatch(name, line string) string {
+	return "diff --git a/" + name + " b/" + name + "\nnew file mode 100644\n--- /dev/null\n+++ b/" + name +
+		"\n@@ -0,0 +1 @@\n+" + line + "\n"
+}
+
+func problemKinds(problems []Problem) map[string]string {
+	kinds := make(map[string]string)
+	for _, p := range problems {
+		kinds[p.Kind] = p.ID
+	}
+	return kinds
+}
+
+func TestCheckAndRepair(t *testing.T) {
+	repo := testutils.NewTestGitRepo(t)
+	defer repo.Cleanup()
+
+	storage, err := NewStorage()
+	if err != nil {
+		t.Fatalf("NewStorage() error = %v", err)
+	}
+
+	first := newTestMiniCommit(t, storage, "First", testPatch("a.txt", "a"))
+	second := newTestMiniCommit(t, storage, "Second", testPatch("b.txt", "b"))
+	third := newTestMiniCommit(t, storage, "Third", testPatch("c.txt", "c"))
+
+	// 編集すると以前の版が操作ログに残る
+	edited := *first
+	edited.Message = "First edited"
+	if err := storage.UpdateMiniCommit(&edited); err != nil {
+		t.Fatalf("UpdateMiniCommit() error = %v", err)
+	}
+
+	// 正常なストアには問題なし
+	problems, err := storage.Check()
+	if err != nil || len(problems) != 0 {
+		t.Fatalf("Expected no problems, got %+v (%v)", problems, err)
+	}
+
+	// 1. パッチオブジェクトの削除・不正なパッチ・インデックスの改ざん、どこからも参照されないオブジェクトと壊れたオブジェクト
+	index, _ := storage.LoadMiniCommits()
+	os.Remove(storage.objectPath(index[0].PatchHash))
+	broken := *second
+	broken.Patch = "garbage\n"
+	if err := storage.UpdateMiniCommit(&broken); err != nil {
+		t.Fatalf("UpdateMiniCommit() error = %v", err)
+	}
+	index, _ = storage.LoadMiniCommits()
+	index[2].Stats = &patch.Stats{Files: 1, Insertions: 5}
+	index[2].CreatedAt = index[2].CreatedAt.Add(time.Nanosecond)
+	if err := storage.writeIndex(&indexFile{FormatVersion: FormatVersion, MiniCommits: index}); err != nil {
+		t.Fatalf("writeIndex() error = %v", err)
+	}
+	if _, err := storage.writeObject(testPatch("d.txt", "d")); err != nil {
+		t.Fatalf("writeObject() error = %v", err)
+	}
+	badObject := "ab" + strings.Repeat("c", 38)
+	os.MkdirAll(filepath.Dir(storage.objectPath(badObject)), 0755)
+	os.WriteFile(storage.objectPath(badObject), []byte("not zlib"), 0644)
+
+	problems, err = storage.Check()
+	if err != nil {
+		t.Fatalf("Check() error = %v", err)
+	}
+	kinds := problemKinds(problems)
+	if kinds[ProblemMissingPatch] != first.ID || kinds[ProblemBadPatch] != second.ID ||
+		kinds[ProblemPatchMismatch] != third.ID || kinds[ProblemIDMismatch] != third.ID ||
+		kinds[ProblemOrphanPatch] == "" || kinds[ProblemBadObject] != badObject {
+		t.Errorf("Unexpected problems: %+v", problems)
+	}
+
+	// Check は何も変更しない
+	assertStack(t, storage, "First edited", "Second", "Third")
+
+	// 2. 修復: パッチのないエントリを削除し、不正なパッチと壊れたオブジェクトを隔離し、孤立パッチを戻す
+	problems, err = storage.Repair()
+	if err != nil {
+		t.Fatalf("Repair() error = %v", err)
+	}
+	for _, p := range problems {
+		if p.Repair == "" {
+			t.Errorf("Expected a repair for %+v", p)
+		}
+	}
+	assertStack(t, storage, "Third", "Recovered mini-commit")
+	if _, err := storage.GetMiniCommit(first.ID); err == nil {
+		t.Errorf("Expected the mini-commit without a patch to be removed")
+	}
+
+	// 作成日時を書き換えたエントリはIDと一致しないまま残る
+	problems, _ = storage.Check()
+	if len(problems) != 1 || problems[0].Kind != ProblemIDMismatch || problems[0].ID != third.ID {
+		t.Errorf("Expected only the ID mismatch to remain after repair, got %+v", problems)
+	}
+
+	// 以前の版は操作ログに残る
+	index, _ = storage.LoadMiniCommits()
+	recovered := index[1]
+	recovered.Message = "Recovered and edited"
+	if err := storage.UpdateMiniCommit(&recovered); err != nil {
+		t.Fatalf("UpdateMiniCommit() error = %v", err)
+	}
+
+	// 3. 壊れたインデックスはlost-foundに移動し、操作ログのスタックとパッチオブジェクトから再構築
+	indexPath := filepath.Join(storage.basePath, IndexFile)
+	os.WriteFile(indexPath, []byte("invalid json"), 0644)
+	os.WriteFile(storage.objectPath(badObject), []byte("not zlib"), 0644)
+
+	problems, err = storage.Repair()
+	if err != nil {
+		t.Fatalf("Repair() error = %v", err)
+	}
+	kinds = problemKinds(problems)
+	if _, ok := kinds[ProblemCorruptIndex]; !ok || kinds[ProblemBadObject] != badObject {
+		t.Errorf("Unexpected problems: %+v", problems)
+	}
+	index, err = storage.LoadMiniCommits()
+	if err != nil || len(index) != 2 {
+		t.Fatalf("Expected 2 mini-commits after rebuilding, got %d (%v)", len(index), err)
+	}
+	// メタデータは操作ログに残る最後の版から、パッチはそのオブジェクトから復元
+	if index[0].ID != third.ID || index[1].ID != recovered.ID || index[1].Message != "Recovered mini-commit" {
+		t.Errorf("Expected metadata recovered from the operation log, got %+v", index)
+	}
+	if _, err := os.Stat(storage.objectPath(badObject)); !os.IsNotExist(err) {
+		t.Errorf("Expected the damaged object to be quarantined")
+	}
+	quarantined, _ := filepath.Glob(filepath.Join(storage.basePath, LostFoundDir, "*", "*"))
+	if len(quarantined) < 2 {
+		t.Errorf("Expected the index and the damaged data in lost-found, got %v", quarantined)
+	}
+	if problems, _ := storage.Check(); len(problems) != 0 {
+		t.Errorf("Expected no problems after rebuilding, got %+v", problems)
+	}
+}
+
+func TestRepairKeepsStackOrder(t *testing.T) {
+	repo := testutils.NewTestGitRepo(t)
+	defer repo.Cleanup()
+
+	storage, err := NewStorage()
+	if err != nil {
+		t.Fatalf("NewStorage() error = %v", err)
+	}
+
+	newTestMiniCommit(t, storage, "First", testPatch("a.txt", "a"))
+	newTestMiniCommit(t, storage, "Second", testPatch("b.txt", "b"))
+	newTestMiniCommit(t, storage, "Third", testPatch("c.txt", "c"))
+
+	// 1. 作成日時の順ではないスタックも、インデックスの順のまま修復する
+	index, _ := storage.LoadMiniCommits()
+	index = types.MiniCommitList{index[2], index[0], index[1]}
+	index[2].Stats = &patch.Stats{Files: 1, Insertions: 5}
+	if err := storage.writeIndex(&indexFile{FormatVersion: FormatVersion, MiniCommits: index}); err != nil {
+		t.Fatalf("writeIndex() error = %v", err)
+	}
+	if _, err := storage.Repair(); err != nil {
+		t.Fatalf("Repair() error = %v", err)
+	}
+	assertStack(t, storage, "Third", "First", "Second")
+
+	// 2. インデックスにないエントリは操作ログに記録された位置に、記録のないパッチは最後に戻す
+	index, _ = storage.LoadMiniCommits()
+	if err := storage.writeIndex(&indexFile{FormatVersion: FormatVersion, MiniCommits: types.MiniCommitList{index[0], index[2]}}); err != nil {
+		t.Fatalf("writeIndex() error = %v", err)
+	}
+	if _, err := storage.writeObject(testPatch("d.txt", "d")); err != nil {
+		t.Fatalf("writeObject() error = %v", err)
+	}
+	if _, err := storage.Repair(); err != nil {
+		t.Fatalf("Repair() error = %v", err)
+	}
+	assertStack(t, storage, "Third", "First", "Second", "Recovered mini-commit")
+
+	// 3. インデックスが壊れていれば操作ログの順で再構築する
+	os.WriteFile(filepath.Join(storage.basePath, IndexFile), []byte("invalid json"), 0644)
+	if _, err := storage.Repair(); err != nil {
+		t.Fatalf("Repair() error = %v", err)
+	}
+	assertStack(t, storage, "Third", "First", "Second", "Recovered mini-commit")
+}
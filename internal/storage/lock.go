@@ -0,0 +1,127 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// LockFile is created exclusively while a process modifies the store, like git's index.lock
+const LockFile = "index.lock"
+
+// lockTimeout is how long a writer waits for another process to release the lock
+var lockTimeout = 10 * time.Second
+
+// SetLockTimeout sets how long writers wait for another process to release the lock
+func SetLockTimeout(timeout time.Duration) {
+	lockTimeout = timeout
+}
+
+// staleLockHandler decides whether a lock left behind by a process that is no
+// longer running is removed; without one, such a lock is reported at once
+var staleLockHandler func(err *LockError) bool
+
+// SetStaleLockHandler sets the function asked whether to remove a lock whose
+// process is no longer running, e.g. by prompting the user. If it returns true,
+// the lock is removed and taken again.
+func SetStaleLockHandler(handler func(err *LockError) bool) {
+	staleLockHandler = handler
+}
+
+// lockStore takes the cross-process store lock, retrying until lockTimeout.
+// A lock whose process is no longer running is not waited for; it is removed
+// if staleLockHandler agrees and reported otherwise. The returned function
+// releases the lock. Callers also hold s.mutex, which only serialises
+// goroutines of this process.
+func (s *Storage) lockStore() (func(), error) {
+	lockPath := filepath.Join(s.basePath, LockFile)
+	deadline := time.Now().Add(lockTimeout)
+	delay := time.Millisecond
+
+	for {
+		f, err := os.OpenFile(lockPath, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+		if err == nil {
+			fmt.Fprintf(f, "%d\n", os.Getpid())
+			f.Close()
+			return func() { os.Remove(lockPath) }, nil
+		}
+		if !os.IsExist(err) {
+			return nil, fmt.Errorf("failed to lock store: %w", err)
+		}
+		// The lock is read again once its process is found gone, as a process
+		// that released it has exited as well
+		if pid := lockHolder(lockPath); pid > 0 && !processRunning(pid) && lockHolder(lockPath) == pid {
+			stale := &LockError{Path: lockPath, PID: pid, Stale: true}
+			if staleLockHandler == nil || !staleLockHandler(stale) {
+				return nil, stale
+			}
+			// Another process may have broken the lock and taken it meanwhile
+			if lockHolder(lockPath) == pid {
+				if err := os.Remove(lockPath); err != nil && !os.IsNotExist(err) {
+					return nil, fmt.Errorf("failed to remove stale lock: %w", err)
+				}
+			}
+			continue
+		}
+		if time.Now().After(deadline) {
+			return nil, &LockError{Path: lockPath, PID: lockHolder(lockPath)}
+		}
+
+		time.Sleep(delay)
+		if delay < 50*time.Millisecond {
+			delay *= 2
+		}
+	}
+}
+
+// lockHolder returns the PID recorded in the lock file, or 0 if there is none,
+// e.g. while the process that created it has not written it yet
+func lockHolder(lockPath string) int {
+	data, err := os.ReadFile(lockPath)
+	if err != nil {
+		return 0
+	}
+	pid, err := strconv.Atoi(strings.TrimSpace(string(data)))
+	if err != nil || pid <= 0 {
+		return 0
+	}
+	return pid
+}
+
+// processRunning reports whether the process pid exists. Signal 0 only checks
+// that it can be signalled; a process of another user still counts as running.
+func processRunning(pid int) bool {
+	p, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+	return !errors.Is(p.Signal(syscall.Signal(0)), os.ErrProcessDone)
+}
+
+// writeFileAtomic replaces path with data through a temporary file, so readers
+// that don't take the lock never see a partially written file
+func writeFileAtomic(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Chmod(0644); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
@@ -0,0 +1,154 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"git-mini-commit/internal/types"
+	"git-mini-commit/testutils"
+)
+
+func TestStoreLock(t *testing.T) {
+	repo := testutils.NewTestGitRepo(t)
+	defer repo.Cleanup()
+
+	storage, err := NewStorage()
+	if err != nil {
+		t.Fatalf("NewStorage() error = %v", err)
+	}
+
+	defer func(timeout time.Duration) { lockTimeout = timeout }(lockTimeout)
+	lockTimeout = 50 * time.Millisecond
+
+	// 他のプロセスがロックを保持している場合はタイムアウト
+	lockPath := filepath.Join(storage.basePath, LockFile)
+	if err := os.WriteFile(lockPath, []byte(fmt.Sprintf("%d\n", os.Getpid())), 0644); err != nil {
+		t.Fatalf("Failed to create lock file: %v", err)
+	}
+	mc := &types.MiniCommit{ID: "locked", Message: "Locked", CreatedAt: time.Now(), Patch: "patch\n"}
+	err = storage.SaveMiniCommit(mc)
+	if err == nil || !strings.Contains(err.Error(), "unable to lock") {
+		t.Fatalf("Expected a lock error, but got %v", err)
+	}
+
+	// ロックが解放されると書き込める
+	os.Remove(lockPath)
+	if err := storage.SaveMiniCommit(mc); err != nil {
+		t.Fatalf("SaveMiniCommit() error = %v", err)
+	}
+	if _, err := os.Stat(lockPath); !os.IsNotExist(err) {
+		t.Errorf("Expected the lock file to be removed after writing")
+	}
+}
+
+func TestStaleLock(t *testing.T) {
+	repo := testutils.NewTestGitRepo(t)
+	defer repo.Cleanup()
+
+	storage, err := NewStorage()
+	if err != nil {
+		t.Fatalf("NewStorage() error = %v", err)
+	}
+
+	defer func(timeout time.Duration) { lockTimeout = timeout }(lockTimeout)
+	lockTimeout = time.Minute
+	defer SetStaleLockHandler(nil)
+
+	// 終了したプロセスのPIDを記録したロックファイルを残す
+	exited := exec.Command("true")
+	if err := exited.Run(); err != nil {
+		t.Fatalf("Failed to run a process: %v", err)
+	}
+	pid := exited.Process.Pid
+	lockPath := filepath.Join(storage.basePath, LockFile)
+	if err := os.WriteFile(lockPath, []byte(fmt.Sprintf("%d\n", pid)), 0644); err != nil {
+		t.Fatalf("Failed to create lock file: %v", err)
+	}
+
+	// 1. 古いロックは待たずに報告する
+	mc := &types.MiniCommit{ID: "stale", Message: "Stale", CreatedAt: time.Now(), Patch: "patch\n"}
+	start := time.Now()
+	err = storage.SaveMiniCommit(mc)
+	var lockErr *LockError
+	if !errors.As(err, &lockErr) || !lockErr.Stale || lockErr.PID != pid || !errors.Is(err, ErrLocked) {
+		t.Fatalf("Expected a stale lock error, got %v", err)
+	}
+	if !strings.Contains(err.Error(), "no longer running") {
+		t.Errorf("Expected the error to tell the process is gone, got %v", err)
+	}
+	if time.Since(start) > 10*time.Second {
+		t.Errorf("Expected a stale lock not to be waited for")
+	}
+
+	// 2. 削除を断るとロックは残る
+	asked := 0
+	SetStaleLockHandler(func(*LockError) bool { asked++; return false })
+	if err := storage.SaveMiniCommit(mc); !errors.As(err, &lockErr) || asked != 1 {
+		t.Fatalf("Expected the declined lock to be reported, got %v (asked %d times)", err, asked)
+	}
+	if _, err := os.Stat(lockPath); err != nil {
+		t.Errorf("Expected the lock file to be kept: %v", err)
+	}
+
+	// 3. 同意すればロックを削除して書き込む
+	SetStaleLockHandler(func(*LockError) bool { return true })
+	if err := storage.SaveMiniCommit(mc); err != nil {
+		t.Fatalf("SaveMiniCommit() error = %v", err)
+	}
+	if _, err := os.Stat(lockPath); !os.IsNotExist(err) {
+		t.Errorf("Expected the lock file to be removed after writing")
+	}
+	assertStack(t, storage, "Stale")
+}
+
+func TestClearMiniCommits(t *testing.T) {
+	repo := testutils.NewTestGitRepo(t)
+	defer repo.Cleanup()
+
+	storage, err := NewStorage()
+	if err != nil {
+		t.Fatalf("NewStorage() error = %v", err)
+	}
+
+	newTestMiniCommit(t, storage, "Keep", "patch 1\n")
+	remove := newTestMiniCommit(t, storage, "Remove", "patch 2\n")
+	newTestMiniCommit(t, storage, "Keep too", "patch 3\n")
+
+	// スタックにないIDは無視する
+	removed, err := storage.ClearMiniCommits([]string{remove.ID, "gone"})
+	if err != nil {
+		t.Fatalf("ClearMiniCommits() error = %v", err)
+	}
+	if len(removed) != 1 || removed[0].Message != "Remove" {
+		t.Errorf("Unexpected removed mini-commits: %+v", removed)
+	}
+	assertStack(t, storage, "Keep", "Keep too")
+
+	// 一致しない場合は何もしない（index.json も書き直さない）
+	indexPath := filepath.Join(storage.basePath, IndexFile)
+	past := time.Now().Add(-time.Hour).Truncate(time.Second)
+	if err := os.Chtimes(indexPath, past, past); err != nil {
+		t.Fatalf("Failed to set index mtime: %v", err)
+	}
+	removed, err = storage.ClearMiniCommits([]string{remove.ID})
+	if err != nil || len(removed) != 0 {
+		t.Errorf("Expected nothing to be removed, got %d (%v)", len(removed), err)
+	}
+	info, err := os.Stat(indexPath)
+	if err != nil {
+		t.Fatalf("Failed to stat index: %v", err)
+	}
+	if !info.ModTime().Equal(past) {
+		t.Errorf("Expected the index not to be rewritten, got mtime %v", info.ModTime())
+	}
+	ops, _ := storage.Operations()
+	if last := ops[len(ops)-1]; last.Kind != OpClear || len(last.After) != 2 {
+		t.Errorf("Expected a single clear operation to be logged, got %+v", last)
+	}
+}
@@ -0,0 +1,360 @@
+package storage
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"git-mini-commit/internal/types"
+	"git-mini-commit/internal/version"
+)
+
+// FormatVersion is the on-disk format written by this version of the tool.
+// Format 1 is the original index.json holding a bare JSON array, format 2 wraps
+// it in a versioned envelope, format 3 keeps patches out of the indexes in
+// <id>.patch files, format 4 stores them as compressed objects and format 5
+// records message trailers.
+const FormatVersion = 5
+
+// BackupDir holds a copy of the store taken before each migration
+const BackupDir = "backups"
+
+// ErrNewerFormat is returned when the store was written in a format this version does not know
+var ErrNewerFormat = errors.New("mini-commit store format is newer than supported")
+
+// FormatError reports a store written by a newer version of the tool; it
+// matches ErrNewerFormat with errors.Is
+type FormatError struct {
+	Version     int
+	ToolVersion string
+}
+
+func (e *FormatError) Error() string {
+	return fmt.Sprintf("mini-commit store format %d is newer than this git-mini-commit supports (%d); "+
+		"it was last written by git-mini-commit %s, please upgrade", e.Version, FormatVersion, e.ToolVersion)
+}
+
+// Is makes errors.Is(err, ErrNewerFormat) succeed
+func (e *FormatError) Is(target error) bool {
+	return target == ErrNewerFormat
+}
+
+// indexFile is the versioned envelope of index.json. ToolVersion is the version
+// that last wrote the store and CreatedBy the one that created it.
+type indexFile struct {
+	FormatVersion int                  `json:"formatVersion"`
+	ToolVersion   string               `json:"toolVersion"`
+	CreatedBy     string               `json:"createdBy"`
+	MiniCommits   types.MiniCommitList `json:"miniCommits"`
+}
+
+// migration upgrades the store from format version from to from+1
+type migration struct {
+	from        int
+	description string
+	apply       func(s *Storage) error
+}
+
+// migrations are applied in order to bring an old store to FormatVersion
+var migrations = []migration{
+	{from: 1, description: "wrap index.json in a versioned envelope", apply: migrateToEnvelope},
+	{from: 2, description: "keep patches only in their patch files", apply: migrateToMetadataIndex},
+	{from: 3, description: "store patches as compressed objects", apply: migrateToObjects},
+	{from: 4, description: "record message trailers", apply: migrateToTrailers},
+}
+
+// decodeIndex parses index.json in any known format and returns its format version
+func decodeIndex(data []byte) (*indexFile, error) {
+	trimmed := bytes.TrimSpace(data)
+	if len(trimmed) > 0 && trimmed[0] == '[' {
+		index := &indexFile{FormatVersion: 1}
+		if err := json.Unmarshal(data, &index.MiniCommits); err != nil {
+			return nil, err
+		}
+		return index, nil
+	}
+
+	index := &indexFile{}
+	if err := json.Unmarshal(data, index); err != nil {
+		return nil, err
+	}
+	if index.FormatVersion < 2 {
+		return nil, fmt.Errorf("missing or invalid formatVersion")
+	}
+	if index.FormatVersion > FormatVersion {
+		return nil, &FormatError{Version: index.FormatVersion, ToolVersion: index.ToolVersion}
+	}
+	return index, nil
+}
+
+// storeFormat returns the format of the store and who created it. A store
+// without an index is new; one whose index cannot be parsed is left to fsck
+// and reported as current, so that commands fail on it with a parse error.
+func (s *Storage) storeFormat() (int, string, error) {
+	data, err := os.ReadFile(filepath.Join(s.basePath, IndexFile))
+	if os.IsNotExist(err) {
+		return FormatVersion, version.String(), nil
+	}
+	if err != nil {
+		return 0, "", fmt.Errorf("failed to read index file: %w", err)
+	}
+
+	index, err := decodeIndex(data)
+	if errors.Is(err, ErrNewerFormat) {
+		return 0, "", err
+	}
+	if err != nil {
+		return FormatVersion, version.String(), nil
+	}
+	return index.FormatVersion, index.CreatedBy, nil
+}
+
+// migrate brings the store to FormatVersion, backing it up first. A store
+// written in a newer format is refused rather than risk losing data.
+func (s *Storage) migrate() error {
+	current, createdBy, err := s.storeFormat()
+	if err != nil || current == FormatVersion {
+		s.createdBy = createdBy
+		return err
+	}
+
+	unlock, err := s.lockStore()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	// Another process may have migrated the store while we waited for the lock
+	current, _, err = s.storeFormat()
+	if err != nil {
+		return err
+	}
+	if current != FormatVersion {
+		backup := filepath.Join(s.basePath, BackupDir, fmt.Sprintf("format-%d-%s", current, time.Now().Format("20060102-150405")))
+		if err := s.backupStore(backup); err != nil {
+			return err
+		}
+		for _, m := range migrations {
+			if m.from < current {
+				continue
+			}
+			if err := m.apply(s); err != nil {
+				return fmt.Errorf("failed to migrate store from format %d (%s): %v; a backup is in %s",
+					m.from, m.description, err, backup)
+			}
+		}
+	}
+
+	_, s.createdBy, err = s.storeFormat()
+	return err
+}
+
+// backupStore copies every file of the store into dir, leaving out earlier
+// backups, quarantined data and the lock file
+func (s *Storage) backupStore(dir string) error {
+	return filepath.WalkDir(s.basePath, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(s.basePath, path)
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if rel == BackupDir || rel == LostFoundDir {
+				return filepath.SkipDir
+			}
+			return os.MkdirAll(filepath.Join(dir, rel), 0755)
+		}
+		if rel == LockFile {
+			return nil
+		}
+
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("failed to back up store: %w", err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, rel), data, 0644); err != nil {
+			return fmt.Errorf("failed to back up store: %w", err)
+		}
+		return nil
+	})
+}
+
+// migrateToEnvelope rewrites a format 1 index, a bare JSON array, in the
+// versioned envelope. The version that created such a store is unknown.
+func migrateToEnvelope(s *Storage) error {
+	data, err := os.ReadFile(filepath.Join(s.basePath, IndexFile))
+	if err != nil {
+		return err
+	}
+	var list types.MiniCommitList
+	if err := json.Unmarshal(data, &list); err != nil {
+		return err
+	}
+	return s.writeIndex(&indexFile{FormatVersion: 2, CreatedBy: "unknown", MiniCommits: list})
+}
+
+// migrateToMetadataIndex drops the patches duplicated in index.json and
+// trash/index.json, recording the stats of each mini-commit instead. Until
+// format 2 the copy in the index was the one read, so it replaces a patch file
+// that differs from it.
+func migrateToMetadataIndex(s *Storage) error {
+	data, err := os.ReadFile(filepath.Join(s.basePath, IndexFile))
+	if err != nil {
+		return err
+	}
+	index, err := decodeIndex(data)
+	if err != nil {
+		return err
+	}
+	for i := range index.MiniCommits {
+		if err := syncPatchFile(filepath.Join(s.basePath, index.MiniCommits[i].ID+".patch"), &index.MiniCommits[i]); err != nil {
+			return err
+		}
+	}
+
+	trash, err := s.loadTrash()
+	if err != nil {
+		return err
+	}
+	for i := range trash {
+		if err := syncPatchFile(filepath.Join(s.basePath, TrashDir, trash[i].ID+".patch"), &trash[i].MiniCommit); err != nil {
+			return err
+		}
+		trash[i].MiniCommit = withoutPatches(types.MiniCommitList{trash[i].MiniCommit})[0]
+	}
+	if trash != nil {
+		if err := s.writeTrash(trash); err != nil {
+			return err
+		}
+	}
+
+	index.FormatVersion = 3
+	index.MiniCommits = withoutPatches(index.MiniCommits)
+	return s.writeIndex(index)
+}
+
+// migrateToObjects stores every <id>.patch file of the stack and the trash as
+// an object, refers to the objects from the indexes and removes the files.
+// Patch files no entry lists become orphan objects that fsck can add back; an
+// entry whose patch file is missing is left for fsck to report.
+func migrateToObjects(s *Storage) error {
+	hashes := make(map[string]string)
+	for _, dir := range []string{s.basePath, filepath.Join(s.basePath, TrashDir)} {
+		entries, err := os.ReadDir(dir)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		for _, entry := range entries {
+			if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".patch") {
+				continue
+			}
+			path := filepath.Join(dir, entry.Name())
+			data, err := os.ReadFile(path)
+			if err != nil {
+				return err
+			}
+			if hashes[path], err = s.writeObject(string(data)); err != nil {
+				return err
+			}
+		}
+	}
+
+	data, err := os.ReadFile(filepath.Join(s.basePath, IndexFile))
+	if err != nil {
+		return err
+	}
+	index, err := decodeIndex(data)
+	if err != nil {
+		return err
+	}
+	for i := range index.MiniCommits {
+		index.MiniCommits[i].PatchHash = hashes[filepath.Join(s.basePath, index.MiniCommits[i].ID+".patch")]
+	}
+
+	trash, err := s.loadTrash()
+	if err != nil {
+		return err
+	}
+	for i := range trash {
+		trash[i].PatchHash = hashes[filepath.Join(s.basePath, TrashDir, trash[i].ID+".patch")]
+	}
+	if trash != nil {
+		if err := s.writeTrash(trash); err != nil {
+			return err
+		}
+	}
+
+	index.FormatVersion = 4
+	if err := s.writeIndex(index); err != nil {
+		return err
+	}
+	for path := range hashes {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+// migrateToTrailers only stamps the new format: trailers are optional, but
+// versions that do not know them would drop them when rewriting the index
+func migrateToTrailers(s *Storage) error {
+	data, err := os.ReadFile(filepath.Join(s.basePath, IndexFile))
+	if err != nil {
+		return err
+	}
+	index, err := decodeIndex(data)
+	if err != nil {
+		return err
+	}
+	index.FormatVersion = 5
+	return s.writeIndex(index)
+}
+
+// syncPatchFile makes the patch file at path hold the patch of mc, taking it
+// from the file when the index has no copy. A patch missing from both is left
+// for fsck to report.
+func syncPatchFile(path string, mc *types.MiniCommit) error {
+	if mc.Patch == "" {
+		data, err := os.ReadFile(path)
+		if os.IsNotExist(err) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		mc.Patch = string(data)
+		return nil
+	}
+	if data, err := os.ReadFile(path); err == nil && string(data) == mc.Patch {
+		return nil
+	}
+	return os.WriteFile(path, []byte(mc.Patch), 0644)
+}
+
+// writeIndex stamps index with the running version and writes it atomically
+func (s *Storage) writeIndex(index *indexFile) error {
+	index.ToolVersion = version.Version
+	if index.MiniCommits == nil {
+		index.MiniCommits = types.MiniCommitList{}
+	}
+	data, err := json.MarshalIndent(index, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to serialize index: %w", err)
+	}
+	if err := writeFileAtomic(filepath.Join(s.basePath, IndexFile), data); err != nil {
+		return fmt.Errorf("failed to save index file: %w", err)
+	}
+	return nil
+}
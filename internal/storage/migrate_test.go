@@ -0,0 +1,193 @@
+package storage
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"git-mini-commit/internal/types"
+	"git-mini-commit/internal/version"
+	"git-mini-commit/testutils"
+)
+
+func TestMigrateFormat1(t *testing.T) {
+	repo := testutils.NewTestGitRepo(t)
+	defer repo.Cleanup()
+
+	// 形式1のストア（index.json が配列のみ）を用意
+	storeDir := filepath.Join(".git", "mini-commits")
+	if err := os.MkdirAll(storeDir, 0755); err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	mc := types.MiniCommit{ID: "abc123", Message: "Old", CreatedAt: time.Now(), Patch: testPatch("a.txt", "a")}
+	legacy, _ := json.MarshalIndent(types.MiniCommitList{mc}, "", "  ")
+	os.WriteFile(filepath.Join(storeDir, IndexFile), legacy, 0644)
+	// 形式2まではインデックス内のコピーが使われていたため、パッチファイルより優先される
+	os.WriteFile(filepath.Join(storeDir, mc.ID+".patch"), []byte("stale\n"), 0644)
+
+	storage, err := NewStorage()
+	if err != nil {
+		t.Fatalf("NewStorage() error = %v", err)
+	}
+	assertStack(t, storage, "Old")
+
+	// 現在の形式に変換され、バージョン情報が記録される
+	data, _ := os.ReadFile(filepath.Join(storeDir, IndexFile))
+	var index indexFile
+	if err := json.Unmarshal(data, &index); err != nil {
+		t.Fatalf("Failed to parse migrated index: %v", err)
+	}
+	if index.FormatVersion != FormatVersion || index.ToolVersion != version.Version || index.CreatedBy != "unknown" {
+		t.Errorf("Unexpected envelope: %+v", index)
+	}
+
+	// パッチはインデックスから除かれ、統計が記録される
+	if strings.Contains(string(data), `"patch"`) || index.MiniCommits[0].Stats == nil || index.MiniCommits[0].Stats.Insertions != 1 {
+		t.Errorf("Expected a metadata-only index with stats, got %s", data)
+	}
+	retrieved, err := storage.GetMiniCommit(mc.ID)
+	if err != nil || retrieved.Patch != mc.Patch {
+		t.Errorf("Expected the patch from the old index, got %+v (%v)", retrieved, err)
+	}
+
+	// 移行前のストアがバックアップされる
+	backups, _ := filepath.Glob(filepath.Join(storeDir, BackupDir, "format-1-*", IndexFile))
+	if len(backups) != 1 {
+		t.Fatalf("Expected one backup of the index, got %v", backups)
+	}
+	if backup, _ := os.ReadFile(backups[0]); string(backup) != string(legacy) {
+		t.Errorf("Expected the backup to hold the original index")
+	}
+
+	// 保存しても作成したバージョンは保持される
+	newTestMiniCommit(t, storage, "New", testPatch("b.txt", "b"))
+	data, _ = os.ReadFile(filepath.Join(storeDir, IndexFile))
+	json.Unmarshal(data, &index)
+	if index.CreatedBy != "unknown" || len(index.MiniCommits) != 2 {
+		t.Errorf("Unexpected envelope after saving: %+v", index)
+	}
+
+	// 移行済みのストアは再度移行しない
+	if _, err := NewStorage(); err != nil {
+		t.Fatalf("NewStorage() error = %v", err)
+	}
+	backups, _ = filepath.Glob(filepath.Join(storeDir, BackupDir, "*"))
+	if len(backups) != 1 {
+		t.Errorf("Expected no further backups, got %v", backups)
+	}
+}
+
+func TestMigrateFormat3(t *testing.T) {
+	repo := testutils.NewTestGitRepo(t)
+	defer repo.Cleanup()
+
+	// 形式3のストア（パッチは <id>.patch、ゴミ箱は trash/<id>.patch）を用意
+	storeDir := filepath.Join(".git", "mini-commits")
+	os.MkdirAll(filepath.Join(storeDir, TrashDir), 0755)
+	shared := testPatch("a.txt", "a")
+	first := types.MiniCommit{ID: "first", Message: "First", CreatedAt: time.Now()}
+	second := types.MiniCommit{ID: "second", Message: "Second", CreatedAt: time.Now()}
+	dropped := TrashEntry{MiniCommit: types.MiniCommit{ID: "dropped", Message: "Dropped", CreatedAt: time.Now()},
+		DeletedAt: time.Now(), Reason: OpDrop}
+	index, _ := json.Marshal(indexFile{FormatVersion: 3, ToolVersion: "0.1.0", CreatedBy: "git-mini-commit 0.1.0",
+		MiniCommits: types.MiniCommitList{first, second}})
+	os.WriteFile(filepath.Join(storeDir, IndexFile), index, 0644)
+	trash, _ := json.Marshal([]TrashEntry{dropped})
+	os.WriteFile(filepath.Join(storeDir, TrashDir, IndexFile), trash, 0644)
+	os.WriteFile(filepath.Join(storeDir, "first.patch"), []byte(shared), 0644)
+	os.WriteFile(filepath.Join(storeDir, "second.patch"), []byte(shared), 0644)
+	os.WriteFile(filepath.Join(storeDir, TrashDir, "dropped.patch"), []byte(testPatch("b.txt", "b")), 0644)
+
+	storage, err := NewStorage()
+	if err != nil {
+		t.Fatalf("NewStorage() error = %v", err)
+	}
+
+	// パッチファイルは圧縮オブジェクトに置き換えられ、同じ内容は1つにまとまる
+	if files, _ := filepath.Glob(filepath.Join(storeDir, "*.patch")); len(files) != 0 {
+		t.Errorf("Expected the patch files to be removed, got %v", files)
+	}
+	if files, _ := filepath.Glob(filepath.Join(storeDir, TrashDir, "*.patch")); len(files) != 0 {
+		t.Errorf("Expected the trashed patch files to be removed, got %v", files)
+	}
+	objects, _ := filepath.Glob(filepath.Join(storeDir, ObjectsDir, "*", "*"))
+	if len(objects) != 2 {
+		t.Errorf("Expected 2 objects, got %v", objects)
+	}
+	for _, id := range []string{"first", "second"} {
+		if mc, err := storage.GetMiniCommit(id); err != nil || mc.Patch != shared {
+			t.Errorf("Expected the migrated patch of %s, got %v", id, err)
+		}
+	}
+	restored, err := storage.RestoreMiniCommit("dropped")
+	if err != nil || restored.Patch != testPatch("b.txt", "b") {
+		t.Errorf("Expected the trashed patch to be migrated, got %v", err)
+	}
+
+	backups, _ := filepath.Glob(filepath.Join(storeDir, BackupDir, "format-3-*", "first.patch"))
+	if len(backups) != 1 {
+		t.Errorf("Expected the patch files in the backup, got %v", backups)
+	}
+}
+
+func TestMigrateFormat4(t *testing.T) {
+	repo := testutils.NewTestGitRepo(t)
+	defer repo.Cleanup()
+
+	// 形式4のストアを用意（トレーラーを知らないバージョンが書いたもの）
+	storage, err := NewStorage()
+	if err != nil {
+		t.Fatalf("NewStorage() error = %v", err)
+	}
+	mc := newTestMiniCommit(t, storage, "First", testPatch("a.txt", "a"))
+	index, _ := storage.loadIndex()
+	if err := storage.writeIndex(&indexFile{FormatVersion: 4, CreatedBy: "git-mini-commit 0.1.1", MiniCommits: index}); err != nil {
+		t.Fatalf("writeIndex() error = %v", err)
+	}
+
+	// 開くと形式5に移行され、トレーラーを保存できる
+	storage, err = NewStorage()
+	if err != nil {
+		t.Fatalf("NewStorage() error = %v", err)
+	}
+	if format, _, _ := storage.storeFormat(); format != 5 {
+		t.Errorf("Expected format 5, got %d", format)
+	}
+	second := &types.MiniCommit{Message: "Second", CreatedAt: time.Now(), Trailers: []types.Trailer{{Key: "Refs", Value: "#12"}}}
+	second.ID = storage.GenerateID(testPatch("b.txt", "b"), second.CreatedAt)
+	second.Patch = testPatch("b.txt", "b")
+	if err := storage.SaveMiniCommit(second); err != nil {
+		t.Fatalf("SaveMiniCommit() error = %v", err)
+	}
+	list, _ := storage.LoadMiniCommits()
+	if len(list) != 2 || list[0].ID != mc.ID || len(list[1].Trailers) != 1 || list[1].Trailers[0].Value != "#12" {
+		t.Errorf("Unexpected mini-commits after migration: %+v", list)
+	}
+}
+
+func TestNewerFormatIsRefused(t *testing.T) {
+	repo := testutils.NewTestGitRepo(t)
+	defer repo.Cleanup()
+
+	storeDir := filepath.Join(".git", "mini-commits")
+	os.MkdirAll(storeDir, 0755)
+	newer := `{"formatVersion": 99, "toolVersion": "9.0.0", "createdBy": "git-mini-commit 9.0.0", "miniCommits": []}`
+	os.WriteFile(filepath.Join(storeDir, IndexFile), []byte(newer), 0644)
+
+	_, err := NewStorage()
+	if !errors.Is(err, ErrNewerFormat) {
+		t.Fatalf("Expected ErrNewerFormat, got %v", err)
+	}
+	if !strings.Contains(err.Error(), "9.0.0") {
+		t.Errorf("Expected the newer version in the error, got %v", err)
+	}
+
+	// ストアは変更されない
+	if data, _ := os.ReadFile(filepath.Join(storeDir, IndexFile)); string(data) != newer {
+		t.Errorf("Expected the newer store to be left untouched")
+	}
+}
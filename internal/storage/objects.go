@@ -0,0 +1,337 @@
+package storage
+
+import (
+	"bufio"
+	"compress/zlib"
+	"crypto/sha1"
+	"fmt"
+	"hash"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"git-mini-commit/internal/types"
+)
+
+// ObjectsDir holds the patches, zlib-compressed and named by the SHA1 of their
+// content like git's loose objects, so that identical patches are stored once
+const ObjectsDir = "objects"
+
+// ObjectError reports a patch object that cannot be decompressed or whose
+// content does not match its name
+type ObjectError struct {
+	Hash string
+	Err  error
+}
+
+func (e *ObjectError) Error() string {
+	return fmt.Sprintf("patch object %s is corrupt: %v", e.Hash, e.Err)
+}
+
+// Is makes errors.Is(err, ErrCorrupt) succeed
+func (e *ObjectError) Is(target error) bool {
+	return target == ErrCorrupt
+}
+
+// PruneResult reports what PruneObjects removed: the number of objects and
+// the disk space they used
+type PruneResult struct {
+	Objects int
+	Bytes   int64
+}
+
+// PruneObjects deletes the patch objects that neither the stack, the trash nor
+// the operation log refers to
+func (s *Storage) PruneObjects() (*PruneResult, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	unlock, err := s.lockStore()
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	referenced, err := s.referencedObjects()
+	if err != nil {
+		return nil, err
+	}
+
+	result := &PruneResult{}
+	objects, err := s.listObjects()
+	if err != nil {
+		return nil, err
+	}
+	for hash, size := range objects {
+		if referenced[hash] {
+			continue
+		}
+		if err := os.Remove(s.objectPath(hash)); err != nil && !os.IsNotExist(err) {
+			return nil, fmt.Errorf("failed to delete patch object: %w", err)
+		}
+		// The fan-out directory is removed once empty; Remove fails harmlessly otherwise
+		os.Remove(filepath.Dir(s.objectPath(hash)))
+		result.Objects++
+		result.Bytes += size
+	}
+	return result, nil
+}
+
+// patchHash returns the name of the object holding content
+func patchHash(content string) string {
+	return fmt.Sprintf("%x", sha1.Sum([]byte(content)))
+}
+
+// objectPath returns the path of the object named hash
+func (s *Storage) objectPath(hash string) string {
+	return filepath.Join(s.basePath, ObjectsDir, hash[:2], hash[2:])
+}
+
+// writeObject stores content as a compressed object and returns its hash.
+// Content that is already stored is not written again.
+func (s *Storage) writeObject(content string) (string, error) {
+	hash := patchHash(content)
+	if _, err := os.Stat(s.objectPath(hash)); err == nil {
+		return hash, nil
+	}
+	return s.streamObject(strings.NewReader(content))
+}
+
+// streamObject stores the content read from r as a compressed object and
+// returns its hash, also copying the content to the writers in also. The
+// content is compressed into a temporary file as it is read, so it is never
+// held in memory, and the file is named once the hash is known. An error
+// reading r is returned as is.
+func (s *Storage) streamObject(r io.Reader, also ...io.Writer) (string, error) {
+	dir := filepath.Join(s.basePath, ObjectsDir)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create objects directory: %w", err)
+	}
+	// Temporary files at the top of the objects directory are never taken for objects
+	tmp, err := os.CreateTemp(dir, "tmp-*")
+	if err != nil {
+		return "", fmt.Errorf("failed to save patch object: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	buffered := bufio.NewWriter(tmp)
+	compressed := zlib.NewWriter(buffered)
+	h := sha1.New()
+	src := &SourceReader{R: r}
+	_, err = io.Copy(io.MultiWriter(append([]io.Writer{h, compressed}, also...)...), src)
+	if err == nil {
+		err = compressed.Close()
+	}
+	if err == nil {
+		err = buffered.Flush()
+	}
+	if err == nil {
+		err = tmp.Chmod(0644)
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if src.Err != nil {
+		return "", src.Err
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to save patch object: %w", err)
+	}
+
+	hash := fmt.Sprintf("%x", h.Sum(nil))
+	path := s.objectPath(hash)
+	if _, err := os.Stat(path); err == nil {
+		return hash, nil
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return "", fmt.Errorf("failed to create objects directory: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return "", fmt.Errorf("failed to save patch object: %w", err)
+	}
+	return hash, nil
+}
+
+// SourceReader remembers the error of the reader it wraps, so that a failure of
+// the source can be told apart from one of its consumer, such as writing the object
+type SourceReader struct {
+	R   io.Reader
+	Err error
+}
+
+func (r *SourceReader) Read(p []byte) (int, error) {
+	n, err := r.R.Read(p)
+	if err != nil && err != io.EOF {
+		r.Err = err
+	}
+	return n, err
+}
+
+// readObject returns the decompressed content of the object named hash. A
+// missing object is reported with an error satisfying os.IsNotExist, a
+// damaged one with an *ObjectError.
+func (s *Storage) readObject(hash string) (string, error) {
+	r, err := s.openObject(hash)
+	if err != nil {
+		return "", err
+	}
+	defer r.Close()
+	content, err := io.ReadAll(r)
+	if err != nil {
+		return "", err
+	}
+	return string(content), nil
+}
+
+// verifyObject reads the object named hash to the end, reporting errors like readObject
+func (s *Storage) verifyObject(hash string) error {
+	r, err := s.openObject(hash)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	_, err = io.Copy(io.Discard, r)
+	return err
+}
+
+// openObject opens the object named hash to stream its decompressed content.
+// Errors are those of readObject; damage found while reading, including
+// content that does not match the name, is returned by Read.
+func (s *Storage) openObject(hash string) (io.ReadCloser, error) {
+	if len(hash) < 3 {
+		return nil, &ObjectError{Hash: hash, Err: fmt.Errorf("invalid object name")}
+	}
+	f, err := os.Open(s.objectPath(hash))
+	if err != nil {
+		return nil, err
+	}
+	r, err := zlib.NewReader(bufio.NewReader(f))
+	if err != nil {
+		f.Close()
+		return nil, &ObjectError{Hash: hash, Err: err}
+	}
+	return &objectReader{hash: hash, file: f, r: r, h: sha1.New()}, nil
+}
+
+// objectReader decompresses an object and checks its content against its name at the end
+type objectReader struct {
+	hash string
+	file *os.File
+	r    io.ReadCloser
+	h    hash.Hash
+}
+
+func (r *objectReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	r.h.Write(p[:n])
+	if err == io.EOF && fmt.Sprintf("%x", r.h.Sum(nil)) != r.hash {
+		return n, &ObjectError{Hash: r.hash, Err: fmt.Errorf("content does not match its name")}
+	}
+	if err != nil && err != io.EOF {
+		return n, &ObjectError{Hash: r.hash, Err: err}
+	}
+	return n, err
+}
+
+func (r *objectReader) Close() error {
+	r.r.Close()
+	return r.file.Close()
+}
+
+// storePatches writes the patch of every entry of list that carries one as an
+// object and returns a copy of list without patches, in which those entries
+// refer to their object and have their stats computed. Entries without a
+// patch keep their recorded object and stats.
+func (s *Storage) storePatches(list types.MiniCommitList) (types.MiniCommitList, error) {
+	stored := make(types.MiniCommitList, len(list))
+	copy(stored, list)
+	for i := range stored {
+		if stored[i].Patch == "" {
+			continue
+		}
+		hash, err := s.writeObject(stored[i].Patch)
+		if err != nil {
+			return nil, err
+		}
+		stored[i].PatchHash = hash
+	}
+	return withoutPatches(stored), nil
+}
+
+// referencedObjects returns the objects that the stack, the trash and the
+// versions recorded in the operation log refer to
+func (s *Storage) referencedObjects() (map[string]bool, error) {
+	index, err := s.loadIndex()
+	if err != nil {
+		return nil, err
+	}
+	trash, err := s.loadTrash()
+	if err != nil {
+		return nil, err
+	}
+	ops, err := s.loadOperations()
+	if err != nil {
+		return nil, err
+	}
+
+	referenced := make(map[string]bool)
+	for _, mc := range index {
+		referenced[mc.PatchHash] = true
+	}
+	for _, entry := range trash {
+		referenced[entry.PatchHash] = true
+	}
+	for _, op := range ops {
+		for _, mc := range op.Removed {
+			referenced[loggedHash(mc)] = true
+		}
+	}
+	return referenced, nil
+}
+
+// listObjects returns every object in the store with its size on disk
+func (s *Storage) listObjects() (map[string]int64, error) {
+	objects := make(map[string]int64)
+	root := filepath.Join(s.basePath, ObjectsDir)
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if os.IsNotExist(err) && path == root {
+			return filepath.SkipDir
+		}
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+		dir, name := filepath.Split(rel)
+		if len(dir) != 3 || !isHex(dir[:2]+name) || len(name) != 38 {
+			// Temporary files of interrupted writes are not objects
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		objects[dir[:2]+name] = info.Size()
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read objects directory: %w", err)
+	}
+	return objects, nil
+}
+
+// isHex reports whether s is a lowercase hexadecimal string
+func isHex(s string) bool {
+	for _, c := range s {
+		if (c < '0' || c > '9') && (c < 'a' || c > 'f') {
+			return false
+		}
+	}
+	return s != ""
+}
@@ -0,0 +1,208 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"testing/iotest"
+	"time"
+
+	"git-mini-commit/internal/patch"
+	"git-mini-commit/internal/types"
+	"git-mini-commit/testutils"
+)
+
+func TestPatchObjects(t *testing.T) {
+	repo := testutils.NewTestGitRepo(t)
+	defer repo.Cleanup()
+
+	storage, err := NewStorage()
+	if err != nil {
+		t.Fatalf("NewStorage() error = %v", err)
+	}
+
+	// 1. 同じ内容のパッチは1つのオブジェクトとして保存される
+	large := testPatch("a.txt", strings.Repeat("repeated line ", 2000))
+	first := newTestMiniCommit(t, storage, "First", large)
+	second := newTestMiniCommit(t, storage, "Second", large)
+	objects, _ := filepath.Glob(filepath.Join(storage.basePath, ObjectsDir, "*", "*"))
+	if len(objects) != 1 {
+		t.Fatalf("Expected one object for identical patches, got %v", objects)
+	}
+
+	// 圧縮して保存され、読み込み時に展開される
+	info, err := os.Stat(objects[0])
+	if err != nil || info.Size() >= int64(len(large)) {
+		t.Errorf("Expected a compressed object smaller than %d bytes, got %v (%v)", len(large), info, err)
+	}
+	for _, id := range []string{first.ID, second.ID} {
+		if mc, err := storage.GetMiniCommit(id); err != nil || mc.Patch != large {
+			t.Errorf("Expected the decompressed patch for %s, got %v", id, err)
+		}
+	}
+
+	// 2. 一方を削除してもオブジェクトは共有されたまま
+	if err := storage.DeleteMiniCommit(first.ID); err != nil {
+		t.Fatalf("DeleteMiniCommit() error = %v", err)
+	}
+	if _, err := storage.PurgeTrash(first.CreatedAt.AddDate(1, 0, 0)); err != nil {
+		t.Fatalf("PurgeTrash() error = %v", err)
+	}
+	if result, err := storage.PruneObjects(); err != nil || result.Objects != 0 {
+		t.Errorf("Expected the shared object to be kept, got %+v (%v)", result, err)
+	}
+
+	// 3. 内容が名前と一致しないオブジェクトは壊れたものとして扱う
+	if err := os.WriteFile(objects[0], []byte("not zlib"), 0644); err != nil {
+		t.Fatalf("Failed to corrupt object: %v", err)
+	}
+	_, err = storage.readObject(patchHash(large))
+	var objErr *ObjectError
+	if !errors.As(err, &objErr) {
+		t.Errorf("Expected an ObjectError, got %v", err)
+	}
+	if _, err := storage.GetMiniCommit(second.ID); err == nil || !strings.Contains(err.Error(), "corrupt") {
+		t.Errorf("Expected an error for the corrupt object, got %v", err)
+	}
+}
+
+// generatedPatch は追加行を必要に応じて生成し、パッチ全体をメモリに持たない
+type generatedPatch struct {
+	header string
+	line   string
+	lines  int
+	offset int
+}
+
+func newGeneratedPatch(lines int) *generatedPatch {
+	return &generatedPatch{
+		header: fmt.Sprintf("diff --git a/big.txt b/big.txt\nnew file mode 100644\n--- /dev/null\n+++ b/big.txt\n@@ -0,0 +1,%d @@\n", lines),
+		line:   "+" + strings.Repeat("x", 63) + "\n",
+		lines:  lines,
+	}
+}
+
+func (g *generatedPatch) size() int {
+	return len(g.header) + g.lines*len(g.line)
+}
+
+func (g *generatedPatch) Read(p []byte) (int, error) {
+	n := 0
+	for n < len(p) && g.offset < g.size() {
+		var src string
+		if g.offset < len(g.header) {
+			src = g.header[g.offset:]
+		} else {
+			src = g.line[(g.offset-len(g.header))%len(g.line):]
+		}
+		copied := copy(p[n:], src)
+		n += copied
+		g.offset += copied
+	}
+	if n == 0 {
+		return 0, io.EOF
+	}
+	return n, nil
+}
+
+func TestCreateMiniCommitStreams(t *testing.T) {
+	repo := testutils.NewTestGitRepo(t)
+	defer repo.Cleanup()
+
+	storage, err := NewStorage()
+	if err != nil {
+		t.Fatalf("NewStorage() error = %v", err)
+	}
+
+	// 1. ストリームから作成しても、IDと統計はパッチ全体から計算したものと同じ
+	content := testPatch("a.txt", "streamed")
+	mc := &types.MiniCommit{Message: "Streamed", CreatedAt: time.Now()}
+	if err := storage.CreateMiniCommit(mc, strings.NewReader(content)); err != nil {
+		t.Fatalf("CreateMiniCommit() error = %v", err)
+	}
+	if mc.ID != storage.GenerateID(content, mc.CreatedAt) || mc.PatchHash != patchHash(content) ||
+		mc.Stats == nil || *mc.Stats != patch.ComputeStats(content) || mc.Patch != "" {
+		t.Errorf("Unexpected mini-commit: %+v", mc)
+	}
+	r, err := storage.OpenPatch(mc)
+	if err != nil {
+		t.Fatalf("OpenPatch() error = %v", err)
+	}
+	streamed, _ := io.ReadAll(r)
+	r.Close()
+	if string(streamed) != content {
+		t.Errorf("Expected OpenPatch to stream the patch, got %q", streamed)
+	}
+	if found, err := storage.FindMiniCommit(mc.ID); err != nil || found.Patch != "" || found.PatchHash != mc.PatchHash {
+		t.Errorf("Expected FindMiniCommit to return the entry without its patch, got %+v (%v)", found, err)
+	}
+
+	// 2. 読み込みエラーはそのまま返り、スタックも一時ファイルも残らない
+	readErr := errors.New("git diff failed")
+	failing := io.MultiReader(strings.NewReader(content), iotest.ErrReader(readErr))
+	if err := storage.CreateMiniCommit(&types.MiniCommit{Message: "Failed", CreatedAt: time.Now()}, failing); err != readErr {
+		t.Errorf("Expected the read error, got %v", err)
+	}
+	assertStack(t, storage, "Streamed")
+	if tmp, _ := filepath.Glob(filepath.Join(storage.basePath, ObjectsDir, "tmp-*")); len(tmp) != 0 {
+		t.Errorf("Expected no temporary files, got %v", tmp)
+	}
+
+	// 3. 壊れたオブジェクトは読み始める前に報告される
+	broken := *mc
+	broken.PatchHash = patchHash("other content")
+	os.MkdirAll(filepath.Dir(storage.objectPath(broken.PatchHash)), 0755)
+	data, _ := os.ReadFile(storage.objectPath(mc.PatchHash))
+	os.WriteFile(storage.objectPath(broken.PatchHash), data, 0644)
+	if _, err := storage.OpenPatch(&broken); err == nil || !strings.Contains(err.Error(), "does not match") {
+		t.Errorf("Expected OpenPatch to report the damaged object, got %v", err)
+	}
+
+	if testing.Short() {
+		return
+	}
+
+	// 4. 大きなパッチでも作成・読み出し・検査・削除・取り消しのメモリ使用量はパッチの大きさに比例しない
+	os.Remove(storage.objectPath(broken.PatchHash))
+	large := newGeneratedPatch(1 << 20)
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	big := &types.MiniCommit{Message: "Big", CreatedAt: time.Now()}
+	if err := storage.CreateMiniCommit(big, large); err != nil {
+		t.Fatalf("CreateMiniCommit() error = %v", err)
+	}
+	r, err = storage.OpenPatch(big)
+	if err != nil {
+		t.Fatalf("OpenPatch() error = %v", err)
+	}
+	n, err := io.Copy(io.Discard, r)
+	r.Close()
+	if problems, err := storage.Check(); err != nil || len(problems) != 0 {
+		t.Errorf("Expected no problems, got %+v (%v)", problems, err)
+	}
+	if err := storage.DeleteMiniCommit(big.ID); err != nil {
+		t.Errorf("DeleteMiniCommit() error = %v", err)
+	}
+	if _, err := storage.Undo(1); err != nil {
+		t.Errorf("Undo() error = %v", err)
+	}
+	if removed, err := storage.ClearMiniCommits([]string{big.ID}); err != nil || len(removed) != 1 {
+		t.Errorf("ClearMiniCommits() = %d, %v", len(removed), err)
+	}
+	runtime.ReadMemStats(&after)
+
+	if err != nil || n != int64(large.size()) {
+		t.Errorf("Expected %d bytes streamed back, got %d (%v)", large.size(), n, err)
+	}
+	if big.Stats == nil || *big.Stats != (patch.Stats{Files: 1, Insertions: 1 << 20}) {
+		t.Errorf("Unexpected stats: %+v", big.Stats)
+	}
+	if allocated := after.TotalAlloc - before.TotalAlloc; allocated > uint64(large.size()/4) {
+		t.Errorf("Expected bounded memory use for a %d-byte patch, allocated %d bytes", large.size(), allocated)
+	}
+}
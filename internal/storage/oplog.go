@@ -0,0 +1,392 @@
+package storage
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"git-mini-commit/internal/types"
+)
+
+// OpLogFile is the log of operations on the store, one JSON object per line. It
+// is only appended to, except when purged content is removed from it.
+const OpLogFile = "oplog.jsonl"
+
+// Operation kinds recorded in the operation log
+const (
+	OpCreate = "create"
+	OpDrop   = "drop"
+	OpPop    = "pop"
+	OpEdit   = "edit"
+	OpClear  = "clear"
+	OpUndo   = "undo"
+	OpSquash = "squash"
+	OpSplit  = "split"
+)
+
+// Operation is one entry of the operation log. Before and After are the IDs of the
+// stack in order; Removed holds the previous version of every mini-commit the
+// operation removed or modified so that it can be undone. Removed versions refer
+// to their patch object, which the log keeps from being pruned, instead of
+// carrying the patch; versions logged by older releases may carry it.
+type Operation struct {
+	Kind    string             `json:"kind"`
+	Time    time.Time          `json:"time"`
+	Summary string             `json:"summary"`
+	Before  []string           `json:"before"`
+	After   []string           `json:"after"`
+	Removed []types.MiniCommit `json:"removed,omitempty"`
+}
+
+// Operations returns the operation log, oldest first
+func (s *Storage) Operations() ([]Operation, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	return s.loadOperations()
+}
+
+// RecordPop logs that mc was applied to the staging area. The store itself is
+// unchanged, so undoing a pop does not unstage anything.
+func (s *Storage) RecordPop(mc *types.MiniCommit) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	unlock, err := s.lockStore()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	index, err := s.loadIndex()
+	if err != nil {
+		return err
+	}
+	ids := stackIDs(index)
+	return s.appendOperation(Operation{Kind: OpPop, Summary: describe(mc), Before: ids, After: ids})
+}
+
+// Undo restores the store to the state it had before the last n operations and
+// logs this as an operation of its own, so an undo can be undone as well.
+// It returns the resulting stack.
+func (s *Storage) Undo(n int) (types.MiniCommitList, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	unlock, err := s.lockStore()
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	ops, err := s.loadOperations()
+	if err != nil {
+		return nil, err
+	}
+	if n < 1 || n > len(ops) {
+		return nil, fmt.Errorf("cannot undo %d operation(s): the log has %d", n, len(ops))
+	}
+
+	index, err := s.loadIndex()
+	if err != nil {
+		return nil, err
+	}
+	restored, err := s.stackBefore(ops, index, n)
+	if err != nil {
+		return nil, err
+	}
+	target := ops[len(ops)-n].Before
+
+	// Keep the current version of everything that is about to be replaced
+	kept := make(map[string]types.MiniCommit, len(restored))
+	for _, mc := range restored {
+		kept[mc.ID] = mc
+	}
+	var removed []types.MiniCommit
+	for _, mc := range index {
+		if old, ok := kept[mc.ID]; !ok || !sameMiniCommit(old, mc) {
+			removed = append(removed, mc)
+		}
+	}
+
+	// Patches logged by older releases are stored as objects along with the index
+	if err := s.saveIndex(restored); err != nil {
+		return nil, fmt.Errorf("failed to save index: %w", err)
+	}
+
+	// Mini-commits back in the stack leave the trash; those taken out go into it
+	if err := s.removeFromTrash(idSet(restored)); err != nil {
+		return nil, err
+	}
+	var trashed []types.MiniCommit
+	for _, mc := range removed {
+		if _, ok := kept[mc.ID]; !ok {
+			trashed = append(trashed, mc)
+		}
+	}
+	if err := s.moveToTrash(trashed, OpUndo); err != nil {
+		return nil, err
+	}
+
+	op := Operation{
+		Kind:    OpUndo,
+		Summary: fmt.Sprintf("%d operation(s)", n),
+		Before:  stackIDs(index),
+		After:   target,
+		Removed: logged(removed),
+	}
+	if err := s.appendOperation(op); err != nil {
+		return nil, err
+	}
+
+	return restored, nil
+}
+
+// StackBefore returns the stack as it was before the last n operations, the
+// one Undo(n) would restore, without changing the store. Like LoadMiniCommits,
+// it leaves the Patch of the mini-commits empty unless an older release logged it.
+func (s *Storage) StackBefore(n int) (types.MiniCommitList, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	ops, err := s.loadOperations()
+	if err != nil {
+		return nil, err
+	}
+	if n < 1 || n > len(ops) {
+		return nil, fmt.Errorf("cannot go back %d operation(s): the log has %d", n, len(ops))
+	}
+	index, err := s.loadIndex()
+	if err != nil {
+		return nil, err
+	}
+	return s.stackBefore(ops, index, n)
+}
+
+// stackBefore rebuilds the stack recorded before the last n of ops from the
+// current index and the versions the operations removed. Every mini-commit of
+// it must still have its patch.
+func (s *Storage) stackBefore(ops []Operation, index types.MiniCommitList, n int) (types.MiniCommitList, error) {
+	// The version of each mini-commit at the target point is the one removed by the
+	// oldest of the undone operations, or the current one if none touched it
+	versions := make(map[string]types.MiniCommit)
+	for _, mc := range index {
+		versions[mc.ID] = mc
+	}
+	for i := len(ops) - 1; i >= len(ops)-n; i-- {
+		for _, mc := range ops[i].Removed {
+			versions[mc.ID] = mc
+		}
+	}
+
+	target := ops[len(ops)-n].Before
+	stack := make(types.MiniCommitList, 0, len(target))
+	for _, id := range target {
+		mc, ok := versions[id]
+		if !ok {
+			return nil, fmt.Errorf("cannot restore mini-commit '%s': its content was not recorded or has been purged", id)
+		}
+		if mc.Patch == "" {
+			if mc.PatchHash == "" {
+				return nil, patchError(&mc, nil)
+			}
+			if _, err := os.Stat(s.objectPath(mc.PatchHash)); err != nil {
+				return nil, patchError(&mc, err)
+			}
+		}
+		stack = append(stack, mc)
+	}
+	return stack, nil
+}
+
+// ExpireOperations removes the operations logged before cutoff from the start
+// of the log and returns how many were removed. The last operation is always
+// kept, as it records the stack that fsck rebuilds the index from. Undo cannot
+// go back further than the oldest operation left, and the patch objects that
+// only the removed operations referred to are left for PruneObjects.
+func (s *Storage) ExpireOperations(cutoff time.Time) (int, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	unlock, err := s.lockStore()
+	if err != nil {
+		return 0, err
+	}
+	defer unlock()
+
+	ops, err := s.loadOperations()
+	if err != nil {
+		return 0, err
+	}
+	expired := 0
+	for expired < len(ops)-1 && ops[expired].Time.Before(cutoff) {
+		expired++
+	}
+	if expired == 0 {
+		return 0, nil
+	}
+	if err := s.writeOperations(ops[expired:]); err != nil {
+		return 0, err
+	}
+	return expired, nil
+}
+
+// appendOperation timestamps op and appends it to the operation log
+func (s *Storage) appendOperation(op Operation) error {
+	if op.Time.IsZero() {
+		op.Time = time.Now()
+	}
+	if op.Before == nil {
+		op.Before = []string{}
+	}
+	if op.After == nil {
+		op.After = []string{}
+	}
+
+	data, err := json.Marshal(op)
+	if err != nil {
+		return fmt.Errorf("failed to serialize operation: %w", err)
+	}
+
+	f, err := os.OpenFile(filepath.Join(s.basePath, OpLogFile), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open operation log: %w", err)
+	}
+	defer f.Close()
+
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("failed to record operation: %w", err)
+	}
+	return nil
+}
+
+// writeOperations replaces the operation log with ops, e.g. once content was purged from it
+func (s *Storage) writeOperations(ops []Operation) error {
+	var b strings.Builder
+	for _, op := range ops {
+		data, err := json.Marshal(op)
+		if err != nil {
+			return fmt.Errorf("failed to serialize operation: %w", err)
+		}
+		b.Write(data)
+		b.WriteByte('\n')
+	}
+	if err := writeFileAtomic(filepath.Join(s.basePath, OpLogFile), []byte(b.String())); err != nil {
+		return fmt.Errorf("failed to rewrite operation log: %w", err)
+	}
+	return nil
+}
+
+// forgetOperations removes the versions of the mini-commits ids from the
+// operation log, so that their content is no longer kept anywhere. The
+// operations themselves stay, but can no longer bring those mini-commits back.
+func (s *Storage) forgetOperations(ids map[string]bool) error {
+	ops, err := s.loadOperations()
+	if err != nil {
+		return err
+	}
+	changed := false
+	for i := range ops {
+		kept := ops[i].Removed[:0]
+		for _, mc := range ops[i].Removed {
+			if ids[mc.ID] {
+				changed = true
+				continue
+			}
+			kept = append(kept, mc)
+		}
+		ops[i].Removed = kept
+	}
+	if !changed {
+		return nil
+	}
+	return s.writeOperations(ops)
+}
+
+// loadOperations reads the operation log; a missing log is empty
+func (s *Storage) loadOperations() ([]Operation, error) {
+	f, err := os.Open(filepath.Join(s.basePath, OpLogFile))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read operation log: %w", err)
+	}
+	defer f.Close()
+
+	var ops []Operation
+	reader := bufio.NewReader(f)
+	for lineNo := 1; ; lineNo++ {
+		line, err := reader.ReadString('\n')
+		if strings.TrimSpace(line) != "" {
+			var op Operation
+			// A partially written last line (e.g. after a crash) is ignored
+			if jsonErr := json.Unmarshal([]byte(line), &op); jsonErr != nil {
+				if err == nil {
+					return nil, corruption{fmt.Errorf("failed to parse operation log line %d: %v", lineNo, jsonErr)}
+				}
+			} else {
+				ops = append(ops, op)
+			}
+		}
+		if err != nil {
+			break
+		}
+	}
+	return ops, nil
+}
+
+// stackIDs returns the IDs of index in order
+func stackIDs(index types.MiniCommitList) []string {
+	ids := make([]string, len(index))
+	for i, mc := range index {
+		ids[i] = mc.ID
+	}
+	return ids
+}
+
+// idSet returns the set of IDs in index
+func idSet(index types.MiniCommitList) map[string]bool {
+	ids := make(map[string]bool, len(index))
+	for _, mc := range index {
+		ids[mc.ID] = true
+	}
+	return ids
+}
+
+// describe summarises a mini-commit for the operation log
+func describe(mc *types.MiniCommit) string {
+	id := mc.ID
+	if len(id) > 8 {
+		id = id[:8]
+	}
+	subject, _, _ := strings.Cut(mc.Message, "\n")
+	return id + " " + subject
+}
+
+// logged returns the versions of list to record in the operation log, which
+// refer to their patch object instead of carrying the patch
+func logged(list []types.MiniCommit) []types.MiniCommit {
+	versions := make([]types.MiniCommit, len(list))
+	for i, mc := range list {
+		mc.PatchHash = loggedHash(mc)
+		mc.Patch = ""
+		versions[i] = mc
+	}
+	return versions
+}
+
+// loggedHash returns the patch object of a version from the operation log.
+// Versions logged by older releases may carry the patch without naming it.
+func loggedHash(mc types.MiniCommit) string {
+	if mc.PatchHash == "" && mc.Patch != "" {
+		return patchHash(mc.Patch)
+	}
+	return mc.PatchHash
+}
+
+// sameMiniCommit reports whether two versions of a mini-commit are identical
+func sameMiniCommit(a, b types.MiniCommit) bool {
+	return a.ID == b.ID && a.Message == b.Message && a.CreatedAt.Equal(b.CreatedAt) &&
+		a.Branch == b.Branch && a.Author == b.Author && a.Base == b.Base && loggedHash(a) == loggedHash(b)
+}
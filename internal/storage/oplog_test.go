@@ -0,0 +1,299 @@
+package storage
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"git-mini-commit/internal/types"
+	"git-mini-commit/testutils"
+)
+
+// newTestMiniCommit テスト用mini-commitを作成して保存
+func newTestMiniCommit(t *testing.T, storage *Storage, message, patch string) *types.MiniCommit {
+	t.Helper()
+	now := time.Now()
+	mc := &types.MiniCommit{
+		ID:        storage.GenerateID(patch, now),
+		Message:   message,
+		CreatedAt: now,
+		Patch:     patch,
+	}
+	if err := storage.SaveMiniCommit(mc); err != nil {
+		t.Fatalf("SaveMiniCommit() error = %v", err)
+	}
+	return mc
+}
+
+// assertStack スタックのメッセージを順番どおりに検証
+func assertStack(t *testing.T, storage *Storage, messages ...string) {
+	t.Helper()
+	index, err := storage.LoadMiniCommits()
+	if err != nil {
+		t.Fatalf("LoadMiniCommits() error = %v", err)
+	}
+	if len(index) != len(messages) {
+		t.Fatalf("Expected %d mini-commits, but got %d", len(messages), len(index))
+	}
+	for i, mc := range index {
+		if mc.Message != messages[i] {
+			t.Errorf("mini-commit %d: expected message %q, but got %q", i, messages[i], mc.Message)
+		}
+	}
+}
+
+func TestOperationLog(t *testing.T) {
+	repo := testutils.NewTestGitRepo(t)
+	defer repo.Cleanup()
+
+	storage, err := NewStorage()
+	if err != nil {
+		t.Fatalf("NewStorage() error = %v", err)
+	}
+
+	mc1 := newTestMiniCommit(t, storage, "First", "patch 1\n")
+	mc2 := newTestMiniCommit(t, storage, "Second", "patch 2\n")
+	if err := storage.RecordPop(mc1); err != nil {
+		t.Fatalf("RecordPop() error = %v", err)
+	}
+	edited := *mc2
+	edited.Message = "Second (edited)"
+	if err := storage.UpdateMiniCommit(&edited); err != nil {
+		t.Fatalf("UpdateMiniCommit() error = %v", err)
+	}
+	if err := storage.DeleteMiniCommit(mc1.ID); err != nil {
+		t.Fatalf("DeleteMiniCommit() error = %v", err)
+	}
+
+	ops, err := storage.Operations()
+	if err != nil {
+		t.Fatalf("Operations() error = %v", err)
+	}
+	kinds := []string{OpCreate, OpCreate, OpPop, OpEdit, OpDrop}
+	if len(ops) != len(kinds) {
+		t.Fatalf("Expected %d operations, but got %d", len(kinds), len(ops))
+	}
+	for i, kind := range kinds {
+		if ops[i].Kind != kind {
+			t.Errorf("operation %d: expected %s, but got %s", i, kind, ops[i].Kind)
+		}
+	}
+
+	// drop は削除したmini-commitをパッチオブジェクトの名前とともに記録し、パッチ自体は記録しない
+	drop := ops[4]
+	if len(drop.Removed) != 1 || drop.Removed[0].Patch != "" || drop.Removed[0].PatchHash != patchHash("patch 1\n") {
+		t.Errorf("Expected the dropped mini-commit to be recorded, but got %+v", drop.Removed)
+	}
+	if data, _ := os.ReadFile(filepath.Join(storage.basePath, OpLogFile)); strings.Contains(string(data), "patch 1") {
+		t.Errorf("Expected the operation log not to contain the patch, but got:\n%s", data)
+	}
+	if len(drop.Before) != 2 || len(drop.After) != 1 {
+		t.Errorf("Unexpected before/after state: %v -> %v", drop.Before, drop.After)
+	}
+
+	// 更新対象が存在しない場合
+	missing := types.MiniCommit{ID: "missing"}
+	if err := storage.UpdateMiniCommit(&missing); err == nil {
+		t.Errorf("Expected an error when updating a missing mini-commit")
+	}
+}
+
+func TestUndo(t *testing.T) {
+	repo := testutils.NewTestGitRepo(t)
+	defer repo.Cleanup()
+
+	storage, err := NewStorage()
+	if err != nil {
+		t.Fatalf("NewStorage() error = %v", err)
+	}
+
+	mc1 := newTestMiniCommit(t, storage, "First", "patch 1\n")
+	mc2 := newTestMiniCommit(t, storage, "Second", "patch 2\n")
+	edited := *mc2
+	edited.Message = "Second (edited)"
+	if err := storage.UpdateMiniCommit(&edited); err != nil {
+		t.Fatalf("UpdateMiniCommit() error = %v", err)
+	}
+	if err := storage.DeleteMiniCommit(mc1.ID); err != nil {
+		t.Fatalf("DeleteMiniCommit() error = %v", err)
+	}
+	assertStack(t, storage, "Second (edited)")
+
+	// 1. drop を取り消すとパッチも復元される
+	if _, err := storage.Undo(1); err != nil {
+		t.Fatalf("Undo(1) error = %v", err)
+	}
+	assertStack(t, storage, "First", "Second (edited)")
+	restored, err := storage.GetMiniCommit(mc1.ID)
+	if err != nil || restored.Patch != "patch 1\n" {
+		t.Errorf("Expected the patch to be restored, got %+v (%v)", restored, err)
+	}
+
+	// 2. undo 自体も取り消せる
+	if _, err := storage.Undo(1); err != nil {
+		t.Fatalf("Undo(1) error = %v", err)
+	}
+	assertStack(t, storage, "Second (edited)")
+
+	// 3. 4つ前（create, create, edit, drop のうち edit より前）まで戻す
+	// ログ: create, create, edit, drop, undo, undo
+	if _, err := storage.Undo(4); err != nil {
+		t.Fatalf("Undo(4) error = %v", err)
+	}
+	assertStack(t, storage, "First", "Second")
+
+	// 4. 最初の状態（空）まで戻す
+	ops, _ := storage.Operations()
+	if _, err := storage.Undo(len(ops)); err != nil {
+		t.Fatalf("Undo(all) error = %v", err)
+	}
+	assertStack(t, storage)
+	if _, err := storage.GetMiniCommit(mc2.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected the mini-commit to be gone, got %v", err)
+	}
+
+	// 範囲外
+	if _, err := storage.Undo(0); err == nil {
+		t.Errorf("Expected an error for Undo(0)")
+	}
+	if _, err := storage.Undo(100); err == nil {
+		t.Errorf("Expected an error for Undo(100)")
+	}
+}
+
+func TestStackBefore(t *testing.T) {
+	repo := testutils.NewTestGitRepo(t)
+	defer repo.Cleanup()
+
+	storage, err := NewStorage()
+	if err != nil {
+		t.Fatalf("NewStorage() error = %v", err)
+	}
+
+	first := newTestMiniCommit(t, storage, "First", "diff --git a/a b/a\n")
+	second := newTestMiniCommit(t, storage, "Second", "diff --git a/b b/b\n")
+	edited := *first
+	edited.Message = "First edited"
+	if err := storage.UpdateMiniCommit(&edited); err != nil {
+		t.Fatalf("UpdateMiniCommit() error = %v", err)
+	}
+	if err := storage.DeleteMiniCommit(second.ID); err != nil {
+		t.Fatalf("DeleteMiniCommit() error = %v", err)
+	}
+
+	// 削除や編集の前の版を含むスタックを、ストアを変えずに返す
+	stack, err := storage.StackBefore(2)
+	if err != nil {
+		t.Fatalf("StackBefore() error = %v", err)
+	}
+	if len(stack) != 2 || stack[0].Message != "First" || stack[1].ID != second.ID || stack[1].PatchHash == "" {
+		t.Errorf("Unexpected stack before the edit: %+v", stack)
+	}
+	assertStack(t, storage, "First edited")
+
+	// ログより前には戻れない
+	if _, err := storage.StackBefore(5); err == nil {
+		t.Errorf("Expected an error beyond the operation log")
+	}
+}
+
+func TestReplaceMiniCommits(t *testing.T) {
+	repo := testutils.NewTestGitRepo(t)
+	defer repo.Cleanup()
+
+	storage, err := NewStorage()
+	if err != nil {
+		t.Fatalf("NewStorage() error = %v", err)
+	}
+
+	mc1 := newTestMiniCommit(t, storage, "First", "patch 1\n")
+	mc2 := newTestMiniCommit(t, storage, "Second", "patch 2\n")
+	mc3 := newTestMiniCommit(t, storage, "Third", "patch 3\n")
+	now := time.Now()
+	squashed := types.MiniCommit{ID: storage.GenerateID("patch 1+2\n", now), Message: "First and second",
+		CreatedAt: now, Patch: "patch 1+2\n"}
+
+	// 1. 隣り合っていないmini-commitは置き換えられない
+	if err := storage.ReplaceMiniCommits(OpSquash, []string{mc1.ID, mc3.ID}, types.MiniCommitList{squashed}); err == nil {
+		t.Errorf("Expected an error for mini-commits that do not follow each other")
+	}
+	var notFound *NotFoundError
+	if err := storage.ReplaceMiniCommits(OpSquash, []string{"missing"}, nil); !errors.As(err, &notFound) {
+		t.Errorf("Expected NotFoundError, got %v", err)
+	}
+
+	// 2. 同じ位置に置き換わり、置き換えられたものはゴミ箱とログに残る
+	if err := storage.ReplaceMiniCommits(OpSquash, []string{mc1.ID, mc2.ID}, types.MiniCommitList{squashed}); err != nil {
+		t.Fatalf("ReplaceMiniCommits() error = %v", err)
+	}
+	assertStack(t, storage, "First and second", "Third")
+	if got, err := storage.GetMiniCommit(squashed.ID); err != nil || got.Patch != "patch 1+2\n" || got.Stats == nil {
+		t.Errorf("Expected the replacement to be stored with its patch, got %+v (%v)", got, err)
+	}
+	trash, _ := storage.TrashedMiniCommits()
+	if len(trash) != 2 {
+		t.Errorf("Expected the replaced mini-commits in the trash, got %d", len(trash))
+	}
+	ops, _ := storage.Operations()
+	if last := ops[len(ops)-1]; last.Kind != OpSquash || len(last.Removed) != 2 || last.Summary != mc1.ID[:8]+" First and 1 more" {
+		t.Errorf("Unexpected operation: %+v", last)
+	}
+
+	// 3. 取り消すと元に戻る
+	if _, err := storage.Undo(1); err != nil {
+		t.Fatalf("Undo(1) error = %v", err)
+	}
+	assertStack(t, storage, "First", "Second", "Third")
+}
+
+func TestExpireOperations(t *testing.T) {
+	repo := testutils.NewTestGitRepo(t)
+	defer repo.Cleanup()
+
+	storage, err := NewStorage()
+	if err != nil {
+		t.Fatalf("NewStorage() error = %v", err)
+	}
+
+	mc1 := newTestMiniCommit(t, storage, "First", "patch 1\n")
+	mc2 := newTestMiniCommit(t, storage, "Second", "patch 2\n")
+	if err := storage.DeleteMiniCommit(mc1.ID); err != nil {
+		t.Fatalf("DeleteMiniCommit() error = %v", err)
+	}
+
+	// 1. ゴミ箱になくても、操作ログが参照するパッチオブジェクトは削除しない
+	if err := storage.removeFromTrash(map[string]bool{mc1.ID: true}); err != nil {
+		t.Fatalf("removeFromTrash() error = %v", err)
+	}
+	if result, err := storage.PruneObjects(); err != nil || result.Objects != 0 {
+		t.Fatalf("Expected the logged patch object to be kept, got %+v (%v)", result, err)
+	}
+
+	// 2. 期限より前の操作を削除する。最新の操作は常に残る
+	if err := storage.RecordPop(mc2); err != nil {
+		t.Fatalf("RecordPop() error = %v", err)
+	}
+	if n, err := storage.ExpireOperations(time.Now().Add(-time.Hour)); err != nil || n != 0 {
+		t.Fatalf("Expected nothing to expire, got %d (%v)", n, err)
+	}
+	n, err := storage.ExpireOperations(time.Now().Add(time.Hour))
+	if err != nil || n != 3 {
+		t.Fatalf("Expected 3 operations to expire, got %d (%v)", n, err)
+	}
+	ops, _ := storage.Operations()
+	if len(ops) != 1 || ops[0].Kind != OpPop {
+		t.Errorf("Expected only the last operation to be kept, got %+v", ops)
+	}
+	if _, err := storage.Undo(2); err == nil {
+		t.Errorf("Expected undo to be unable to go back past the expired operations")
+	}
+
+	// 3. 削除した操作だけが参照していたパッチオブジェクトは PruneObjects で削除される
+	if result, err := storage.PruneObjects(); err != nil || result.Objects != 1 {
+		t.Fatalf("Expected the patch object of the expired drop to be pruned, got %+v (%v)", result, err)
+	}
+	assertStack(t, storage, "Second")
+}
@@ -2,7 +2,7 @@ package storage
 
 import (
 	"crypto/sha1"
-	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"os"
@@ -10,6 +10,7 @@ import (
 	"sync"
 	"time"
 
+	"git-mini-commit/internal/patch"
 	"git-mini-commit/internal/types"
 )
 
@@ -20,8 +21,9 @@ const (
 
 // Storage manages mini-commit storage
 type Storage struct {
-	basePath string
-	mutex    sync.RWMutex
+	basePath  string
+	createdBy string
+	mutex     sync.RWMutex
 }
 
 // NewStorage creates a new storage instance
@@ -34,73 +36,268 @@ func NewStorage() (*Storage, error) {
 	// Create mini-commits directory
 	miniCommitsPath := filepath.Join(".git", "mini-commits")
 	if err := os.MkdirAll(miniCommitsPath, 0755); err != nil {
-		return nil, fmt.Errorf("failed to create mini-commits directory: %v", err)
+		return nil, fmt.Errorf("failed to create mini-commits directory: %w", err)
 	}
 
-	return &Storage{
+	s := &Storage{
 		basePath: miniCommitsPath,
-	}, nil
+	}
+	if err := s.migrate(); err != nil {
+		return nil, err
+	}
+	return s, nil
 }
 
 // SaveMiniCommit saves a mini-commit
 func (s *Storage) SaveMiniCommit(mc *types.MiniCommit) error {
 	s.mutex.Lock()
 	defer s.mutex.Unlock()
+	unlock, err := s.lockStore()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	return s.pushMiniCommit(mc)
+}
+
+// CreateMiniCommit saves a new mini-commit whose patch is read from r, such as
+// the output of git diff. The patch is streamed into its object while its ID
+// and stats are computed, so it is never held in memory; mc.ID, mc.PatchHash
+// and mc.Stats are filled in and mc.Patch is left empty. An error reading r is
+// returned as is and leaves the stack unchanged.
+func (s *Storage) CreateMiniCommit(mc *types.MiniCommit, r io.Reader) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	unlock, err := s.lockStore()
+	if err != nil {
+		return err
+	}
+	defer unlock()
 
+	// The ID is computed as GenerateID does, from the patch and the creation time
+	id := sha1.New()
+	stats := &patch.Writer{}
+	hash, err := s.streamObject(r, id, stats)
+	if err != nil {
+		return err
+	}
+	stats.Close()
+	_, _ = io.WriteString(id, mc.CreatedAt.Format(time.RFC3339Nano))
+
+	mc.ID = fmt.Sprintf("%x", id.Sum(nil))
+	mc.Patch = ""
+	mc.PatchHash = hash
+	computed := stats.Stats()
+	mc.Stats = &computed
+	return s.pushMiniCommit(mc)
+}
 
+// pushMiniCommit adds mc on top of the stack and logs its creation; the store must be locked
+func (s *Storage) pushMiniCommit(mc *types.MiniCommit) error {
 	// Load existing index
 	index, err := s.loadIndex()
 	if err != nil {
-		return fmt.Errorf("failed to load index: %v", err)
+		return fmt.Errorf("failed to load index: %w", err)
 	}
 
 	// Add new mini-commit
+	before := stackIDs(index)
 	index = append(index, *mc)
 
-	// Save index
+	// Save index; the patch object is written before the index refers to it,
+	// so a crash in between leaves an orphan object that fsck can add back
 	if err := s.saveIndex(index); err != nil {
-		return fmt.Errorf("failed to save index: %v", err)
+		return fmt.Errorf("failed to save index: %w", err)
 	}
 
-	// Save patch file
-	patchPath := filepath.Join(s.basePath, mc.ID+".patch")
-	if err := os.WriteFile(patchPath, []byte(mc.Patch), 0644); err != nil {
-		return fmt.Errorf("failed to save patch file: %v", err)
+	// The creation time is logged exactly, so that fsck can recover the entry from its patch object
+	return s.appendOperation(Operation{Kind: OpCreate, Time: mc.CreatedAt, Summary: describe(mc), Before: before,
+		After: stackIDs(index)})
+}
+
+// UpdateMiniCommit replaces the stored mini-commit that has the same ID as mc,
+// e.g. to change its message
+func (s *Storage) UpdateMiniCommit(mc *types.MiniCommit) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	unlock, err := s.lockStore()
+	if err != nil {
+		return err
 	}
+	defer unlock()
 
-	return nil
+	index, err := s.loadIndex()
+	if err != nil {
+		return err
+	}
+
+	position := -1
+	for i := range index {
+		if index[i].ID == mc.ID {
+			position = i
+			break
+		}
+	}
+	if position < 0 {
+		return &NotFoundError{ID: mc.ID}
+	}
+
+	// The previous version is logged so that the edit can be undone
+	previous := index[position]
+	index[position] = *mc
+
+	if err := s.saveIndex(index); err != nil {
+		return fmt.Errorf("failed to save index: %w", err)
+	}
+
+	ids := stackIDs(index)
+	return s.appendOperation(Operation{Kind: OpEdit, Summary: describe(mc), Before: ids, After: ids,
+		Removed: logged([]types.MiniCommit{previous})})
 }
 
-// LoadMiniCommits loads all mini-commits
+// ReplaceMiniCommits replaces the mini-commits ids, which must follow each other
+// in the stack, with replacement at their position, e.g. to squash or split
+// them. Every replacement carries its patch. The replaced mini-commits go to
+// the trash and are logged as an operation of the given kind.
+func (s *Storage) ReplaceMiniCommits(kind string, ids []string, replacement types.MiniCommitList) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	unlock, err := s.lockStore()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	index, err := s.loadIndex()
+	if err != nil {
+		return err
+	}
+	if len(ids) == 0 {
+		return fmt.Errorf("no mini-commit to replace")
+	}
+
+	start := -1
+	for i := range index {
+		if index[i].ID == ids[0] {
+			start = i
+			break
+		}
+	}
+	if start < 0 {
+		return &NotFoundError{ID: ids[0]}
+	}
+	for i, id := range ids {
+		if start+i >= len(index) || index[start+i].ID != id {
+			return fmt.Errorf("cannot replace mini-commit '%s': the mini-commits do not follow each other in the stack", id)
+		}
+	}
+
+	// The previous versions are logged so that the operation can be undone
+	removed := make(types.MiniCommitList, len(ids))
+	copy(removed, index[start:start+len(ids)])
+
+	newIndex := make(types.MiniCommitList, 0, len(index)-len(ids)+len(replacement))
+	newIndex = append(newIndex, index[:start]...)
+	newIndex = append(newIndex, replacement...)
+	newIndex = append(newIndex, index[start+len(ids):]...)
+	if err := s.saveIndex(newIndex); err != nil {
+		return fmt.Errorf("failed to save index: %w", err)
+	}
+
+	summary := describe(&removed[0])
+	if len(removed) > 1 {
+		summary += fmt.Sprintf(" and %d more", len(removed)-1)
+	}
+	op := Operation{Kind: kind, Summary: summary, Before: stackIDs(index), After: stackIDs(newIndex),
+		Removed: logged(removed)}
+	if err := s.appendOperation(op); err != nil {
+		return err
+	}
+
+	return s.moveToTrash(removed, kind)
+}
+
+// LoadMiniCommits loads all mini-commits from the index. Their Patch is empty;
+// use LoadPatch for the ones whose patch is needed.
 func (s *Storage) LoadMiniCommits() (types.MiniCommitList, error) {
 	s.mutex.RLock()
 	defer s.mutex.RUnlock()
 	return s.loadIndex()
 }
 
-// GetMiniCommit gets a mini-commit by ID
+// LoadPatch reads the patch of mc from its object into mc.Patch
+func (s *Storage) LoadPatch(mc *types.MiniCommit) error {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	return s.loadPatch(mc)
+}
+
+// OpenPatch opens the patch of mc for streaming, e.g. into git apply, so that
+// it need not fit in memory. The object is checked before it is opened, so
+// that a damaged patch is reported before anything is read from it.
+func (s *Storage) OpenPatch(mc *types.MiniCommit) (io.ReadCloser, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	if mc.PatchHash == "" {
+		return nil, patchError(mc, nil)
+	}
+	if err := s.verifyObject(mc.PatchHash); err != nil {
+		return nil, patchError(mc, err)
+	}
+	r, err := s.openObject(mc.PatchHash)
+	if err != nil {
+		return nil, patchError(mc, err)
+	}
+	return r, nil
+}
+
+// GetMiniCommit gets a mini-commit by ID or unique prefix, patch included
 func (s *Storage) GetMiniCommit(id string) (*types.MiniCommit, error) {
 	s.mutex.RLock()
 	defer s.mutex.RUnlock()
 
-	index, err := s.loadIndex()
+	mc, err := s.findMiniCommit(id)
 	if err != nil {
 		return nil, err
 	}
+	if err := s.loadPatch(mc); err != nil {
+		return nil, err
+	}
+	return mc, nil
+}
 
-	for _, mc := range index {
-		if mc.ID == id {
-			return &mc, nil
-		}
+// FindMiniCommit gets a mini-commit by ID or unique prefix without its patch, for callers that
+// stream it with OpenPatch or do not need it
+func (s *Storage) FindMiniCommit(id string) (*types.MiniCommit, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	return s.findMiniCommit(id)
+}
+
+func (s *Storage) findMiniCommit(id string) (*types.MiniCommit, error) {
+	index, err := s.loadIndex()
+	if err != nil {
+		return nil, err
 	}
 
-	return nil, fmt.Errorf("mini-commit '%s' not found", id)
+	i, err := MatchID(stackIDs(index), id)
+	if err != nil {
+		return nil, err
+	}
+	return &index[i], nil
 }
 
-// DeleteMiniCommit deletes a mini-commit by ID
+// DeleteMiniCommit removes a mini-commit by ID, moving it to the trash
 func (s *Storage) DeleteMiniCommit(id string) error {
 	s.mutex.Lock()
 	defer s.mutex.Unlock()
+	unlock, err := s.lockStore()
+	if err != nil {
+		return err
+	}
+	defer unlock()
 
 	index, err := s.loadIndex()
 	if err != nil {
@@ -109,54 +306,102 @@ func (s *Storage) DeleteMiniCommit(id string) error {
 
 	// Remove from index
 	var newIndex types.MiniCommitList
-	found := false
-	for _, mc := range index {
+	var removed *types.MiniCommit
+	for i, mc := range index {
 		if mc.ID != id {
 			newIndex = append(newIndex, mc)
 		} else {
-			found = true
+			removed = &index[i]
 		}
 	}
 
-	if !found {
-		return fmt.Errorf("mini-commit '%s' not found", id)
+	if removed == nil {
+		return &NotFoundError{ID: id}
 	}
 
 	// Save index
 	if err := s.saveIndex(newIndex); err != nil {
-		return fmt.Errorf("failed to save index: %v", err)
+		return fmt.Errorf("failed to save index: %w", err)
 	}
 
-	// Delete patch file
-	patchPath := filepath.Join(s.basePath, id+".patch")
-	if err := os.Remove(patchPath); err != nil && !os.IsNotExist(err) {
-		return fmt.Errorf("failed to delete patch file: %v", err)
+	// Record the removed mini-commit so that it survives the trash
+	op := Operation{Kind: OpDrop, Summary: describe(removed), Before: stackIDs(index), After: stackIDs(newIndex),
+		Removed: logged([]types.MiniCommit{*removed})}
+	if err := s.appendOperation(op); err != nil {
+		return err
 	}
 
-	return nil
+	// Move it to the trash
+	return s.moveToTrash([]types.MiniCommit{*removed}, OpDrop)
 }
 
-// ClearAllMiniCommits deletes all mini-commits
+// ClearAllMiniCommits removes all mini-commits, moving them to the trash
 func (s *Storage) ClearAllMiniCommits() error {
+	_, err := s.clearMiniCommits(func(*types.MiniCommit) bool { return true })
+	return err
+}
+
+// ClearMiniCommits removes the mini-commits with the given IDs, moving them to
+// the trash, and returns them. IDs no longer in the stack are skipped, and
+// mini-commits created meanwhile are kept, so only those the caller chose, for
+// example after confirmation, are removed.
+func (s *Storage) ClearMiniCommits(ids []string) (types.MiniCommitList, error) {
+	chosen := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		chosen[id] = true
+	}
+	return s.clearMiniCommits(func(mc *types.MiniCommit) bool { return chosen[mc.ID] })
+}
+
+// clearMiniCommits removes the mini-commits for which match returns true, given
+// them without their patch
+func (s *Storage) clearMiniCommits(match func(mc *types.MiniCommit) bool) (types.MiniCommitList, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	unlock, err := s.lockStore()
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
 	index, err := s.loadIndex()
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	// Delete all patch files
-	for _, mc := range index {
-		patchPath := filepath.Join(s.basePath, mc.ID+".patch")
-		if err := os.Remove(patchPath); err != nil && !os.IsNotExist(err) {
-			return fmt.Errorf("failed to delete patch file '%s': %v", patchPath, err)
+	kept := types.MiniCommitList{}
+	var removed types.MiniCommitList
+	for i := range index {
+		if match(&index[i]) {
+			removed = append(removed, index[i])
+		} else {
+			kept = append(kept, index[i])
 		}
 	}
 
-	// Save empty index
-	if err := s.saveIndex(types.MiniCommitList{}); err != nil {
-		return fmt.Errorf("failed to save index: %v", err)
+	// Leave the index as it is when nothing matches
+	if len(removed) == 0 {
+		return nil, nil
 	}
 
-	return nil
+	// Save the remaining index
+	if err := s.saveIndex(kept); err != nil {
+		return nil, fmt.Errorf("failed to save index: %w", err)
+	}
+
+	// Record the removed mini-commits so that they survive the trash
+	op := Operation{Kind: OpClear, Summary: fmt.Sprintf("%d mini-commit(s)", len(removed)), Before: stackIDs(index),
+		After: stackIDs(kept), Removed: logged(removed)}
+	if err := s.appendOperation(op); err != nil {
+		return nil, err
+	}
+
+	// Move them to the trash
+	if err := s.moveToTrash(removed, OpClear); err != nil {
+		return nil, err
+	}
+
+	return removed, nil
 }
 
 // loadIndex loads the index file
@@ -170,35 +415,78 @@ func (s *Storage) loadIndex() (types.MiniCommitList, error) {
 
 	data, err := os.ReadFile(indexPath)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read index file: %v", err)
+		return nil, fmt.Errorf("failed to read index file: %w", err)
 	}
 
-	var index types.MiniCommitList
-	if err := json.Unmarshal(data, &index); err != nil {
-		return nil, fmt.Errorf("failed to parse index: %v", err)
+	index, err := decodeIndex(data)
+	if errors.Is(err, ErrNewerFormat) {
+		return nil, err
+	}
+	if err != nil {
+		return nil, corruption{fmt.Errorf("failed to parse index: %w", err)}
 	}
 
-	return index, nil
+	return index.MiniCommits, nil
 }
 
-// saveIndex saves the index file
+// saveIndex saves the index file in the current format. Patches are stored as
+// objects first, so only the metadata, stats and object of each mini-commit are
+// written to the index.
 func (s *Storage) saveIndex(index types.MiniCommitList) error {
-	indexPath := filepath.Join(s.basePath, IndexFile)
+	stored, err := s.storePatches(index)
+	if err != nil {
+		return err
+	}
+	return s.writeIndex(&indexFile{FormatVersion: FormatVersion, CreatedBy: s.createdBy, MiniCommits: stored})
+}
 
-	data, err := json.MarshalIndent(index, "", "  ")
+// loadPatch reads the patch object of mc into mc.Patch
+func (s *Storage) loadPatch(mc *types.MiniCommit) error {
+	if mc.PatchHash == "" {
+		return patchError(mc, nil)
+	}
+	content, err := s.readObject(mc.PatchHash)
 	if err != nil {
-		return fmt.Errorf("failed to serialize index: %v", err)
+		return patchError(mc, err)
 	}
+	mc.Patch = content
+	return nil
+}
 
-	if err := os.WriteFile(indexPath, data, 0644); err != nil {
-		return fmt.Errorf("failed to save index file: %v", err)
+// patchError describes why the patch of mc cannot be read; a nil err means it has no object
+func patchError(mc *types.MiniCommit, err error) error {
+	switch {
+	case err == nil:
+		return corruption{fmt.Errorf("mini-commit '%s' has no patch object", mc.ID)}
+	case os.IsNotExist(err):
+		return corruption{fmt.Errorf("patch object of mini-commit '%s' is missing", mc.ID)}
+	default:
+		return fmt.Errorf("failed to read patch of mini-commit '%s': %w", mc.ID, err)
 	}
+}
 
-	return nil
+// withoutPatches returns a copy of index without patches. The stats of entries
+// that carry a patch are computed from it; the others keep their recorded stats.
+func withoutPatches(index types.MiniCommitList) types.MiniCommitList {
+	stripped := make(types.MiniCommitList, len(index))
+	for i, mc := range index {
+		if mc.Patch != "" {
+			stats := patch.ComputeStats(mc.Patch)
+			mc.Stats = &stats
+		}
+		mc.Patch = ""
+		stripped[i] = mc
+	}
+	return stripped
 }
 
 // GenerateID generates ID from patch content and timestamp
 func (s *Storage) GenerateID(patch string, timestamp time.Time) string {
+	return generateID(patch, timestamp)
+}
+
+// generateID is GenerateID, which needs no store
+func generateID(patch string, timestamp time.Time) string {
 	h := sha1.New()
 	_, _ = io.WriteString(h, patch)
 	_, _ = io.WriteString(h, timestamp.Format(time.RFC3339Nano))
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
@@ -89,10 +90,9 @@ func TestSaveMiniCommit(t *testing.T) {
 		t.Errorf("Expected index file to exist")
 	}
 
-	// patchファイルが作成されているかチェック
-	patchPath := filepath.Join(storage.basePath, mc.ID+".patch")
-	if _, err := os.Stat(patchPath); os.IsNotExist(err) {
-		t.Errorf("Expected patch file to exist")
+	// パッチが圧縮されたオブジェクトとして保存されているかチェック
+	if _, err := os.Stat(storage.objectPath(patchHash(patch))); os.IsNotExist(err) {
+		t.Errorf("Expected patch object to exist")
 	}
 }
 
@@ -258,10 +258,9 @@ func TestDeleteMiniCommit(t *testing.T) {
 		t.Errorf("Expected error for deleted mini-commit")
 	}
 
-	// patchファイルが削除されているかチェック
-	patchPath := filepath.Join(storage.basePath, mc.ID+".patch")
-	if _, err := os.Stat(patchPath); !os.IsNotExist(err) {
-		t.Errorf("Expected patch file to be deleted")
+	// パッチオブジェクトはゴミ箱から参照されるため残る
+	if _, err := os.Stat(storage.objectPath(patchHash(patch))); err != nil {
+		t.Errorf("Expected the patch object to be kept for the trash: %v", err)
 	}
 }
 
@@ -331,15 +330,10 @@ func TestClearAllMiniCommits(t *testing.T) {
 		t.Errorf("Expected 0 mini-commits, but got %d", len(miniCommits))
 	}
 
-	// patchファイルが削除されているかチェック
-	patch1Path := filepath.Join(storage.basePath, mc1.ID+".patch")
-	patch2Path := filepath.Join(storage.basePath, mc2.ID+".patch")
-
-	if _, err := os.Stat(patch1Path); !os.IsNotExist(err) {
-		t.Errorf("Expected patch file 1 to be deleted")
-	}
-	if _, err := os.Stat(patch2Path); !os.IsNotExist(err) {
-		t.Errorf("Expected patch file 2 to be deleted")
+	// すべてゴミ箱に移動されているかチェック
+	trash, err := storage.TrashedMiniCommits()
+	if err != nil || len(trash) != 2 {
+		t.Errorf("Expected 2 mini-commits in the trash, but got %d (%v)", len(trash), err)
 	}
 }
 
@@ -482,3 +476,37 @@ func TestStorageConcurrency(t *testing.T) {
 		t.Errorf("Expected at least 5 mini-commits, but got %d", len(miniCommits))
 	}
 }
+
+func TestIndexWithoutPatches(t *testing.T) {
+	repo := testutils.NewTestGitRepo(t)
+	defer repo.Cleanup()
+
+	storage, err := NewStorage()
+	if err != nil {
+		t.Fatalf("NewStorage() error = %v", err)
+	}
+	mc := newTestMiniCommit(t, storage, "Lazy", testPatch("a.txt", "unique line"))
+
+	// index.json にはパッチ本体を保存しない
+	data, err := os.ReadFile(filepath.Join(storage.basePath, IndexFile))
+	if err != nil {
+		t.Fatalf("Failed to read index: %v", err)
+	}
+	if strings.Contains(string(data), "unique line") {
+		t.Errorf("Expected the index to hold no patch, got %s", data)
+	}
+
+	// 一覧はメタデータと統計のみ
+	list, err := storage.LoadMiniCommits()
+	if err != nil {
+		t.Fatalf("LoadMiniCommits() error = %v", err)
+	}
+	if list[0].Patch != "" || list[0].Stats == nil || list[0].Stats.Files != 1 || list[0].Stats.Insertions != 1 {
+		t.Errorf("Expected metadata with stats only, got %+v", list[0])
+	}
+
+	// パッチは必要な時に読み込む
+	if err := storage.LoadPatch(&list[0]); err != nil || list[0].Patch != mc.Patch {
+		t.Errorf("LoadPatch() = %q, %v", list[0].Patch, err)
+	}
+}
@@ -0,0 +1,240 @@
+package storage
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"git-mini-commit/internal/types"
+)
+
+// TrashDir holds removed mini-commits until they are purged, with its own index.json
+const TrashDir = "trash"
+
+// OpRestore is logged when a mini-commit is restored from the trash
+const OpRestore = "restore"
+
+// TrashEntry is a removed mini-commit with when and why it was removed.
+// Reason is the kind of operation that removed it (drop, clear or undo).
+type TrashEntry struct {
+	types.MiniCommit
+	DeletedAt time.Time `json:"deletedAt"`
+	Reason    string    `json:"reason"`
+}
+
+// TrashedMiniCommits returns the mini-commits in the trash, oldest removal first.
+// Like LoadMiniCommits, it leaves their Patch empty.
+func (s *Storage) TrashedMiniCommits() ([]TrashEntry, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	return s.loadTrash()
+}
+
+// RestoreMiniCommit moves a mini-commit, given by ID or unique prefix, from the
+// trash back into the stack, at its position by creation time
+func (s *Storage) RestoreMiniCommit(id string) (*types.MiniCommit, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	unlock, err := s.lockStore()
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	trash, err := s.loadTrash()
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]string, len(trash))
+	for i := range trash {
+		ids[i] = trash[i].ID
+	}
+	match, err := MatchID(ids, id)
+	if err != nil {
+		return nil, err
+	}
+	mc := trash[match].MiniCommit
+	id = mc.ID
+	if err := s.loadPatch(&mc); err != nil {
+		return nil, err
+	}
+
+	index, err := s.loadIndex()
+	if err != nil {
+		return nil, err
+	}
+	position := len(index)
+	for i := range index {
+		if index[i].ID == id {
+			return nil, fmt.Errorf("mini-commit '%s' is already in the stack", id)
+		}
+		if position == len(index) && index[i].CreatedAt.After(mc.CreatedAt) {
+			position = i
+		}
+	}
+
+	newIndex := make(types.MiniCommitList, 0, len(index)+1)
+	newIndex = append(newIndex, index[:position]...)
+	newIndex = append(newIndex, mc)
+	newIndex = append(newIndex, index[position:]...)
+
+	if err := s.saveIndex(newIndex); err != nil {
+		return nil, fmt.Errorf("failed to save index: %w", err)
+	}
+	if err := s.removeFromTrash(map[string]bool{id: true}); err != nil {
+		return nil, err
+	}
+
+	op := Operation{Kind: OpRestore, Summary: describe(&mc), Before: stackIDs(index), After: stackIDs(newIndex)}
+	if err := s.appendOperation(op); err != nil {
+		return nil, err
+	}
+	return &mc, nil
+}
+
+// PurgeTrash permanently removes the trashed mini-commits removed at or before
+// cutoff and returns them. Their versions are removed from the operation log as
+// well, so that undo cannot bring them back; the patch objects no longer
+// referred to are deleted by PruneObjects.
+func (s *Storage) PurgeTrash(cutoff time.Time) ([]TrashEntry, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	unlock, err := s.lockStore()
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	trash, err := s.loadTrash()
+	if err != nil {
+		return nil, err
+	}
+
+	var kept, purged []TrashEntry
+	for _, entry := range trash {
+		if entry.DeletedAt.After(cutoff) {
+			kept = append(kept, entry)
+		} else {
+			purged = append(purged, entry)
+		}
+	}
+	if len(purged) == 0 {
+		return nil, nil
+	}
+
+	if err := s.saveTrash(kept); err != nil {
+		return nil, err
+	}
+
+	// A mini-commit is only in the trash while it is out of the stack, but an
+	// ID back in the stack keeps its history all the same
+	index, err := s.loadIndex()
+	if err != nil {
+		return nil, err
+	}
+	live := idSet(index)
+	forgotten := make(map[string]bool, len(purged))
+	for _, entry := range purged {
+		if !live[entry.ID] {
+			forgotten[entry.ID] = true
+		}
+	}
+	if err := s.forgetOperations(forgotten); err != nil {
+		return nil, err
+	}
+	return purged, nil
+}
+
+// moveToTrash records removed mini-commits in the trash, which keeps their patch
+// objects referenced. A mini-commit trashed again replaces its older entry.
+func (s *Storage) moveToTrash(removed []types.MiniCommit, reason string) error {
+	if len(removed) == 0 {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Join(s.basePath, TrashDir), 0755); err != nil {
+		return fmt.Errorf("failed to create trash directory: %w", err)
+	}
+
+	trash, err := s.loadTrash()
+	if err != nil {
+		return err
+	}
+	ids := idSet(removed)
+	kept := trash[:0]
+	for _, entry := range trash {
+		if !ids[entry.ID] {
+			kept = append(kept, entry)
+		}
+	}
+
+	now := time.Now()
+	for _, mc := range removed {
+		kept = append(kept, TrashEntry{MiniCommit: mc, DeletedAt: now, Reason: reason})
+	}
+	return s.saveTrash(kept)
+}
+
+// removeFromTrash drops the given IDs from the trash, e.g. once they are back in the stack
+func (s *Storage) removeFromTrash(ids map[string]bool) error {
+	trash, err := s.loadTrash()
+	if err != nil {
+		return err
+	}
+
+	kept := trash[:0]
+	for _, entry := range trash {
+		if !ids[entry.ID] {
+			kept = append(kept, entry)
+		}
+	}
+	if len(kept) == len(trash) {
+		return nil
+	}
+	return s.saveTrash(kept)
+}
+
+// loadTrash loads the trash index; a missing trash is empty
+func (s *Storage) loadTrash() ([]TrashEntry, error) {
+	data, err := os.ReadFile(filepath.Join(s.basePath, TrashDir, IndexFile))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read trash index: %w", err)
+	}
+
+	var trash []TrashEntry
+	if err := json.Unmarshal(data, &trash); err != nil {
+		return nil, corruption{fmt.Errorf("failed to parse trash index: %w", err)}
+	}
+	return trash, nil
+}
+
+// saveTrash saves the trash index; like index.json, it refers to patch objects
+// instead of holding the patches
+func (s *Storage) saveTrash(trash []TrashEntry) error {
+	stored := make([]TrashEntry, len(trash))
+	for i, entry := range trash {
+		list, err := s.storePatches(types.MiniCommitList{entry.MiniCommit})
+		if err != nil {
+			return err
+		}
+		entry.MiniCommit = list[0]
+		stored[i] = entry
+	}
+	return s.writeTrash(stored)
+}
+
+// writeTrash writes the trash index as given
+func (s *Storage) writeTrash(trash []TrashEntry) error {
+	data, err := json.MarshalIndent(trash, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to serialize trash index: %w", err)
+	}
+	if err := writeFileAtomic(filepath.Join(s.basePath, TrashDir, IndexFile), data); err != nil {
+		return fmt.Errorf("failed to save trash index: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,105 @@
+package storage
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"git-mini-commit/testutils"
+)
+
+func TestTrash(t *testing.T) {
+	repo := testutils.NewTestGitRepo(t)
+	defer repo.Cleanup()
+
+	storage, err := NewStorage()
+	if err != nil {
+		t.Fatalf("NewStorage() error = %v", err)
+	}
+
+	mc1 := newTestMiniCommit(t, storage, "First", "patch 1\n")
+	mc2 := newTestMiniCommit(t, storage, "Second", "patch 2\n")
+	newTestMiniCommit(t, storage, "Third", "patch 3\n")
+
+	// 1. drop はゴミ箱に移動する
+	if err := storage.DeleteMiniCommit(mc1.ID); err != nil {
+		t.Fatalf("DeleteMiniCommit() error = %v", err)
+	}
+	trash, err := storage.TrashedMiniCommits()
+	if err != nil {
+		t.Fatalf("TrashedMiniCommits() error = %v", err)
+	}
+	if len(trash) != 1 || trash[0].ID != mc1.ID || trash[0].Reason != OpDrop || trash[0].DeletedAt.IsZero() {
+		t.Fatalf("Unexpected trash: %+v", trash)
+	}
+	if _, err := os.Stat(storage.objectPath(trash[0].PatchHash)); err != nil {
+		t.Errorf("Expected the trash to keep the patch object: %v", err)
+	}
+
+	// 2. 復元すると作成日時の位置に戻る
+	if _, err := storage.RestoreMiniCommit(mc1.ID); err != nil {
+		t.Fatalf("RestoreMiniCommit() error = %v", err)
+	}
+	assertStack(t, storage, "First", "Second", "Third")
+	if trash, _ := storage.TrashedMiniCommits(); len(trash) != 0 {
+		t.Errorf("Expected the trash to be empty, but got %d entries", len(trash))
+	}
+	if _, err := storage.RestoreMiniCommit(mc1.ID); err == nil {
+		t.Errorf("Expected an error when restoring a mini-commit that is not in the trash")
+	}
+
+	// 3. clear はすべてゴミ箱へ、undo で戻すとゴミ箱から消える
+	if err := storage.ClearAllMiniCommits(); err != nil {
+		t.Fatalf("ClearAllMiniCommits() error = %v", err)
+	}
+	trash, _ = storage.TrashedMiniCommits()
+	if len(trash) != 3 || trash[0].Reason != OpClear {
+		t.Fatalf("Expected 3 cleared entries, but got %+v", trash)
+	}
+	if _, err := storage.Undo(1); err != nil {
+		t.Fatalf("Undo() error = %v", err)
+	}
+	assertStack(t, storage, "First", "Second", "Third")
+	if trash, _ := storage.TrashedMiniCommits(); len(trash) != 0 {
+		t.Errorf("Expected undo to take the mini-commits out of the trash, but got %d entries", len(trash))
+	}
+
+	// 4. 保持期間より古いものだけ削除
+	if err := storage.DeleteMiniCommit(mc2.ID); err != nil {
+		t.Fatalf("DeleteMiniCommit() error = %v", err)
+	}
+	purged, err := storage.PurgeTrash(time.Now().Add(-time.Hour))
+	if err != nil || len(purged) != 0 {
+		t.Fatalf("Expected nothing to be purged, got %d (%v)", len(purged), err)
+	}
+	purged, err = storage.PurgeTrash(time.Now())
+	if err != nil || len(purged) != 1 || purged[0].ID != mc2.ID {
+		t.Fatalf("Expected the dropped mini-commit to be purged, got %+v (%v)", purged, err)
+	}
+
+	// 削除したものは操作ログからも消え、undo で戻せない
+	ops, _ := storage.Operations()
+	for _, op := range ops {
+		for _, mc := range op.Removed {
+			if mc.ID == mc2.ID {
+				t.Errorf("Expected the purged mini-commit to be removed from the %s operation", op.Kind)
+			}
+		}
+	}
+	if _, err := storage.Undo(1); err == nil {
+		t.Errorf("Expected undo to be unable to bring back a purged mini-commit")
+	}
+
+	// 5. 参照されなくなったパッチオブジェクトは PruneObjects で削除される
+	result, err := storage.PruneObjects()
+	if err != nil || result.Objects != 1 || result.Bytes == 0 {
+		t.Fatalf("Expected the purged patch object to be pruned, got %+v (%v)", result, err)
+	}
+	if _, err := os.Stat(storage.objectPath(patchHash(mc2.Patch))); !os.IsNotExist(err) {
+		t.Errorf("Expected the patch object to be removed")
+	}
+	assertStack(t, storage, "First", "Third")
+	if result, _ := storage.PruneObjects(); result.Objects != 0 {
+		t.Errorf("Expected nothing left to prune, got %+v", result)
+	}
+}
@@ -0,0 +1,318 @@
+package summary
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	"git-mini-commit/internal/lint"
+	"git-mini-commit/internal/patch"
+)
+
+// Ways of grouping the mini-commits in the body
+const (
+	GroupByType      = "type"
+	GroupByDirectory = "directory"
+)
+
+// RootDirectory is the group of the changes to files at the top of the repository
+const RootDirectory = "."
+
+// typeTitles are the headings of the conventional commit types
+var typeTitles = map[string]string{
+	"feat":     "Features",
+	"fix":      "Bug Fixes",
+	"docs":     "Documentation",
+	"style":    "Styles",
+	"refactor": "Code Refactoring",
+	"perf":     "Performance Improvements",
+	"test":     "Tests",
+	"build":    "Build System",
+	"ci":       "Continuous Integration",
+	"chore":    "Chores",
+	"revert":   "Reverts",
+}
+
+// otherTitle is the heading of the messages that are not conventional commits
+const otherTitle = "Other Changes"
+
+// Entry is a mini-commit to summarize. Files are only needed to group by directory.
+type Entry struct {
+	Message string
+	Files   []*patch.File
+}
+
+// Options configure the generated message
+type Options struct {
+	// GroupBy is GroupByType or GroupByDirectory
+	GroupBy string
+	// Depth is how many leading path components name a directory; 0 uses the whole directory
+	Depth int
+	// Types orders the type groups; types not listed follow in the order they appear
+	Types []string
+	// SubjectMaxLength limits the subject joined from the descriptions; 0 allows any length
+	SubjectMaxLength int
+}
+
+// Group is a heading of the body and the mini-commits listed under it
+type Group struct {
+	Key     string
+	Title   string
+	Entries []int
+	Items   []string
+}
+
+// Message is a generated commit message
+type Message struct {
+	Subject string
+	Body    string
+	Groups  []Group
+}
+
+func (m Message) String() string {
+	if m.Body == "" {
+		return m.Subject
+	}
+	return m.Subject + "\n\n" + m.Body
+}
+
+// item is one entry as it is grouped and listed
+type item struct {
+	key    string
+	text   string
+	header lint.Header
+	ok     bool
+}
+
+// Generate summarizes the entries, oldest first, as a subject line and a body
+// listing them under one heading per type or directory. A single entry keeps
+// its own message.
+func Generate(entries []Entry, opts Options) Message {
+	if len(entries) == 0 {
+		return Message{}
+	}
+
+	items := make([]item, len(entries))
+	for i, e := range entries {
+		line, _, _ := strings.Cut(e.Message, "\n")
+		it := item{text: line}
+		it.header, it.ok = lint.ParseHeader(line)
+		if opts.GroupBy == GroupByDirectory {
+			it.key = mainDirectory(e.Files, opts.Depth)
+		} else if it.ok {
+			it.key = strings.ToLower(it.header.Type)
+			it.text = it.header.Description
+			if it.header.Scope != "" {
+				it.text = it.header.Scope + ": " + it.text
+			}
+		}
+		items[i] = it
+	}
+
+	groups := groupItems(items, opts)
+	var body strings.Builder
+	for i, g := range groups {
+		if i > 0 {
+			body.WriteString("\n")
+		}
+		body.WriteString(g.Title + ":\n")
+		for _, text := range g.Items {
+			body.WriteString("- " + text + "\n")
+		}
+	}
+
+	if len(entries) == 1 {
+		message := strings.TrimRight(entries[0].Message, "\n")
+		subject, rest, _ := strings.Cut(message, "\n")
+		return Message{Subject: subject, Body: strings.TrimLeft(rest, "\n"), Groups: groups}
+	}
+	return Message{Subject: subjectLine(items, groups, opts), Body: strings.TrimSuffix(body.String(), "\n"), Groups: groups}
+}
+
+// groupItems collects the items under their headings: the configured types
+// first, then other types and directories in the order they appear
+func groupItems(items []item, opts Options) []Group {
+	var order []string
+	seen := map[string]bool{}
+	add := func(key string) {
+		if !seen[key] {
+			seen[key] = true
+			order = append(order, key)
+		}
+	}
+
+	present := map[string]bool{}
+	for _, it := range items {
+		present[it.key] = true
+	}
+	if opts.GroupBy != GroupByDirectory {
+		for _, t := range opts.Types {
+			if present[t] {
+				add(t)
+			}
+		}
+	}
+	for _, it := range items {
+		if it.key != "" {
+			add(it.key)
+		}
+	}
+	if present[""] {
+		add("")
+	}
+
+	groups := make([]Group, 0, len(order))
+	for _, key := range order {
+		g := Group{Key: key, Title: title(key, opts.GroupBy)}
+		for i, it := range items {
+			if it.key == key {
+				g.Entries = append(g.Entries, i)
+				g.Items = append(g.Items, it.text)
+			}
+		}
+		groups = append(groups, g)
+	}
+	return groups
+}
+
+// title returns the heading of a group
+func title(key, groupBy string) string {
+	switch {
+	case groupBy == GroupByDirectory:
+		return key
+	case key == "":
+		return otherTitle
+	case typeTitles[key] != "":
+		return typeTitles[key]
+	}
+	return key
+}
+
+// subjectLine joins the items in the order of the body when they fit, and
+// otherwise counts the changes. Grouped by type, it takes the type of the first
+// group, the scope all the messages share and "!" when one of them is a
+// breaking change.
+func subjectLine(items []item, groups []Group, opts Options) string {
+	var ordered []int
+	for _, g := range groups {
+		ordered = append(ordered, g.Entries...)
+	}
+
+	prefix := ""
+	if opts.GroupBy != GroupByDirectory && groups[0].Key != "" {
+		scope := commonScope(items)
+		prefix = groups[0].Key
+		if scope != "" {
+			prefix += "(" + scope + ")"
+		}
+		for _, it := range items {
+			if it.ok && it.header.Breaking {
+				prefix += "!"
+				break
+			}
+		}
+		prefix += ": "
+
+		descriptions := make([]string, 0, len(items))
+		for _, i := range ordered {
+			if scope != "" {
+				descriptions = append(descriptions, items[i].header.Description)
+			} else {
+				descriptions = append(descriptions, items[i].text)
+			}
+		}
+		if subject := prefix + strings.Join(descriptions, ", "); fits(subject, opts) {
+			return subject
+		}
+
+		counts := make([]string, 0, len(groups))
+		for _, g := range groups {
+			key := g.Key
+			if key == "" {
+				key = "other"
+			}
+			counts = append(counts, fmt.Sprintf("%d %s", len(g.Entries), key))
+		}
+		subject := fmt.Sprintf("%s%d changes", prefix, len(items))
+		if len(groups) > 1 {
+			subject += " (" + strings.Join(counts, ", ") + ")"
+		}
+		return subject
+	}
+
+	subjects := make([]string, 0, len(items))
+	for _, i := range ordered {
+		subjects = append(subjects, items[i].text)
+	}
+	if subject := strings.Join(subjects, ", "); fits(subject, opts) {
+		return subject
+	}
+	if opts.GroupBy == GroupByDirectory {
+		if len(groups) <= 3 {
+			dirs := make([]string, 0, len(groups))
+			for _, g := range groups {
+				dirs = append(dirs, g.Key)
+			}
+			return "Update " + joinWords(dirs)
+		}
+		return fmt.Sprintf("Update %d directories", len(groups))
+	}
+	return fmt.Sprintf("Integrate %d mini-commits", len(items))
+}
+
+// commonScope returns the scope of the messages when they are all conventional commits of the same scope
+func commonScope(items []item) string {
+	scope := ""
+	for _, it := range items {
+		if !it.ok || it.header.Scope == "" || (scope != "" && it.header.Scope != scope) {
+			return ""
+		}
+		scope = it.header.Scope
+	}
+	return scope
+}
+
+func fits(subject string, opts Options) bool {
+	return opts.SubjectMaxLength == 0 || len([]rune(subject)) <= opts.SubjectMaxLength
+}
+
+// joinWords joins words as "a, b and c"
+func joinWords(words []string) string {
+	if len(words) == 1 {
+		return words[0]
+	}
+	return strings.Join(words[:len(words)-1], ", ") + " and " + words[len(words)-1]
+}
+
+// mainDirectory returns the directory, cut to depth components, with the most
+// changed lines; ties go to the directory listed first in the patch
+func mainDirectory(files []*patch.File, depth int) string {
+	best, bestLines := RootDirectory, -1
+	lines := map[string]int{}
+	var order []string
+	for _, f := range files {
+		dir := directory(f.Path(), depth)
+		if _, ok := lines[dir]; !ok {
+			order = append(order, dir)
+		}
+		lines[dir] += f.Insertions + f.Deletions
+	}
+	for _, dir := range order {
+		if lines[dir] > bestLines {
+			best, bestLines = dir, lines[dir]
+		}
+	}
+	return best
+}
+
+// directory returns the directory of a file, cut to depth components
+func directory(file string, depth int) string {
+	dir := path.Dir(file)
+	if dir == "." {
+		return RootDirectory
+	}
+	if parts := strings.Split(dir, "/"); depth > 0 && len(parts) > depth {
+		dir = strings.Join(parts[:depth], "/")
+	}
+	return dir
+}
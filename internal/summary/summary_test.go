@@ -0,0 +1,115 @@
+package summary
+
+import (
+	"testing"
+
+	"git-mini-commit/internal/lint"
+	"git-mini-commit/internal/patch"
+)
+
+// entry テスト用のエントリ。各ファイルは1行追加として扱う
+func entry(message string, files ...string) Entry {
+	e := Entry{Message: message}
+	for _, f := range files {
+		e.Files = append(e.Files, &patch.File{NewPath: f, OldPath: f, Status: patch.StatusModified, Insertions: 1})
+	}
+	return e
+}
+
+func TestGenerateByType(t *testing.T) {
+	opts := Options{GroupBy: GroupByType, Types: lint.DefaultTypes, SubjectMaxLength: 72}
+	entries := []Entry{
+		entry("fix(parser): handle empty hunks"),
+		entry("Update README"),
+		entry("feat(cli): add the message command\n\nBody"),
+		entry("feat: group by directory"),
+	}
+
+	got := Generate(entries, opts)
+	want := "feat: 4 changes (2 feat, 1 fix, 1 other)\n\n" +
+		"Features:\n- cli: add the message command\n- group by directory\n\n" +
+		"Bug Fixes:\n- parser: handle empty hunks\n\n" +
+		"Other Changes:\n- Update README"
+	if got.String() != want {
+		t.Errorf("Generate() =\n%s\nwant\n%s", got, want)
+	}
+	if len(got.Groups) != 3 || got.Groups[0].Entries[0] != 2 || got.Groups[2].Key != "" {
+		t.Errorf("Unexpected groups: %+v", got.Groups)
+	}
+}
+
+func TestGenerateSubject(t *testing.T) {
+	tests := []struct {
+		name    string
+		opts    Options
+		entries []Entry
+		want    string
+	}{
+		{
+			name:    "descriptions that fit are joined",
+			opts:    Options{Types: lint.DefaultTypes, SubjectMaxLength: 72},
+			entries: []Entry{entry("fix(parser): a"), entry("fix(parser)!: b")},
+			want:    "fix(parser)!: a, b",
+		},
+		{
+			name:    "one type is counted without a breakdown",
+			opts:    Options{Types: lint.DefaultTypes, SubjectMaxLength: 8},
+			entries: []Entry{entry("fix: a"), entry("fix: b")},
+			want:    "fix: 2 changes",
+		},
+		{
+			name:    "configured types come first",
+			opts:    Options{Types: []string{"docs", "fix"}},
+			entries: []Entry{entry("fix: a"), entry("docs: b")},
+			want:    "docs: b, a",
+		},
+		{
+			name:    "not conventional",
+			opts:    Options{SubjectMaxLength: 10},
+			entries: []Entry{entry("Add a"), entry("Add b")},
+			want:    "Integrate 2 mini-commits",
+		},
+		{
+			name:    "directories",
+			opts:    Options{GroupBy: GroupByDirectory, Depth: 1, SubjectMaxLength: 10},
+			entries: []Entry{entry("Add a", "cmd/a.go"), entry("Add b", "internal/lint/b.go"), entry("Add c", "c.go")},
+			want:    "Update cmd, internal and .",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Generate(tt.entries, tt.opts).Subject; got != tt.want {
+				t.Errorf("Subject = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGenerateByDirectory(t *testing.T) {
+	big := entry("Move parser", "cmd/a.go", "internal/patch/parse.go")
+	big.Files[1].Insertions = 10
+	entries := []Entry{
+		entry("Add a", "cmd/a.go"),
+		big,
+		entry("Add b", "cmd/b.go", "README.md"),
+	}
+
+	got := Generate(entries, Options{GroupBy: GroupByDirectory, Depth: 0})
+	want := "Add a, Add b, Move parser\n\n" +
+		"cmd:\n- Add a\n- Add b\n\n" +
+		"internal/patch:\n- Move parser"
+	if got.String() != want {
+		t.Errorf("Generate() =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestGenerateSingle(t *testing.T) {
+	got := Generate([]Entry{entry("feat: a\n\nWhy a is needed\n")}, Options{})
+	if got.Subject != "feat: a" || got.Body != "Why a is needed" {
+		t.Errorf("Expected the message of the mini-commit, got %+v", got)
+	}
+	if got := Generate(nil, Options{}); got.String() != "" {
+		t.Errorf("Expected an empty message, got %q", got)
+	}
+}
@@ -0,0 +1,102 @@
+package tui
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// App is a full-screen program run by Run
+type App interface {
+	// View returns the lines of the screen: height lines of width columns
+	View(width, height int) []string
+	// Update handles a key press and reports whether the program is done
+	Update(key Key) (done bool)
+}
+
+// Input delivers the key presses; io.EOF ends the program
+type Input interface {
+	ReadKey() (Key, error)
+}
+
+// Screen shows the frames of a program
+type Screen interface {
+	Size() (width, height int)
+	Draw(lines []string) error
+}
+
+// Run draws app, then feeds it the keys from in and draws it again after each
+// one, until it is done or in has no more keys
+func Run(app App, in Input, screen Screen) error {
+	for {
+		width, height := screen.Size()
+		if err := screen.Draw(app.View(width, height)); err != nil {
+			return err
+		}
+
+		key, err := in.ReadKey()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if app.Update(key) {
+			return nil
+		}
+	}
+}
+
+// Script is an Input that plays keys back, to drive a program without a
+// terminal, e.g. in tests
+type Script struct {
+	keys []Key
+}
+
+// NewScript returns an Input that delivers keys, then io.EOF
+func NewScript(keys []Key) *Script {
+	return &Script{keys: keys}
+}
+
+// ReadKey returns the next key of the script
+func (s *Script) ReadKey() (Key, error) {
+	if len(s.keys) == 0 {
+		return "", io.EOF
+	}
+	key := s.keys[0]
+	s.keys = s.keys[1:]
+	return key, nil
+}
+
+// TextScreen is a Screen of a fixed size that writes every frame to w as
+// lines of text, each frame after a "--- frame N" line. Trailing spaces are
+// removed, so that the frames can be compared with expected text.
+type TextScreen struct {
+	w      io.Writer
+	width  int
+	height int
+	frames int
+}
+
+// NewTextScreen returns a TextScreen of width columns and height lines
+func NewTextScreen(w io.Writer, width, height int) *TextScreen {
+	return &TextScreen{w: w, width: width, height: height}
+}
+
+// Size returns the size the screen was created with
+func (s *TextScreen) Size() (int, int) {
+	return s.width, s.height
+}
+
+// Draw writes a frame
+func (s *TextScreen) Draw(lines []string) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "--- frame %d\n", s.frames)
+	for _, line := range lines {
+		b.WriteString(strings.TrimRight(line, " "))
+		b.WriteByte('\n')
+	}
+	s.frames++
+	_, err := io.WriteString(s.w, b.String())
+	return err
+}
@@ -0,0 +1,39 @@
+package tui
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// counter 押されたキーを数えるだけのアプリ
+type counter struct {
+	keys []Key
+}
+
+func (c *counter) View(width, height int) []string {
+	return []string{Fit(fmt.Sprintf("%d keys", len(c.keys)), width)}
+}
+
+func (c *counter) Update(key Key) bool {
+	c.keys = append(c.keys, key)
+	return key == "q"
+}
+
+func TestRun(t *testing.T) {
+	// 1. キーごとに再描画し、アプリが終了を返したら止まる
+	var out strings.Builder
+	app := &counter{}
+	if err := Run(app, NewScript([]Key{"j", "q", "k"}), NewTextScreen(&out, 10, 1)); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if want := "--- frame 0\n0 keys\n--- frame 1\n1 keys\n"; out.String() != want {
+		t.Errorf("Run() frames = %q, want %q", out.String(), want)
+	}
+
+	// 2. スクリプトが終わると止まる
+	app = &counter{}
+	if err := Run(app, NewScript([]Key{"j"}), NewTextScreen(&out, 10, 1)); err != nil || len(app.keys) != 1 {
+		t.Errorf("Run() = %v after %d keys", err, len(app.keys))
+	}
+}
@@ -0,0 +1,133 @@
+package tui
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Key is a key press: the character typed, such as "j" or "?", or the name of
+// a special key, such as "enter" or "ctrl-c"
+type Key string
+
+// Names of the special keys
+const (
+	KeyUp        Key = "up"
+	KeyDown      Key = "down"
+	KeyLeft      Key = "left"
+	KeyRight     Key = "right"
+	KeyHome      Key = "home"
+	KeyEnd       Key = "end"
+	KeyPageUp    Key = "pgup"
+	KeyPageDown  Key = "pgdown"
+	KeyEnter     Key = "enter"
+	KeyTab       Key = "tab"
+	KeySpace     Key = "space"
+	KeyBackspace Key = "backspace"
+	KeyEsc       Key = "esc"
+	KeyCtrlC     Key = "ctrl-c"
+)
+
+// specialKeys are the names a script can use besides single characters and ctrl-<letter>
+var specialKeys = map[Key]bool{
+	KeyUp: true, KeyDown: true, KeyLeft: true, KeyRight: true, KeyHome: true, KeyEnd: true,
+	KeyPageUp: true, KeyPageDown: true, KeyEnter: true, KeyTab: true, KeySpace: true,
+	KeyBackspace: true, KeyEsc: true,
+}
+
+// escapeSequences are the sequences terminals send for the special keys, after ESC
+var escapeSequences = map[string]Key{
+	"[A": KeyUp, "[B": KeyDown, "[C": KeyRight, "[D": KeyLeft,
+	"OA": KeyUp, "OB": KeyDown, "OC": KeyRight, "OD": KeyLeft,
+	"[H": KeyHome, "[F": KeyEnd, "OH": KeyHome, "OF": KeyEnd,
+	"[1~": KeyHome, "[7~": KeyHome, "[4~": KeyEnd, "[8~": KeyEnd,
+	"[5~": KeyPageUp, "[6~": KeyPageDown,
+}
+
+// DecodeKeys splits what a terminal in raw mode sent into key presses. Escape
+// sequences of keys that have no name are skipped.
+func DecodeKeys(b []byte) []Key {
+	var keys []Key
+	for len(b) > 0 {
+		switch c := b[0]; {
+		case c == 0x1b:
+			n := escapeLength(b)
+			if n == 1 {
+				keys = append(keys, KeyEsc)
+			} else if key, ok := escapeSequences[string(b[1:n])]; ok {
+				keys = append(keys, key)
+			}
+			b = b[n:]
+			continue
+		case c == '\r' || c == '\n':
+			keys = append(keys, KeyEnter)
+		case c == '\t':
+			keys = append(keys, KeyTab)
+		case c == ' ':
+			keys = append(keys, KeySpace)
+		case c == 0x7f || c == 0x08:
+			keys = append(keys, KeyBackspace)
+		case c >= 0x01 && c <= 0x1a:
+			keys = append(keys, Key("ctrl-"+string(rune('a'+c-1))))
+		case c < 0x20:
+			// Other control characters have no key of their own
+		default:
+			r, size := utf8.DecodeRune(b)
+			if r != utf8.RuneError {
+				keys = append(keys, Key(string(r)))
+			}
+			b = b[size:]
+			continue
+		}
+		b = b[1:]
+	}
+	return keys
+}
+
+// escapeLength returns the length of the escape sequence b starts with: ESC
+// alone, or ESC followed by "[" or "O" and the bytes up to the final letter or "~"
+func escapeLength(b []byte) int {
+	if len(b) < 2 || (b[1] != '[' && b[1] != 'O') {
+		return 1
+	}
+	for i := 2; i < len(b); i++ {
+		if b[i] >= 0x40 && b[i] <= 0x7e {
+			return i + 1
+		}
+	}
+	return len(b)
+}
+
+// ParseScript reads the keys of a script: key names separated by white space,
+// one or more per line, with lines starting with "#" ignored. A name is a
+// single character, ctrl-<letter> or the name of a special key, such as
+// "enter"; "space" stands for the space bar.
+func ParseScript(script string) ([]Key, error) {
+	var keys []Key
+	for n, line := range strings.Split(script, "\n") {
+		if strings.HasPrefix(strings.TrimSpace(line), "#") {
+			continue
+		}
+		for _, name := range strings.Fields(line) {
+			key := Key(name)
+			if !validKey(key) {
+				return nil, fmt.Errorf("line %d: unknown key '%s'", n+1, name)
+			}
+			keys = append(keys, key)
+		}
+	}
+	return keys, nil
+}
+
+// validKey reports whether key is a name DecodeKeys can return
+func validKey(key Key) bool {
+	if specialKeys[key] {
+		return true
+	}
+	if letter, ok := strings.CutPrefix(string(key), "ctrl-"); ok {
+		return len(letter) == 1 && letter[0] >= 'a' && letter[0] <= 'z'
+	}
+	r, size := utf8.DecodeRuneInString(string(key))
+	return size == len(key) && r != utf8.RuneError && unicode.IsPrint(r) && r != ' '
+}
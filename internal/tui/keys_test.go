@@ -0,0 +1,43 @@
+package tui
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDecodeKeys(t *testing.T) {
+	tests := []struct {
+		input string
+		want  []Key
+	}{
+		{"jk", []Key{"j", "k"}},
+		{"\033[A\033[B\033OH\033[6~", []Key{KeyUp, KeyDown, KeyHome, KeyPageDown}},
+		{"\033", []Key{KeyEsc}},
+		{"\r \t\x7f\x03", []Key{KeyEnter, KeySpace, KeyTab, KeyBackspace, KeyCtrlC}},
+		// 名前のないシーケンスは読み飛ばす
+		{"\033[1;5Aq", []Key{"q"}},
+		{"あ?", []Key{"あ", "?"}},
+	}
+	for _, tt := range tests {
+		if got := DecodeKeys([]byte(tt.input)); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("DecodeKeys(%q) = %q, want %q", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestParseScript(t *testing.T) {
+	keys, err := ParseScript("# 2つ下へ移動して削除\nj j\nd y\n\nenter space ctrl-c\n")
+	if err != nil {
+		t.Fatalf("ParseScript() error = %v", err)
+	}
+	want := []Key{"j", "j", "d", "y", KeyEnter, KeySpace, KeyCtrlC}
+	if !reflect.DeepEqual(keys, want) {
+		t.Errorf("ParseScript() = %q, want %q", keys, want)
+	}
+
+	for _, script := range []string{"jj", "q\nctrl-1", "F1"} {
+		if _, err := ParseScript(script); err == nil {
+			t.Errorf("ParseScript(%q): expected an error", script)
+		}
+	}
+}
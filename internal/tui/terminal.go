@@ -0,0 +1,144 @@
+package tui
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// Escape sequences that switch to the alternate screen, where the program
+// draws without scrolling the terminal, and back
+const (
+	enterScreen = "\033[?1049h\033[?25l"
+	leaveScreen = "\033[?25h\033[?1049l"
+)
+
+// Default size of a terminal that does not report one
+const (
+	defaultWidth  = 80
+	defaultHeight = 24
+)
+
+// Terminal is the Input and Screen of a program run full-screen on the
+// terminal of in and out. The terminal is switched to raw mode with stty, so
+// it is only available where stty is.
+type Terminal struct {
+	ctx     context.Context
+	in      *os.File
+	out     *os.File
+	state   string
+	pending []Key
+}
+
+// OpenTerminal switches the terminal to raw mode and to the alternate screen.
+// Close must be called to restore it. Reading keys stops with the error of ctx
+// once it is done.
+func OpenTerminal(ctx context.Context, in, out *os.File) (*Terminal, error) {
+	t := &Terminal{ctx: ctx, in: in, out: out}
+	state, err := t.stty("-g")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the terminal settings: %w", err)
+	}
+	t.state = strings.TrimSpace(state)
+	if err := t.enter(); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// enter switches to raw mode, in which reads wait at most a tenth of a second
+// so that ctx is checked, and to the alternate screen
+func (t *Terminal) enter() error {
+	if _, err := t.stty("raw", "-echo", "min", "0", "time", "1"); err != nil {
+		return fmt.Errorf("failed to switch the terminal to raw mode: %w", err)
+	}
+	_, err := io.WriteString(t.out, enterScreen)
+	return err
+}
+
+// Close restores the screen and the settings of the terminal
+func (t *Terminal) Close() error {
+	io.WriteString(t.out, leaveScreen)
+	if _, err := t.stty(t.state); err != nil {
+		return fmt.Errorf("failed to restore the terminal settings: %w", err)
+	}
+	return nil
+}
+
+// Suspend restores the terminal while fn runs, e.g. to let an editor use it
+func (t *Terminal) Suspend(fn func() error) error {
+	if err := t.Close(); err != nil {
+		return err
+	}
+	err := fn()
+	if enterErr := t.enter(); enterErr != nil && err == nil {
+		err = enterErr
+	}
+	return err
+}
+
+// Size returns the size of the terminal, asked for every frame so that a
+// resized terminal is redrawn to its new size
+func (t *Terminal) Size() (int, int) {
+	out, err := t.stty("size")
+	if err != nil {
+		return defaultWidth, defaultHeight
+	}
+	fields := strings.Fields(out)
+	if len(fields) != 2 {
+		return defaultWidth, defaultHeight
+	}
+	height, errH := strconv.Atoi(fields[0])
+	width, errW := strconv.Atoi(fields[1])
+	if errH != nil || errW != nil || width <= 0 || height <= 0 {
+		return defaultWidth, defaultHeight
+	}
+	return width, height
+}
+
+// Draw replaces the screen with lines; raw mode needs "\r\n" to start a line
+func (t *Terminal) Draw(lines []string) error {
+	var b strings.Builder
+	b.WriteString("\033[H")
+	for i, line := range lines {
+		if i > 0 {
+			b.WriteString("\r\n")
+		}
+		b.WriteString(line)
+		b.WriteString("\033[K")
+	}
+	b.WriteString("\033[J")
+	_, err := io.WriteString(t.out, b.String())
+	return err
+}
+
+// ReadKey waits for the next key press
+func (t *Terminal) ReadKey() (Key, error) {
+	buf := make([]byte, 64)
+	for len(t.pending) == 0 {
+		if err := t.ctx.Err(); err != nil {
+			return "", err
+		}
+		// A read that times out returns nothing, which os.File reports as io.EOF
+		n, err := t.in.Read(buf)
+		if err != nil && err != io.EOF {
+			return "", err
+		}
+		t.pending = DecodeKeys(buf[:n])
+	}
+	key := t.pending[0]
+	t.pending = t.pending[1:]
+	return key, nil
+}
+
+// stty runs stty on the terminal and returns its output
+func (t *Terminal) stty(args ...string) (string, error) {
+	cmd := exec.Command("stty", args...)
+	cmd.Stdin = t.in
+	out, err := cmd.Output()
+	return string(out), err
+}
@@ -0,0 +1,110 @@
+package tui
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// tabWidth is the distance between tab stops
+const tabWidth = 8
+
+// reset ends the colors of a line that was cut
+const reset = "\033[m"
+
+// Width returns the number of columns s takes on a terminal. ANSI escape
+// sequences take none, tabs reach the next tab stop and wide characters, such
+// as those of Japanese, take two.
+func Width(s string) int {
+	width := 0
+	for i := 0; i < len(s); {
+		if n := escapeAt(s, i); n > 0 {
+			i += n
+			continue
+		}
+		r, size := utf8.DecodeRuneInString(s[i:])
+		i += size
+		if r == '\t' {
+			width += tabWidth - width%tabWidth
+		} else {
+			width += runeWidth(r)
+		}
+	}
+	return width
+}
+
+// Fit cuts or pads s with spaces to exactly width columns. Tabs are expanded
+// and other control characters dropped, so that the line cannot move the
+// cursor; ANSI escape sequences are kept, and the colors are reset after them.
+func Fit(s string, width int) string {
+	var b strings.Builder
+	col := 0
+	colored := false
+	for i := 0; i < len(s) && col < width; {
+		if n := escapeAt(s, i); n > 0 {
+			b.WriteString(s[i : i+n])
+			colored = true
+			i += n
+			continue
+		}
+		r, size := utf8.DecodeRuneInString(s[i:])
+		i += size
+		if r == '\t' {
+			stop := col + tabWidth - col%tabWidth
+			for ; col < stop && col < width; col++ {
+				b.WriteByte(' ')
+			}
+			continue
+		}
+		w := runeWidth(r)
+		if w == 0 && unicode.IsControl(r) {
+			continue
+		}
+		if col+w > width {
+			break
+		}
+		b.WriteRune(r)
+		col += w
+	}
+	if colored {
+		b.WriteString(reset)
+	}
+	if col < width {
+		b.WriteString(strings.Repeat(" ", width-col))
+	}
+	return b.String()
+}
+
+// escapeAt returns the length of the ANSI escape sequence at s[i], or 0
+func escapeAt(s string, i int) int {
+	if s[i] != 0x1b || i+1 >= len(s) || s[i+1] != '[' {
+		return 0
+	}
+	for j := i + 2; j < len(s); j++ {
+		if s[j] >= 0x40 && s[j] <= 0x7e {
+			return j - i + 1
+		}
+	}
+	return len(s) - i
+}
+
+// runeWidth returns the columns r takes: none for control characters and
+// combining marks, two for the wide characters of East Asian scripts
+func runeWidth(r rune) int {
+	switch {
+	case unicode.IsControl(r), unicode.Is(unicode.Mn, r), unicode.Is(unicode.Me, r):
+		return 0
+	case r >= 0x1100 && r <= 0x115f, // Hangul Jamo
+		r >= 0x2e80 && r <= 0xa4cf && r != 0x303f, // CJK, Hiragana, Katakana
+		r >= 0xac00 && r <= 0xd7a3,                // Hangul syllables
+		r >= 0xf900 && r <= 0xfaff,                // CJK compatibility ideographs
+		r >= 0xfe30 && r <= 0xfe4f,                // CJK compatibility forms
+		r >= 0xff00 && r <= 0xff60,                // fullwidth forms
+		r >= 0xffe0 && r <= 0xffe6,
+		r >= 0x1f300 && r <= 0x1f64f, // emoji
+		r >= 0x1f900 && r <= 0x1f9ff,
+		r >= 0x20000 && r <= 0x3fffd:
+		return 2
+	}
+	return 1
+}
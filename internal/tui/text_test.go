@@ -0,0 +1,46 @@
+package tui
+
+import "testing"
+
+func TestWidth(t *testing.T) {
+	tests := []struct {
+		s    string
+		want int
+	}{
+		{"abc", 3},
+		{"\033[32m+added\033[m", 6},
+		{"a\tb", 9},
+		{"日本語", 6},
+	}
+	for _, tt := range tests {
+		if got := Width(tt.s); got != tt.want {
+			t.Errorf("Width(%q) = %d, want %d", tt.s, got, tt.want)
+		}
+	}
+}
+
+func TestFit(t *testing.T) {
+	tests := []struct {
+		s     string
+		width int
+		want  string
+	}{
+		{"abc", 5, "abc  "},
+		{"abcdef", 4, "abcd"},
+		{"\033[31m-removed\033[m", 4, "\033[31m-rem\033[m"},
+		{"\033[1mab\033[m", 4, "\033[1mab\033[m\033[m  "},
+		{"a\tb", 4, "a   "},
+		// 幅の足りない全角文字は空白で埋める
+		{"日本語", 5, "日本 "},
+		{"a\rb", 3, "ab "},
+	}
+	for _, tt := range tests {
+		got := Fit(tt.s, tt.width)
+		if got != tt.want {
+			t.Errorf("Fit(%q, %d) = %q, want %q", tt.s, tt.width, got, tt.want)
+		}
+		if Width(got) != tt.width {
+			t.Errorf("Fit(%q, %d) is %d columns wide", tt.s, tt.width, Width(got))
+		}
+	}
+}
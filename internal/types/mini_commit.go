@@ -2,14 +2,28 @@ package types
 
 import (
 	"time"
+
+	"git-mini-commit/internal/patch"
 )
 
 // MiniCommit mini-commitのデータ構造
 type MiniCommit struct {
-	ID        string    `json:"id"`        // SHA1ハッシュ
-	Message   string    `json:"message"`   // コミットメッセージ
-	CreatedAt time.Time `json:"createdAt"` // 作成日時
-	Patch     string    `json:"patch"`     // 差分（patch形式）
+	ID        string       `json:"id"`                  // SHA1ハッシュ
+	Message   string       `json:"message"`             // コミットメッセージ
+	CreatedAt time.Time    `json:"createdAt"`           // 作成日時
+	Branch    string       `json:"branch,omitempty"`    // 作成時のブランチ（detached HEADの場合は空）
+	Author    string       `json:"author,omitempty"`    // 作成者（"Name <email>"）
+	Base      string       `json:"base,omitempty"`      // 作成時のHEADコミット（初回コミット前は空）
+	Trailers  []Trailer    `json:"trailers,omitempty"`  // --trailer で指定したトレーラー（メッセージには含めない）
+	Stats     *patch.Stats `json:"stats,omitempty"`     // 変更の統計（index.jsonに保存し、一覧でパッチを読まずに済ませる）
+	PatchHash string       `json:"patchHash,omitempty"` // パッチを保存したオブジェクトの名前（内容のSHA1）
+	Patch     string       `json:"patch,omitempty"`     // 差分（patch形式）。index.jsonには保存せず、必要な時にオブジェクトから読み込む
+}
+
+// Trailer "Key: value" 形式のメタデータ（git interpret-trailers のトレーラーと同じ）
+type Trailer struct {
+	Key   string `json:"key"`
+	Value string `json:"value"`
 }
 
 // MiniCommitList mini-commitの一覧
@@ -0,0 +1,11 @@
+// Package version holds the version of git-mini-commit. Release builds set it with
+// -ldflags "-X git-mini-commit/internal/version.Version=<version>".
+package version
+
+// Version is the version of this build; "dev" when built without ldflags
+var Version = "dev"
+
+// String returns the program name with its version, as recorded in the store
+func String() string {
+	return "git-mini-commit " + Version
+}
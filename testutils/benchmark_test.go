@@ -1,10 +1,15 @@
 package testutils
 
 import (
+	"encoding/json"
 	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
+	patchpkg "git-mini-commit/internal/patch"
 	"git-mini-commit/internal/storage"
 	"git-mini-commit/internal/types"
 )
@@ -316,3 +321,109 @@ func BenchmarkStorageScalability(b *testing.B) {
 		})
 	}
 }
+
+// seedLargeStore 大きなパッチを持つmini-commitをn件、形式3のストアとして直接書き込む
+// （SaveMiniCommitを繰り返すと準備だけで件数の2乗の時間がかかるため）。
+// 次のNewStorageで現在の形式へ移行される
+func seedLargeStore(b *testing.B, n, patchLines int) {
+	b.Helper()
+
+	var body strings.Builder
+	for i := 0; i < patchLines; i++ {
+		fmt.Fprintf(&body, "+line %d of a fairly large change to exercise patch loading\n", i)
+	}
+
+	dir := storage.MiniCommitsDir
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		b.Fatalf("Failed to create store: %v", err)
+	}
+	list := make(types.MiniCommitList, 0, n)
+	start := time.Now().Add(-time.Duration(n) * time.Second)
+	for i := 0; i < n; i++ {
+		p := fmt.Sprintf("diff --git a/file%d.txt b/file%d.txt\nnew file mode 100644\n--- /dev/null\n+++ b/file%d.txt\n@@ -0,0 +1,%d @@\n%s",
+			i, i, i, patchLines, body.String())
+		createdAt := start.Add(time.Duration(i) * time.Second)
+		stats := patchpkg.ComputeStats(p)
+		mc := types.MiniCommit{ID: fmt.Sprintf("%040x", i), Message: fmt.Sprintf("Change %d", i), CreatedAt: createdAt, Stats: &stats}
+		if err := os.WriteFile(filepath.Join(dir, mc.ID+".patch"), []byte(p), 0644); err != nil {
+			b.Fatalf("Failed to write patch: %v", err)
+		}
+		list = append(list, mc)
+	}
+
+	index := map[string]interface{}{
+		"formatVersion": 3,
+		"toolVersion":   "benchmark",
+		"createdBy":     "benchmark",
+		"miniCommits":   list,
+	}
+	data, err := json.Marshal(index)
+	if err != nil {
+		b.Fatalf("Failed to serialize index: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, storage.IndexFile), data, 0644); err != nil {
+		b.Fatalf("Failed to write index: %v", err)
+	}
+}
+
+// BenchmarkListLargeStore 一覧表示（メタデータと統計のみ）は、パッチの総量に関係なく
+// 件数に比例した時間で済むことを確認する。WithPatches は全パッチを読む場合の比較用
+func BenchmarkListLargeStore(b *testing.B) {
+	sizes := []int{100, 1000, 5000}
+	if testing.Short() {
+		sizes = []int{100, 1000}
+	}
+
+	for _, size := range sizes {
+		repo := NewTestGitRepo(&testing.T{})
+		seedLargeStore(b, size, 200)
+
+		s, err := storage.NewStorage()
+		if err != nil {
+			b.Fatalf("Failed to create storage: %v", err)
+		}
+
+		b.Run(fmt.Sprintf("List%d", size), func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				list, err := s.LoadMiniCommits()
+				if err != nil {
+					b.Fatalf("LoadMiniCommits() error = %v", err)
+				}
+				insertions := 0
+				for j := range list {
+					insertions += list[j].Stats.Insertions
+				}
+				if insertions != size*200 {
+					b.Fatalf("Expected %d insertions, got %d", size*200, insertions)
+				}
+			}
+		})
+
+		b.Run(fmt.Sprintf("WithPatches%d", size), func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				list, err := s.LoadMiniCommits()
+				if err != nil {
+					b.Fatalf("LoadMiniCommits() error = %v", err)
+				}
+				for j := range list {
+					if err := s.LoadPatch(&list[j]); err != nil {
+						b.Fatalf("LoadPatch() error = %v", err)
+					}
+				}
+			}
+		})
+
+		b.Run(fmt.Sprintf("Show%d", size), func(b *testing.B) {
+			id := fmt.Sprintf("%040x", size/2)
+			for i := 0; i < b.N; i++ {
+				if _, err := s.GetMiniCommit(id); err != nil {
+					b.Fatalf("GetMiniCommit() error = %v", err)
+				}
+			}
+		})
+
+		repo.Cleanup()
+	}
+}
@@ -2,6 +2,7 @@ package testutils
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
 	"os"
 	"os/exec"
@@ -168,7 +169,8 @@ func (s *TestStorage) AssertMiniCommitCount(t *testing.T, expected int) {
 
 // TestCLI テスト用CLI実行
 type TestCLI struct {
-	repo *TestGitRepo
+	repo  *TestGitRepo
+	stdin string
 }
 
 // NewTestCLI テスト用CLIを作成
@@ -181,6 +183,11 @@ func (c *TestCLI) SetRepo(repo *TestGitRepo) {
 	c.repo = repo
 }
 
+// SetStdin コマンドの標準入力を設定
+func (c *TestCLI) SetStdin(input string) {
+	c.stdin = input
+}
+
 // RunCommand CLIコマンドを実行
 func (c *TestCLI) RunCommand(args ...string) (string, string, error) {
 	// 元のプロジェクトディレクトリのバイナリを使用
@@ -242,6 +249,9 @@ func (c *TestCLI) RunCommand(args ...string) (string, string, error) {
 	var stdout, stderr bytes.Buffer
 	cmd.Stdout = &stdout
 	cmd.Stderr = &stderr
+	if c.stdin != "" {
+		cmd.Stdin = strings.NewReader(c.stdin)
+	}
 
 	// テスト用のストレージディレクトリを設定
 	if c.repo != nil {
@@ -286,6 +296,16 @@ func (c *TestCLI) AssertCommandFailure(t *testing.T, args ...string) string {
 	return combined
 }
 
+// AssertExitCode コマンドが指定した終了コードで失敗することを確認
+func (c *TestCLI) AssertExitCode(t *testing.T, code int, args ...string) string {
+	stdout, stderr, err := c.RunCommand(args...)
+	var exitErr *exec.ExitError
+	if !errors.As(err, &exitErr) || exitErr.ExitCode() != code {
+		t.Errorf("Expected %v to exit with %d, got %v. stderr: %s", args, code, err, stderr)
+	}
+	return stdout + stderr
+}
+
 // AssertOutputContains 出力に特定の文字列が含まれることを確認
 func (c *TestCLI) AssertOutputContains(t *testing.T, output, expected string) {
 	if !strings.Contains(output, expected) {